@@ -2,10 +2,13 @@ package channels
 
 import (
 	"bytes"
+	"encoding/json"
+	"time"
 
 	"github.com/ipfs/go-cid"
 	"github.com/ipld/go-ipld-prime"
 	"github.com/ipld/go-ipld-prime/codec/dagcbor"
+	"github.com/ipld/go-ipld-prime/codec/dagjson"
 	basicnode "github.com/ipld/go-ipld-prime/node/basic"
 	peer "github.com/libp2p/go-libp2p-core/peer"
 	cbg "github.com/whyrusleeping/cbor-gen"
@@ -40,6 +43,16 @@ type channelState struct {
 	sent uint64
 	// total bytes received by this node (0 if sender)
 	received uint64
+	// when a timed pause ends, in nanoseconds since the unix epoch
+	pausedUntil int64
+	// the name of the transport that moves the data
+	transport string
+	// the initiator's signature over the request, if it was signed
+	requestSignature *datatransfer.RequestSignature
+	// the part of the request the initiator signed, if it was signed
+	signedRequest *datatransfer.SignedRequest
+	// the receipt signed by the receiver for the data it received
+	receipt *datatransfer.Receipt
 	// more informative status on a channel
 	message string
 	// additional vouchers
@@ -60,6 +73,42 @@ func (c channelState) Status() datatransfer.Status { return c.status }
 // Received returns the number of bytes received
 func (c channelState) Queued() uint64 { return c.queued }
 
+// PausedUntil returns when this node will resume a timed pause, or the zero
+// time if there is none
+func (c channelState) PausedUntil() time.Time {
+	if c.pausedUntil == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, c.pausedUntil)
+}
+
+// TransportName returns the name of the transport that moves the data for
+// this channel
+func (c channelState) TransportName() string {
+	if c.transport == "" {
+		return datatransfer.DefaultTransportName
+	}
+	return c.transport
+}
+
+// RequestSignature returns the initiator's signature over the request that
+// opened this channel, or nil if the request was not signed
+func (c channelState) RequestSignature() *datatransfer.RequestSignature {
+	return c.requestSignature
+}
+
+// SignedRequest returns the part of the request that opened this channel that
+// the initiator signed, or nil if the request was not signed
+func (c channelState) SignedRequest() *datatransfer.SignedRequest {
+	return c.signedRequest
+}
+
+// Receipt returns the receipt the receiver signed for the data it received on
+// this channel once it completed, or nil if there is none
+func (c channelState) Receipt() *datatransfer.Receipt {
+	return c.receipt
+}
+
 // Sent returns the number of bytes sent
 func (c channelState) Sent() uint64 { return c.sent }
 
@@ -171,6 +220,100 @@ func (c channelState) OtherPeer() peer.ID {
 	return c.sender
 }
 
+// voucherJSON is the JSON representation of a voucher or voucher result. The
+// decoded form is only present when the type is registered and decodes
+type voucherJSON struct {
+	Type    datatransfer.TypeIdentifier
+	Raw     []byte
+	Decoded interface{} `json:",omitempty"`
+}
+
+func encodeVoucherJSON(decoderByType DecoderByTypeFunc, typ datatransfer.TypeIdentifier, raw []byte) voucherJSON {
+	encoded := voucherJSON{Type: typ, Raw: raw}
+	decoder, has := decoderByType(typ)
+	if !has {
+		return encoded
+	}
+	decoded, err := decoder.DecodeFromCbor(raw)
+	if err != nil {
+		return encoded
+	}
+	// raw vouchers carry nothing more than the encoded form
+	if _, ok := decoded.(*RawVoucher); !ok {
+		encoded.Decoded = decoded
+	}
+	return encoded
+}
+
+// MarshalJSON encodes the channel state with human readable names for the
+// status, the selector as DAG-JSON, and vouchers decoded where possible
+func (c channelState) MarshalJSON() ([]byte, error) {
+	var selector json.RawMessage
+	if c.selector != nil {
+		buf := new(bytes.Buffer)
+		if err := dagjson.Encoder(c.Selector(), buf); err != nil {
+			return nil, err
+		}
+		selector = buf.Bytes()
+	}
+	vouchers := make([]voucherJSON, 0, len(c.vouchers))
+	for _, encoded := range c.vouchers {
+		vouchers = append(vouchers, encodeVoucherJSON(c.voucherDecoder, encoded.Type, encoded.Voucher.Raw))
+	}
+	var pausedUntil *time.Time
+	if c.pausedUntil != 0 {
+		t := c.PausedUntil()
+		pausedUntil = &t
+	}
+	voucherResults := make([]voucherJSON, 0, len(c.voucherResults))
+	for _, encoded := range c.voucherResults {
+		voucherResults = append(voucherResults, encodeVoucherJSON(c.voucherResultDecoder, encoded.Type, encoded.VoucherResult.Raw))
+	}
+	return json.Marshal(struct {
+		ChannelID        datatransfer.ChannelID
+		Status           string
+		SelfPeer         peer.ID
+		Sender           peer.ID
+		Recipient        peer.ID
+		IsPull           bool
+		BaseCID          cid.Cid
+		Selector         json.RawMessage `json:",omitempty"`
+		TotalSize        uint64
+		Queued           uint64
+		Sent             uint64
+		Received         uint64
+		Message          string
+		Vouchers         []voucherJSON
+		VoucherResults   []voucherJSON
+		PausedUntil      *time.Time `json:",omitempty"`
+		Transport        string
+		RequestSignature *datatransfer.RequestSignature `json:",omitempty"`
+		SignedRequest    *datatransfer.SignedRequest    `json:",omitempty"`
+		Receipt          *datatransfer.Receipt          `json:",omitempty"`
+	}{
+		ChannelID:        c.ChannelID(),
+		Status:           datatransfer.Statuses[c.status],
+		SelfPeer:         c.selfPeer,
+		Sender:           c.sender,
+		Recipient:        c.recipient,
+		IsPull:           c.isPull,
+		BaseCID:          c.baseCid,
+		Selector:         selector,
+		TotalSize:        c.totalSize,
+		Queued:           c.queued,
+		Sent:             c.sent,
+		Received:         c.received,
+		Message:          c.message,
+		Vouchers:         vouchers,
+		VoucherResults:   voucherResults,
+		PausedUntil:      pausedUntil,
+		Transport:        c.TransportName(),
+		RequestSignature: c.requestSignature,
+		SignedRequest:    c.signedRequest,
+		Receipt:          c.receipt,
+	})
+}
+
 func fromInternalChannelState(c internal.ChannelState, voucherDecoder DecoderByTypeFunc, voucherResultDecoder DecoderByTypeFunc, channelCIDsReader ChannelCIDsReader) datatransfer.ChannelState {
 	return channelState{
 		selfPeer:             c.SelfPeer,
@@ -188,6 +331,11 @@ func fromInternalChannelState(c internal.ChannelState, voucherDecoder DecoderByT
 		message:              c.Message,
 		vouchers:             c.Vouchers,
 		voucherResults:       c.VoucherResults,
+		pausedUntil:          c.PausedUntil,
+		transport:            c.Transport,
+		requestSignature:     c.RequestSignature,
+		signedRequest:        c.SignedRequest,
+		receipt:              c.Receipt,
 		voucherResultDecoder: voucherResultDecoder,
 		voucherDecoder:       voucherDecoder,
 		channelCIDsReader:    channelCIDsReader,
@@ -12,8 +12,8 @@ import (
 	cbg "github.com/whyrusleeping/cbor-gen"
 	"golang.org/x/xerrors"
 
-	versioning "github.com/filecoin-project/go-ds-versioning/pkg"
 	versionedfsm "github.com/filecoin-project/go-ds-versioning/pkg/fsm"
+	"github.com/filecoin-project/go-statemachine"
 	"github.com/filecoin-project/go-statemachine/fsm"
 
 	datatransfer "github.com/filecoin-project/go-data-transfer"
@@ -61,6 +61,14 @@ type ChannelEnvironment interface {
 	Unprotect(id peer.ID, tag string) bool
 	ID() peer.ID
 	CleanupChannel(chid datatransfer.ChannelID)
+	// CompleteChannel is called when a channel finishes successfully, before it
+	// is cleaned up. If it returns an error the channel fails instead
+	CompleteChannel(chid datatransfer.ChannelID) error
+	// IssueReceipt is called when a channel this node received data on
+	// completes, with a receipt for the bytes it received. It counts the
+	// blocks received and signs the receipt, or returns nil if this node does
+	// not sign receipts
+	IssueReceipt(receipt datatransfer.Receipt) (*datatransfer.Receipt, error)
 }
 
 // New returns a new thread safe list of channels
@@ -89,7 +97,7 @@ func New(ds datastore.Batching,
 		StateEntryFuncs: ChannelStateEntryFuncs,
 		Notifier:        c.dispatch,
 		FinalityStates:  ChannelFinalityStates,
-	}, channelMigrations, versioning.VersionKey("2"))
+	}, channelMigrations, StoreVersion)
 	if err != nil {
 		return nil, err
 	}
@@ -120,8 +128,9 @@ func (c *Channels) dispatch(eventName fsm.EventName, channel fsm.StateType) {
 }
 
 // CreateNew creates a new channel id and channel state and saves to channels.
+// The transport is the name of the transport that moves the channel's data.
 // returns error if the channel exists already.
-func (c *Channels) CreateNew(selfPeer peer.ID, tid datatransfer.TransferID, baseCid cid.Cid, selector ipld.Node, voucher datatransfer.Voucher, initiator, dataSender, dataReceiver peer.ID) (datatransfer.ChannelID, error) {
+func (c *Channels) CreateNew(selfPeer peer.ID, tid datatransfer.TransferID, baseCid cid.Cid, selector ipld.Node, voucher datatransfer.Voucher, initiator, dataSender, dataReceiver peer.ID, transport string) (datatransfer.ChannelID, error) {
 	var responder peer.ID
 	if dataSender == initiator {
 		responder = dataReceiver
@@ -154,7 +163,8 @@ func (c *Channels) CreateNew(selfPeer peer.ID, tid datatransfer.TransferID, base
 				},
 			},
 		},
-		Status: datatransfer.Requested,
+		Status:    datatransfer.Requested,
+		Transport: transport,
 	})
 	if err != nil {
 		return datatransfer.ChannelID{}, err
@@ -192,6 +202,18 @@ func (c *Channels) GetByID(ctx context.Context, chid datatransfer.ChannelID) (da
 	return fromInternalChannelState(internalChannel, c.voucherDecoder, c.voucherResultDecoder, c.cidLists.ReadList), nil
 }
 
+// Peek returns the stored state of a channel without waiting for the events
+// that are still being processed, so it can be called while one of the
+// channel's events is handled
+func (c *Channels) Peek(chid datatransfer.ChannelID) (datatransfer.ChannelState, error) {
+	var internalChannel internal.ChannelState
+	err := c.stateMachines.Get(chid).Get(&internalChannel)
+	if err != nil {
+		return nil, NewErrNotFound(chid)
+	}
+	return fromInternalChannelState(internalChannel, c.voucherDecoder, c.voucherResultDecoder, c.cidLists.ReadList), nil
+}
+
 // Accept marks a data transfer as accepted
 func (c *Channels) Accept(chid datatransfer.ChannelID) error {
 	return c.send(chid, datatransfer.Accept)
@@ -232,6 +254,44 @@ func (c *Channels) PauseResponder(chid datatransfer.ChannelID) error {
 	return c.send(chid, datatransfer.PauseResponder)
 }
 
+// TimedPause records that this node will resume the channel at the given time
+func (c *Channels) TimedPause(chid datatransfer.ChannelID, until time.Time) error {
+	return c.send(chid, datatransfer.TimedPause, until)
+}
+
+// RequestSigned records the initiator's signature over the request that
+// opened a channel, and the part of the request it signed
+func (c *Channels) RequestSigned(chid datatransfer.ChannelID, signed datatransfer.SignedRequest, sig *datatransfer.RequestSignature) error {
+	return c.send(chid, datatransfer.RequestSigned, &signed, sig)
+}
+
+// ReceiptReceived records the receipt the receiver sent for the data it
+// received on a channel this node sent data on. The receipt usually arrives
+// after the channel has ended and its state machine has stopped, in which
+// case it is written to the stored channel state directly
+func (c *Channels) ReceiptReceived(chid datatransfer.ChannelID, receipt *datatransfer.Receipt) error {
+	err := c.send(chid, datatransfer.ReceiptReceived, receipt)
+	if !xerrors.Is(err, statemachine.ErrTerminated) {
+		return err
+	}
+	var updated internal.ChannelState
+	err = c.stateMachines.Get(chid).Mutate(func(chst *internal.ChannelState) error {
+		chst.Receipt = receipt
+		updated = *chst
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	c.dispatch(datatransfer.ReceiptReceived, updated)
+	return nil
+}
+
+// TimedPauseExpired records that the timed pause on this channel has ended
+func (c *Channels) TimedPauseExpired(chid datatransfer.ChannelID) error {
+	return c.send(chid, datatransfer.TimedPauseExpired)
+}
+
 // ResumeInitiator resumes the initator of this channel
 func (c *Channels) ResumeInitiator(chid datatransfer.ChannelID) error {
 	return c.send(chid, datatransfer.ResumeInitiator)
@@ -1,8 +1,11 @@
 package channels
 
 import (
+	"time"
+
 	logging "github.com/ipfs/go-log/v2"
 	cbg "github.com/whyrusleeping/cbor-gen"
+	"golang.org/x/xerrors"
 
 	"github.com/filecoin-project/go-statemachine/fsm"
 
@@ -80,20 +83,50 @@ var ChannelEvents = fsm.Events{
 	fsm.Event(datatransfer.PauseInitiator).
 		FromMany(datatransfer.Requested, datatransfer.Ongoing).To(datatransfer.InitiatorPaused).
 		From(datatransfer.ResponderPaused).To(datatransfer.BothPaused).
-		FromAny().ToJustRecord(),
+		FromAny().ToJustRecord().
+		Action(clearTimedPause(true)),
 	fsm.Event(datatransfer.PauseResponder).
 		FromMany(datatransfer.Requested, datatransfer.Ongoing).To(datatransfer.ResponderPaused).
 		From(datatransfer.InitiatorPaused).To(datatransfer.BothPaused).
-		FromAny().ToJustRecord(),
+		FromAny().ToJustRecord().
+		Action(clearTimedPause(false)),
 	fsm.Event(datatransfer.ResumeInitiator).
 		From(datatransfer.InitiatorPaused).To(datatransfer.Ongoing).
 		From(datatransfer.BothPaused).To(datatransfer.ResponderPaused).
-		FromAny().ToJustRecord(),
+		FromAny().ToJustRecord().
+		Action(clearTimedPause(true)),
 	fsm.Event(datatransfer.ResumeResponder).
 		From(datatransfer.ResponderPaused).To(datatransfer.Ongoing).
 		From(datatransfer.BothPaused).To(datatransfer.InitiatorPaused).
 		From(datatransfer.Finalizing).To(datatransfer.Completing).
-		FromAny().ToJustRecord(),
+		FromAny().ToJustRecord().
+		Action(clearTimedPause(false)),
+	fsm.Event(datatransfer.TimedPause).FromAny().ToNoChange().
+		Action(func(chst *internal.ChannelState, until time.Time) error {
+			chst.PausedUntil = until.UnixNano()
+			return nil
+		}),
+	fsm.Event(datatransfer.TimedPauseExpired).FromAny().ToNoChange().
+		Action(func(chst *internal.ChannelState) error {
+			chst.PausedUntil = 0
+			return nil
+		}),
+	fsm.Event(datatransfer.RequestSigned).FromAny().ToNoChange().
+		Action(func(chst *internal.ChannelState, signed *datatransfer.SignedRequest, sig *datatransfer.RequestSignature) error {
+			chst.SignedRequest = signed
+			chst.RequestSignature = sig
+			return nil
+		}),
+	fsm.Event(datatransfer.ReceiptIssued).FromAny().ToNoChange().
+		Action(func(chst *internal.ChannelState, receipt *datatransfer.Receipt) error {
+			chst.Receipt = receipt
+			return nil
+		}),
+	fsm.Event(datatransfer.ReceiptReceived).FromAny().ToNoChange().
+		Action(func(chst *internal.ChannelState, receipt *datatransfer.Receipt) error {
+			chst.Receipt = receipt
+			return nil
+		}),
 	fsm.Event(datatransfer.FinishTransfer).
 		FromAny().To(datatransfer.TransferFinished).
 		FromMany(datatransfer.Failing, datatransfer.Cancelling).ToJustRecord().
@@ -121,12 +154,55 @@ var ChannelEvents = fsm.Events{
 	fsm.Event(datatransfer.CompleteCleanupOnRestart).FromAny().ToNoChange(),
 }
 
+// clearTimedPause returns an action that clears the deadline of a timed pause
+// when this node pauses or resumes again, since that replaces the timed pause.
+// Pauses and resumes of the other node leave it alone
+func clearTimedPause(initiator bool) func(chst *internal.ChannelState) error {
+	return func(chst *internal.ChannelState) error {
+		if (chst.SelfPeer == chst.Initiator) == initiator {
+			chst.PausedUntil = 0
+		}
+		return nil
+	}
+}
+
 // ChannelStateEntryFuncs are handlers called as we enter different states
 // (currently unused for this fsm)
 var ChannelStateEntryFuncs = fsm.StateEntryFuncs{
 	datatransfer.Cancelling: cleanupConnection,
 	datatransfer.Failing:    cleanupConnection,
-	datatransfer.Completing: cleanupConnection,
+	datatransfer.Completing: completeChannel,
+}
+
+func completeChannel(ctx fsm.Context, env ChannelEnvironment, channel internal.ChannelState) error {
+	chid := datatransfer.ChannelID{ID: channel.TransferID, Initiator: channel.Initiator, Responder: channel.Responder}
+	if err := env.CompleteChannel(chid); err != nil {
+		return ctx.Trigger(datatransfer.Error, xerrors.Errorf("unable to complete channel: %w", err))
+	}
+	if channel.Recipient == env.ID() {
+		if err := issueReceipt(ctx, env, chid, channel); err != nil {
+			log.Warnf("unable to issue receipt for channel %s: %s", chid, err)
+		}
+	}
+	return cleanupConnection(ctx, env, channel)
+}
+
+func issueReceipt(ctx fsm.Context, env ChannelEnvironment, chid datatransfer.ChannelID, channel internal.ChannelState) error {
+	selectorHash, err := datatransfer.SelectorHash(channelState{selector: channel.Selector}.Selector())
+	if err != nil {
+		return err
+	}
+	receipt, err := env.IssueReceipt(datatransfer.Receipt{
+		ChannelID:    chid,
+		Receiver:     channel.Recipient,
+		Root:         channel.BaseCid,
+		SelectorHash: selectorHash,
+		Bytes:        channel.Received,
+	})
+	if err != nil || receipt == nil {
+		return err
+	}
+	return ctx.Trigger(datatransfer.ReceiptIssued, receipt)
 }
 
 func cleanupConnection(ctx fsm.Context, env ChannelEnvironment, channel internal.ChannelState) error {
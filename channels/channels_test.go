@@ -3,6 +3,7 @@ package channels_test
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"math/rand"
 	"os"
@@ -11,10 +12,12 @@ import (
 
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-datastore"
+	dss "github.com/ipfs/go-datastore/sync"
 	"github.com/ipld/go-ipld-prime/codec/dagcbor"
 	basicnode "github.com/ipld/go-ipld-prime/node/basic"
 	"github.com/ipld/go-ipld-prime/traversal/selector/builder"
 	peer "github.com/libp2p/go-libp2p-core/peer"
+	libp2ptest "github.com/libp2p/go-libp2p-core/test"
 	"github.com/stretchr/testify/require"
 	cbg "github.com/whyrusleeping/cbor-gen"
 	"golang.org/x/xerrors"
@@ -60,19 +63,20 @@ func TestChannels(t *testing.T) {
 	err = channelList.Start(ctx)
 	require.NoError(t, err)
 	t.Run("adding channels", func(t *testing.T) {
-		chid, err := channelList.CreateNew(peers[0], tid1, cids[0], selector, fv1, peers[0], peers[0], peers[1])
+		chid, err := channelList.CreateNew(peers[0], tid1, cids[0], selector, fv1, peers[0], peers[0], peers[1], "")
 		require.NoError(t, err)
 		require.Equal(t, peers[0], chid.Initiator)
 		require.Equal(t, tid1, chid.ID)
 
 		// cannot add twice for same channel id
-		_, err = channelList.CreateNew(peers[0], tid1, cids[1], selector, fv2, peers[0], peers[1], peers[0])
+		_, err = channelList.CreateNew(peers[0], tid1, cids[1], selector, fv2, peers[0], peers[1], peers[0], "")
 		require.Error(t, err)
 		state := checkEvent(ctx, t, received, datatransfer.Open)
 		require.Equal(t, datatransfer.Requested, state.Status())
+		require.Equal(t, datatransfer.DefaultTransportName, state.TransportName())
 
 		// can add for different id
-		chid, err = channelList.CreateNew(peers[2], tid2, cids[1], selector, fv2, peers[3], peers[2], peers[3])
+		chid, err = channelList.CreateNew(peers[2], tid2, cids[1], selector, fv2, peers[3], peers[2], peers[3], "second")
 		require.NoError(t, err)
 		require.Equal(t, peers[3], chid.Initiator)
 		require.Equal(t, tid2, chid.ID)
@@ -80,6 +84,7 @@ func TestChannels(t *testing.T) {
 		require.Equal(t, datatransfer.Requested, state.Status())
 		require.Equal(t, peers[2], state.SelfPeer())
 		require.Equal(t, peers[3], state.OtherPeer())
+		require.Equal(t, "second", state.TransportName())
 	})
 
 	t.Run("in progress channels", func(t *testing.T) {
@@ -136,7 +141,7 @@ func TestChannels(t *testing.T) {
 		err = channelList.Start(ctx)
 		require.NoError(t, err)
 
-		_, err = channelList.CreateNew(peers[0], tid1, cids[0], selector, fv1, peers[0], peers[0], peers[1])
+		_, err = channelList.CreateNew(peers[0], tid1, cids[0], selector, fv1, peers[0], peers[0], peers[1], "")
 		require.NoError(t, err)
 		state := checkEvent(ctx, t, received, datatransfer.Open)
 		require.Equal(t, datatransfer.Requested, state.Status())
@@ -213,6 +218,43 @@ func TestChannels(t *testing.T) {
 		require.Equal(t, datatransfer.Ongoing, state.Status())
 	})
 
+	t.Run("timed pause", func(t *testing.T) {
+		chid := datatransfer.ChannelID{Initiator: peers[0], Responder: peers[1], ID: tid1}
+		until := time.Unix(0, time.Now().Add(time.Hour).UnixNano())
+		st, err := channelList.GetByID(ctx, chid)
+		require.NoError(t, err)
+		require.True(t, st.PausedUntil().IsZero())
+
+		require.NoError(t, channelList.PauseInitiator(chid))
+		_ = checkEvent(ctx, t, received, datatransfer.PauseInitiator)
+		require.NoError(t, channelList.TimedPause(chid, until))
+		st = checkEvent(ctx, t, received, datatransfer.TimedPause)
+		require.Equal(t, datatransfer.InitiatorPaused, st.Status())
+		require.True(t, until.Equal(st.PausedUntil()))
+
+		// pausing and resuming the other peer leaves the deadline alone
+		require.NoError(t, channelList.PauseResponder(chid))
+		st = checkEvent(ctx, t, received, datatransfer.PauseResponder)
+		require.True(t, until.Equal(st.PausedUntil()))
+		require.NoError(t, channelList.ResumeResponder(chid))
+		st = checkEvent(ctx, t, received, datatransfer.ResumeResponder)
+		require.True(t, until.Equal(st.PausedUntil()))
+
+		// resuming this peer clears it
+		require.NoError(t, channelList.ResumeInitiator(chid))
+		st = checkEvent(ctx, t, received, datatransfer.ResumeInitiator)
+		require.Equal(t, datatransfer.Ongoing, st.Status())
+		require.True(t, st.PausedUntil().IsZero())
+
+		// as does the deadline passing
+		require.NoError(t, channelList.TimedPause(chid, until))
+		st = checkEvent(ctx, t, received, datatransfer.TimedPause)
+		require.True(t, until.Equal(st.PausedUntil()))
+		require.NoError(t, channelList.TimedPauseExpired(chid))
+		st = checkEvent(ctx, t, received, datatransfer.TimedPauseExpired)
+		require.True(t, st.PausedUntil().IsZero())
+	})
+
 	t.Run("new vouchers & voucherResults", func(t *testing.T) {
 		fv3 := testutil.NewFakeDTType()
 		fvr1 := testutil.NewFakeDTType()
@@ -265,7 +307,7 @@ func TestChannels(t *testing.T) {
 		state = checkEvent(ctx, t, received, datatransfer.CleanupComplete)
 		require.Equal(t, datatransfer.Failed, state.Status())
 
-		chid, err := channelList.CreateNew(peers[0], tid2, cids[1], selector, fv2, peers[2], peers[1], peers[2])
+		chid, err := channelList.CreateNew(peers[0], tid2, cids[1], selector, fv2, peers[2], peers[1], peers[2], "")
 		require.NoError(t, err)
 		require.Equal(t, peers[2], chid.Initiator)
 		require.Equal(t, tid2, chid.ID)
@@ -282,7 +324,7 @@ func TestChannels(t *testing.T) {
 
 	t.Run("test self peer and other peer", func(t *testing.T) {
 		// sender is self peer
-		chid, err := channelList.CreateNew(peers[1], tid1, cids[0], selector, fv1, peers[1], peers[1], peers[2])
+		chid, err := channelList.CreateNew(peers[1], tid1, cids[0], selector, fv1, peers[1], peers[1], peers[2], "")
 		require.NoError(t, err)
 		ch, err := channelList.GetByID(context.Background(), chid)
 		require.NoError(t, err)
@@ -290,7 +332,7 @@ func TestChannels(t *testing.T) {
 		require.Equal(t, peers[2], ch.OtherPeer())
 
 		// recipient is self peer
-		chid, err = channelList.CreateNew(peers[2], datatransfer.TransferID(1001), cids[0], selector, fv1, peers[1], peers[2], peers[1])
+		chid, err = channelList.CreateNew(peers[2], datatransfer.TransferID(1001), cids[0], selector, fv1, peers[1], peers[2], peers[1], "")
 		require.NoError(t, err)
 		ch, err = channelList.GetByID(context.Background(), chid)
 		require.NoError(t, err)
@@ -312,7 +354,7 @@ func TestChannels(t *testing.T) {
 		err = channelList.Start(ctx)
 		require.NoError(t, err)
 
-		chid, err := channelList.CreateNew(peers[3], tid1, cids[0], selector, fv1, peers[3], peers[0], peers[3])
+		chid, err := channelList.CreateNew(peers[3], tid1, cids[0], selector, fv1, peers[3], peers[0], peers[3], "")
 		require.NoError(t, err)
 		state := checkEvent(ctx, t, received, datatransfer.Open)
 		require.Equal(t, datatransfer.Requested, state.Status())
@@ -326,7 +368,7 @@ func TestChannels(t *testing.T) {
 	t.Run("test self peer and other peer", func(t *testing.T) {
 		peers := testutil.GeneratePeers(3)
 		// sender is self peer
-		chid, err := channelList.CreateNew(peers[1], tid1, cids[0], selector, fv1, peers[1], peers[1], peers[2])
+		chid, err := channelList.CreateNew(peers[1], tid1, cids[0], selector, fv1, peers[1], peers[1], peers[2], "")
 		require.NoError(t, err)
 		ch, err := channelList.GetByID(context.Background(), chid)
 		require.NoError(t, err)
@@ -334,7 +376,7 @@ func TestChannels(t *testing.T) {
 		require.Equal(t, peers[2], ch.OtherPeer())
 
 		// recipient is self peer
-		chid, err = channelList.CreateNew(peers[2], datatransfer.TransferID(1001), cids[0], selector, fv1, peers[1], peers[2], peers[1])
+		chid, err = channelList.CreateNew(peers[2], datatransfer.TransferID(1001), cids[0], selector, fv1, peers[1], peers[2], peers[1], "")
 		require.NoError(t, err)
 		ch, err = channelList.GetByID(context.Background(), chid)
 		require.NoError(t, err)
@@ -621,7 +663,112 @@ func checkEvent(ctx context.Context, t *testing.T, received chan event, code dat
 	return evt.state
 }
 
+func TestInspector(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	ds := datastore.NewMapDatastore()
+	cidLists, err := cidlists.NewCIDLists(os.TempDir())
+	require.NoError(t, err)
+	peers := testutil.GeneratePeers(2)
+	voucher := testutil.NewFakeDTType()
+	inspector := channels.NewInspector(ds, cidLists)
+
+	// an unmigrated store cannot be read
+	require.NoError(t, ds.Put(datastore.NewKey("/versions/current"), []byte("1")))
+	_, err = inspector.List()
+	require.EqualError(t, err, `channel store is at version "1", expected "2": migrations must be run first`)
+	require.NoError(t, ds.Delete(datastore.NewKey("/versions/current")))
+
+	received := make(chan datatransfer.Event, 1)
+	notifier := func(evt datatransfer.Event, chst datatransfer.ChannelState) {
+		received <- evt
+	}
+	channelList, err := channels.New(ds, cidLists, notifier, decoderByType, decoderByType, &fakeEnv{}, peers[0])
+	require.NoError(t, err)
+	require.NoError(t, channelList.Start(ctx))
+	chid, err := channelList.CreateNew(peers[0], datatransfer.TransferID(rand.Uint64()), testutil.GenerateCids(1)[0], testutil.AllSelector(), voucher, peers[0], peers[0], peers[1], "")
+	require.NoError(t, err)
+	<-received
+
+	version, err := inspector.Version()
+	require.NoError(t, err)
+	require.Equal(t, channels.StoreVersion, version)
+	chsts, err := inspector.List()
+	require.NoError(t, err)
+	require.Len(t, chsts, 1)
+	require.Equal(t, chid, chsts[0].ChannelID())
+	encoded, err := encoding.Encode(voucher)
+	require.NoError(t, err)
+	require.Equal(t, &channels.RawVoucher{VoucherType: voucher.Type(), Raw: encoded}, chsts[0].Voucher())
+
+	require.NoError(t, inspector.Delete(chid))
+	_, err = inspector.Get(chid)
+	require.EqualError(t, err, channels.NewErrNotFound(chid).Error())
+}
+
+func TestChannelStateJSON(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	ds := datastore.NewMapDatastore()
+	cidLists, err := cidlists.NewCIDLists(os.TempDir())
+	require.NoError(t, err)
+	peers := make([]peer.ID, 2)
+	for i := range peers {
+		peers[i], err = libp2ptest.RandPeerID()
+		require.NoError(t, err)
+	}
+	voucher := &testutil.FakeDTType{Data: "json voucher"}
+	baseCid := testutil.GenerateCids(1)[0]
+
+	received := make(chan datatransfer.ChannelState, 1)
+	notifier := func(evt datatransfer.Event, chst datatransfer.ChannelState) {
+		received <- chst
+	}
+	channelList, err := channels.New(ds, cidLists, notifier, decoderByType, decoderByType, &fakeEnv{}, peers[0])
+	require.NoError(t, err)
+	require.NoError(t, channelList.Start(ctx))
+	chid, err := channelList.CreateNew(peers[0], datatransfer.TransferID(rand.Uint64()), baseCid, testutil.AllSelector(), voucher, peers[0], peers[0], peers[1], "")
+	require.NoError(t, err)
+	chst := <-received
+
+	encoded, err := json.Marshal(chst)
+	require.NoError(t, err)
+	var decoded struct {
+		ChannelID datatransfer.ChannelID
+		Status    string
+		BaseCID   cid.Cid
+		Selector  map[string]interface{}
+		Vouchers  []struct {
+			Type    datatransfer.TypeIdentifier
+			Raw     []byte
+			Decoded *testutil.FakeDTType
+		}
+	}
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+	require.Equal(t, chid, decoded.ChannelID)
+	require.Equal(t, "Requested", decoded.Status)
+	require.Equal(t, baseCid, decoded.BaseCID)
+	require.NotEmpty(t, decoded.Selector)
+	require.Len(t, decoded.Vouchers, 1)
+	require.Equal(t, voucher.Type(), decoded.Vouchers[0].Type)
+	require.Equal(t, voucher, decoded.Vouchers[0].Decoded)
+	raw, err := encoding.Encode(voucher)
+	require.NoError(t, err)
+	require.Equal(t, raw, decoded.Vouchers[0].Raw)
+
+	// vouchers of unregistered types are only included in encoded form
+	inspected, err := channels.NewInspector(ds, cidLists).Get(chid)
+	require.NoError(t, err)
+	encoded, err = json.Marshal(inspected)
+	require.NoError(t, err)
+	decoded.Vouchers = nil
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+	require.Nil(t, decoded.Vouchers[0].Decoded)
+	require.Equal(t, raw, decoded.Vouchers[0].Raw)
+}
+
 type fakeEnv struct {
+	completeErr error
 }
 
 func (fe *fakeEnv) Protect(id peer.ID, tag string) {
@@ -638,6 +785,14 @@ func (fe *fakeEnv) ID() peer.ID {
 func (fe *fakeEnv) CleanupChannel(chid datatransfer.ChannelID) {
 }
 
+func (fe *fakeEnv) CompleteChannel(chid datatransfer.ChannelID) error {
+	return fe.completeErr
+}
+
+func (fe *fakeEnv) IssueReceipt(receipt datatransfer.Receipt) (*datatransfer.Receipt, error) {
+	return nil, nil
+}
+
 func decoderByType(identifier datatransfer.TypeIdentifier) (encoding.Decoder, bool) {
 	if identifier == testutil.NewFakeDTType().Type() {
 		decoder, err := encoding.NewDecoder(testutil.NewFakeDTType())
@@ -648,3 +803,32 @@ func decoderByType(identifier datatransfer.TypeIdentifier) (encoding.Decoder, bo
 	}
 	return nil, false
 }
+
+func TestChannelCompleteFails(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	received := make(chan event)
+	notifier := func(evt datatransfer.Event, chst datatransfer.ChannelState) {
+		received <- event{evt, chst}
+	}
+	peers := testutil.GeneratePeers(2)
+	cidLists, err := cidlists.NewCIDLists(os.TempDir())
+	require.NoError(t, err)
+	env := &fakeEnv{completeErr: errors.New("disk full")}
+	channelList, err := channels.New(dss.MutexWrap(datastore.NewMapDatastore()), cidLists, notifier, decoderByType, decoderByType, env, peers[0])
+	require.NoError(t, err)
+	require.NoError(t, channelList.Start(ctx))
+
+	chid, err := channelList.CreateNew(peers[0], datatransfer.TransferID(1), testutil.GenerateCids(1)[0], testutil.AllSelector(), &testutil.FakeDTType{}, peers[0], peers[1], peers[0], "")
+	require.NoError(t, err)
+	checkEvent(ctx, t, received, datatransfer.Open)
+
+	require.NoError(t, channelList.Complete(chid))
+	state := checkEvent(ctx, t, received, datatransfer.Complete)
+	require.Equal(t, datatransfer.Completing, state.Status())
+	state = checkEvent(ctx, t, received, datatransfer.Error)
+	require.Equal(t, datatransfer.Failing, state.Status())
+	require.Equal(t, "unable to complete channel: disk full", state.Message())
+	state = checkEvent(ctx, t, received, datatransfer.CleanupComplete)
+	require.Equal(t, datatransfer.Failed, state.Status())
+}
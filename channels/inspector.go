@@ -0,0 +1,192 @@
+package channels
+
+import (
+	"bytes"
+	"io"
+	"os"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	"github.com/ipfs/go-datastore/query"
+	"golang.org/x/xerrors"
+
+	versioning "github.com/filecoin-project/go-ds-versioning/pkg"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+	"github.com/filecoin-project/go-data-transfer/channels/internal"
+	"github.com/filecoin-project/go-data-transfer/cidlists"
+	"github.com/filecoin-project/go-data-transfer/encoding"
+	"github.com/filecoin-project/go-data-transfer/staging"
+)
+
+// StoreVersion is the version of the channel state store written by Channels
+const StoreVersion versioning.VersionKey = "2"
+
+var versionKey = datastore.NewKey("/versions/current")
+
+// the manager keeps each channel's revalidator binding and staged blocks
+// under these namespaces of the datastore it shares with the channel state
+var (
+	revalidatorBindingsKey = datastore.NewKey("/revalidator-bindings")
+	stagingKey             = datastore.NewKey("/staging")
+)
+
+// RawVoucher is a voucher or voucher result in its encoded form. It is used to
+// read channels without registering their voucher types
+type RawVoucher struct {
+	VoucherType datatransfer.TypeIdentifier
+	Raw         []byte
+}
+
+// Type returns the type identifier the voucher was stored with
+func (rv *RawVoucher) Type() datatransfer.TypeIdentifier {
+	return rv.VoucherType
+}
+
+// MarshalCBOR writes the encoded voucher
+func (rv *RawVoucher) MarshalCBOR(w io.Writer) error {
+	_, err := w.Write(rv.Raw)
+	return err
+}
+
+type rawDecoder struct {
+	identifier datatransfer.TypeIdentifier
+}
+
+func (rd rawDecoder) DecodeFromCbor(raw []byte) (encoding.Encodable, error) {
+	return &RawVoucher{VoucherType: rd.identifier, Raw: raw}, nil
+}
+
+// RawDecoder decodes vouchers and voucher results of any type to RawVoucher
+func RawDecoder(identifier datatransfer.TypeIdentifier) (encoding.Decoder, bool) {
+	return rawDecoder{identifier}, true
+}
+
+// Inspector reads and repairs channel state directly in the datastore, without
+// running the state machines or migrations, so it can be used offline on a
+// read-only datastore. Vouchers and voucher results are read as RawVoucher.
+// It must not be used to write to a datastore in use by a running manager
+type Inspector struct {
+	ds       datastore.Batching
+	states   datastore.Datastore
+	cidLists cidlists.CIDLists
+}
+
+// NewInspector returns an inspector for the channels in the given datastore
+// and CID lists, as passed to New
+func NewInspector(ds datastore.Batching, cidLists cidlists.CIDLists) *Inspector {
+	return &Inspector{
+		ds:       ds,
+		states:   namespace.Wrap(ds, datastore.NewKey(string(StoreVersion))),
+		cidLists: cidLists,
+	}
+}
+
+// Version returns the version of the channel state store, or an empty version
+// if the store is not versioned
+func (i *Inspector) Version() (versioning.VersionKey, error) {
+	version, err := i.ds.Get(versionKey)
+	if err == datastore.ErrNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", xerrors.Errorf("reading store version: %w", err)
+	}
+	return versioning.VersionKey(version), nil
+}
+
+func (i *Inspector) checkVersion() error {
+	version, err := i.Version()
+	if err != nil {
+		return err
+	}
+	if version != StoreVersion {
+		return xerrors.Errorf("channel store is at version %q, expected %q: migrations must be run first", version, StoreVersion)
+	}
+	return nil
+}
+
+// List returns the state of all channels
+func (i *Inspector) List() ([]datatransfer.ChannelState, error) {
+	if err := i.checkVersion(); err != nil {
+		return nil, err
+	}
+	results, err := i.states.Query(query.Query{})
+	if err != nil {
+		return nil, xerrors.Errorf("querying channels: %w", err)
+	}
+	defer results.Close()
+
+	var chsts []datatransfer.ChannelState
+	for res := range results.Next() {
+		if res.Error != nil {
+			return nil, xerrors.Errorf("querying channels: %w", res.Error)
+		}
+		var internalChannel internal.ChannelState
+		if err := internalChannel.UnmarshalCBOR(bytes.NewReader(res.Value)); err != nil {
+			return nil, xerrors.Errorf("decoding channel %s: %w", res.Key, err)
+		}
+		chsts = append(chsts, i.fromInternal(internalChannel))
+	}
+	return chsts, nil
+}
+
+// Get returns the state of the given channel
+func (i *Inspector) Get(chid datatransfer.ChannelID) (datatransfer.ChannelState, error) {
+	internalChannel, err := i.get(chid)
+	if err != nil {
+		return nil, err
+	}
+	return i.fromInternal(internalChannel), nil
+}
+
+func (i *Inspector) get(chid datatransfer.ChannelID) (internal.ChannelState, error) {
+	if err := i.checkVersion(); err != nil {
+		return internal.ChannelState{}, err
+	}
+	value, err := i.states.Get(datastore.NewKey(chid.String()))
+	if err == datastore.ErrNotFound {
+		return internal.ChannelState{}, NewErrNotFound(chid)
+	}
+	if err != nil {
+		return internal.ChannelState{}, xerrors.Errorf("reading channel %s: %w", chid, err)
+	}
+	var internalChannel internal.ChannelState
+	if err := internalChannel.UnmarshalCBOR(bytes.NewReader(value)); err != nil {
+		return internal.ChannelState{}, xerrors.Errorf("decoding channel %s: %w", chid, err)
+	}
+	return internalChannel, nil
+}
+
+// Delete removes the state and CID list of the given channel, along with the
+// data the manager keeps for it
+func (i *Inspector) Delete(chid datatransfer.ChannelID) error {
+	if _, err := i.get(chid); err != nil {
+		return err
+	}
+	if err := i.states.Delete(datastore.NewKey(chid.String())); err != nil {
+		return xerrors.Errorf("deleting channel %s: %w", chid, err)
+	}
+	if err := i.cidLists.DeleteList(chid); err != nil && !os.IsNotExist(err) {
+		return xerrors.Errorf("deleting CID list for channel %s: %w", chid, err)
+	}
+	return i.DeleteManagerData(chid)
+}
+
+// DeleteManagerData removes the revalidator binding and staged blocks the
+// manager keeps for the given channel. A running manager removes them when
+// the channel ends, so they must be removed when a channel is failed offline
+func (i *Inspector) DeleteManagerData(chid datatransfer.ChannelID) error {
+	err := i.ds.Delete(revalidatorBindingsKey.Child(datastore.NewKey(chid.String())))
+	if err != nil && err != datastore.ErrNotFound {
+		return xerrors.Errorf("deleting revalidator binding for channel %s: %w", chid, err)
+	}
+	if err := staging.New(namespace.Wrap(i.ds, stagingKey)).Discard(chid); err != nil {
+		return xerrors.Errorf("deleting staged blocks for channel %s: %w", chid, err)
+	}
+	return nil
+}
+
+func (i *Inspector) fromInternal(internalChannel internal.ChannelState) datatransfer.ChannelState {
+	return fromInternalChannelState(internalChannel, RawDecoder, RawDecoder, i.cidLists.ReadList)
+}
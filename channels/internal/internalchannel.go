@@ -58,4 +58,19 @@ type ChannelState struct {
 	Message        string
 	Vouchers       []EncodedVoucher
 	VoucherResults []EncodedVoucherResult
+	// PausedUntil is when this node will resume a timed pause, in nanoseconds
+	// since the unix epoch, or zero if there is no timed pause
+	PausedUntil int64
+	// Transport is the name of the transport that moves the data for this
+	// channel, or empty for the default transport
+	Transport string
+	// RequestSignature is the initiator's signature over the request that
+	// opened this channel, if it was signed
+	RequestSignature *datatransfer.RequestSignature
+	// Receipt is the receipt the receiver signed for the data it received on
+	// this channel, if it signs receipts
+	Receipt *datatransfer.Receipt
+	// SignedRequest is the part of the request that opened this channel that
+	// the initiator signed, if it was signed
+	SignedRequest *datatransfer.SignedRequest
 }
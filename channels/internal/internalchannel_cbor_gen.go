@@ -19,7 +19,7 @@ func (t *ChannelState) MarshalCBOR(w io.Writer) error {
 		_, err := w.Write(cbg.CborNull)
 		return err
 	}
-	if _, err := w.Write([]byte{176}); err != nil {
+	if _, err := w.Write([]byte{181}); err != nil {
 		return err
 	}
 
@@ -340,6 +340,99 @@ func (t *ChannelState) MarshalCBOR(w io.Writer) error {
 			return err
 		}
 	}
+
+	// t.PausedUntil (int64) (int64)
+	if len("PausedUntil") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"PausedUntil\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("PausedUntil"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("PausedUntil")); err != nil {
+		return err
+	}
+
+	if t.PausedUntil >= 0 {
+		if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.PausedUntil)); err != nil {
+			return err
+		}
+	} else {
+		if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajNegativeInt, uint64(-t.PausedUntil-1)); err != nil {
+			return err
+		}
+	}
+
+	// t.Transport (string) (string)
+	if len("Transport") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Transport\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("Transport"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Transport")); err != nil {
+		return err
+	}
+
+	if len(t.Transport) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.Transport was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len(t.Transport))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string(t.Transport)); err != nil {
+		return err
+	}
+
+	// t.RequestSignature (datatransfer.RequestSignature) (struct)
+	if len("RequestSignature") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"RequestSignature\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("RequestSignature"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("RequestSignature")); err != nil {
+		return err
+	}
+
+	if err := t.RequestSignature.MarshalCBOR(w); err != nil {
+		return err
+	}
+
+	// t.Receipt (datatransfer.Receipt) (struct)
+	if len("Receipt") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Receipt\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("Receipt"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Receipt")); err != nil {
+		return err
+	}
+
+	if err := t.Receipt.MarshalCBOR(w); err != nil {
+		return err
+	}
+
+	// t.SignedRequest (datatransfer.SignedRequest) (struct)
+	if len("SignedRequest") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"SignedRequest\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("SignedRequest"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("SignedRequest")); err != nil {
+		return err
+	}
+
+	if err := t.SignedRequest.MarshalCBOR(w); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -616,6 +709,104 @@ func (t *ChannelState) UnmarshalCBOR(r io.Reader) error {
 				t.VoucherResults[i] = v
 			}
 
+			// t.PausedUntil (int64) (int64)
+		case "PausedUntil":
+			{
+				maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+				var extraI int64
+				if err != nil {
+					return err
+				}
+				switch maj {
+				case cbg.MajUnsignedInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 positive overflow")
+					}
+				case cbg.MajNegativeInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 negative oveflow")
+					}
+					extraI = -1 - extraI
+				default:
+					return fmt.Errorf("wrong type for int64 field: %d", maj)
+				}
+
+				t.PausedUntil = int64(extraI)
+			}
+			// t.Transport (string) (string)
+		case "Transport":
+
+			{
+				sval, err := cbg.ReadStringBuf(br, scratch)
+				if err != nil {
+					return err
+				}
+
+				t.Transport = string(sval)
+			}
+			// t.RequestSignature (datatransfer.RequestSignature) (struct)
+		case "RequestSignature":
+
+			{
+
+				b, err := br.ReadByte()
+				if err != nil {
+					return err
+				}
+				if b != cbg.CborNull[0] {
+					if err := br.UnreadByte(); err != nil {
+						return err
+					}
+					t.RequestSignature = new(datatransfer.RequestSignature)
+					if err := t.RequestSignature.UnmarshalCBOR(br); err != nil {
+						return xerrors.Errorf("unmarshaling t.RequestSignature pointer: %w", err)
+					}
+				}
+
+			}
+			// t.Receipt (datatransfer.Receipt) (struct)
+		case "Receipt":
+
+			{
+
+				b, err := br.ReadByte()
+				if err != nil {
+					return err
+				}
+				if b != cbg.CborNull[0] {
+					if err := br.UnreadByte(); err != nil {
+						return err
+					}
+					t.Receipt = new(datatransfer.Receipt)
+					if err := t.Receipt.UnmarshalCBOR(br); err != nil {
+						return xerrors.Errorf("unmarshaling t.Receipt pointer: %w", err)
+					}
+				}
+
+			}
+			// t.SignedRequest (datatransfer.SignedRequest) (struct)
+		case "SignedRequest":
+
+			{
+
+				b, err := br.ReadByte()
+				if err != nil {
+					return err
+				}
+				if b != cbg.CborNull[0] {
+					if err := br.UnreadByte(); err != nil {
+						return err
+					}
+					t.SignedRequest = new(datatransfer.SignedRequest)
+					if err := t.SignedRequest.UnmarshalCBOR(br); err != nil {
+						return xerrors.Errorf("unmarshaling t.SignedRequest pointer: %w", err)
+					}
+				}
+
+			}
+
 		default:
 			return fmt.Errorf("unknown struct field %d: '%s'", i, name)
 		}
@@ -0,0 +1,90 @@
+// Package clock abstracts the passage of time, so that behaviour driven by
+// timers can be tested deterministically with a mock clock
+package clock
+
+import (
+	"time"
+)
+
+// Clock tells the time and creates timers
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+	// Since returns the time elapsed since t
+	Since(t time.Time) time.Duration
+	// Until returns the duration until t
+	Until(t time.Time) time.Duration
+	// After waits for the duration to elapse and then sends the current time
+	// on the returned channel
+	After(d time.Duration) <-chan time.Time
+	// NewTimer creates a timer that sends the current time on its channel
+	// after the duration elapses
+	NewTimer(d time.Duration) Timer
+	// AfterFunc waits for the duration to elapse and then calls f in its own
+	// goroutine. The returned timer can be used to cancel the call
+	AfterFunc(d time.Duration, f func()) Timer
+	// NewTicker creates a ticker that sends the current time on its channel
+	// every period
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer is a single event created by a Clock
+type Timer interface {
+	// Chan returns the channel the time is sent on when the timer fires. It
+	// is nil for timers created with AfterFunc
+	Chan() <-chan time.Time
+	// Stop prevents the timer from firing. It returns false if the timer
+	// already fired or was stopped
+	Stop() bool
+	// Reset changes the timer to fire after the duration. It returns true if
+	// the timer was still pending
+	Reset(d time.Duration) bool
+}
+
+// Ticker sends the time on a channel at intervals
+type Ticker interface {
+	// Chan returns the channel the ticks are sent on
+	Chan() <-chan time.Time
+	// Stop turns off the ticker
+	Stop()
+}
+
+// New returns a clock that follows the system time
+func New() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (realClock) Until(t time.Time) time.Duration        { return time.Until(t) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return realTimer{time.AfterFunc(d, f)}
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) Chan() <-chan time.Time {
+	return t.C
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) Chan() <-chan time.Time {
+	return t.C
+}
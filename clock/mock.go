@@ -0,0 +1,206 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Mock is a clock that only moves when it is told to. Timers and tickers fire
+// as the clock passes their deadlines, in deadline order
+type Mock struct {
+	lk     sync.Mutex
+	added  *sync.Cond
+	now    time.Time
+	timers []*mockTimer
+}
+
+var _ Clock = (*Mock)(nil)
+
+// NewMock returns a mock clock set to an arbitrary fixed time
+func NewMock() *Mock {
+	m := &Mock{now: time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)}
+	m.added = sync.NewCond(&m.lk)
+	return m
+}
+
+// Now returns the current time of the mock clock
+func (m *Mock) Now() time.Time {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	return m.now
+}
+
+// Since returns the time elapsed since t on the mock clock
+func (m *Mock) Since(t time.Time) time.Duration {
+	return m.Now().Sub(t)
+}
+
+// Until returns the duration until t on the mock clock
+func (m *Mock) Until(t time.Time) time.Duration {
+	return t.Sub(m.Now())
+}
+
+// After returns a channel the time is sent on once the clock moves forward
+// by the duration
+func (m *Mock) After(d time.Duration) <-chan time.Time {
+	return m.NewTimer(d).Chan()
+}
+
+// NewTimer creates a timer that fires once the clock moves forward by the
+// duration
+func (m *Mock) NewTimer(d time.Duration) Timer {
+	t := &mockTimer{mock: m, c: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+// AfterFunc calls f in its own goroutine once the clock moves forward by the
+// duration
+func (m *Mock) AfterFunc(d time.Duration, f func()) Timer {
+	t := &mockTimer{mock: m, f: f}
+	t.Reset(d)
+	return t
+}
+
+// NewTicker creates a ticker that fires each time the clock moves forward by
+// the period
+func (m *Mock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	t := &mockTimer{mock: m, c: make(chan time.Time, 1), period: d}
+	t.Reset(d)
+	return mockTicker{t}
+}
+
+// Add moves the clock forward by the duration, firing the timers that are
+// due on the way
+func (m *Mock) Add(d time.Duration) {
+	m.Set(m.Now().Add(d))
+}
+
+// Set moves the clock to the given time, firing the timers that are due on
+// the way. Moving the clock backwards fires nothing
+func (m *Mock) Set(t time.Time) {
+	for {
+		m.lk.Lock()
+		next := m.nextDue(t)
+		if next == nil {
+			m.now = t
+			m.lk.Unlock()
+			return
+		}
+		if next.deadline.After(m.now) {
+			m.now = next.deadline
+		}
+		now := m.now
+		if next.period > 0 {
+			next.deadline = next.deadline.Add(next.period)
+		} else {
+			m.remove(next)
+		}
+		m.lk.Unlock()
+		next.fire(now)
+	}
+}
+
+// WaitForTimers blocks until at least n timers and tickers are pending. It
+// lets a test wait for code running in another goroutine to set a timer
+// before moving the clock forward
+func (m *Mock) WaitForTimers(n int) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	for len(m.timers) < n {
+		m.added.Wait()
+	}
+}
+
+// Timers returns the number of pending timers and tickers
+func (m *Mock) Timers() int {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	return len(m.timers)
+}
+
+// nextDue returns the pending timer with the earliest deadline at or before
+// t, or nil if there is none
+func (m *Mock) nextDue(t time.Time) *mockTimer {
+	var next *mockTimer
+	for _, timer := range m.timers {
+		if timer.deadline.After(t) {
+			continue
+		}
+		if next == nil || timer.deadline.Before(next.deadline) {
+			next = timer
+		}
+	}
+	return next
+}
+
+// remove removes a timer from the pending timers, and returns true if it was
+// pending
+func (m *Mock) remove(t *mockTimer) bool {
+	for i, timer := range m.timers {
+		if timer == t {
+			m.timers = append(m.timers[:i], m.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type mockTimer struct {
+	mock     *Mock
+	deadline time.Time
+	period   time.Duration
+	c        chan time.Time
+	f        func()
+}
+
+func (t *mockTimer) Chan() <-chan time.Time {
+	return t.c
+}
+
+func (t *mockTimer) Stop() bool {
+	t.mock.lk.Lock()
+	defer t.mock.lk.Unlock()
+	return t.mock.remove(t)
+}
+
+func (t *mockTimer) Reset(d time.Duration) bool {
+	m := t.mock
+	m.lk.Lock()
+	pending := m.remove(t)
+	now := m.now
+	if d <= 0 && t.period == 0 {
+		m.lk.Unlock()
+		t.fire(now)
+		return pending
+	}
+	t.deadline = now.Add(d)
+	m.timers = append(m.timers, t)
+	m.added.Broadcast()
+	m.lk.Unlock()
+	return pending
+}
+
+type mockTicker struct {
+	*mockTimer
+}
+
+func (t mockTicker) Stop() {
+	t.mockTimer.Stop()
+}
+
+// fire sends the time on the timer's channel, dropping it if the last one
+// was not received yet, or calls the timer's function
+func (t *mockTimer) fire(now time.Time) {
+	if t.f != nil {
+		go t.f()
+		return
+	}
+	select {
+	case t.c <- now:
+	default:
+	}
+}
@@ -0,0 +1,109 @@
+package clock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-data-transfer/clock"
+)
+
+func TestMock(t *testing.T) {
+	received := func(c <-chan time.Time) (time.Time, bool) {
+		select {
+		case now := <-c:
+			return now, true
+		default:
+			return time.Time{}, false
+		}
+	}
+
+	t.Run("timers fire in deadline order", func(t *testing.T) {
+		clk := clock.NewMock()
+		start := clk.Now()
+		late := clk.NewTimer(2 * time.Second)
+		early := clk.NewTimer(time.Second)
+		stopped := clk.NewTimer(time.Second)
+		require.True(t, stopped.Stop())
+		require.False(t, stopped.Stop())
+
+		clk.Add(999 * time.Millisecond)
+		_, ok := received(early.Chan())
+		require.False(t, ok)
+
+		clk.Add(time.Millisecond)
+		now, ok := received(early.Chan())
+		require.True(t, ok)
+		require.Equal(t, start.Add(time.Second), now)
+		_, ok = received(late.Chan())
+		require.False(t, ok)
+		require.False(t, early.Stop())
+
+		clk.Add(5 * time.Second)
+		now, ok = received(late.Chan())
+		require.True(t, ok)
+		require.Equal(t, start.Add(2*time.Second), now)
+		require.Equal(t, start.Add(6*time.Second), clk.Now())
+		_, ok = received(stopped.Chan())
+		require.False(t, ok)
+		require.Equal(t, 0, clk.Timers())
+	})
+
+	t.Run("reset moves a timer", func(t *testing.T) {
+		clk := clock.NewMock()
+		timer := clk.NewTimer(time.Second)
+		require.True(t, timer.Reset(3*time.Second))
+		clk.Add(2 * time.Second)
+		_, ok := received(timer.Chan())
+		require.False(t, ok)
+		clk.Add(time.Second)
+		_, ok = received(timer.Chan())
+		require.True(t, ok)
+		require.False(t, timer.Reset(0))
+		_, ok = received(timer.Chan())
+		require.True(t, ok)
+	})
+
+	t.Run("tickers fire every period", func(t *testing.T) {
+		clk := clock.NewMock()
+		ticker := clk.NewTicker(time.Second)
+		for i := 0; i < 3; i++ {
+			clk.Add(time.Second)
+			_, ok := received(ticker.Chan())
+			require.True(t, ok)
+		}
+		ticker.Stop()
+		clk.Add(time.Second)
+		_, ok := received(ticker.Chan())
+		require.False(t, ok)
+	})
+
+	t.Run("after func runs once due", func(t *testing.T) {
+		clk := clock.NewMock()
+		called := make(chan struct{})
+		clk.AfterFunc(time.Minute, func() { close(called) })
+		clk.Add(time.Minute)
+		select {
+		case <-called:
+		case <-time.After(time.Second):
+			t.Fatal("function was not called")
+		}
+	})
+
+	t.Run("waits for timers set elsewhere", func(t *testing.T) {
+		clk := clock.NewMock()
+		fired := make(chan struct{})
+		go func() {
+			<-clk.After(time.Hour)
+			close(fired)
+		}()
+		clk.WaitForTimers(1)
+		clk.Add(time.Hour)
+		select {
+		case <-fired:
+		case <-time.After(time.Second):
+			t.Fatal("timer did not fire")
+		}
+	})
+}
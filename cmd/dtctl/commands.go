@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipld/go-ipld-prime/codec/dagcbor"
+	"github.com/ipld/go-ipld-prime/codec/dagjson"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"golang.org/x/xerrors"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+	"github.com/filecoin-project/go-data-transfer/channels"
+	"github.com/filecoin-project/go-data-transfer/cidlists"
+)
+
+// env is what commands operate on
+type env struct {
+	ds       datastore.Batching
+	cidLists cidlists.CIDLists
+	selfPeer peer.ID
+	out      io.Writer
+}
+
+type command struct {
+	usage       string
+	description string
+	run         func(ctx context.Context, e *env, args []string) error
+}
+
+var commands = map[string]command{
+	"list": {
+		usage:       "list [-status <status>]",
+		description: "list all channels, optionally only those with the given status",
+		run:         listChannels,
+	},
+	"show": {
+		usage:       "show <channel id>",
+		description: "show the state of a channel",
+		run:         showChannel,
+	},
+	"vouchers": {
+		usage:       "vouchers [-results] [-format hex|json] <channel id>",
+		description: "dump the vouchers, or voucher results, of a channel",
+		run:         dumpVouchers,
+	},
+	"cids": {
+		usage:       "cids <channel id>",
+		description: "print the CIDs received on a channel",
+		run:         printCids,
+	},
+	"fail": {
+		usage:       "fail [-reason <message>] <channel id>",
+		description: "force a channel to fail and remove its staged blocks (needs -write and -peer)",
+		run:         failChannel,
+	},
+	"delete": {
+		usage:       "delete <channel id>",
+		description: "delete the state, CID list and staged blocks of a channel (needs -write)",
+		run:         deleteChannel,
+	},
+	"migrate": {
+		usage:       "migrate",
+		description: "run pending channel store migrations (needs -write and -peer)",
+		run:         migrate,
+	},
+}
+
+// parseChannelID parses a channel ID in the form printed by ChannelID.String
+func parseChannelID(s string) (datatransfer.ChannelID, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 3 {
+		return datatransfer.ChannelID{}, xerrors.Errorf("invalid channel id %q: expected <initiator>-<responder>-<transfer id>", s)
+	}
+	initiator, err := peer.Decode(parts[0])
+	if err != nil {
+		return datatransfer.ChannelID{}, xerrors.Errorf("invalid initiator in channel id %q: %w", s, err)
+	}
+	responder, err := peer.Decode(parts[1])
+	if err != nil {
+		return datatransfer.ChannelID{}, xerrors.Errorf("invalid responder in channel id %q: %w", s, err)
+	}
+	id, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		return datatransfer.ChannelID{}, xerrors.Errorf("invalid transfer id in channel id %q: %w", s, err)
+	}
+	return datatransfer.ChannelID{Initiator: initiator, Responder: responder, ID: datatransfer.TransferID(id)}, nil
+}
+
+// channelArg parses the flags of a command followed by a single channel ID
+func channelArg(flags *flag.FlagSet, args []string) (datatransfer.ChannelID, error) {
+	if err := flags.Parse(args); err != nil {
+		return datatransfer.ChannelID{}, err
+	}
+	if flags.NArg() != 1 {
+		return datatransfer.ChannelID{}, xerrors.New("expected a single channel id")
+	}
+	return parseChannelID(flags.Arg(0))
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet(name, flag.ContinueOnError)
+}
+
+func listChannels(ctx context.Context, e *env, args []string) error {
+	flags := newFlagSet("list")
+	status := flags.String("status", "", "only list channels with this status")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	chsts, err := channels.NewInspector(e.ds, e.cidLists).List()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(e.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CHANNEL\tSTATUS\tSENT\tRECEIVED\tMESSAGE")
+	for _, chst := range chsts {
+		statusName := datatransfer.Statuses[chst.Status()]
+		if *status != "" && !strings.EqualFold(*status, statusName) {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\n", chst.ChannelID(), statusName, chst.Sent(), chst.Received(), chst.Message())
+	}
+	return w.Flush()
+}
+
+func showChannel(ctx context.Context, e *env, args []string) error {
+	chid, err := channelArg(newFlagSet("show"), args)
+	if err != nil {
+		return err
+	}
+	chst, err := channels.NewInspector(e.ds, e.cidLists).Get(chid)
+	if err != nil {
+		return err
+	}
+	selector := new(bytes.Buffer)
+	if err := dagjson.Encoder(chst.Selector(), selector); err != nil {
+		return xerrors.Errorf("encoding selector: %w", err)
+	}
+	var voucherTypes []string
+	for _, voucher := range chst.Vouchers() {
+		voucherTypes = append(voucherTypes, string(voucher.Type()))
+	}
+	var resultTypes []string
+	for _, result := range chst.VoucherResults() {
+		resultTypes = append(resultTypes, string(result.Type()))
+	}
+	receivedCids, err := e.cidLists.ReadList(chid)
+	if err != nil {
+		receivedCids = nil
+	}
+
+	w := tabwriter.NewWriter(e.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Channel:\t%s\n", chid)
+	fmt.Fprintf(w, "Status:\t%s\n", datatransfer.Statuses[chst.Status()])
+	fmt.Fprintf(w, "Self:\t%s\n", chst.SelfPeer())
+	fmt.Fprintf(w, "Sender:\t%s\n", chst.Sender())
+	fmt.Fprintf(w, "Recipient:\t%s\n", chst.Recipient())
+	fmt.Fprintf(w, "Pull:\t%t\n", chst.IsPull())
+	fmt.Fprintf(w, "Base CID:\t%s\n", chst.BaseCID())
+	fmt.Fprintf(w, "Selector:\t%s\n", selector)
+	fmt.Fprintf(w, "Total size:\t%d\n", chst.TotalSize())
+	fmt.Fprintf(w, "Queued:\t%d\n", chst.Queued())
+	fmt.Fprintf(w, "Sent:\t%d\n", chst.Sent())
+	fmt.Fprintf(w, "Received:\t%d\n", chst.Received())
+	fmt.Fprintf(w, "Received CIDs:\t%d\n", len(receivedCids))
+	fmt.Fprintf(w, "Vouchers:\t%s\n", strings.Join(voucherTypes, ", "))
+	fmt.Fprintf(w, "Voucher results:\t%s\n", strings.Join(resultTypes, ", "))
+	fmt.Fprintf(w, "Message:\t%s\n", chst.Message())
+	return w.Flush()
+}
+
+type jsonVoucher struct {
+	Type    datatransfer.TypeIdentifier
+	Voucher json.RawMessage
+}
+
+func dumpVouchers(ctx context.Context, e *env, args []string) error {
+	flags := newFlagSet("vouchers")
+	results := flags.Bool("results", false, "dump voucher results instead of vouchers")
+	format := flags.String("format", "hex", "output format: hex or json")
+	chid, err := channelArg(flags, args)
+	if err != nil {
+		return err
+	}
+	if *format != "hex" && *format != "json" {
+		return xerrors.Errorf("unknown format %q", *format)
+	}
+	chst, err := channels.NewInspector(e.ds, e.cidLists).Get(chid)
+	if err != nil {
+		return err
+	}
+	var vouchers []datatransfer.Registerable
+	if *results {
+		for _, result := range chst.VoucherResults() {
+			vouchers = append(vouchers, result)
+		}
+	} else {
+		for _, voucher := range chst.Vouchers() {
+			vouchers = append(vouchers, voucher)
+		}
+	}
+
+	for _, voucher := range vouchers {
+		raw := voucher.(*channels.RawVoucher)
+		if *format == "hex" {
+			fmt.Fprintf(e.out, "%s %s\n", raw.Type(), hex.EncodeToString(raw.Raw))
+			continue
+		}
+		nb := basicnode.Prototype.Any.NewBuilder()
+		if err := dagcbor.Decoder(nb, bytes.NewReader(raw.Raw)); err != nil {
+			return xerrors.Errorf("decoding voucher of type %s: %w", raw.Type(), err)
+		}
+		encoded := new(bytes.Buffer)
+		if err := dagjson.Encoder(nb.Build(), encoded); err != nil {
+			return xerrors.Errorf("encoding voucher of type %s: %w", raw.Type(), err)
+		}
+		line, err := json.Marshal(jsonVoucher{Type: raw.Type(), Voucher: encoded.Bytes()})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(e.out, string(line))
+	}
+	return nil
+}
+
+func printCids(ctx context.Context, e *env, args []string) error {
+	chid, err := channelArg(newFlagSet("cids"), args)
+	if err != nil {
+		return err
+	}
+	if _, err := channels.NewInspector(e.ds, e.cidLists).Get(chid); err != nil {
+		return err
+	}
+	cids, err := e.cidLists.ReadList(chid)
+	if err != nil {
+		return xerrors.Errorf("reading CID list for channel %s: %w", chid, err)
+	}
+	for _, c := range cids {
+		fmt.Fprintln(e.out, c)
+	}
+	return nil
+}
+
+func deleteChannel(ctx context.Context, e *env, args []string) error {
+	chid, err := channelArg(newFlagSet("delete"), args)
+	if err != nil {
+		return err
+	}
+	if err := channels.NewInspector(e.ds, e.cidLists).Delete(chid); err != nil {
+		return err
+	}
+	fmt.Fprintf(e.out, "deleted channel %s\n", chid)
+	return nil
+}
+
+// offlineEnvironment is the channel environment used to run the channel state
+// machines without a network or transport
+type offlineEnvironment struct {
+	selfPeer peer.ID
+}
+
+func (oe offlineEnvironment) Protect(id peer.ID, tag string)                    {}
+func (oe offlineEnvironment) Unprotect(id peer.ID, tag string) bool             { return false }
+func (oe offlineEnvironment) ID() peer.ID                                       { return oe.selfPeer }
+func (oe offlineEnvironment) CleanupChannel(chid datatransfer.ChannelID)        {}
+func (oe offlineEnvironment) CompleteChannel(chid datatransfer.ChannelID) error { return nil }
+func (oe offlineEnvironment) IssueReceipt(receipt datatransfer.Receipt) (*datatransfer.Receipt, error) {
+	return nil, nil
+}
+
+// startChannels runs migrations and starts the channel state machines
+func startChannels(ctx context.Context, e *env, notifier channels.Notifier) (*channels.Channels, error) {
+	if e.selfPeer == "" {
+		return nil, xerrors.New("the peer ID of the node that owns the datastore must be given with -peer")
+	}
+	chans, err := channels.New(e.ds, e.cidLists, notifier, channels.RawDecoder, channels.RawDecoder, offlineEnvironment{e.selfPeer}, e.selfPeer)
+	if err != nil {
+		return nil, err
+	}
+	if err := chans.Start(ctx); err != nil {
+		return nil, xerrors.Errorf("migrating channel store: %w", err)
+	}
+	return chans, nil
+}
+
+func failChannel(ctx context.Context, e *env, args []string) error {
+	flags := newFlagSet("fail")
+	reason := flags.String("reason", "failed by dtctl", "the message recorded on the channel")
+	chid, err := channelArg(flags, args)
+	if err != nil {
+		return err
+	}
+	chst, err := channels.NewInspector(e.ds, e.cidLists).Get(chid)
+	if err != nil {
+		return err
+	}
+	if channels.IsChannelTerminated(chst.Status()) {
+		return xerrors.Errorf("channel %s is already %s", chid, datatransfer.Statuses[chst.Status()])
+	}
+
+	failed := make(chan struct{}, 1)
+	notifier := func(evt datatransfer.Event, chst datatransfer.ChannelState) {
+		if chst.ChannelID() == chid && chst.Status() == datatransfer.Failed {
+			select {
+			case failed <- struct{}{}:
+			default:
+			}
+		}
+	}
+	chans, err := startChannels(ctx, e, notifier)
+	if err != nil {
+		return err
+	}
+	if err := chans.Error(chid, xerrors.New(*reason)); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	select {
+	case <-ctx.Done():
+		return xerrors.Errorf("waiting for channel %s to fail: %w", chid, ctx.Err())
+	case <-failed:
+	}
+	if err := channels.NewInspector(e.ds, e.cidLists).DeleteManagerData(chid); err != nil {
+		return err
+	}
+	fmt.Fprintf(e.out, "failed channel %s\n", chid)
+	return nil
+}
+
+func migrate(ctx context.Context, e *env, args []string) error {
+	inspector := channels.NewInspector(e.ds, e.cidLists)
+	version, err := inspector.Version()
+	if err != nil {
+		return err
+	}
+	if version == channels.StoreVersion {
+		fmt.Fprintf(e.out, "channel store is already at version %s\n", version)
+		return nil
+	}
+	if _, err := startChannels(ctx, e, func(datatransfer.Event, datatransfer.ChannelState) {}); err != nil {
+		return err
+	}
+	fmt.Fprintf(e.out, "migrated channel store from version %q to %s\n", version, channels.StoreVersion)
+	return nil
+}
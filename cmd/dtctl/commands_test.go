@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	badgerds "github.com/ipfs/go-ds-badger"
+	"github.com/libp2p/go-libp2p-core/peer"
+	libp2ptest "github.com/libp2p/go-libp2p-core/test"
+	"github.com/stretchr/testify/require"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+	"github.com/filecoin-project/go-data-transfer/channels"
+	"github.com/filecoin-project/go-data-transfer/cidlists"
+	"github.com/filecoin-project/go-data-transfer/encoding"
+	"github.com/filecoin-project/go-data-transfer/testutil"
+)
+
+func TestCommands(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ds := datastore.NewMapDatastore()
+	dir, err := ioutil.TempDir("", "dtctl")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	cidLists, err := cidlists.NewCIDLists(dir)
+	require.NoError(t, err)
+	// channel IDs are parsed back from their string form, so the peer IDs
+	// must be valid
+	var peers []peer.ID
+	for i := 0; i < 2; i++ {
+		p, err := libp2ptest.RandPeerID()
+		require.NoError(t, err)
+		peers = append(peers, p)
+	}
+	e := &env{ds: ds, cidLists: cidLists, selfPeer: peers[0]}
+
+	// populate the store the way a running manager would
+	chans, err := startChannels(ctx, e, func(datatransfer.Event, datatransfer.ChannelState) {})
+	require.NoError(t, err)
+	voucher := testutil.NewFakeDTType()
+	voucherResult := testutil.NewFakeDTType()
+	baseCid := testutil.GenerateCids(1)[0]
+	received := testutil.GenerateCids(2)
+	chid, err := chans.CreateNew(peers[0], 1, baseCid, testutil.AllSelector(), voucher, peers[0], peers[1], peers[0], "")
+	require.NoError(t, err)
+	stuck, err := chans.CreateNew(peers[0], 2, baseCid, testutil.AllSelector(), voucher, peers[0], peers[1], peers[0], "")
+	require.NoError(t, err)
+	require.NoError(t, chans.Accept(chid))
+	require.NoError(t, chans.NewVoucherResult(chid, voucherResult))
+	for _, c := range received {
+		require.NoError(t, chans.DataReceived(chid, c, 100))
+	}
+	require.Eventually(t, func() bool {
+		chst, err := chans.GetByID(ctx, chid)
+		return err == nil && chst.Received() == 200 && len(chst.VoucherResults()) == 1
+	}, time.Second, 10*time.Millisecond)
+	// the revalidator bindings and staged blocks a manager keeps per channel
+	managerKeys := func(chid datatransfer.ChannelID) []datastore.Key {
+		return []datastore.Key{
+			datastore.NewKey("/revalidator-bindings/" + chid.String()),
+			datastore.NewKey("/staging/" + chid.String() + "/" + received[0].String()),
+		}
+	}
+	for _, key := range append(managerKeys(chid), managerKeys(stuck)...) {
+		require.NoError(t, ds.Put(key, []byte("data")))
+	}
+	requireDeleted := func(t *testing.T, keys []datastore.Key) {
+		for _, key := range keys {
+			has, err := ds.Has(key)
+			require.NoError(t, err)
+			require.False(t, has, key.String())
+		}
+	}
+
+	runCommand := func(e *env, name string, args ...string) (string, error) {
+		out := new(bytes.Buffer)
+		e.out = out
+		err := commands[name].run(ctx, e, args)
+		return out.String(), err
+	}
+	readOnly := &env{ds: readOnlyDatastore{ds}, cidLists: cidLists, selfPeer: peers[0]}
+
+	t.Run("list", func(t *testing.T) {
+		out, err := runCommand(readOnly, "list")
+		require.NoError(t, err)
+		require.Contains(t, out, chid.String())
+		require.Contains(t, out, stuck.String())
+
+		out, err = runCommand(readOnly, "list", "-status", "requested")
+		require.NoError(t, err)
+		require.NotContains(t, out, chid.String())
+		require.Contains(t, out, stuck.String())
+	})
+
+	t.Run("show", func(t *testing.T) {
+		out, err := runCommand(readOnly, "show", chid.String())
+		require.NoError(t, err)
+		require.Contains(t, out, "Ongoing")
+		require.Contains(t, out, baseCid.String())
+		require.Contains(t, out, string(voucher.Type()))
+
+		_, err = runCommand(readOnly, "show", "not-a-channel")
+		require.Error(t, err)
+	})
+
+	t.Run("vouchers", func(t *testing.T) {
+		encodedVoucher, err := encoding.Encode(voucher)
+		require.NoError(t, err)
+		out, err := runCommand(readOnly, "vouchers", chid.String())
+		require.NoError(t, err)
+		require.Equal(t, string(voucher.Type())+" "+hex.EncodeToString(encodedVoucher)+"\n", out)
+
+		out, err = runCommand(readOnly, "vouchers", "-results", "-format", "json", chid.String())
+		require.NoError(t, err)
+		var dumped jsonVoucher
+		require.NoError(t, json.Unmarshal([]byte(out), &dumped))
+		require.Equal(t, voucherResult.Type(), dumped.Type)
+		require.NotEmpty(t, dumped.Voucher)
+	})
+
+	t.Run("cids", func(t *testing.T) {
+		out, err := runCommand(readOnly, "cids", chid.String())
+		require.NoError(t, err)
+		require.Equal(t, received[0].String()+"\n"+received[1].String()+"\n", out)
+	})
+
+	t.Run("read-only datastore rejects repairs", func(t *testing.T) {
+		_, err := runCommand(readOnly, "delete", chid.String())
+		require.EqualError(t, err, "deleting channel "+chid.String()+": "+errReadOnly.Error())
+		_, err = runCommand(readOnly, "fail", stuck.String())
+		require.Error(t, err)
+	})
+
+	t.Run("migrate", func(t *testing.T) {
+		out, err := runCommand(e, "migrate")
+		require.NoError(t, err)
+		require.Equal(t, "channel store is already at version 2\n", out)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		_, err := runCommand(e, "fail", "-reason", "stuck", stuck.String())
+		require.NoError(t, err)
+		chst, err := channels.NewInspector(ds, cidLists).Get(stuck)
+		require.NoError(t, err)
+		require.Equal(t, datatransfer.Failed, chst.Status())
+		require.Equal(t, "stuck", chst.Message())
+		requireDeleted(t, managerKeys(stuck))
+
+		_, err = runCommand(e, "fail", stuck.String())
+		require.EqualError(t, err, "channel "+stuck.String()+" is already Failed")
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		_, err := runCommand(e, "delete", chid.String())
+		require.NoError(t, err)
+		_, err = channels.NewInspector(ds, cidLists).Get(chid)
+		require.Error(t, err)
+		_, err = cidLists.ReadList(chid)
+		require.True(t, os.IsNotExist(err))
+		requireDeleted(t, managerKeys(chid))
+	})
+}
+
+func TestOpenDatastore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dtctl")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	key := datastore.NewKey("/key")
+
+	_, _, err = openDatastore(dir, "/prefix", true)
+	require.Error(t, err)
+	bds, err := badgerds.NewDatastore(dir, nil)
+	require.NoError(t, err)
+	require.NoError(t, bds.Close())
+
+	ds, closeDs, err := openDatastore(dir, "/prefix", true)
+	require.NoError(t, err)
+	require.NoError(t, ds.Put(key, []byte("value")))
+	require.NoError(t, closeDs())
+
+	ds, closeDs, err = openDatastore(dir, "/prefix", false)
+	require.NoError(t, err)
+	defer closeDs()
+	value, err := ds.Get(key)
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), value)
+	require.Equal(t, errReadOnly, ds.Put(key, []byte("other")))
+}
@@ -0,0 +1,80 @@
+// Command dtctl inspects and repairs the channel state of a data transfer
+// manager while the node that owns it is stopped. It opens the badger
+// datastore and CID lists directory passed to NewDataTransfer, read-only
+// unless -write is given. Only badger datastores are supported.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+
+	"github.com/filecoin-project/go-data-transfer/cidlists"
+)
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "usage: dtctl -datastore <dir> -cidlists <dir> [options] <command> [arguments]\n\noptions:\n")
+	flag.PrintDefaults()
+	fmt.Fprintf(flag.CommandLine.Output(), "\ncommands:\n")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(flag.CommandLine.Output(), "  %s\n    \t%s\n", commands[name].usage, commands[name].description)
+	}
+}
+
+func main() {
+	dsPath := flag.String("datastore", "", "path to the badger datastore (other datastores are not supported)")
+	prefix := flag.String("prefix", "", "key prefix of the data transfer datastore within the badger datastore")
+	cidListsDir := flag.String("cidlists", "", "path to the CID lists directory")
+	write := flag.Bool("write", false, "open the datastore read-write")
+	selfPeer := flag.String("peer", "", "peer ID of the node that owns the datastore")
+	flag.Usage = usage
+	flag.Parse()
+
+	if *dsPath == "" || *cidListsDir == "" || flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+
+	if err := run(cmd, *dsPath, *prefix, *cidListsDir, *write, *selfPeer, flag.Args()[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "dtctl: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(cmd command, dsPath string, prefix string, cidListsDir string, write bool, selfPeer string, args []string) error {
+	e := &env{out: os.Stdout}
+	if selfPeer != "" {
+		p, err := peer.Decode(selfPeer)
+		if err != nil {
+			return fmt.Errorf("invalid peer ID: %w", err)
+		}
+		e.selfPeer = p
+	}
+	cidLists, err := cidlists.NewCIDLists(cidListsDir)
+	if err != nil {
+		return err
+	}
+	e.cidLists = cidLists
+	ds, closeDs, err := openDatastore(dsPath, prefix, write)
+	if err != nil {
+		return err
+	}
+	defer closeDs()
+	e.ds = ds
+	return cmd.run(context.Background(), e, args)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	badgerds "github.com/ipfs/go-ds-badger"
+	"golang.org/x/xerrors"
+)
+
+// errReadOnly is returned for writes to a datastore opened without -write
+var errReadOnly = xerrors.New("datastore is opened read-only: pass -write to modify it")
+
+// readOnlyDatastore rejects all writes to the wrapped datastore
+type readOnlyDatastore struct {
+	datastore.Batching
+}
+
+func (ds readOnlyDatastore) Put(key datastore.Key, value []byte) error {
+	return errReadOnly
+}
+
+func (ds readOnlyDatastore) Delete(key datastore.Key) error {
+	return errReadOnly
+}
+
+func (ds readOnlyDatastore) Batch() (datastore.Batch, error) {
+	return nil, errReadOnly
+}
+
+// badgerManifest is the file found in every badger datastore directory
+const badgerManifest = "MANIFEST"
+
+// openDatastore opens the badger datastore at the given path, and returns the
+// datastore passed to NewDataTransfer, found under the given prefix, along
+// with a function to close it. Only existing badger datastores can be opened:
+// badger would otherwise create a new, empty datastore at the path
+func openDatastore(path string, prefix string, write bool) (datastore.Batching, func() error, error) {
+	if _, err := os.Stat(filepath.Join(path, badgerManifest)); err != nil {
+		return nil, nil, xerrors.Errorf("%s is not a badger datastore: %w", path, err)
+	}
+	opts := badgerds.DefaultOptions
+	// no garbage collection for a short lived process
+	opts.GcInterval = 0
+	if !write {
+		opts.ReadOnly = true
+		opts.Truncate = false
+	}
+	bds, err := badgerds.NewDatastore(path, &opts)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("opening datastore %s: %w", path, err)
+	}
+	var ds datastore.Batching = bds
+	if prefix != "" {
+		ds = namespace.Wrap(ds, datastore.NewKey(prefix))
+	}
+	if !write {
+		ds = readOnlyDatastore{ds}
+	}
+	return ds, bds.Close, nil
+}
@@ -39,3 +39,74 @@ const ErrDisconnected = errorType("other peer appears to have hung up. restart C
 
 // ErrRemoved indicates the channel was inactive long enough that it was put in a permaneant error state
 const ErrRemoved = errorType("channel removed due to inactivity")
+
+// ErrDeferred is a special error that a RequestValidator can return to leave a
+// new request in the Requested state until it is accepted or rejected with
+// AcceptChannel / RejectChannel
+const ErrDeferred = errorType("request acceptance deferred")
+
+// ErrJournalDisabled indicates the event journal was read but was not enabled
+const ErrJournalDisabled = errorType("event journal is not enabled")
+
+// ErrChannelFailed indicates a channel ended in the Failed status
+const ErrChannelFailed = errorType("channel failed")
+
+// ErrChannelCancelled indicates a channel ended in the Cancelled status
+const ErrChannelCancelled = errorType("channel cancelled")
+
+// ErrManagerStopped indicates a transfer was still in progress when the data
+// transfer manager stopped
+const ErrManagerStopped = errorType("data transfer manager stopped")
+
+// ErrMessageTooLarge indicates a message received from a peer exceeded the
+// maximum message size
+const ErrMessageTooLarge = errorType("message exceeds maximum size")
+
+// ErrVoucherTooLarge indicates a request was rejected because its encoded
+// voucher exceeded the maximum voucher size
+const ErrVoucherTooLarge = errorType("voucher exceeds maximum size")
+
+// ErrSelectorTooDeep indicates a request was rejected because its selector
+// was nested deeper than the maximum selector depth
+const ErrSelectorTooDeep = errorType("selector exceeds maximum depth")
+
+// ErrSelectorTooLarge indicates a request was rejected because its selector
+// had more nodes than the maximum selector node count
+const ErrSelectorTooLarge = errorType("selector exceeds maximum node count")
+
+// ErrRecursionLimitExceeded indicates a request was rejected because its
+// selector recurses deeper than the maximum recursion depth, or without limit
+const ErrRecursionLimitExceeded = errorType("selector recursion exceeds maximum depth")
+
+// ErrPeerStatsDisabled indicates peer stats were read but tracking was not
+// enabled
+const ErrPeerStatsDisabled = errorType("peer stats tracking is not enabled")
+
+// ErrDraining indicates a channel was not opened because the manager is
+// draining before shutdown
+const ErrDraining = errorType("data transfer manager is draining")
+
+// ErrTransportNotFound indicates a channel asked for a transport that is not
+// configured on this node
+const ErrTransportNotFound = errorType("transport not found")
+
+// ErrQuotaExceeded indicates a channel received more data than a quota
+// allows
+const ErrQuotaExceeded = errorType("receive quota exceeded")
+
+// ErrQuotasDisabled indicates a peer quota was released but receive quotas
+// were not enabled
+const ErrQuotasDisabled = errorType("receive quotas are not enabled")
+
+// ErrInvalidSignature indicates a signed request or receipt does not carry a
+// valid signature from the peer it names
+const ErrInvalidSignature = errorType("invalid signature")
+
+// ErrPeerMisbehaving indicates a request was rejected because the peer that
+// sent it broke the protocol, for example by sending an invalid signature or
+// restarting a channel it did not open. Such peers get a strike towards a ban
+const ErrPeerMisbehaving = errorType("peer misbehaving")
+
+// ErrRateLimited indicates a request was dropped because the peer that sent
+// it is over its request rate limit or is banned
+const ErrRateLimited = errorType("peer is rate limited or banned")
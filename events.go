@@ -1,6 +1,11 @@
 package datatransfer
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+)
 
 // EventCode is a name for an event that occurs on a data transfer channel
 type EventCode int
@@ -73,6 +78,26 @@ const (
 
 	// DataQueued is emmited is read and queued for sending to the remote peer
 	DataQueued
+
+	// TimedPause emits when this node pauses transfer until a deadline, after
+	// which it resumes automatically
+	TimedPause
+
+	// TimedPauseExpired emits when the deadline of a timed pause passes, just
+	// before this node resumes transfer
+	TimedPauseExpired
+
+	// RequestSigned emits when the initiator's signature over the request that
+	// opened a channel is recorded
+	RequestSigned
+
+	// ReceiptIssued emits when this node signs a receipt for the data it
+	// received on a channel that completed
+	ReceiptIssued
+
+	// ReceiptReceived emits when the receiver of the data sent on a channel
+	// sends this node the receipt it signed for the data
+	ReceiptReceived
 )
 
 // Events are human readable names for data transfer events
@@ -97,6 +122,11 @@ var Events = map[EventCode]string{
 	Complete:                    "Complete",
 	CompleteCleanupOnRestart:    "CompleteCleanupOnRestart",
 	DataQueued:                  "DataQueued",
+	TimedPause:                  "TimedPause",
+	TimedPauseExpired:           "TimedPauseExpired",
+	RequestSigned:               "RequestSigned",
+	ReceiptIssued:               "ReceiptIssued",
+	ReceiptReceived:             "ReceiptReceived",
 }
 
 // Event is a struct containing information about a data transfer event
@@ -106,8 +136,144 @@ type Event struct {
 	Timestamp time.Time // when the event happened
 }
 
+// MarshalJSON encodes the event with the human readable name of its code
+func (e Event) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Code      string
+		Message   string
+		Timestamp time.Time
+	}{
+		Code:      Events[e.Code],
+		Message:   e.Message,
+		Timestamp: e.Timestamp,
+	})
+}
+
 // Subscriber is a callback that is called when events are emitted
 type Subscriber func(event Event, channelState ChannelState)
 
 // Unsubscribe is a function that gets called to unsubscribe from data transfer events
 type Unsubscribe func()
+
+// OverflowPolicy determines what happens when an event is published to a
+// subscriber whose event queue is full
+type OverflowPolicy int
+
+const (
+	// OverflowDropOldest discards the oldest queued event to make room. This
+	// is the default, so a slow subscriber never holds up other subscribers
+	OverflowDropOldest OverflowPolicy = iota
+
+	// OverflowDisconnect unsubscribes the subscriber. Events already queued
+	// are still delivered
+	OverflowDisconnect
+
+	// OverflowBlock waits until the subscriber makes room in its queue. This
+	// holds up delivery of events to every other subscriber, so it should
+	// only be used by subscribers that must see every event
+	OverflowBlock
+)
+
+// DefaultSubscriberQueueSize is the number of events queued for a filtered
+// subscriber when no queue size is set
+const DefaultSubscriberQueueSize = 256
+
+// SubscribeConfig is the configuration of a filtered subscription
+type SubscribeConfig struct {
+	// ChannelID, if set, limits events to a single channel
+	ChannelID *ChannelID
+	// EventCodes, if non empty, limits events to the given codes
+	EventCodes map[EventCode]struct{}
+	// Peer, if set, limits events to channels with the given peer on the
+	// other side
+	Peer peer.ID
+	// QueueSize is the maximum number of events waiting to be delivered
+	QueueSize int
+	// OverflowPolicy determines what happens when the queue is full
+	OverflowPolicy OverflowPolicy
+}
+
+// SubscribeOption configures a filtered subscription
+type SubscribeOption func(*SubscribeConfig)
+
+// NewSubscribeConfig returns the configuration for the given subscribe options
+func NewSubscribeConfig(options ...SubscribeOption) SubscribeConfig {
+	cfg := SubscribeConfig{
+		QueueSize:      DefaultSubscriberQueueSize,
+		OverflowPolicy: OverflowDropOldest,
+	}
+	for _, option := range options {
+		option(&cfg)
+	}
+	return cfg
+}
+
+// Matches returns true if an event on the given channel passes the filters in
+// the configuration
+func (cfg SubscribeConfig) Matches(event Event, channelState ChannelState) bool {
+	if cfg.ChannelID != nil && channelState.ChannelID() != *cfg.ChannelID {
+		return false
+	}
+	if len(cfg.EventCodes) > 0 {
+		if _, ok := cfg.EventCodes[event.Code]; !ok {
+			return false
+		}
+	}
+	if cfg.Peer != "" && channelState.OtherPeer() != cfg.Peer {
+		return false
+	}
+	return true
+}
+
+// FilterChannel limits a subscription to events on the given channel
+func FilterChannel(chid ChannelID) SubscribeOption {
+	return func(cfg *SubscribeConfig) {
+		cfg.ChannelID = &chid
+	}
+}
+
+// FilterEventCodes limits a subscription to events with the given codes
+func FilterEventCodes(codes ...EventCode) SubscribeOption {
+	return func(cfg *SubscribeConfig) {
+		if cfg.EventCodes == nil {
+			cfg.EventCodes = make(map[EventCode]struct{}, len(codes))
+		}
+		for _, code := range codes {
+			cfg.EventCodes[code] = struct{}{}
+		}
+	}
+}
+
+// FilterPeer limits a subscription to events on channels with the given peer
+func FilterPeer(p peer.ID) SubscribeOption {
+	return func(cfg *SubscribeConfig) {
+		cfg.Peer = p
+	}
+}
+
+// SubscriberQueueSize sets the number of events that can wait to be delivered
+// to a subscriber
+func SubscriberQueueSize(size int) SubscribeOption {
+	return func(cfg *SubscribeConfig) {
+		cfg.QueueSize = size
+	}
+}
+
+// SubscriberOverflowPolicy sets what happens when a subscriber's queue is full
+func SubscriberOverflowPolicy(policy OverflowPolicy) SubscribeOption {
+	return func(cfg *SubscribeConfig) {
+		cfg.OverflowPolicy = policy
+	}
+}
+
+// JournalEntry is an event recorded in the durable event journal
+type JournalEntry struct {
+	// Sequence is the position of the entry in the journal
+	Sequence uint64
+	// ChannelID is the channel the event happened on
+	ChannelID ChannelID
+	// Event is the event that happened
+	Event Event
+	// Status is the status of the channel after the event
+	Status Status
+}
@@ -0,0 +1,87 @@
+package impl
+
+import (
+	"sync"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	"golang.org/x/xerrors"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+)
+
+// revalidatorBindings records which revalidator owns each channel, by the
+// voucher type the revalidator is registered for, so that revalidation calls
+// can be dispatched directly instead of offering them to every revalidator
+type revalidatorBindings struct {
+	ds datastore.Datastore
+
+	lk sync.RWMutex
+	// cache holds bindings read from or written to the datastore.
+	// EmptyTypeIdentifier records that a channel is known to have no binding
+	cache map[datatransfer.ChannelID]datatransfer.TypeIdentifier
+}
+
+func newRevalidatorBindings(ds datastore.Batching) *revalidatorBindings {
+	return &revalidatorBindings{
+		ds:    namespace.Wrap(ds, datastore.NewKey("/revalidator-bindings")),
+		cache: make(map[datatransfer.ChannelID]datatransfer.TypeIdentifier),
+	}
+}
+
+// bind records that the revalidator for the given voucher type owns the channel
+func (rb *revalidatorBindings) bind(chid datatransfer.ChannelID, revalidatorType datatransfer.TypeIdentifier) error {
+	rb.lk.Lock()
+	defer rb.lk.Unlock()
+	if rb.cache[chid] == revalidatorType {
+		return nil
+	}
+	rb.cache[chid] = revalidatorType
+	if err := rb.ds.Put(datastore.NewKey(chid.String()), []byte(revalidatorType)); err != nil {
+		return xerrors.Errorf("binding channel %s to revalidator %s: %w", chid, revalidatorType, err)
+	}
+	return nil
+}
+
+// get returns the voucher type of the revalidator that owns the channel, if any
+func (rb *revalidatorBindings) get(chid datatransfer.ChannelID) (datatransfer.TypeIdentifier, bool) {
+	rb.lk.RLock()
+	revalidatorType, cached := rb.cache[chid]
+	rb.lk.RUnlock()
+	if cached {
+		return revalidatorType, revalidatorType != datatransfer.EmptyTypeIdentifier
+	}
+
+	value, err := rb.ds.Get(datastore.NewKey(chid.String()))
+	if err != nil && err != datastore.ErrNotFound {
+		log.Warnf("channel %s: reading revalidator binding: %s", chid, err)
+		return datatransfer.EmptyTypeIdentifier, false
+	}
+	revalidatorType = datatransfer.TypeIdentifier(value)
+
+	rb.lk.Lock()
+	defer rb.lk.Unlock()
+	// a binding may have been made while reading from the datastore
+	if current, ok := rb.cache[chid]; ok {
+		revalidatorType = current
+	} else {
+		rb.cache[chid] = revalidatorType
+	}
+	return revalidatorType, revalidatorType != datatransfer.EmptyTypeIdentifier
+}
+
+// unbind removes the binding for a channel
+func (rb *revalidatorBindings) unbind(chid datatransfer.ChannelID) error {
+	rb.lk.Lock()
+	defer rb.lk.Unlock()
+	revalidatorType, cached := rb.cache[chid]
+	delete(rb.cache, chid)
+	if cached && revalidatorType == datatransfer.EmptyTypeIdentifier {
+		return nil
+	}
+	err := rb.ds.Delete(datastore.NewKey(chid.String()))
+	if err != nil && err != datastore.ErrNotFound {
+		return xerrors.Errorf("removing revalidator binding for channel %s: %w", chid, err)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package impl
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/libp2p/go-libp2p-core/peer"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+	"github.com/filecoin-project/go-data-transfer/encoding"
+)
+
+// DeduplicatePulls attaches pulls for the same root and selector with the
+// same voucher from the same peer over the same transport to a single channel
+// while it is in progress. Every caller receives the ID of the shared
+// channel, so events and completion for it reach each of them. Closing a
+// shared channel with CloseDataTransferChannel only cancels it once every
+// caller has closed it, while CancelPeer cancels it straight away
+func DeduplicatePulls() DataTransferOption {
+	return func(m *manager) {
+		m.pulls = newSharedPulls()
+	}
+}
+
+// pullKey identifies pulls that can share a channel
+type pullKey struct {
+	peer        peer.ID
+	root        cid.Cid
+	selector    string
+	voucherType datatransfer.TypeIdentifier
+	voucher     string
+	transport   string
+}
+
+// sharedPull is a channel opened for one or more identical pulls
+type sharedPull struct {
+	key     pullKey
+	ready   chan struct{}
+	chid    datatransfer.ChannelID
+	err     error
+	callers int
+}
+
+// sharedPulls tracks the pulls in progress that new identical pulls attach to
+type sharedPulls struct {
+	lk     sync.Mutex
+	byKey  map[pullKey]*sharedPull
+	byChid map[datatransfer.ChannelID]*sharedPull
+}
+
+func newSharedPulls() *sharedPulls {
+	return &sharedPulls{
+		byKey:  make(map[pullKey]*sharedPull),
+		byChid: make(map[datatransfer.ChannelID]*sharedPull),
+	}
+}
+
+// join attaches a caller to the pull for the key, and returns whether the
+// caller is the first one and so must open the channel
+func (sp *sharedPulls) join(key pullKey) (*sharedPull, bool) {
+	sp.lk.Lock()
+	defer sp.lk.Unlock()
+	if pull, ok := sp.byKey[key]; ok {
+		pull.callers++
+		return pull, false
+	}
+	pull := &sharedPull{key: key, ready: make(chan struct{}), callers: 1}
+	sp.byKey[key] = pull
+	return pull, true
+}
+
+// opened records the result of opening the channel for a pull, and releases
+// the callers waiting on it. A pull that failed to open is forgotten so the
+// next identical pull tries again
+func (sp *sharedPulls) opened(pull *sharedPull, chid datatransfer.ChannelID, err error) {
+	sp.lk.Lock()
+	pull.chid, pull.err = chid, err
+	if err != nil {
+		delete(sp.byKey, pull.key)
+	} else {
+		sp.byChid[chid] = pull
+	}
+	sp.lk.Unlock()
+	close(pull.ready)
+}
+
+// abandon detaches a caller that stopped waiting for the channel to open
+func (sp *sharedPulls) abandon(pull *sharedPull) {
+	sp.lk.Lock()
+	defer sp.lk.Unlock()
+	pull.callers--
+}
+
+// leave detaches a caller from a shared channel, and returns true if other
+// callers are still attached to it
+func (sp *sharedPulls) leave(chid datatransfer.ChannelID) bool {
+	sp.lk.Lock()
+	defer sp.lk.Unlock()
+	pull, ok := sp.byChid[chid]
+	if !ok {
+		return false
+	}
+	pull.callers--
+	if pull.callers > 0 {
+		return true
+	}
+	sp.forget(pull)
+	return false
+}
+
+// remove forgets a channel that has ended, so later pulls open a new one
+func (sp *sharedPulls) remove(chid datatransfer.ChannelID) {
+	sp.lk.Lock()
+	defer sp.lk.Unlock()
+	if pull, ok := sp.byChid[chid]; ok {
+		sp.forget(pull)
+	}
+}
+
+func (sp *sharedPulls) forget(pull *sharedPull) {
+	delete(sp.byChid, pull.chid)
+	if sp.byKey[pull.key] == pull {
+		delete(sp.byKey, pull.key)
+	}
+}
+
+// openSharedPull attaches the pull to an identical pull in progress, or
+// opens a channel for it that later identical pulls can attach to
+func (m *manager) openSharedPull(ctx context.Context, requestTo peer.ID, voucher datatransfer.Voucher, baseCid cid.Cid, selector ipld.Node, transportName string) (datatransfer.ChannelID, error) {
+	selBytes, err := encoding.Encode(selector)
+	if err != nil {
+		return datatransfer.ChannelID{}, err
+	}
+	voucherBytes, err := encoding.Encode(voucher)
+	if err != nil {
+		return datatransfer.ChannelID{}, err
+	}
+	key := pullKey{
+		peer:        requestTo,
+		root:        baseCid,
+		selector:    string(selBytes),
+		voucherType: voucher.Type(),
+		voucher:     string(voucherBytes),
+		transport:   transportName,
+	}
+	pull, first := m.pulls.join(key)
+	if first {
+		chid, err := m.openPullDataChannel(ctx, requestTo, voucher, baseCid, selector, transportName)
+		m.pulls.opened(pull, chid, err)
+		return chid, err
+	}
+
+	select {
+	case <-pull.ready:
+	case <-ctx.Done():
+		m.pulls.abandon(pull)
+		return datatransfer.ChannelID{}, ctx.Err()
+	}
+	if pull.err != nil {
+		return datatransfer.ChannelID{}, pull.err
+	}
+	log.Infof("pull from %s with base cid %s attached to channel %s", requestTo, baseCid, pull.chid)
+	return pull.chid, nil
+}
@@ -0,0 +1,97 @@
+package impl
+
+import (
+	"context"
+	"sync/atomic"
+
+	"golang.org/x/xerrors"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+	"github.com/filecoin-project/go-data-transfer/channels"
+)
+
+// Drain stops the manager opening or accepting new channels, and restarting or
+// resuming channels at a peer's request, then waits for the channels in
+// progress to finish until the context is done. Channels that are paused on
+// this node's side are not waited for. Channels still running when the context
+// is done are paused and their peers sent pause messages, so the channels can
+// be resumed once the manager restarts
+func (m *manager) Drain(ctx context.Context) error {
+	log.Info("drain data-transfer module")
+	atomic.StoreInt32(&m.draining, 1)
+
+	updated := make(chan struct{}, 1)
+	unsub := m.SubscribeToEvents(func(datatransfer.Event, datatransfer.ChannelState) {
+		select {
+		case updated <- struct{}{}:
+		default:
+		}
+	})
+	defer unsub()
+
+	for {
+		running, err := m.runningChannels()
+		if err != nil {
+			return err
+		}
+		if len(running) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return m.pauseForDrain(running)
+		case <-updated:
+		}
+	}
+}
+
+func (m *manager) isDraining() bool {
+	return atomic.LoadInt32(&m.draining) == 1
+}
+
+// runningChannels returns the channels that have not ended and are not paused
+// on this node's side
+func (m *manager) runningChannels() ([]datatransfer.ChannelState, error) {
+	chans, err := m.channels.InProgress()
+	if err != nil {
+		return nil, err
+	}
+	var running []datatransfer.ChannelState
+	for _, chst := range chans {
+		if channels.IsChannelTerminated(chst.Status()) || m.canResume(chst) {
+			continue
+		}
+		running = append(running, chst)
+	}
+	return running, nil
+}
+
+// pauseForDrain pauses the channels that did not finish while draining.
+// Channels that are cleaning up or finalizing cannot be paused and are left
+// to finish
+func (m *manager) pauseForDrain(running []datatransfer.ChannelState) error {
+	// the drain context is done, so pause with a fresh one
+	ctx := context.Background()
+	var paused, failed int
+	var firstErr error
+	for _, chst := range running {
+		if !m.canPause(chst) {
+			continue
+		}
+		chid := chst.ChannelID()
+		if err := m.PauseDataTransferChannel(ctx, chid); err != nil {
+			log.Warnf("unable to pause channel %s while draining: %s", chid, err)
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		paused++
+	}
+	log.Infof("drain deadline reached: paused %d channels", paused)
+	if firstErr != nil {
+		return xerrors.Errorf("failed to pause %d channels while draining: %w", failed, firstErr)
+	}
+	return nil
+}
@@ -26,5 +26,28 @@ func (ce *channelEnvironment) CleanupChannel(chid datatransfer.ChannelID) {
 	ce.m.reconnectsLk.Lock()
 	delete(ce.m.reconnects, chid)
 	ce.m.reconnectsLk.Unlock()
-	ce.m.transport.CleanupChannel(chid)
+	transport, err := ce.m.channelTransport(chid)
+	if err != nil {
+		log.Warnf("unable to clean up channel %s: %s", chid, err)
+		return
+	}
+	transport.CleanupChannel(chid)
+	if ce.m.staging != nil {
+		if err := ce.m.staging.discard(chid); err != nil {
+			log.Warnf("unable to discard staged blocks for channel %s: %s", chid, err)
+		}
+	}
+}
+
+func (ce *channelEnvironment) CompleteChannel(chid datatransfer.ChannelID) error {
+	if ce.m.staging == nil {
+		return nil
+	}
+	return ce.m.staging.commit(chid, func() (channelStore, error) {
+		return ce.m.chooseStagingStore(chid)
+	})
+}
+
+func (ce *channelEnvironment) IssueReceipt(receipt datatransfer.Receipt) (*datatransfer.Receipt, error) {
+	return ce.m.issueReceipt(receipt)
 }
@@ -3,7 +3,6 @@ package impl
 import (
 	"context"
 	"errors"
-	"time"
 
 	"github.com/ipfs/go-cid"
 	"github.com/ipld/go-ipld-prime"
@@ -31,6 +30,9 @@ func (m *manager) OnChannelOpened(chid datatransfer.ChannelID) error {
 }
 
 func (m *manager) OnDataReceived(chid datatransfer.ChannelID, link ipld.Link, size uint64) error {
+	if err := m.checkQuota(chid, size); err != nil {
+		return err
+	}
 	err := m.channels.DataReceived(chid, link.(cidlink.Link).Cid, size)
 	if err != nil {
 		return err
@@ -50,21 +52,13 @@ func (m *manager) OnDataReceived(chid datatransfer.ChannelID, link ipld.Link, si
 	m.reconnectsLk.RUnlock()
 
 	if chid.Initiator != m.peerID {
-		var result datatransfer.VoucherResult
-		var err error
-		var handled bool
-		_ = m.revalidators.Each(func(_ datatransfer.TypeIdentifier, _ encoding.Decoder, processor registry.Processor) error {
-			revalidator := processor.(datatransfer.Revalidator)
-			handled, result, err = revalidator.OnPushDataReceived(chid, size)
-			if handled {
-				return errors.New("stop processing")
-			}
-			return nil
+		result, err := m.revalidate(chid, func(revalidator datatransfer.Revalidator) (bool, datatransfer.VoucherResult, error) {
+			return revalidator.OnPushDataReceived(chid, size)
 		})
 		if err != nil || result != nil {
 			msg, err := m.processRevalidationResult(chid, result, err)
 			if msg != nil {
-				if err := m.dataTransferNetwork.SendMessage(context.TODO(), chid.Initiator, msg); err != nil {
+				if err := m.dataTransferNetwork.SendMessage(m.channelSpans.context(context.TODO(), chid), chid.Initiator, msg); err != nil {
 					return err
 				}
 			}
@@ -79,16 +73,8 @@ func (m *manager) OnDataQueued(chid datatransfer.ChannelID, link ipld.Link, size
 		return nil, err
 	}
 	if chid.Initiator != m.peerID {
-		var result datatransfer.VoucherResult
-		var err error
-		var handled bool
-		_ = m.revalidators.Each(func(_ datatransfer.TypeIdentifier, _ encoding.Decoder, processor registry.Processor) error {
-			revalidator := processor.(datatransfer.Revalidator)
-			handled, result, err = revalidator.OnPullDataSent(chid, size)
-			if handled {
-				return errors.New("stop processing")
-			}
-			return nil
+		result, err := m.revalidate(chid, func(revalidator datatransfer.Revalidator) (bool, datatransfer.VoucherResult, error) {
+			return revalidator.OnPullDataSent(chid, size)
 		})
 		if err != nil || result != nil {
 			return m.processRevalidationResult(chid, result, err)
@@ -115,17 +101,34 @@ func (m *manager) OnDataSent(chid datatransfer.ChannelID, link ipld.Link, size u
 }
 
 func (m *manager) OnRequestReceived(chid datatransfer.ChannelID, request datatransfer.Request) (datatransfer.Response, error) {
+	return m.onRequestReceived(requestedTransport(request), chid, request)
+}
+
+// onRequestReceived processes a request that arrived for the named transport
+func (m *manager) onRequestReceived(transportName string, chid datatransfer.ChannelID, request datatransfer.Request) (datatransfer.Response, error) {
+	if (request.IsNew() || request.IsRestart()) && !m.allowRequest(chid.Initiator) {
+		return nil, xerrors.Errorf("dropping request for channel %s: %w", chid, datatransfer.ErrRateLimited)
+	}
+
 	if request.IsRestart() {
-		return m.receiveRestartRequest(chid, request)
+		response, err := m.receiveRestartRequest(chid, request)
+		m.recordRejection(chid.Initiator, err)
+		return response, err
 	}
 
 	if request.IsNew() {
-		return m.receiveNewRequest(chid.Initiator, request)
+		response, err := m.receiveNewRequest(transportName, chid, request)
+		m.recordRejection(chid.Initiator, err)
+		return response, err
 	}
 	if request.IsCancel() {
 		log.Infof("channel %s: received cancel request, cleaning up channel", chid)
 
-		m.transport.CleanupChannel(chid)
+		transport, err := m.channelTransport(chid)
+		if err != nil {
+			return nil, err
+		}
+		transport.CleanupChannel(chid)
 		return nil, m.channels.Cancel(chid)
 	}
 	if request.IsVoucher() {
@@ -134,6 +137,10 @@ func (m *manager) OnRequestReceived(chid datatransfer.ChannelID, request datatra
 	if request.IsPaused() {
 		return nil, m.pauseOther(chid)
 	}
+	if m.isDraining() {
+		// the channel stays paused until the manager restarts
+		return nil, datatransfer.ErrDraining
+	}
 	err := m.resumeOther(chid)
 	if err != nil {
 		return nil, err
@@ -217,13 +224,13 @@ func (m *manager) OnRequestTimedOut(ctx context.Context, chid datatransfer.Chann
 		m.reconnects[chid] = reconnect
 	}
 	m.reconnectsLk.Unlock()
-	timer := time.NewTimer(m.channelRemoveTimeout)
+	timer := m.clock.NewTimer(m.channelRemoveTimeout)
 
 	go func() {
 		select {
 		case <-ctx.Done():
 		case <-reconnect:
-		case <-timer.C:
+		case <-timer.Chan():
 			channel, err := m.channels.GetByID(ctx, chid)
 			if err == nil {
 				if !(channels.IsChannelTerminated(channel.Status()) ||
@@ -263,12 +270,12 @@ func (m *manager) OnRequestDisconnected(ctx context.Context, chid datatransfer.C
 		m.reconnects[chid] = reconnect
 	}
 	m.reconnectsLk.Unlock()
-	timer := time.NewTimer(m.channelRemoveTimeout)
+	timer := m.clock.NewTimer(m.channelRemoveTimeout)
 	go func() {
 		select {
 		case <-ctx.Done():
 		case <-reconnect:
-		case <-timer.C:
+		case <-timer.Chan():
 			channel, err := m.channels.GetByID(ctx, chid)
 			if err == nil {
 				if !(channels.IsChannelTerminated(channel.Status()) ||
@@ -295,7 +302,7 @@ func (m *manager) OnChannelCompleted(chid datatransfer.ChannelID, success bool)
 			}
 			if msg != nil {
 				log.Infof("channel %s: sending completion message", chid)
-				if err := m.dataTransferNetwork.SendMessage(context.Background(), chid.Initiator, msg); err != nil {
+				if err := m.dataTransferNetwork.SendMessage(m.channelSpans.context(context.Background(), chid), chid.Initiator, msg); err != nil {
 					log.Warnf("channel %s: failed to send completion message: %s", chid, err)
 					return m.OnRequestDisconnected(context.TODO(), chid)
 				}
@@ -333,11 +340,32 @@ func (m *manager) receiveRestartRequest(chid datatransfer.ChannelID, incoming da
 }
 
 func (m *manager) receiveNewRequest(
-	initiator peer.ID,
+	transportName string,
+	chid datatransfer.ChannelID,
 	incoming datatransfer.Request) (datatransfer.Response, error) {
+	initiator := chid.Initiator
 	log.Infof("received new channel request from %s", initiator)
 
-	result, err := m.acceptRequest(initiator, incoming)
+	ctx := context.Background()
+	if carrier, ok := incoming.(datatransfer.TraceCarrier); ok && carrier.TraceContext() != nil {
+		ctx = m.tracer.Extract(ctx, carrier.TraceContext())
+	}
+	_, started := m.channelSpans.start(ctx, chid)
+
+	result, err := m.acceptRequest(transportName, chid, incoming)
+	if started && err != nil && err != datatransfer.ErrPause && err != datatransfer.ErrDeferred {
+		// the channel span is otherwise ended when the channel terminates
+		m.channelSpans.end(chid, err)
+	}
+	if err == datatransfer.ErrDeferred {
+		log.Infof("deferring acceptance of request from %s", initiator)
+		if incoming.IsPull() {
+			// hold the transport response paused until the request is accepted
+			// or rejected
+			return nil, datatransfer.ErrPause
+		}
+		return nil, err
+	}
 	msg, msgErr := m.response(false, true, err, incoming.TransferID(), result)
 	if msgErr != nil {
 		return nil, msgErr
@@ -350,9 +378,35 @@ func (m *manager) restartRequest(chid datatransfer.ChannelID,
 
 	initiator := chid.Initiator
 	if m.peerID == initiator {
-		return nil, xerrors.New("initiator cannot be manager peer for a restart request")
+		return nil, peerMisbehaving(xerrors.New("initiator cannot be manager peer for a restart request"))
+	}
+
+	ctx := context.Background()
+	if carrier, ok := incoming.(datatransfer.TraceCarrier); ok && carrier.TraceContext() != nil {
+		ctx = m.tracer.Extract(ctx, carrier.TraceContext())
 	}
+	// channels restored from the datastore have no span yet
+	_, started := m.channelSpans.start(ctx, chid)
+	_, span := m.channelSpans.startChild(ctx, chid, "restart")
 
+	result, err := m.restartChannelRequest(chid, incoming)
+	recordSpanError(span, err)
+	span.End()
+	if started && err != nil && err != datatransfer.ErrPause {
+		// a restart for an unknown or invalid channel must not leave a span
+		// behind, since no terminal status will ever end it
+		m.channelSpans.end(chid, err)
+	}
+	return result, err
+}
+
+func (m *manager) restartChannelRequest(chid datatransfer.ChannelID,
+	incoming datatransfer.Request) (datatransfer.VoucherResult, error) {
+
+	if m.isDraining() {
+		return nil, datatransfer.ErrDraining
+	}
+	initiator := chid.Initiator
 	if err := m.validateRestartRequest(context.Background(), initiator, chid, incoming); err != nil {
 		return nil, xerrors.Errorf("restart request for channel %s failed validation: %w", chid, err)
 	}
@@ -362,12 +416,20 @@ func (m *manager) restartRequest(chid datatransfer.ChannelID,
 		return nil, err
 	}
 
-	voucher, result, err := m.validateVoucher(initiator, incoming, incoming.IsPull(), incoming.BaseCid(), stor)
+	voucher, result, err := m.validateVoucher(chid, initiator, incoming, incoming.IsPull(), incoming.BaseCid(), stor)
 	if err != nil && err != datatransfer.ErrPause {
 		return result, xerrors.Errorf("failed to validate voucher: %w", err)
 	}
 	voucherErr := err
 
+	transport, err := m.channelTransport(chid)
+	if err != nil {
+		return result, err
+	}
+	if err := m.configureStore(chid, voucher, transport, !incoming.IsPull(), true); err != nil {
+		return result, err
+	}
+
 	if result != nil {
 		err := m.channels.NewVoucherResult(chid, result)
 		if err != nil {
@@ -377,10 +439,10 @@ func (m *manager) restartRequest(chid datatransfer.ChannelID,
 	if err := m.channels.Restart(chid); err != nil {
 		return result, xerrors.Errorf("failed to restart channel %s: %w", chid, err)
 	}
-	processor, has := m.transportConfigurers.Processor(voucher.Type())
-	if has {
-		transportConfigurer := processor.(datatransfer.TransportConfigurer)
-		transportConfigurer(chid, voucher, m.transport)
+	if _, bound := m.revalidatorBindings.get(chid); !bound {
+		if _, has := m.revalidators.Processor(voucher.Type()); has {
+			m.bindRevalidator(chid, voucher.Type())
+		}
 	}
 	m.dataTransferNetwork.Protect(initiator, chid.String())
 	if voucherErr == datatransfer.ErrPause {
@@ -393,20 +455,38 @@ func (m *manager) restartRequest(chid datatransfer.ChannelID,
 }
 
 func (m *manager) acceptRequest(
-	initiator peer.ID,
+	transportName string,
+	chid datatransfer.ChannelID,
 	incoming datatransfer.Request) (datatransfer.VoucherResult, error) {
 
+	if m.isDraining() {
+		return nil, datatransfer.ErrDraining
+	}
+	transport, err := m.transports.get(transportName)
+	if err != nil {
+		return nil, err
+	}
+
+	initiator := chid.Initiator
 	stor, err := incoming.Selector()
 	if err != nil {
 		return nil, err
 	}
+	signed, sig, err := m.verifyRequestSignature(chid, incoming)
+	if err != nil {
+		return nil, err
+	}
 
-	voucher, result, err := m.validateVoucher(initiator, incoming, incoming.IsPull(), incoming.BaseCid(), stor)
-	if err != nil && err != datatransfer.ErrPause {
+	voucher, result, err := m.validateVoucher(chid, initiator, incoming, incoming.IsPull(), incoming.BaseCid(), stor)
+	if err != nil && err != datatransfer.ErrPause && err != datatransfer.ErrDeferred {
 		return result, err
 	}
 	voucherErr := err
-
+	if voucherErr == datatransfer.ErrDeferred && incoming.IsPull() {
+		if _, ok := transport.(datatransfer.PauseableTransport); !ok {
+			return result, xerrors.Errorf("cannot defer pull request: %w", datatransfer.ErrUnsupported)
+		}
+	}
 	var dataSender, dataReceiver peer.ID
 	if incoming.IsPull() {
 		dataSender = m.peerID
@@ -416,29 +496,51 @@ func (m *manager) acceptRequest(
 		dataReceiver = m.peerID
 	}
 
-	chid, err := m.channels.CreateNew(m.peerID, incoming.TransferID(), incoming.BaseCid(), stor, voucher, initiator, dataSender, dataReceiver)
+	_, err = m.channels.CreateNew(m.peerID, incoming.TransferID(), incoming.BaseCid(), stor, voucher, initiator, dataSender, dataReceiver, transportName)
 	if err != nil {
 		return result, err
 	}
+	// the store is only selected once the channel exists, so a duplicate
+	// request cannot select it again. From here on the channel has to be
+	// failed and cleaned up in the transport if it cannot be accepted
+	fail := func(err error) (datatransfer.VoucherResult, error) {
+		transport.CleanupChannel(chid)
+		if fsmErr := m.channels.Error(chid, err); fsmErr != nil {
+			log.Warnf("unable to fail channel %s: %s", chid, fsmErr)
+		}
+		return result, err
+	}
+	m.transports.setChannel(chid, transportName)
+	if err := m.configureStore(chid, voucher, transport, !incoming.IsPull(), true); err != nil {
+		return fail(err)
+	}
+	if sig != nil {
+		if err := m.channels.RequestSigned(chid, *signed, sig); err != nil {
+			return fail(err)
+		}
+	}
+	if _, has := m.revalidators.Processor(voucher.Type()); has {
+		m.bindRevalidator(chid, voucher.Type())
+	}
 	if result != nil {
 		err := m.channels.NewVoucherResult(chid, result)
 		if err != nil {
-			return result, err
+			return fail(err)
 		}
 	}
-	if err := m.channels.Accept(chid); err != nil {
-		return result, err
+	m.dataTransferNetwork.Protect(initiator, chid.String())
+	if voucherErr == datatransfer.ErrDeferred {
+		// leave the channel in the Requested state until AcceptChannel or
+		// RejectChannel is called
+		return result, voucherErr
 	}
-	processor, has := m.transportConfigurers.Processor(voucher.Type())
-	if has {
-		transportConfigurer := processor.(datatransfer.TransportConfigurer)
-		transportConfigurer(chid, voucher, m.transport)
+	if err := m.channels.Accept(chid); err != nil {
+		return fail(err)
 	}
-	m.dataTransferNetwork.Protect(initiator, chid.String())
 	if voucherErr == datatransfer.ErrPause {
 		err := m.channels.PauseResponder(chid)
 		if err != nil {
-			return result, err
+			return fail(err)
 		}
 	}
 	return result, voucherErr
@@ -447,21 +549,37 @@ func (m *manager) acceptRequest(
 // validateVoucher converts a voucher in an incoming message to its appropriate
 // voucher struct, then runs the validator and returns the results.
 // returns error if:
+//   * the voucher or selector exceed the request limits
 //   * reading voucher fails
 //   * deserialization of selector fails
 //   * validation fails
-func (m *manager) validateVoucher(sender peer.ID,
+func (m *manager) validateVoucher(chid datatransfer.ChannelID,
+	sender peer.ID,
 	incoming datatransfer.Request,
 	isPull bool,
 	baseCid cid.Cid,
 	stor ipld.Node) (datatransfer.Voucher, datatransfer.VoucherResult, error) {
-	vouch, err := m.decodeVoucher(incoming, m.validatedTypes)
+	_, span := m.channelSpans.startChild(context.Background(), chid, "validate")
+	defer span.End()
+
+	if err := m.limits.checkRequest(incoming); err != nil {
+		span.RecordError(err)
+		return nil, nil, err
+	}
+	vouch, processor, err := m.decodeVoucher(incoming, m.validatedTypes)
 	if err != nil {
+		span.RecordError(err)
+		return nil, nil, err
+	}
+	span.SetAttribute("voucher-type", string(vouch.Type()))
+	requestValidator, ok := processor.(datatransfer.RequestValidator)
+	if !ok {
+		err := xerrors.Errorf("no request validator for voucher type %s: %w", vouch.Type(), datatransfer.ErrHandlerNotSet)
+		span.RecordError(err)
 		return nil, nil, err
 	}
 	var validatorFunc func(peer.ID, datatransfer.Voucher, cid.Cid, ipld.Node) (datatransfer.VoucherResult, error)
-	processor, _ := m.validatedTypes.Processor(vouch.Type())
-	validator := processor.(datatransfer.RequestValidator)
+	validator := m.middlewares.validator(vouch.Type(), requestValidator)
 	if isPull {
 		validatorFunc = validator.ValidatePull
 	} else {
@@ -469,31 +587,153 @@ func (m *manager) validateVoucher(sender peer.ID,
 	}
 
 	result, err := validatorFunc(sender, vouch, baseCid, stor)
+	recordSpanError(span, err)
 	return vouch, result, err
 }
 
+// configureStore chooses the store for the channel and sets the transport to
+// use it. For channels this node responds to, the validator for the voucher
+// type selects a store first. The transport configurer for the voucher type
+// then runs, and a store it sets takes precedence. If this node receives the
+// channel's data and staging is enabled, the blocks are staged and the chosen
+// store is where they are committed to
+func (m *manager) configureStore(chid datatransfer.ChannelID, voucher datatransfer.Voucher, transport datatransfer.Transport, receiving bool, responder bool) error {
+	var store channelStore
+	if responder {
+		var err error
+		store, err = m.selectStore(chid, voucher)
+		if err != nil {
+			return err
+		}
+	}
+	if !receiving || m.staging == nil {
+		if !store.isDefault() {
+			loader, storer := store.loaderStorer()
+			if err := m.useStore(chid, transport, loader, storer); err != nil {
+				return err
+			}
+		}
+		m.configureTransport(chid, voucher, transport)
+		return nil
+	}
+	recording := &storeRecordingTransport{Transport: transport, store: store}
+	m.configureTransport(chid, voucher, recording)
+	loader, storer := m.staging.stage(chid, recording.store)
+	return m.useStore(chid, transport, loader, storer)
+}
+
+// chooseStagingStore chooses the store the blocks staged for a channel are
+// committed to again, the same way configureStore does, for a channel that
+// completes after this node restarted. The transport keeps using the store it
+// uses for the channel. It is called while the channel completes, so it
+// reads the channel's stored state
+func (m *manager) chooseStagingStore(chid datatransfer.ChannelID) (channelStore, error) {
+	chst, err := m.channels.Peek(chid)
+	if err != nil {
+		return channelStore{}, err
+	}
+	transport, err := m.channelTransport(chid)
+	if err != nil {
+		return channelStore{}, err
+	}
+	var store channelStore
+	if chid.Responder == m.peerID {
+		store, err = m.selectStore(chid, chst.Voucher())
+		if err != nil {
+			return channelStore{}, err
+		}
+	}
+	recording := &storeRecordingTransport{Transport: transport, store: store}
+	m.configureTransport(chid, chst.Voucher(), recording)
+	return recording.store, nil
+}
+
+// selectStore asks the validator for the voucher type which store to use for
+// the channel's data
+func (m *manager) selectStore(chid datatransfer.ChannelID, voucher datatransfer.Voucher) (channelStore, error) {
+	processor, has := m.validatedTypes.Processor(voucher.Type())
+	if !has {
+		return channelStore{}, nil
+	}
+	if bsSelector, ok := processor.(datatransfer.BlockstoreSelectingValidator); ok {
+		bs, err := bsSelector.SelectBlockstore(chid, voucher)
+		if err != nil {
+			return channelStore{}, xerrors.Errorf("failed to select blockstore for channel %s: %w", chid, err)
+		}
+		return channelStore{bs: bs}, nil
+	}
+	if storeSelector, ok := processor.(datatransfer.StoreSelectingValidator); ok {
+		loader, storer, err := storeSelector.SelectStore(chid, voucher)
+		if err != nil {
+			return channelStore{}, xerrors.Errorf("failed to select store for channel %s: %w", chid, err)
+		}
+		return channelStore{loader: loader, storer: storer}, nil
+	}
+	return channelStore{}, nil
+}
+
+// configureTransport runs the transport configurer for the voucher type, if
+// there is one
+func (m *manager) configureTransport(chid datatransfer.ChannelID, voucher datatransfer.Voucher, transport datatransfer.Transport) {
+	processor, has := m.transportConfigurers.Processor(voucher.Type())
+	if has {
+		transportConfigurer := processor.(datatransfer.TransportConfigurer)
+		transportConfigurer(chid, voucher, transport)
+	}
+}
+
+// useStore sets the transport to use the given loader and storer for the
+// channel
+func (m *manager) useStore(chid datatransfer.ChannelID, transport datatransfer.Transport, loader ipld.Loader, storer ipld.Storer) error {
+	configurable, ok := transport.(datatransfer.StoreConfigurableTransport)
+	if !ok {
+		return xerrors.Errorf("cannot use store for channel %s: %w", chid, datatransfer.ErrUnsupported)
+	}
+	if err := configurable.UseStore(chid, loader, storer); err != nil {
+		return xerrors.Errorf("failed to use store for channel %s: %w", chid, err)
+	}
+	return nil
+}
+
 // revalidateVoucher converts a voucher in an incoming message to its appropriate
 // voucher struct, then runs the revalidator and returns the results.
 // returns error if:
+//   * the voucher exceeds the maximum voucher size
 //   * reading voucher fails
 //   * deserialization of selector fails
 //   * validation fails
 func (m *manager) revalidateVoucher(chid datatransfer.ChannelID,
 	incoming datatransfer.Request) (datatransfer.Voucher, datatransfer.VoucherResult, error) {
-	vouch, err := m.decodeVoucher(incoming, m.revalidators)
+	_, span := m.channelSpans.startChild(context.Background(), chid, "revalidate")
+	defer span.End()
+
+	if err := m.limits.checkVoucher(incoming); err != nil {
+		span.RecordError(err)
+		return nil, nil, err
+	}
+	vouch, processor, err := m.decodeVoucher(incoming, m.revalidators)
 	if err != nil {
+		span.RecordError(err)
+		return nil, nil, err
+	}
+	span.SetAttribute("voucher-type", string(vouch.Type()))
+	revalidator, ok := processor.(datatransfer.Revalidator)
+	if !ok {
+		err := xerrors.Errorf("no revalidator for voucher type %s: %w", vouch.Type(), datatransfer.ErrHandlerNotSet)
+		span.RecordError(err)
 		return nil, nil, err
 	}
-	processor, _ := m.revalidators.Processor(vouch.Type())
-	validator := processor.(datatransfer.Revalidator)
+	validator := m.middlewares.revalidator(vouch.Type(), revalidator)
 
 	result, err := validator.Revalidate(chid, vouch)
+	recordSpanError(span, err)
 	return vouch, result, err
 }
 
 func (m *manager) processUpdateVoucher(chid datatransfer.ChannelID, request datatransfer.Request) (datatransfer.Response, error) {
 	vouch, result, voucherErr := m.revalidateVoucher(chid, request)
 	if vouch != nil {
+		m.bindRevalidator(chid, vouch.Type())
 		err := m.channels.NewVoucher(chid, vouch)
 		if err != nil {
 			return nil, err
@@ -543,18 +783,53 @@ func (m *manager) processRevalidationResult(chid datatransfer.ChannelID, result
 	return vresMessage, resultErr
 }
 
-func (m *manager) completeMessage(chid datatransfer.ChannelID) (datatransfer.Response, error) {
+// revalidate makes a revalidation call on the revalidator that owns the
+// channel. Channels with no recorded owner are offered to each revalidator in
+// turn, and the first revalidator to handle the call becomes the owner
+func (m *manager) revalidate(chid datatransfer.ChannelID,
+	call func(datatransfer.Revalidator) (bool, datatransfer.VoucherResult, error)) (datatransfer.VoucherResult, error) {
+	if revalidatorType, bound := m.revalidatorBindings.get(chid); bound {
+		processor, has := m.revalidators.Processor(revalidatorType)
+		if !has {
+			// the revalidator was unregistered, so the channel continues
+			// without revalidation
+			return nil, nil
+		}
+		_, result, err := call(m.middlewares.revalidator(revalidatorType, processor.(datatransfer.Revalidator)))
+		return result, err
+	}
+
 	var result datatransfer.VoucherResult
-	var resultErr error
+	var err error
 	var handled bool
-	_ = m.revalidators.Each(func(_ datatransfer.TypeIdentifier, _ encoding.Decoder, processor registry.Processor) error {
-		revalidator := processor.(datatransfer.Revalidator)
-		handled, result, resultErr = revalidator.OnComplete(chid)
+	_ = m.revalidators.Each(func(revalidatorType datatransfer.TypeIdentifier, _ encoding.Decoder, processor registry.Processor) error {
+		revalidator := m.middlewares.revalidator(revalidatorType, processor.(datatransfer.Revalidator))
+		handled, result, err = call(revalidator)
 		if handled {
+			m.bindRevalidator(chid, revalidatorType)
 			return errors.New("stop processing")
 		}
 		return nil
 	})
+	return result, err
+}
+
+// bindRevalidator records the revalidator for the given voucher type as the
+// owner of the channel
+func (m *manager) bindRevalidator(chid datatransfer.ChannelID, revalidatorType datatransfer.TypeIdentifier) {
+	if err := m.revalidatorBindings.bind(chid, revalidatorType); err != nil {
+		log.Warnf("channel %s: %s", chid, err)
+	}
+}
+
+func (m *manager) completeMessage(chid datatransfer.ChannelID) (datatransfer.Response, error) {
+	_, span := m.channelSpans.startChild(context.Background(), chid, "revalidate")
+	span.SetAttribute("stage", "complete")
+	result, resultErr := m.revalidate(chid, func(revalidator datatransfer.Revalidator) (bool, datatransfer.VoucherResult, error) {
+		return revalidator.OnComplete(chid)
+	})
+	recordSpanError(span, resultErr)
+	span.End()
 	if result != nil {
 		err := m.channels.NewVoucherResult(chid, result)
 		if err != nil {
@@ -10,9 +10,11 @@ import (
 	"github.com/hannahhoward/go-pubsub"
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
 	logging "github.com/ipfs/go-log/v2"
 	"github.com/ipld/go-ipld-prime"
 	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/libp2p/go-libp2p-core/crypto"
 	"github.com/libp2p/go-libp2p-core/peer"
 	"golang.org/x/xerrors"
 
@@ -21,11 +23,16 @@ import (
 	datatransfer "github.com/filecoin-project/go-data-transfer"
 	"github.com/filecoin-project/go-data-transfer/channels"
 	"github.com/filecoin-project/go-data-transfer/cidlists"
+	"github.com/filecoin-project/go-data-transfer/clock"
 	"github.com/filecoin-project/go-data-transfer/encoding"
+	"github.com/filecoin-project/go-data-transfer/journal"
 	"github.com/filecoin-project/go-data-transfer/message"
 	"github.com/filecoin-project/go-data-transfer/network"
+	"github.com/filecoin-project/go-data-transfer/peerstats"
 	"github.com/filecoin-project/go-data-transfer/pushchannelmonitor"
 	"github.com/filecoin-project/go-data-transfer/registry"
+	"github.com/filecoin-project/go-data-transfer/staging"
+	"github.com/filecoin-project/go-data-transfer/tracing"
 )
 
 var log = logging.Logger("dt-impl")
@@ -36,18 +43,46 @@ type manager struct {
 	resultTypes           *registry.Registry
 	revalidators          *registry.Registry
 	transportConfigurers  *registry.Registry
+	middlewares           *validatorMiddlewares
+	retiredVoucherTypes   *retiredDecoders
+	retiredResultTypes    *retiredDecoders
+	revalidatorBindings   *revalidatorBindings
+	journal               *journal.Journal
+	journalRetention      *journal.Retention
+	journalEvents         []datatransfer.EventCode
+	peerStats             *peerstats.Tracker
+	peerStatsWeight       *float64
 	pubSub                *pubsub.PubSub
 	readySub              *pubsub.PubSub
 	channels              *channels.Channels
 	peerID                peer.ID
-	transport             datatransfer.Transport
+	transports            *transports
 	storedCounter         *storedcounter.StoredCounter
 	channelRemoveTimeout  time.Duration
 	reconnectsLk          sync.RWMutex
 	reconnects            map[datatransfer.ChannelID]chan struct{}
+	timedPausesLk         sync.Mutex
+	timedPauses           map[datatransfer.ChannelID]*timedPause
+	decidingLk            sync.Mutex
+	deciding              map[datatransfer.ChannelID]struct{}
 	cidLists              cidlists.CIDLists
 	pushChannelMonitor    *pushchannelmonitor.Monitor
 	pushChannelMonitorCfg *pushchannelmonitor.Config
+	tracer                tracing.Tracer
+	propagateTraceContext bool
+	channelSpans          *channelSpans
+	limits                requestLimits
+	draining              int32
+	staging               *stagedStorage
+	quotas                *quotaTracker
+	pulls                 *sharedPulls
+	requestKey            crypto.PrivKey
+	receiptKey            crypto.PrivKey
+	clock                 clock.Clock
+	// stopCtx is cancelled when the manager stops, ending work that runs for
+	// as long as the manager does
+	stopCtx context.Context
+	stop    context.CancelFunc
 }
 
 type internalEvent struct {
@@ -91,6 +126,16 @@ func ChannelRemoveTimeout(timeout time.Duration) DataTransferOption {
 	}
 }
 
+// Clock sets the clock used for the manager's timers, which are the timers
+// that remove timed out and disconnected channels and resume timed pauses.
+// It is also used by the push channel monitor, unless the monitor is
+// configured with its own clock
+func Clock(clk clock.Clock) DataTransferOption {
+	return func(m *manager) {
+		m.clock = clk
+	}
+}
+
 // PushChannelRestartConfig sets the configuration options for automatically
 // restarting push channels
 // - interval is the time over which minBytesSent must have been sent
@@ -119,6 +164,45 @@ func PushChannelRestartConfig(
 	}
 }
 
+// EventJournal records channel events in an append-only journal in the
+// datastore, so they can be read with ReadEvents after a restart. Entries are
+// removed once they fall outside the given retention limits. Only the given
+// events are recorded, or journal.DefaultEvents if none are given, which
+// leaves out the events emitted for each block
+func EventJournal(retention journal.Retention, events ...datatransfer.EventCode) DataTransferOption {
+	return func(m *manager) {
+		m.journalRetention = &retention
+		m.journalEvents = events
+	}
+}
+
+// TrackPeerStats keeps a scorecard for each peer from the outcomes of channels
+// with the peer, stored in the datastore and read with PeerStats. Weight is how
+// much each new channel counts towards the rolling averages, from 0 to 1. A
+// weight of zero uses peerstats.DefaultWeight
+func TrackPeerStats(weight float64) DataTransferOption {
+	return func(m *manager) {
+		m.peerStatsWeight = &weight
+	}
+}
+
+// Tracer sets the tracer used to record spans for channels and the operations
+// on them. By default nothing is recorded
+func Tracer(tracer tracing.Tracer) DataTransferOption {
+	return func(m *manager) {
+		m.tracer = tracer
+	}
+}
+
+// PropagateTraceContext sends the trace context of a channel to the other peer
+// in the requests that open and restart it, so the other peer's spans for the
+// channel continue the same trace. Only the 1.2 protocol carries the context
+func PropagateTraceContext() DataTransferOption {
+	return func(m *manager) {
+		m.propagateTraceContext = true
+	}
+}
+
 const defaultChannelRemoveTimeout = 1 * time.Hour
 
 // NewDataTransfer initializes a new instance of a data transfer manager
@@ -129,13 +213,21 @@ func NewDataTransfer(ds datastore.Batching, cidListsDir string, dataTransferNetw
 		resultTypes:          registry.NewRegistry(),
 		revalidators:         registry.NewRegistry(),
 		transportConfigurers: registry.NewRegistry(),
+		middlewares:          newValidatorMiddlewares(),
+		retiredVoucherTypes:  newRetiredDecoders(),
+		retiredResultTypes:   newRetiredDecoders(),
+		revalidatorBindings:  newRevalidatorBindings(ds),
 		pubSub:               pubsub.New(dispatcher),
 		readySub:             pubsub.New(readyDispatcher),
 		peerID:               dataTransferNetwork.ID(),
-		transport:            transport,
+		transports:           newTransports(transport),
 		storedCounter:        storedCounter,
 		channelRemoveTimeout: defaultChannelRemoveTimeout,
 		reconnects:           make(map[datatransfer.ChannelID]chan struct{}),
+		timedPauses:          make(map[datatransfer.ChannelID]*timedPause),
+		deciding:             make(map[datatransfer.ChannelID]struct{}),
+		tracer:               tracing.NoopTracer,
+		clock:                clock.New(),
 	}
 
 	cidLists, err := cidlists.NewCIDLists(cidListsDir)
@@ -143,7 +235,7 @@ func NewDataTransfer(ds datastore.Batching, cidListsDir string, dataTransferNetw
 		return nil, err
 	}
 	m.cidLists = cidLists
-	channels, err := channels.New(ds, cidLists, m.notifier, m.voucherDecoder, m.resultTypes.Decoder, &channelEnvironment{m}, dataTransferNetwork.ID())
+	channels, err := channels.New(ds, cidLists, m.notifier, m.voucherDecoder, m.resultDecoder, &channelEnvironment{m}, dataTransferNetwork.ID())
 	if err != nil {
 		return nil, err
 	}
@@ -153,9 +245,40 @@ func NewDataTransfer(ds datastore.Batching, cidListsDir string, dataTransferNetw
 	for _, option := range options {
 		option(m)
 	}
+	if err := m.transports.validate(); err != nil {
+		return nil, err
+	}
+	if err := m.validateSigningKeys(); err != nil {
+		return nil, err
+	}
+
+	m.channelSpans = newChannelSpans(m.tracer)
+	m.stopCtx, m.stop = context.WithCancel(context.Background())
+
+	if m.staging != nil {
+		m.staging.store = staging.New(namespace.Wrap(ds, datastore.NewKey("/staging")))
+	}
+
+	if m.quotas != nil {
+		m.quotas.ds = namespace.Wrap(ds, datastore.NewKey("/quotas"))
+	}
+
+	if m.journalRetention != nil {
+		m.journal, err = journal.New(namespace.Wrap(ds, datastore.NewKey("/journal")), *m.journalRetention, m.journalEvents...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if m.peerStatsWeight != nil {
+		m.peerStats = peerstats.New(namespace.Wrap(ds, datastore.NewKey("/peerstats")), *m.peerStatsWeight)
+	}
 
 	// Start push channel monitor after applying config options as the config
 	// options may apply to the monitor
+	if m.pushChannelMonitorCfg != nil && m.pushChannelMonitorCfg.Clock == nil {
+		m.pushChannelMonitorCfg.Clock = m.clock
+	}
 	m.pushChannelMonitor = pushchannelmonitor.NewMonitor(m, m.pushChannelMonitorCfg)
 	m.pushChannelMonitor.Start()
 
@@ -164,13 +287,59 @@ func NewDataTransfer(ds datastore.Batching, cidListsDir string, dataTransferNetw
 
 func (m *manager) voucherDecoder(voucherType datatransfer.TypeIdentifier) (encoding.Decoder, bool) {
 	decoder, has := m.validatedTypes.Decoder(voucherType)
-	if !has {
-		return m.revalidators.Decoder(voucherType)
+	if has {
+		return decoder, true
+	}
+	decoder, has = m.revalidators.Decoder(voucherType)
+	if has {
+		return decoder, true
 	}
-	return decoder, true
+	return m.retiredVoucherTypes.Decoder(voucherType)
+}
+
+func (m *manager) resultDecoder(resultType datatransfer.TypeIdentifier) (encoding.Decoder, bool) {
+	decoder, has := m.resultTypes.Decoder(resultType)
+	if has {
+		return decoder, true
+	}
+	return m.retiredResultTypes.Decoder(resultType)
 }
 
 func (m *manager) notifier(evt datatransfer.Event, chst datatransfer.ChannelState) {
+	if m.journal != nil {
+		if err := m.journal.Append(evt, chst); err != nil {
+			log.Warnf("err journaling DT event: %s", err.Error())
+		}
+	}
+	if m.peerStats != nil {
+		if err := m.peerStats.Record(evt, chst); err != nil {
+			log.Warnf("err recording peer stats for DT event: %s", err.Error())
+		}
+	}
+	if chst.Status() != datatransfer.Requested {
+		m.endDecision(chst.ChannelID())
+	}
+	if evt.Code == datatransfer.ReceiptIssued {
+		go m.sendReceipt(chst)
+	}
+	if channels.IsChannelTerminated(chst.Status()) {
+		if err := m.revalidatorBindings.unbind(chst.ChannelID()); err != nil {
+			log.Warnf("err cleaning up DT channel: %s", err.Error())
+		}
+		m.cancelScheduledResume(chst.ChannelID())
+		m.transports.removeChannel(chst.ChannelID())
+		if m.quotas != nil {
+			// staged blocks are only kept if the channel completes
+			discarded := m.staging != nil && chst.Status() != datatransfer.Completed
+			if err := m.quotas.release(chst.ChannelID(), discarded); err != nil {
+				log.Warnf("err recording quota usage for DT channel: %s", err.Error())
+			}
+		}
+		m.endChannelSpan(chst)
+	}
+	if m.pulls != nil && (channels.IsChannelCleaningUp(chst.Status()) || channels.IsChannelTerminated(chst.Status())) {
+		m.pulls.remove(chst.ChannelID())
+	}
 	err := m.pubSub.Publish(internalEvent{evt, chst})
 	if err != nil {
 		log.Warnf("err publishing DT event: %s", err.Error())
@@ -185,6 +354,8 @@ func (m *manager) Start(ctx context.Context) error {
 		err := m.channels.Start(ctx)
 		if err != nil {
 			log.Errorf("Migrating data transfer state machines: %s", err.Error())
+		} else if err := m.restoreTimedPauses(); err != nil {
+			log.Warnf("Restoring timed pauses: %s", err.Error())
 		}
 		err = m.readySub.Publish(err)
 		if err != nil {
@@ -194,7 +365,12 @@ func (m *manager) Start(ctx context.Context) error {
 
 	dtReceiver := &receiver{m}
 	m.dataTransferNetwork.SetDelegate(dtReceiver)
-	return m.transport.SetEventHandler(m)
+	for name, transport := range m.transports.byName {
+		if err := transport.SetEventHandler(&transportEvents{m, name}); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // OnReady registers a listener for when the data transfer manager has finished starting up
@@ -205,8 +381,24 @@ func (m *manager) OnReady(ready datatransfer.ReadyFunc) {
 // Stop terminates all data transfers and ends processing
 func (m *manager) Stop(ctx context.Context) error {
 	log.Info("stop data-transfer module")
+	m.stop()
 	m.pushChannelMonitor.Shutdown()
-	return m.transport.Shutdown(ctx)
+	m.cancelAllScheduledResumes()
+	if m.peerStats != nil {
+		if err := m.peerStats.Flush(); err != nil {
+			log.Warnf("err writing peer stats: %s", err.Error())
+		}
+	}
+	var firstErr error
+	for name, transport := range m.transports.byName {
+		if err := transport.Shutdown(ctx); err != nil {
+			log.Warnf("err shutting down transport %s: %s", name, err.Error())
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
 }
 
 // RegisterVoucherType registers a validator for the given voucher type
@@ -224,27 +416,43 @@ func (m *manager) RegisterVoucherType(voucherType datatransfer.Voucher, validato
 
 // OpenPushDataChannel opens a data transfer that will send data to the recipient peer and
 // transfer parts of the piece that match the selector
-func (m *manager) OpenPushDataChannel(ctx context.Context, requestTo peer.ID, voucher datatransfer.Voucher, baseCid cid.Cid, selector ipld.Node) (datatransfer.ChannelID, error) {
+func (m *manager) OpenPushDataChannel(ctx context.Context, requestTo peer.ID, voucher datatransfer.Voucher, baseCid cid.Cid, selector ipld.Node, options ...datatransfer.OpenOption) (datatransfer.ChannelID, error) {
 	log.Infof("open push channel to %s with base cid %s", requestTo, baseCid)
 
 	req, err := m.newRequest(ctx, selector, false, voucher, baseCid, requestTo)
 	if err != nil {
 		return datatransfer.ChannelID{}, err
 	}
+	transportName, err := m.transports.choose(ctx, datatransfer.NewOpenConfig(options...), voucher, requestTo)
+	if err != nil {
+		return datatransfer.ChannelID{}, err
+	}
+	transport, err := m.transports.get(transportName)
+	if err != nil {
+		return datatransfer.ChannelID{}, err
+	}
 
 	chid, err := m.channels.CreateNew(m.peerID, req.TransferID(), baseCid, selector, voucher,
-		m.peerID, m.peerID, requestTo) // initiator = us, sender = us, receiver = them
+		m.peerID, m.peerID, requestTo, transportName) // initiator = us, sender = us, receiver = them
 	if err != nil {
 		return chid, err
 	}
+	m.transports.setChannel(chid, transportName)
+	m.channelSpans.start(ctx, chid)
+	req, err = m.signRequest(chid, req)
+	if err != nil {
+		_ = m.channels.Error(chid, err)
+		return chid, err
+	}
+	req = withTransportName(transportName, m.withTraceContext(chid, req))
 	processor, has := m.transportConfigurers.Processor(voucher.Type())
 	if has {
 		transportConfigurer := processor.(datatransfer.TransportConfigurer)
-		transportConfigurer(chid, voucher, m.transport)
+		transportConfigurer(chid, voucher, transport)
 	}
 	m.dataTransferNetwork.Protect(requestTo, chid.String())
 	monitoredChan := m.pushChannelMonitor.AddChannel(chid)
-	if err := m.dataTransferNetwork.SendMessage(ctx, requestTo, req); err != nil {
+	if err := m.dataTransferNetwork.SendMessage(m.channelSpans.context(ctx, chid), requestTo, req); err != nil {
 		err = fmt.Errorf("Unable to send request: %w", err)
 		_ = m.channels.Error(chid, err)
 
@@ -262,26 +470,48 @@ func (m *manager) OpenPushDataChannel(ctx context.Context, requestTo peer.ID, vo
 
 // OpenPullDataChannel opens a data transfer that will request data from the sending peer and
 // transfer parts of the piece that match the selector
-func (m *manager) OpenPullDataChannel(ctx context.Context, requestTo peer.ID, voucher datatransfer.Voucher, baseCid cid.Cid, selector ipld.Node) (datatransfer.ChannelID, error) {
+func (m *manager) OpenPullDataChannel(ctx context.Context, requestTo peer.ID, voucher datatransfer.Voucher, baseCid cid.Cid, selector ipld.Node, options ...datatransfer.OpenOption) (datatransfer.ChannelID, error) {
 	log.Infof("open pull channel to %s with base cid %s", requestTo, baseCid)
 
+	transportName, err := m.transports.choose(ctx, datatransfer.NewOpenConfig(options...), voucher, requestTo)
+	if err != nil {
+		return datatransfer.ChannelID{}, err
+	}
+	if m.pulls != nil {
+		return m.openSharedPull(ctx, requestTo, voucher, baseCid, selector, transportName)
+	}
+	return m.openPullDataChannel(ctx, requestTo, voucher, baseCid, selector, transportName)
+}
+
+func (m *manager) openPullDataChannel(ctx context.Context, requestTo peer.ID, voucher datatransfer.Voucher, baseCid cid.Cid, selector ipld.Node, transportName string) (datatransfer.ChannelID, error) {
 	req, err := m.newRequest(ctx, selector, true, voucher, baseCid, requestTo)
 	if err != nil {
 		return datatransfer.ChannelID{}, err
 	}
+	transport, err := m.transports.get(transportName)
+	if err != nil {
+		return datatransfer.ChannelID{}, err
+	}
 	// initiator = us, sender = them, receiver = us
 	chid, err := m.channels.CreateNew(m.peerID, req.TransferID(), baseCid, selector, voucher,
-		m.peerID, requestTo, m.peerID)
+		m.peerID, requestTo, m.peerID, transportName)
 	if err != nil {
 		return chid, err
 	}
-	processor, has := m.transportConfigurers.Processor(voucher.Type())
-	if has {
-		transportConfigurer := processor.(datatransfer.TransportConfigurer)
-		transportConfigurer(chid, voucher, m.transport)
+	m.transports.setChannel(chid, transportName)
+	m.channelSpans.start(ctx, chid)
+	req, err = m.signRequest(chid, req)
+	if err != nil {
+		_ = m.channels.Error(chid, err)
+		return chid, err
+	}
+	req = withTransportName(transportName, m.withTraceContext(chid, req))
+	if err := m.configureStore(chid, voucher, transport, true, false); err != nil {
+		_ = m.channels.Error(chid, err)
+		return chid, err
 	}
 	m.dataTransferNetwork.Protect(requestTo, chid.String())
-	if err := m.transport.OpenChannel(ctx, requestTo, chid, cidlink.Link{Cid: baseCid}, selector, nil, req); err != nil {
+	if err := transport.OpenChannel(m.channelSpans.context(ctx, chid), requestTo, chid, cidlink.Link{Cid: baseCid}, selector, nil, req); err != nil {
 		err = fmt.Errorf("Unable to send request: %w", err)
 		_ = m.channels.Error(chid, err)
 		return chid, err
@@ -302,7 +532,7 @@ func (m *manager) SendVoucher(ctx context.Context, channelID datatransfer.Channe
 	if err != nil {
 		return err
 	}
-	if err := m.dataTransferNetwork.SendMessage(ctx, chst.OtherPeer(), updateRequest); err != nil {
+	if err := m.dataTransferNetwork.SendMessage(m.channelSpans.context(ctx, channelID), chst.OtherPeer(), updateRequest); err != nil {
 		err = fmt.Errorf("Unable to send request: %w", err)
 		_ = m.OnRequestDisconnected(ctx, channelID)
 		return err
@@ -314,17 +544,31 @@ func (m *manager) SendVoucher(ctx context.Context, channelID datatransfer.Channe
 func (m *manager) CloseDataTransferChannel(ctx context.Context, chid datatransfer.ChannelID) error {
 	log.Infof("close channel %s", chid)
 
+	if m.pulls != nil && m.pulls.leave(chid) {
+		log.Infof("channel %s is still shared with other callers, leaving it open", chid)
+		return nil
+	}
+	return m.closeChannel(ctx, chid)
+}
+
+// closeChannel cancels a channel, even if it is a pull shared with other
+// callers
+func (m *manager) closeChannel(ctx context.Context, chid datatransfer.ChannelID) error {
 	chst, err := m.channels.GetByID(ctx, chid)
 	if err != nil {
 		return err
 	}
-	err = m.transport.CloseChannel(ctx, chid)
+	transport, err := m.transports.get(chst.TransportName())
+	if err != nil {
+		return err
+	}
+	err = transport.CloseChannel(ctx, chid)
 	if err != nil {
 		log.Warnf("unable to close channel %s: %s", chid, err)
 	}
 
 	log.Infof("%s: sending close channel to %s for channel %s", m.peerID, chst.OtherPeer(), chid)
-	err = m.dataTransferNetwork.SendMessage(ctx, chst.OtherPeer(), m.cancelMessage(chid))
+	err = m.dataTransferNetwork.SendMessage(m.channelSpans.context(ctx, chid), chst.OtherPeer(), m.cancelMessage(chid))
 	if err != nil {
 		err = fmt.Errorf("Unable to send cancel message: %w", err)
 		_ = m.OnRequestDisconnected(ctx, chid)
@@ -346,40 +590,67 @@ func (m *manager) CloseDataTransferChannel(ctx context.Context, chid datatransfe
 func (m *manager) PauseDataTransferChannel(ctx context.Context, chid datatransfer.ChannelID) error {
 	log.Infof("pause channel %s", chid)
 
-	pausable, ok := m.transport.(datatransfer.PauseableTransport)
+	ctx, span := m.channelSpans.startChild(ctx, chid, "pause")
+	defer span.End()
+
+	transport, err := m.channelTransport(chid)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	pausable, ok := transport.(datatransfer.PauseableTransport)
 	if !ok {
+		span.RecordError(datatransfer.ErrUnsupported)
 		return datatransfer.ErrUnsupported
 	}
 
-	err := pausable.PauseChannel(ctx, chid)
+	m.cancelScheduledResume(chid)
+
+	err = pausable.PauseChannel(ctx, chid)
 	if err != nil {
 		log.Warnf("Error attempting to pause at transport level: %s", err.Error())
 	}
 
 	if err := m.dataTransferNetwork.SendMessage(ctx, chid.OtherParty(m.peerID), m.pauseMessage(chid)); err != nil {
 		err = fmt.Errorf("Unable to send pause message: %w", err)
+		span.RecordError(err)
 		_ = m.OnRequestDisconnected(ctx, chid)
 		return err
 	}
 
-	return m.pause(chid)
+	err = m.pause(chid)
+	recordSpanError(span, err)
+	return err
 }
 
 // resume a running data transfer channel
 func (m *manager) ResumeDataTransferChannel(ctx context.Context, chid datatransfer.ChannelID) error {
 	log.Infof("resume channel %s", chid)
 
-	pausable, ok := m.transport.(datatransfer.PauseableTransport)
+	ctx, span := m.channelSpans.startChild(ctx, chid, "resume")
+	defer span.End()
+
+	transport, err := m.channelTransport(chid)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	pausable, ok := transport.(datatransfer.PauseableTransport)
 	if !ok {
+		span.RecordError(datatransfer.ErrUnsupported)
 		return datatransfer.ErrUnsupported
 	}
 
-	err := pausable.ResumeChannel(ctx, m.resumeMessage(chid), chid)
+	m.cancelScheduledResume(chid)
+
+	err = pausable.ResumeChannel(ctx, m.resumeMessage(chid), chid)
 	if err != nil {
 		log.Warnf("Error attempting to pause at transport level: %s", err.Error())
 	}
 
-	return m.resume(chid)
+	err = m.resume(chid)
+	recordSpanError(span, err)
+	return err
 }
 
 // get channel state
@@ -401,6 +672,22 @@ func (m *manager) SubscribeToEvents(subscriber datatransfer.Subscriber) datatran
 	return datatransfer.Unsubscribe(m.pubSub.Subscribe(subscriber))
 }
 
+// ReadEvents reads events from the event journal, starting at the given cursor
+func (m *manager) ReadEvents(ctx context.Context, cursor uint64, limit int) ([]datatransfer.JournalEntry, uint64, error) {
+	if m.journal == nil {
+		return nil, cursor, datatransfer.ErrJournalDisabled
+	}
+	return m.journal.Read(cursor, limit)
+}
+
+// PeerStats returns the scorecard of channel outcomes for the given peer
+func (m *manager) PeerStats(ctx context.Context, p peer.ID) (datatransfer.PeerStats, error) {
+	if m.peerStats == nil {
+		return datatransfer.PeerStats{}, datatransfer.ErrPeerStatsDisabled
+	}
+	return m.peerStats.Stats(p)
+}
+
 // get all in progress transfers
 func (m *manager) InProgressChannels(ctx context.Context) (map[datatransfer.ChannelID]datatransfer.ChannelState, error) {
 	return m.channels.InProgress()
@@ -419,6 +706,26 @@ func (m *manager) RegisterRevalidator(voucherType datatransfer.Voucher, revalida
 	return nil
 }
 
+// RegisterValidatorMiddleware registers middleware that wraps the validators
+// and revalidators for every voucher type
+func (m *manager) RegisterValidatorMiddleware(middleware datatransfer.ValidatorMiddleware) error {
+	err := m.middlewares.addGlobal(middleware)
+	if err != nil {
+		return xerrors.Errorf("error registering validator middleware: %w", err)
+	}
+	return nil
+}
+
+// RegisterVoucherTypeMiddleware registers middleware that wraps the validator
+// and revalidator for the given voucher type
+func (m *manager) RegisterVoucherTypeMiddleware(voucherType datatransfer.Voucher, middleware datatransfer.ValidatorMiddleware) error {
+	err := m.middlewares.addForType(voucherType.Type(), middleware)
+	if err != nil {
+		return xerrors.Errorf("error registering validator middleware for voucher type %s: %w", voucherType.Type(), err)
+	}
+	return nil
+}
+
 // RegisterVoucherResultType allows deserialization of a voucher result,
 // so that a listener can read the metadata
 func (m *manager) RegisterVoucherResultType(resultType datatransfer.VoucherResult) error {
@@ -439,6 +746,81 @@ func (m *manager) RegisterTransportConfigurer(voucherType datatransfer.Voucher,
 	return nil
 }
 
+// UnregisterVoucherType removes the validator for the given voucher type
+func (m *manager) UnregisterVoucherType(voucherType datatransfer.TypeIdentifier) error {
+	decoder, err := m.validatedTypes.Unregister(voucherType)
+	if err != nil {
+		return xerrors.Errorf("error unregistering voucher type: %w", err)
+	}
+	m.retiredVoucherTypes.retire(voucherType, decoder)
+	return nil
+}
+
+// ReplaceVoucherType replaces the validator for a registered voucher type
+func (m *manager) ReplaceVoucherType(voucherType datatransfer.Voucher, validator datatransfer.RequestValidator) error {
+	err := m.validatedTypes.Replace(voucherType, validator)
+	if err != nil {
+		return xerrors.Errorf("error replacing voucher type: %w", err)
+	}
+	return nil
+}
+
+// UnregisterRevalidator removes the revalidator for the given voucher type
+func (m *manager) UnregisterRevalidator(voucherType datatransfer.TypeIdentifier) error {
+	decoder, err := m.revalidators.Unregister(voucherType)
+	if err != nil {
+		return xerrors.Errorf("error unregistering revalidator type: %w", err)
+	}
+	m.retiredVoucherTypes.retire(voucherType, decoder)
+	return nil
+}
+
+// ReplaceRevalidator replaces the revalidator for a registered voucher type
+func (m *manager) ReplaceRevalidator(voucherType datatransfer.Voucher, revalidator datatransfer.Revalidator) error {
+	err := m.revalidators.Replace(voucherType, revalidator)
+	if err != nil {
+		return xerrors.Errorf("error replacing revalidator type: %w", err)
+	}
+	return nil
+}
+
+// UnregisterVoucherResultType removes the given voucher result type
+func (m *manager) UnregisterVoucherResultType(resultType datatransfer.TypeIdentifier) error {
+	decoder, err := m.resultTypes.Unregister(resultType)
+	if err != nil {
+		return xerrors.Errorf("error unregistering voucher result type: %w", err)
+	}
+	m.retiredResultTypes.retire(resultType, decoder)
+	return nil
+}
+
+// ReplaceVoucherResultType replaces the decoding of a registered voucher result type
+func (m *manager) ReplaceVoucherResultType(resultType datatransfer.VoucherResult) error {
+	err := m.resultTypes.Replace(resultType, nil)
+	if err != nil {
+		return xerrors.Errorf("error replacing voucher result type: %w", err)
+	}
+	return nil
+}
+
+// UnregisterTransportConfigurer removes the transport configurer for the given voucher type
+func (m *manager) UnregisterTransportConfigurer(voucherType datatransfer.TypeIdentifier) error {
+	_, err := m.transportConfigurers.Unregister(voucherType)
+	if err != nil {
+		return xerrors.Errorf("error unregistering transport configurer: %w", err)
+	}
+	return nil
+}
+
+// ReplaceTransportConfigurer replaces the transport configurer for a registered voucher type
+func (m *manager) ReplaceTransportConfigurer(voucherType datatransfer.Voucher, configurer datatransfer.TransportConfigurer) error {
+	err := m.transportConfigurers.Replace(voucherType, configurer)
+	if err != nil {
+		return xerrors.Errorf("error replacing transport configurer: %w", err)
+	}
+	return nil
+}
+
 // RestartDataTransferChannel restarts data transfer on the channel with the given channelId
 func (m *manager) RestartDataTransferChannel(ctx context.Context, chid datatransfer.ChannelID) error {
 	log.Infof("restart channel %s", chid)
@@ -459,22 +841,148 @@ func (m *manager) RestartDataTransferChannel(ctx context.Context, chid datatrans
 		return m.channels.CompleteCleanupOnRestart(channel.ChannelID())
 	}
 
+	// channels restored from the datastore have no span yet
+	m.channelSpans.start(ctx, chid)
+	ctx, span := m.channelSpans.startChild(ctx, chid, "restart")
+	defer span.End()
+
 	// initiate restart
 	chType := m.channelDataTransferType(channel)
 	switch chType {
 	case ManagerPeerReceivePush:
-		return m.restartManagerPeerReceivePush(ctx, channel)
+		err = m.restartManagerPeerReceivePush(ctx, channel)
 	case ManagerPeerReceivePull:
-		return m.restartManagerPeerReceivePull(ctx, channel)
+		err = m.restartManagerPeerReceivePull(ctx, channel)
 	case ManagerPeerCreatePull:
-		return m.openPullRestartChannel(ctx, channel)
+		err = m.openPullRestartChannel(ctx, channel)
 	case ManagerPeerCreatePush:
-		return m.openPushRestartChannel(ctx, channel)
+		err = m.openPushRestartChannel(ctx, channel)
 	}
 
+	recordSpanError(span, err)
+	return err
+}
+
+// AcceptChannel accepts a request whose validation was deferred, sending the
+// response to the initiator and starting the transfer
+func (m *manager) AcceptChannel(ctx context.Context, chid datatransfer.ChannelID) error {
+	log.Infof("accept channel %s", chid)
+
+	chst, err := m.beginDecision(ctx, chid)
+	if err != nil {
+		return err
+	}
+	response, err := m.response(false, true, nil, chid.ID, lastVoucherResult(chst))
+	if err != nil {
+		m.endDecision(chid)
+		return err
+	}
+	transport, err := m.transports.get(chst.TransportName())
+	if err != nil {
+		m.endDecision(chid)
+		return err
+	}
+	if err := m.channels.Accept(chid); err != nil {
+		m.endDecision(chid)
+		return xerrors.Errorf("unable to accept channel %s: %w", chid, err)
+	}
+	if chst.IsPull() {
+		// the transport response was held paused while acceptance was deferred
+		return transport.(datatransfer.PauseableTransport).ResumeChannel(m.channelSpans.context(ctx, chid), response, chid)
+	}
+	return transport.OpenChannel(m.channelSpans.context(ctx, chid), chid.Initiator, chid, cidlink.Link{Cid: chst.BaseCID()}, chst.Selector(), nil, response)
+}
+
+// RejectChannel rejects a request whose validation was deferred and notifies
+// the initiator
+func (m *manager) RejectChannel(ctx context.Context, chid datatransfer.ChannelID) error {
+	log.Infof("reject channel %s", chid)
+
+	chst, err := m.beginDecision(ctx, chid)
+	if err != nil {
+		return err
+	}
+	response, err := m.response(false, true, datatransfer.ErrRejected, chid.ID, lastVoucherResult(chst))
+	if err != nil {
+		m.endDecision(chid)
+		return err
+	}
+	if chst.IsPull() {
+		transport, err := m.transports.get(chst.TransportName())
+		if err != nil {
+			m.endDecision(chid)
+			return err
+		}
+		if err := transport.CloseChannel(ctx, chid); err != nil {
+			log.Warnf("unable to close channel %s: %s", chid, err)
+		}
+	}
+	err = m.dataTransferNetwork.SendMessage(m.channelSpans.context(ctx, chid), chid.Initiator, response)
+	if err != nil {
+		err = fmt.Errorf("Unable to send rejection: %w", err)
+		log.Warn(err)
+	}
+
+	fsmerr := m.channels.Error(chid, datatransfer.ErrRejected)
+	if err != nil {
+		return err
+	}
+	if fsmerr != nil {
+		return xerrors.Errorf("unable to send error to channel FSM: %w", fsmerr)
+	}
 	return nil
 }
 
+// PeerBans returns the peers currently banned from opening or restarting
+// channels
+func (m *manager) PeerBans(ctx context.Context) ([]datatransfer.PeerBan, error) {
+	banner, ok := m.dataTransferNetwork.(network.PeerBanner)
+	if !ok {
+		return nil, datatransfer.ErrUnsupported
+	}
+	return banner.PeerBans(), nil
+}
+
+// ClearPeerBan lifts the ban on the given peer and clears its strikes
+func (m *manager) ClearPeerBan(ctx context.Context, p peer.ID) error {
+	banner, ok := m.dataTransferNetwork.(network.PeerBanner)
+	if !ok {
+		return datatransfer.ErrUnsupported
+	}
+	log.Infof("clearing ban on peer %s", p)
+	banner.ClearPeerBan(p)
+	return nil
+}
+
+// beginDecision returns the state of a channel whose acceptance is awaiting
+// a call to AcceptChannel or RejectChannel, and claims the decision for the
+// caller. The channel status only changes once the FSM processes the event, so
+// the claim is held until the channel leaves the Requested status, which stops
+// a second call from accepting or rejecting the channel again
+func (m *manager) beginDecision(ctx context.Context, chid datatransfer.ChannelID) (datatransfer.ChannelState, error) {
+	m.decidingLk.Lock()
+	defer m.decidingLk.Unlock()
+	if _, ok := m.deciding[chid]; ok {
+		return nil, xerrors.Errorf("channel %s is already being accepted or rejected", chid)
+	}
+	chst, err := m.channels.GetByID(ctx, chid)
+	if err != nil {
+		return nil, err
+	}
+	if chid.Responder != m.peerID || chst.Status() != datatransfer.Requested {
+		return nil, xerrors.Errorf("channel %s is not awaiting acceptance", chid)
+	}
+	m.deciding[chid] = struct{}{}
+	return chst, nil
+}
+
+// endDecision releases the claim on accepting or rejecting a channel
+func (m *manager) endDecision(chid datatransfer.ChannelID) {
+	m.decidingLk.Lock()
+	defer m.decidingLk.Unlock()
+	delete(m.deciding, chid)
+}
+
 func (m *manager) channelDataTransferType(channel datatransfer.ChannelState) ChannelDataTransferType {
 	initiator := channel.ChannelID().Initiator
 	if channel.IsPull() {
@@ -4,12 +4,15 @@ import (
 	"context"
 	"math/rand"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-datastore"
 	dss "github.com/ipfs/go-datastore/sync"
+	"github.com/ipfs/go-graphsync/storeutil"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
 	"github.com/ipld/go-ipld-prime"
 	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
 	"github.com/libp2p/go-libp2p-core/peer"
@@ -20,6 +23,7 @@ import (
 
 	datatransfer "github.com/filecoin-project/go-data-transfer"
 	"github.com/filecoin-project/go-data-transfer/channels"
+	"github.com/filecoin-project/go-data-transfer/clock"
 	. "github.com/filecoin-project/go-data-transfer/impl"
 	"github.com/filecoin-project/go-data-transfer/message"
 	"github.com/filecoin-project/go-data-transfer/testutil"
@@ -92,8 +96,8 @@ func TestDataTransferInitiating(t *testing.T) {
 				channelID, err := h.dt.OpenPullDataChannel(h.ctx, h.peers[1], h.voucher, h.baseCid, h.stor)
 				require.NoError(t, err)
 				require.NoError(t, h.transport.EventHandler.OnRequestTimedOut(ctx, channelID))
-				// need time for the events to take place
-				time.Sleep(1 * time.Second)
+				h.clock.WaitForTimers(1)
+				h.clock.Add(10 * time.Millisecond)
 			},
 		},
 		"Remove disconnected request": {
@@ -103,8 +107,8 @@ func TestDataTransferInitiating(t *testing.T) {
 				channelID, err := h.dt.OpenPullDataChannel(h.ctx, h.peers[1], h.voucher, h.baseCid, h.stor)
 				require.NoError(t, err)
 				require.NoError(t, h.transport.EventHandler.OnRequestDisconnected(ctx, channelID))
-				// need time for the events to take place
-				time.Sleep(1 * time.Second)
+				h.clock.WaitForTimers(1)
+				h.clock.Add(10 * time.Millisecond)
 			},
 		},
 		"Remove disconnected push request": {
@@ -118,8 +122,8 @@ func TestDataTransferInitiating(t *testing.T) {
 				err = h.transport.EventHandler.OnResponseReceived(channelID, response)
 				require.NoError(t, err)
 				require.NoError(t, h.transport.EventHandler.OnRequestDisconnected(ctx, channelID))
-				// need time for the events to take place
-				time.Sleep(1 * time.Second)
+				h.clock.WaitForTimers(1)
+				h.clock.Add(10 * time.Millisecond)
 			},
 		},
 		"Disconnected request resumes": {
@@ -274,6 +278,360 @@ func TestDataTransferInitiating(t *testing.T) {
 				require.Equal(t, resumeMessage.TransferID(), channelID.ID)
 			},
 		},
+		"push request, timed pause": {
+			expectedEvents: []datatransfer.EventCode{datatransfer.Open, datatransfer.Accept, datatransfer.ResumeResponder, datatransfer.PauseInitiator, datatransfer.TimedPause, datatransfer.TimedPauseExpired, datatransfer.ResumeInitiator},
+			verify: func(t *testing.T, h *harness) {
+				channelID, err := h.dt.OpenPushDataChannel(h.ctx, h.peers[1], h.voucher, h.baseCid, h.stor)
+				require.NoError(t, err)
+				response, err := message.NewResponse(channelID.ID, true, false, datatransfer.EmptyTypeIdentifier, nil)
+				require.NoError(t, err)
+				err = h.transport.EventHandler.OnResponseReceived(channelID, response)
+				require.NoError(t, err)
+				until := h.clock.Now().Add(100 * time.Millisecond)
+				err = h.dt.PauseDataTransferChannelUntil(h.ctx, channelID, until)
+				require.NoError(t, err)
+				require.Len(t, h.transport.PausedChannels, 1)
+				require.Equal(t, h.transport.PausedChannels[0], channelID)
+				state, err := h.dt.ChannelState(h.ctx, channelID)
+				require.NoError(t, err)
+				require.Equal(t, datatransfer.InitiatorPaused, state.Status())
+				require.Equal(t, until.UnixNano(), state.PausedUntil().UnixNano())
+				h.clock.Add(100 * time.Millisecond)
+			},
+		},
+		"push request, timed pause survives restart": {
+			expectedEvents: []datatransfer.EventCode{datatransfer.Open, datatransfer.Accept, datatransfer.ResumeResponder, datatransfer.PauseInitiator, datatransfer.TimedPause},
+			verify: func(t *testing.T, h *harness) {
+				channelID, err := h.dt.OpenPushDataChannel(h.ctx, h.peers[1], h.voucher, h.baseCid, h.stor)
+				require.NoError(t, err)
+				response, err := message.NewResponse(channelID.ID, true, false, datatransfer.EmptyTypeIdentifier, nil)
+				require.NoError(t, err)
+				err = h.transport.EventHandler.OnResponseReceived(channelID, response)
+				require.NoError(t, err)
+				err = h.dt.PauseDataTransferChannelUntil(h.ctx, channelID, h.clock.Now().Add(200*time.Millisecond))
+				require.NoError(t, err)
+				// wait for the timed pause to be stored before stopping
+				_, err = h.dt.ChannelState(h.ctx, channelID)
+				require.NoError(t, err)
+				require.NoError(t, h.dt.Stop(h.ctx))
+
+				// a new manager on the same datastore resumes the channel
+				transport := testutil.NewFakeTransport()
+				dt, err := NewDataTransfer(h.ds, os.TempDir(), testutil.NewFakeNetwork(h.peers[0]), transport, h.storedCounter, Clock(h.clock))
+				require.NoError(t, err)
+				ev := eventVerifier{
+					expectedEvents: []datatransfer.EventCode{datatransfer.TimedPauseExpired, datatransfer.ResumeInitiator},
+					events:         make(chan datatransfer.EventCode, 2),
+				}
+				ev.setup(t, dt)
+				testutil.StartAndWaitForReady(h.ctx, t, dt)
+				h.clock.WaitForTimers(1)
+				h.clock.Add(200 * time.Millisecond)
+				ev.verify(h.ctx, t)
+				require.Len(t, transport.ResumedChannels, 1)
+				require.Equal(t, channelID, transport.ResumedChannels[0].ChannelID)
+				state, err := dt.ChannelState(h.ctx, channelID)
+				require.NoError(t, err)
+				require.Equal(t, datatransfer.Ongoing, state.Status())
+				require.True(t, state.PausedUntil().IsZero())
+			},
+		},
+		"pause, resume and cancel channels with a peer": {
+			verify: func(t *testing.T, h *harness) {
+				other := testutil.GeneratePeers(1)[0]
+				openChannel := func(p peer.ID, accept bool) datatransfer.ChannelID {
+					chid, err := h.dt.OpenPushDataChannel(h.ctx, p, h.voucher, h.baseCid, h.stor)
+					require.NoError(t, err)
+					if accept {
+						response, err := message.NewResponse(chid.ID, true, false, datatransfer.EmptyTypeIdentifier, nil)
+						require.NoError(t, err)
+						require.NoError(t, h.transport.EventHandler.OnResponseReceived(chid, response))
+						_, err = h.dt.WaitForChannel(h.ctx, chid, datatransfer.Ongoing)
+						require.NoError(t, err)
+					}
+					return chid
+				}
+				chid1 := openChannel(h.peers[1], true)
+				chid2 := openChannel(h.peers[1], false)
+				chid3 := openChannel(other, true)
+				requireStatus := func(chid datatransfer.ChannelID, status datatransfer.Status) {
+					chst, err := h.dt.ChannelState(h.ctx, chid)
+					require.NoError(t, err)
+					require.Equal(t, status, chst.Status())
+				}
+				requireResults := func(results []datatransfer.ChannelResult, chids ...datatransfer.ChannelID) {
+					var resultChids []datatransfer.ChannelID
+					for _, result := range results {
+						require.NoError(t, result.Err)
+						resultChids = append(resultChids, result.ChannelID)
+					}
+					require.ElementsMatch(t, chids, resultChids)
+				}
+
+				results, err := h.dt.PausePeer(h.ctx, h.peers[1])
+				require.NoError(t, err)
+				requireResults(results, chid1, chid2)
+				require.ElementsMatch(t, []datatransfer.ChannelID{chid1, chid2}, h.transport.PausedChannels)
+				requireStatus(chid1, datatransfer.InitiatorPaused)
+				requireStatus(chid2, datatransfer.InitiatorPaused)
+				requireStatus(chid3, datatransfer.Ongoing)
+
+				// channels already paused are skipped
+				results, err = h.dt.PausePeer(h.ctx, h.peers[1])
+				require.NoError(t, err)
+				require.Empty(t, results)
+
+				results, err = h.dt.ResumePeer(h.ctx, h.peers[1])
+				require.NoError(t, err)
+				requireResults(results, chid1, chid2)
+				require.Len(t, h.transport.ResumedChannels, 2)
+				requireStatus(chid1, datatransfer.Ongoing)
+				requireStatus(chid2, datatransfer.Ongoing)
+
+				results, err = h.dt.CancelPeer(h.ctx, h.peers[1])
+				require.NoError(t, err)
+				requireResults(results, chid1, chid2)
+				require.ElementsMatch(t, []datatransfer.ChannelID{chid1, chid2}, h.transport.ClosedChannels)
+				for _, chid := range []datatransfer.ChannelID{chid1, chid2} {
+					chst, err := h.dt.WaitForChannel(h.ctx, chid)
+					require.NoError(t, err)
+					require.Equal(t, datatransfer.Cancelled, chst.Status())
+				}
+				requireStatus(chid3, datatransfer.Ongoing)
+
+				// channels that have ended are skipped
+				results, err = h.dt.CancelPeer(h.ctx, h.peers[1])
+				require.NoError(t, err)
+				require.Empty(t, results)
+			},
+		},
+		"receive quota fails channel": {
+			expectedEvents: []datatransfer.EventCode{datatransfer.Open, datatransfer.Accept, datatransfer.ResumeResponder, datatransfer.DataReceived, datatransfer.Error, datatransfer.CleanupComplete},
+			options:        []DataTransferOption{ReceiveQuota(ReceiveQuotas{MaxChannelBytes: 150})},
+			verify: func(t *testing.T, h *harness) {
+				chid := openAcceptedPull(t, h)
+				links := testutil.GenerateCids(2)
+				require.NoError(t, h.transport.EventHandler.OnDataReceived(chid, cidlink.Link{Cid: links[0]}, 100))
+				err := h.transport.EventHandler.OnDataReceived(chid, cidlink.Link{Cid: links[1]}, 100)
+				require.True(t, xerrors.Is(err, datatransfer.ErrQuotaExceeded))
+				chst, err := h.dt.WaitForChannel(h.ctx, chid)
+				require.NoError(t, err)
+				require.Equal(t, datatransfer.Failed, chst.Status())
+				require.Equal(t, uint64(100), chst.Received())
+				require.Contains(t, chst.Message(), datatransfer.ErrQuotaExceeded.Error())
+			},
+		},
+		"receive quota pauses channel": {
+			expectedEvents: []datatransfer.EventCode{datatransfer.Open, datatransfer.Accept, datatransfer.ResumeResponder, datatransfer.DataReceived, datatransfer.PauseInitiator},
+			options:        []DataTransferOption{ReceiveQuota(ReceiveQuotas{MaxChannelBlocks: 1, Action: QuotaPause})},
+			verify: func(t *testing.T, h *harness) {
+				chid := openAcceptedPull(t, h)
+				links := testutil.GenerateCids(3)
+				require.NoError(t, h.transport.EventHandler.OnDataReceived(chid, cidlink.Link{Cid: links[0]}, 100))
+				err := h.transport.EventHandler.OnDataReceived(chid, cidlink.Link{Cid: links[1]}, 100)
+				require.Equal(t, datatransfer.ErrPause, err)
+				chst, err := h.dt.ChannelState(h.ctx, chid)
+				require.NoError(t, err)
+				require.Equal(t, datatransfer.InitiatorPaused, chst.Status())
+				require.Len(t, h.network.SentMessages, 1)
+				require.True(t, h.network.SentMessages[0].Message.IsPaused())
+
+				// blocks already in flight leave the channel paused
+				err = h.transport.EventHandler.OnDataReceived(chid, cidlink.Link{Cid: links[2]}, 100)
+				require.Equal(t, datatransfer.ErrPause, err)
+				require.Len(t, h.network.SentMessages, 1)
+			},
+		},
+		"receive quota for a peer spans its channels": {
+			options: []DataTransferOption{ReceiveQuota(ReceiveQuotas{MaxPeerBytes: 150})},
+			verify: func(t *testing.T, h *harness) {
+				chid1 := openAcceptedPull(t, h)
+				chid2 := openAcceptedPull(t, h)
+				links := testutil.GenerateCids(4)
+				require.NoError(t, h.transport.EventHandler.OnDataReceived(chid1, cidlink.Link{Cid: links[0]}, 100))
+				err := h.transport.EventHandler.OnDataReceived(chid2, cidlink.Link{Cid: links[1]}, 100)
+				require.True(t, xerrors.Is(err, datatransfer.ErrQuotaExceeded))
+				_, err = h.dt.WaitForChannel(h.ctx, chid2)
+				require.NoError(t, err)
+
+				// the data kept from the peer still counts once its channels
+				// end, and is reloaded from the datastore
+				require.NoError(t, h.dt.CloseDataTransferChannel(h.ctx, chid1))
+				_, err = h.dt.WaitForChannel(h.ctx, chid1)
+				require.NoError(t, err)
+				chid3 := openAcceptedPull(t, h)
+				err = h.transport.EventHandler.OnDataReceived(chid3, cidlink.Link{Cid: links[2]}, 100)
+				require.True(t, xerrors.Is(err, datatransfer.ErrQuotaExceeded))
+				_, err = h.dt.WaitForChannel(h.ctx, chid3)
+				require.NoError(t, err)
+
+				// the quota is freed once the data is released
+				require.NoError(t, h.dt.ReleasePeerQuota(h.ctx, h.peers[1], 100))
+				chid4 := openAcceptedPull(t, h)
+				require.NoError(t, h.transport.EventHandler.OnDataReceived(chid4, cidlink.Link{Cid: links[3]}, 100))
+			},
+		},
+		"releasing a peer quota requires receive quotas": {
+			verify: func(t *testing.T, h *harness) {
+				err := h.dt.ReleasePeerQuota(h.ctx, h.peers[1], 100)
+				require.Equal(t, datatransfer.ErrQuotasDisabled, err)
+			},
+		},
+		"deduplicated pulls share a channel": {
+			options: []DataTransferOption{DeduplicatePulls()},
+			verify: func(t *testing.T, h *harness) {
+				chids := make(chan datatransfer.ChannelID, 3)
+				var wg sync.WaitGroup
+				for i := 0; i < 3; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						chid, err := h.dt.OpenPullDataChannel(h.ctx, h.peers[1], h.voucher, h.baseCid, h.stor)
+						require.NoError(t, err)
+						chids <- chid
+					}()
+				}
+				wg.Wait()
+				close(chids)
+				chid := <-chids
+				for other := range chids {
+					require.Equal(t, chid, other)
+				}
+				require.Len(t, h.transport.OpenedChannels, 1)
+
+				// pulls for a different root, peer or voucher open their own
+				// channels
+				other, err := h.dt.OpenPullDataChannel(h.ctx, h.peers[1], h.voucher, testutil.GenerateCids(1)[0], h.stor)
+				require.NoError(t, err)
+				require.NotEqual(t, chid, other)
+				other, err = h.dt.OpenPullDataChannel(h.ctx, testutil.GeneratePeers(1)[0], h.voucher, h.baseCid, h.stor)
+				require.NoError(t, err)
+				require.NotEqual(t, chid, other)
+				other, err = h.dt.OpenPullDataChannel(h.ctx, h.peers[1], testutil.NewFakeDTType(), h.baseCid, h.stor)
+				require.NoError(t, err)
+				require.NotEqual(t, chid, other)
+				require.Len(t, h.transport.OpenedChannels, 4)
+			},
+		},
+		"deduplicated pull closes once every caller closes it": {
+			options: []DataTransferOption{DeduplicatePulls()},
+			verify: func(t *testing.T, h *harness) {
+				chid := openAcceptedPull(t, h)
+				shared, err := h.dt.OpenPullDataChannel(h.ctx, h.peers[1], h.voucher, h.baseCid, h.stor)
+				require.NoError(t, err)
+				require.Equal(t, chid, shared)
+
+				require.NoError(t, h.dt.CloseDataTransferChannel(h.ctx, chid))
+				require.Empty(t, h.transport.ClosedChannels)
+				chst, err := h.dt.ChannelState(h.ctx, chid)
+				require.NoError(t, err)
+				require.Equal(t, datatransfer.Ongoing, chst.Status())
+
+				require.NoError(t, h.dt.CloseDataTransferChannel(h.ctx, chid))
+				require.Equal(t, []datatransfer.ChannelID{chid}, h.transport.ClosedChannels)
+				_, err = h.dt.WaitForChannel(h.ctx, chid)
+				require.NoError(t, err)
+
+				// once the channel ends, an identical pull opens a new one
+				next := openAcceptedPull(t, h)
+				require.NotEqual(t, chid, next)
+			},
+		},
+		"cancelling a peer cancels deduplicated pulls with it": {
+			options: []DataTransferOption{DeduplicatePulls()},
+			verify: func(t *testing.T, h *harness) {
+				chid := openAcceptedPull(t, h)
+				shared, err := h.dt.OpenPullDataChannel(h.ctx, h.peers[1], h.voucher, h.baseCid, h.stor)
+				require.NoError(t, err)
+				require.Equal(t, chid, shared)
+
+				results, err := h.dt.CancelPeer(h.ctx, h.peers[1])
+				require.NoError(t, err)
+				require.Equal(t, []datatransfer.ChannelResult{{ChannelID: chid}}, results)
+				require.Equal(t, []datatransfer.ChannelID{chid}, h.transport.ClosedChannels)
+				chst, err := h.dt.WaitForChannel(h.ctx, chid)
+				require.NoError(t, err)
+				require.Equal(t, datatransfer.Cancelled, chst.Status())
+			},
+		},
+		"draining pauses deduplicated pulls for every caller": {
+			options: []DataTransferOption{DeduplicatePulls()},
+			verify: func(t *testing.T, h *harness) {
+				chid := openAcceptedPull(t, h)
+				shared, err := h.dt.OpenPullDataChannel(h.ctx, h.peers[1], h.voucher, h.baseCid, h.stor)
+				require.NoError(t, err)
+				require.Equal(t, chid, shared)
+
+				ctx, cancel := context.WithTimeout(h.ctx, 50*time.Millisecond)
+				defer cancel()
+				require.NoError(t, h.dt.Drain(ctx))
+				require.Equal(t, []datatransfer.ChannelID{chid}, h.transport.PausedChannels)
+				chst, err := h.dt.ChannelState(h.ctx, chid)
+				require.NoError(t, err)
+				require.Equal(t, datatransfer.InitiatorPaused, chst.Status())
+			},
+		},
+		"drain waits for channels to finish": {
+			expectedEvents: []datatransfer.EventCode{datatransfer.Open, datatransfer.Accept, datatransfer.ResumeResponder, datatransfer.FinishTransfer, datatransfer.ResponderCompletes, datatransfer.CleanupComplete},
+			verify: func(t *testing.T, h *harness) {
+				channelID, err := h.dt.OpenPushDataChannel(h.ctx, h.peers[1], h.voucher, h.baseCid, h.stor)
+				require.NoError(t, err)
+				response, err := message.NewResponse(channelID.ID, true, false, datatransfer.EmptyTypeIdentifier, nil)
+				require.NoError(t, err)
+				require.NoError(t, h.transport.EventHandler.OnResponseReceived(channelID, response))
+				_, err = h.dt.WaitForChannel(h.ctx, channelID, datatransfer.Ongoing)
+				require.NoError(t, err)
+
+				drained := make(chan error, 1)
+				go func() {
+					drained <- h.dt.Drain(h.ctx)
+				}()
+
+				require.NoError(t, h.transport.EventHandler.OnChannelCompleted(channelID, true))
+				response, err = message.CompleteResponse(channelID.ID, true, false, datatransfer.EmptyTypeIdentifier, nil)
+				require.NoError(t, err)
+				require.NoError(t, h.transport.EventHandler.OnResponseReceived(channelID, response))
+				select {
+				case <-h.ctx.Done():
+					t.Fatal("drain did not finish")
+				case err := <-drained:
+					require.NoError(t, err)
+				}
+				require.Empty(t, h.transport.PausedChannels)
+			},
+		},
+		"drain pauses channels that do not finish": {
+			expectedEvents: []datatransfer.EventCode{datatransfer.Open, datatransfer.Accept, datatransfer.ResumeResponder, datatransfer.PauseInitiator},
+			verify: func(t *testing.T, h *harness) {
+				channelID, err := h.dt.OpenPushDataChannel(h.ctx, h.peers[1], h.voucher, h.baseCid, h.stor)
+				require.NoError(t, err)
+				response, err := message.NewResponse(channelID.ID, true, false, datatransfer.EmptyTypeIdentifier, nil)
+				require.NoError(t, err)
+				require.NoError(t, h.transport.EventHandler.OnResponseReceived(channelID, response))
+				_, err = h.dt.WaitForChannel(h.ctx, channelID, datatransfer.Ongoing)
+				require.NoError(t, err)
+
+				ctx, cancel := context.WithTimeout(h.ctx, 50*time.Millisecond)
+				defer cancel()
+				require.NoError(t, h.dt.Drain(ctx))
+				require.Equal(t, []datatransfer.ChannelID{channelID}, h.transport.PausedChannels)
+				require.Len(t, h.network.SentMessages, 2)
+				pauseMessage := h.network.SentMessages[1].Message
+				require.True(t, pauseMessage.IsUpdate())
+				require.True(t, pauseMessage.IsPaused())
+				require.Equal(t, channelID.ID, pauseMessage.TransferID())
+				chst, err := h.dt.ChannelState(h.ctx, channelID)
+				require.NoError(t, err)
+				require.Equal(t, datatransfer.InitiatorPaused, chst.Status())
+
+				// channels paused here are not waited for
+				require.NoError(t, h.dt.Drain(h.ctx))
+
+				// no new channels are opened once draining
+				_, err = h.dt.OpenPushDataChannel(h.ctx, h.peers[1], h.voucher, h.baseCid, h.stor)
+				require.True(t, xerrors.Is(err, datatransfer.ErrDraining))
+			},
+		},
 		"close push request": {
 			expectedEvents: []datatransfer.EventCode{datatransfer.Open, datatransfer.Cancel, datatransfer.CleanupComplete},
 			verify: func(t *testing.T, h *harness) {
@@ -293,6 +651,94 @@ func TestDataTransferInitiating(t *testing.T) {
 				require.Equal(t, cancelMessage.TransferID(), channelID.ID)
 			},
 		},
+		"OpenPushTransfer finishes when the channel completes": {
+			expectedEvents: []datatransfer.EventCode{
+				datatransfer.Open,
+				datatransfer.Accept,
+				datatransfer.ResumeResponder,
+				datatransfer.FinishTransfer,
+				datatransfer.ResponderCompletes,
+				datatransfer.CleanupComplete,
+			},
+			verify: func(t *testing.T, h *harness) {
+				transfer, err := h.dt.OpenPushTransfer(h.ctx, h.peers[1], h.voucher, h.baseCid, h.stor)
+				require.NoError(t, err)
+				channelID := transfer.ChannelID()
+				require.Equal(t, channelID.Initiator, h.peers[0])
+				require.Nil(t, transfer.Err())
+				require.Nil(t, transfer.State())
+
+				response, err := message.NewResponse(channelID.ID, true, false, datatransfer.EmptyTypeIdentifier, nil)
+				require.NoError(t, err)
+				require.NoError(t, h.transport.EventHandler.OnResponseReceived(channelID, response))
+				chst, err := h.dt.WaitForChannel(h.ctx, channelID, datatransfer.Ongoing)
+				require.NoError(t, err)
+				require.Equal(t, datatransfer.Ongoing, chst.Status())
+
+				require.NoError(t, h.transport.EventHandler.OnChannelCompleted(channelID, true))
+				response, err = message.CompleteResponse(channelID.ID, true, false, datatransfer.EmptyTypeIdentifier, nil)
+				require.NoError(t, err)
+				require.NoError(t, h.transport.EventHandler.OnResponseReceived(channelID, response))
+
+				select {
+				case <-h.ctx.Done():
+					t.Fatal("transfer did not finish")
+				case <-transfer.Done():
+				}
+				require.NoError(t, transfer.Err())
+				require.Equal(t, datatransfer.Completed, transfer.State().Status())
+
+				// waiting on a channel that has already finished returns straight away
+				chst, err = h.dt.WaitForChannel(h.ctx, channelID)
+				require.NoError(t, err)
+				require.Equal(t, datatransfer.Completed, chst.Status())
+				_, err = h.dt.WaitForChannel(h.ctx, channelID, datatransfer.Ongoing)
+				require.Error(t, err)
+			},
+		},
+		"OpenPullTransfer reports cancellation": {
+			expectedEvents: []datatransfer.EventCode{datatransfer.Open, datatransfer.Cancel, datatransfer.CleanupComplete},
+			verify: func(t *testing.T, h *harness) {
+				transfer, err := h.dt.OpenPullTransfer(h.ctx, h.peers[1], h.voucher, h.baseCid, h.stor)
+				require.NoError(t, err)
+				require.NoError(t, h.dt.CloseDataTransferChannel(h.ctx, transfer.ChannelID()))
+
+				select {
+				case <-h.ctx.Done():
+					t.Fatal("transfer did not finish")
+				case <-transfer.Done():
+				}
+				require.True(t, xerrors.Is(transfer.Err(), datatransfer.ErrChannelCancelled))
+				require.Equal(t, datatransfer.Cancelled, transfer.State().Status())
+			},
+		},
+		"OpenPushTransfer finishes when the manager stops": {
+			expectedEvents: []datatransfer.EventCode{datatransfer.Open},
+			verify: func(t *testing.T, h *harness) {
+				transfer, err := h.dt.OpenPushTransfer(h.ctx, h.peers[1], h.voucher, h.baseCid, h.stor)
+				require.NoError(t, err)
+				require.NoError(t, h.dt.Stop(h.ctx))
+
+				select {
+				case <-h.ctx.Done():
+					t.Fatal("transfer did not finish")
+				case <-transfer.Done():
+				}
+				require.True(t, xerrors.Is(transfer.Err(), datatransfer.ErrManagerStopped))
+				require.Nil(t, transfer.State())
+			},
+		},
+		"WaitForChannel returns when the context is cancelled": {
+			expectedEvents: []datatransfer.EventCode{datatransfer.Open},
+			verify: func(t *testing.T, h *harness) {
+				channelID, err := h.dt.OpenPushDataChannel(h.ctx, h.peers[1], h.voucher, h.baseCid, h.stor)
+				require.NoError(t, err)
+				ctx, cancel := context.WithTimeout(h.ctx, 50*time.Millisecond)
+				defer cancel()
+				_, err = h.dt.WaitForChannel(ctx, channelID)
+				require.EqualError(t, err, context.DeadlineExceeded.Error())
+			},
+		},
 		"pull request, pause behavior": {
 			expectedEvents: []datatransfer.EventCode{datatransfer.Open, datatransfer.Accept, datatransfer.ResumeResponder, datatransfer.PauseInitiator, datatransfer.ResumeInitiator},
 			verify: func(t *testing.T, h *harness) {
@@ -400,7 +846,9 @@ func TestDataTransferInitiating(t *testing.T) {
 			h.transport = testutil.NewFakeTransport()
 			h.ds = dss.MutexWrap(datastore.NewMapDatastore())
 			h.storedCounter = storedcounter.New(h.ds, datastore.NewKey("counter"))
-			dt, err := NewDataTransfer(h.ds, os.TempDir(), h.network, h.transport, h.storedCounter, verify.options...)
+			h.clock = clock.NewMock()
+			options := append([]DataTransferOption{Clock(h.clock)}, verify.options...)
+			dt, err := NewDataTransfer(h.ds, os.TempDir(), h.network, h.transport, h.storedCounter, options...)
 			require.NoError(t, err)
 			testutil.StartAndWaitForReady(ctx, t, dt)
 			h.dt = dt
@@ -690,6 +1138,7 @@ type harness struct {
 	transport        *testutil.FakeTransport
 	ds               datastore.Batching
 	storedCounter    *storedcounter.StoredCounter
+	clock            *clock.Mock
 	dt               datatransfer.Manager
 	voucherValidator *testutil.StubbedValidator
 	stor             ipld.Node
@@ -735,3 +1184,314 @@ func (e eventVerifier) verify(ctx context.Context, t *testing.T) {
 		require.Equal(t, e.expectedEvents, receivedEvents)
 	}
 }
+
+// openAcceptedPull opens a pull channel and accepts it on behalf of the other
+// peer
+func openAcceptedPull(t *testing.T, h *harness) datatransfer.ChannelID {
+	chid, err := h.dt.OpenPullDataChannel(h.ctx, h.peers[1], h.voucher, h.baseCid, h.stor)
+	require.NoError(t, err)
+	response, err := message.NewResponse(chid.ID, true, false, datatransfer.EmptyTypeIdentifier, nil)
+	require.NoError(t, err)
+	require.NoError(t, h.transport.EventHandler.OnResponseReceived(chid, response))
+	_, err = h.dt.WaitForChannel(h.ctx, chid, datatransfer.Ongoing)
+	require.NoError(t, err)
+	return chid
+}
+
+func TestNamedTransports(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	peers := testutil.GeneratePeers(2)
+	baseCid := testutil.GenerateCids(1)[0]
+	stor := testutil.AllSelector()
+	voucher := testutil.NewFakeDTType()
+
+	type setup struct {
+		network   *testutil.FakeNetwork
+		transport *testutil.FakeTransport
+		dt        datatransfer.Manager
+	}
+	newSetup := func(t *testing.T, second datatransfer.Transport, options ...DataTransferOption) setup {
+		ds := dss.MutexWrap(datastore.NewMapDatastore())
+		s := setup{
+			network:   testutil.NewFakeNetwork(peers[0]),
+			transport: testutil.NewFakeTransport(),
+		}
+		options = append([]DataTransferOption{NamedTransport("second", second)}, options...)
+		dt, err := NewDataTransfer(ds, os.TempDir(), s.network, s.transport, storedcounter.New(ds, datastore.NewKey("counter")), options...)
+		require.NoError(t, err)
+		testutil.StartAndWaitForReady(ctx, t, dt)
+		s.dt = dt
+		return s
+	}
+	transportName := func(t *testing.T, msg datatransfer.Message) string {
+		carrier, ok := msg.(datatransfer.TransportCarrier)
+		require.True(t, ok)
+		return carrier.TransportName()
+	}
+
+	t.Run("channel opened with a named transport", func(t *testing.T) {
+		second := testutil.NewFakeTransport()
+		s := newSetup(t, second)
+		chid, err := s.dt.OpenPullDataChannel(ctx, peers[1], voucher, baseCid, stor, datatransfer.UseTransport("second"))
+		require.NoError(t, err)
+		require.Empty(t, s.transport.OpenedChannels)
+		require.Len(t, second.OpenedChannels, 1)
+		require.Equal(t, "second", transportName(t, second.OpenedChannels[0].Message))
+		chst, err := s.dt.ChannelState(ctx, chid)
+		require.NoError(t, err)
+		require.Equal(t, "second", chst.TransportName())
+
+		// events for the channel are only accepted from its transport
+		require.Error(t, s.transport.EventHandler.OnDataReceived(chid, cidlink.Link{Cid: baseCid}, 100))
+		require.NoError(t, second.EventHandler.OnDataReceived(chid, cidlink.Link{Cid: baseCid}, 100))
+
+		require.NoError(t, s.dt.PauseDataTransferChannel(ctx, chid))
+		require.Empty(t, s.transport.PausedChannels)
+		require.Equal(t, []datatransfer.ChannelID{chid}, second.PausedChannels)
+
+		_, err = s.dt.OpenPullDataChannel(ctx, peers[1], voucher, baseCid, stor, datatransfer.UseTransport("missing"))
+		require.True(t, xerrors.Is(err, datatransfer.ErrTransportNotFound))
+	})
+
+	t.Run("channel uses the transport for its voucher type", func(t *testing.T) {
+		second := testutil.NewFakeTransport()
+		s := newSetup(t, second, VoucherTypeTransport(voucher.Type(), "second"))
+		_, err := s.dt.OpenPushDataChannel(ctx, peers[1], voucher, baseCid, stor)
+		require.NoError(t, err)
+		require.Len(t, s.network.SentMessages, 1)
+		require.Equal(t, "second", transportName(t, s.network.SentMessages[0].Message))
+
+		// the default transport is used if named explicitly
+		_, err = s.dt.OpenPushDataChannel(ctx, peers[1], voucher, baseCid, stor, datatransfer.UseTransport(datatransfer.DefaultTransportName))
+		require.NoError(t, err)
+		require.Len(t, s.network.SentMessages, 2)
+		require.Equal(t, "", transportName(t, s.network.SentMessages[1].Message))
+	})
+
+	t.Run("channel uses a transport that supports the peer", func(t *testing.T) {
+		second := &peerCapableTransport{testutil.NewFakeTransport(), peers[1]}
+		s := newSetup(t, second)
+		_, err := s.dt.OpenPullDataChannel(ctx, peers[1], voucher, baseCid, stor)
+		require.NoError(t, err)
+		require.Len(t, second.OpenedChannels, 1)
+
+		other := testutil.GeneratePeers(1)[0]
+		_, err = s.dt.OpenPullDataChannel(ctx, other, voucher, baseCid, stor)
+		require.NoError(t, err)
+		require.Len(t, s.transport.OpenedChannels, 1)
+		require.Len(t, second.OpenedChannels, 1)
+	})
+
+	t.Run("responder uses the transport named in the request", func(t *testing.T) {
+		second := testutil.NewFakeTransport()
+		s := newSetup(t, second)
+		sv := testutil.NewStubbedValidator()
+		sv.StubSuccessPush()
+		require.NoError(t, s.dt.RegisterVoucherType(voucher, sv))
+
+		request, err := message.NewRequest(datatransfer.TransferID(rand.Uint64()), false, false, voucher.Type(), voucher, baseCid, stor)
+		require.NoError(t, err)
+		request = request.(datatransfer.TransportCarrier).WithTransportName("second").(datatransfer.Request)
+		s.network.Delegate.ReceiveRequest(ctx, peers[1], request)
+		require.Empty(t, s.transport.OpenedChannels)
+		require.Len(t, second.OpenedChannels, 1)
+		chst, err := s.dt.ChannelState(ctx, second.OpenedChannels[0].ChannelID)
+		require.NoError(t, err)
+		require.Equal(t, "second", chst.TransportName())
+
+		// requests for transports this node does not have are rejected
+		request, err = message.NewRequest(datatransfer.TransferID(rand.Uint64()), false, false, voucher.Type(), voucher, baseCid, stor)
+		require.NoError(t, err)
+		request = request.(datatransfer.TransportCarrier).WithTransportName("missing").(datatransfer.Request)
+		s.network.Delegate.ReceiveRequest(ctx, peers[1], request)
+		require.Len(t, s.network.SentMessages, 1)
+		response, ok := s.network.SentMessages[0].Message.(datatransfer.Response)
+		require.True(t, ok)
+		require.False(t, response.Accepted())
+	})
+
+	t.Run("transport names must be unique", func(t *testing.T) {
+		ds := dss.MutexWrap(datastore.NewMapDatastore())
+		_, err := NewDataTransfer(ds, os.TempDir(), testutil.NewFakeNetwork(peers[0]), testutil.NewFakeTransport(), storedcounter.New(ds, datastore.NewKey("counter")),
+			NamedTransport(datatransfer.DefaultTransportName, testutil.NewFakeTransport()))
+		require.Error(t, err)
+		_, err = NewDataTransfer(ds, os.TempDir(), testutil.NewFakeNetwork(peers[0]), testutil.NewFakeTransport(), storedcounter.New(ds, datastore.NewKey("counter")),
+			VoucherTypeTransport(voucher.Type(), "missing"))
+		require.True(t, xerrors.Is(err, datatransfer.ErrTransportNotFound))
+	})
+}
+
+// peerCapableTransport is a fake transport that only supports a single peer
+type peerCapableTransport struct {
+	*testutil.FakeTransport
+	supported peer.ID
+}
+
+func (pt *peerCapableTransport) SupportsPeer(ctx context.Context, p peer.ID) bool {
+	return p == pt.supported
+}
+
+func TestStagedStorage(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	peers := testutil.GeneratePeers(2)
+	baseCid := testutil.GenerateCids(1)[0]
+	block := cidlink.Link{Cid: testutil.GenerateCids(1)[0]}
+
+	type setup struct {
+		ds        datastore.Batching
+		bs        blockstore.Blockstore
+		transport *testutil.FakeTransport
+		dt        datatransfer.Manager
+	}
+	newSetup := func(t *testing.T, ds datastore.Batching, bs blockstore.Blockstore, configurer datatransfer.TransportConfigurer) setup {
+		s := setup{
+			ds:        ds,
+			bs:        bs,
+			transport: testutil.NewFakeTransport(),
+		}
+		dt, err := NewDataTransfer(ds, os.TempDir(), testutil.NewFakeNetwork(peers[0]), s.transport, storedcounter.New(ds, datastore.NewKey("counter")), StagedStorage(bs))
+		require.NoError(t, err)
+		testutil.StartAndWaitForReady(ctx, t, dt)
+		require.NoError(t, dt.RegisterVoucherType(testutil.NewFakeDTType(), testutil.NewStubbedValidator()))
+		if configurer != nil {
+			require.NoError(t, dt.RegisterTransportConfigurer(testutil.NewFakeDTType(), configurer))
+		}
+		s.dt = dt
+		return s
+	}
+	newBlockstore := func() blockstore.Blockstore {
+		return blockstore.NewBlockstore(dss.MutexWrap(datastore.NewMapDatastore()))
+	}
+	openAndStage := func(t *testing.T, s setup) datatransfer.ChannelID {
+		chid, err := s.dt.OpenPullDataChannel(ctx, peers[1], testutil.NewFakeDTType(), baseCid, testutil.AllSelector())
+		require.NoError(t, err)
+		require.Len(t, s.transport.UsedStores, 1)
+		require.Equal(t, chid, s.transport.UsedStores[0].ChannelID)
+		w, commit, err := s.transport.UsedStores[0].Storer(ipld.LinkContext{})
+		require.NoError(t, err)
+		_, err = w.Write([]byte("block"))
+		require.NoError(t, err)
+		require.NoError(t, commit(block))
+		_, err = s.transport.UsedStores[0].Loader(block, ipld.LinkContext{})
+		require.NoError(t, err)
+		has, err := s.bs.Has(block.Cid)
+		require.NoError(t, err)
+		require.False(t, has)
+		return chid
+	}
+	complete := func(t *testing.T, s setup, chid datatransfer.ChannelID) datatransfer.ChannelState {
+		response, err := message.CompleteResponse(chid.ID, true, false, datatransfer.EmptyTypeIdentifier, nil)
+		require.NoError(t, err)
+		require.NoError(t, s.transport.EventHandler.OnResponseReceived(chid, response))
+		require.NoError(t, s.transport.EventHandler.OnChannelCompleted(chid, true))
+		chst, err := s.dt.WaitForChannel(ctx, chid)
+		require.NoError(t, err)
+		return chst
+	}
+	accept := func(t *testing.T, s setup, chid datatransfer.ChannelID) {
+		response, err := message.NewResponse(chid.ID, true, false, datatransfer.EmptyTypeIdentifier, nil)
+		require.NoError(t, err)
+		require.NoError(t, s.transport.EventHandler.OnResponseReceived(chid, response))
+	}
+
+	t.Run("blocks are committed when the channel completes", func(t *testing.T) {
+		s := newSetup(t, dss.MutexWrap(datastore.NewMapDatastore()), newBlockstore(), nil)
+		chid := openAndStage(t, s)
+		accept(t, s, chid)
+		chst := complete(t, s, chid)
+		require.Equal(t, datatransfer.Completed, chst.Status())
+		blk, err := s.bs.Get(block.Cid)
+		require.NoError(t, err)
+		require.Equal(t, "block", string(blk.RawData()))
+	})
+
+	t.Run("channels that complete after a restart commit to the store chosen again", func(t *testing.T) {
+		s := newSetup(t, dss.MutexWrap(datastore.NewMapDatastore()), newBlockstore(), nil)
+		chid := openAndStage(t, s)
+		accept(t, s, chid)
+		require.NoError(t, s.dt.Stop(ctx))
+
+		s = newSetup(t, s.ds, s.bs, nil)
+		chst := complete(t, s, chid)
+		require.Equal(t, datatransfer.Completed, chst.Status())
+		has, err := s.bs.Has(block.Cid)
+		require.NoError(t, err)
+		require.True(t, has)
+	})
+
+	t.Run("blocks are committed to the store the transport configurer sets", func(t *testing.T) {
+		configured := newBlockstore()
+		configurer := func(chid datatransfer.ChannelID, voucher datatransfer.Voucher, transport datatransfer.Transport) {
+			configurable := transport.(datatransfer.StoreConfigurableTransport)
+			require.NoError(t, configurable.UseStore(chid, storeutil.LoaderForBlockstore(configured), storeutil.StorerForBlockstore(configured)))
+		}
+		s := newSetup(t, dss.MutexWrap(datastore.NewMapDatastore()), newBlockstore(), configurer)
+		// the transport is only set to use the staging store
+		chid := openAndStage(t, s)
+		accept(t, s, chid)
+		require.NoError(t, s.dt.Stop(ctx))
+
+		// the configurer chooses the store again after a restart
+		s = newSetup(t, s.ds, s.bs, configurer)
+		chst := complete(t, s, chid)
+		require.Equal(t, datatransfer.Completed, chst.Status())
+		has, err := configured.Has(block.Cid)
+		require.NoError(t, err)
+		require.True(t, has)
+		has, err = s.bs.Has(block.Cid)
+		require.NoError(t, err)
+		require.False(t, has)
+	})
+
+	t.Run("accepted push requests stage into the store the transport configurer sets", func(t *testing.T) {
+		configured := newBlockstore()
+		configurer := func(chid datatransfer.ChannelID, voucher datatransfer.Voucher, transport datatransfer.Transport) {
+			configurable := transport.(datatransfer.StoreConfigurableTransport)
+			require.NoError(t, configurable.UseStore(chid, storeutil.LoaderForBlockstore(configured), storeutil.StorerForBlockstore(configured)))
+		}
+		s := newSetup(t, dss.MutexWrap(datastore.NewMapDatastore()), newBlockstore(), configurer)
+		sv := testutil.NewStubbedValidator()
+		sv.StubSuccessPush()
+		require.NoError(t, s.dt.ReplaceVoucherType(testutil.NewFakeDTType(), sv))
+		request, err := message.NewRequest(1, false, false, testutil.NewFakeDTType().Type(), testutil.NewFakeDTType(), baseCid, testutil.AllSelector())
+		require.NoError(t, err)
+		chid := datatransfer.ChannelID{Initiator: peers[1], Responder: peers[0], ID: 1}
+		_, err = s.transport.EventHandler.OnRequestReceived(chid, request)
+		require.NoError(t, err)
+
+		require.Len(t, s.transport.UsedStores, 1)
+		w, commit, err := s.transport.UsedStores[0].Storer(ipld.LinkContext{})
+		require.NoError(t, err)
+		_, err = w.Write([]byte("block"))
+		require.NoError(t, err)
+		require.NoError(t, commit(block))
+		has, err := configured.Has(block.Cid)
+		require.NoError(t, err)
+		require.False(t, has)
+	})
+
+	t.Run("blocks are discarded when the channel is cancelled", func(t *testing.T) {
+		s := newSetup(t, dss.MutexWrap(datastore.NewMapDatastore()), newBlockstore(), nil)
+		chid := openAndStage(t, s)
+		require.NoError(t, s.dt.CloseDataTransferChannel(ctx, chid))
+		chst, err := s.dt.WaitForChannel(ctx, chid)
+		require.NoError(t, err)
+		require.Equal(t, datatransfer.Cancelled, chst.Status())
+		has, err := s.bs.Has(block.Cid)
+		require.NoError(t, err)
+		require.False(t, has)
+		_, err = s.transport.UsedStores[0].Loader(block, ipld.LinkContext{})
+		require.Error(t, err)
+	})
+
+	t.Run("restarted channels reuse staged blocks", func(t *testing.T) {
+		s := newSetup(t, dss.MutexWrap(datastore.NewMapDatastore()), newBlockstore(), nil)
+		chid := openAndStage(t, s)
+		require.NoError(t, s.dt.RestartDataTransferChannel(ctx, chid))
+		require.Len(t, s.transport.UsedStores, 2)
+		_, err := s.transport.UsedStores[1].Loader(block, ipld.LinkContext{})
+		require.NoError(t, err)
+	})
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/libp2p/go-libp2p-core/protocol"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
 
 	"github.com/filecoin-project/go-storedcounter"
 
@@ -229,6 +230,8 @@ func TestRoundTrip(t *testing.T) {
 				} else {
 					assert.Equal(t, chid.Initiator, host1.ID())
 				}
+				testutil.AssertNoTransportLeaks(ctx, t, tp1)
+				testutil.AssertNoTransportLeaks(ctx, t, tp2)
 			})
 		}
 	} //
@@ -694,6 +697,8 @@ func TestRoundTripCancelledRequest(t *testing.T) {
 					t.Fatalf("received error on data transfer: %s", err)
 				}
 			}
+			testutil.AssertNoTransportLeaks(ctx, t, tp1)
+			testutil.AssertNoTransportLeaks(ctx, t, tp2)
 		})
 	}
 }
@@ -1080,6 +1085,123 @@ func TestUnrecognizedVoucherRoundTrip(t *testing.T) {
 	}
 }
 
+func TestSignedRequestsAndReceipts(t *testing.T) {
+	ctx := context.Background()
+	testCases := map[string]bool{
+		"push requests": false,
+		"pull requests": true,
+	}
+	for testCase, isPull := range testCases {
+		t.Run(testCase, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+			defer cancel()
+
+			gsData := testutil.NewGraphsyncTestingData(ctx, t, nil, nil)
+			host1 := gsData.Host1 // data sender
+			host2 := gsData.Host2 // data recipient
+			key1 := host1.Peerstore().PrivKey(host1.ID())
+			key2 := host2.Peerstore().PrivKey(host2.ID())
+
+			tp1 := gsData.SetupGSTransportHost1()
+			tp2 := gsData.SetupGSTransportHost2()
+
+			dt1, err := NewDataTransfer(gsData.DtDs1, gsData.TempDir1, gsData.DtNet1, tp1, gsData.StoredCounter1, SignRequests(key1), SignReceipts(key1))
+			require.NoError(t, err)
+			testutil.StartAndWaitForReady(ctx, t, dt1)
+			dt2, err := NewDataTransfer(gsData.DtDs2, gsData.TempDir2, gsData.DtNet2, tp2, gsData.StoredCounter2, SignRequests(key2), SignReceipts(key2))
+			require.NoError(t, err)
+			testutil.StartAndWaitForReady(ctx, t, dt2)
+
+			voucher := testutil.FakeDTType{Data: "applesauce"}
+			sv := testutil.NewStubbedValidator()
+			root, _ := testutil.LoadUnixFSFile(ctx, t, gsData.DagService1, loremFile)
+			rootCid := root.(cidlink.Link).Cid
+
+			var chid datatransfer.ChannelID
+			initiator, responder := dt1, dt2
+			if isPull {
+				initiator, responder = dt2, dt1
+				sv.ExpectSuccessPull()
+				require.NoError(t, dt1.RegisterVoucherType(&testutil.FakeDTType{}, sv))
+				chid, err = dt2.OpenPullDataChannel(ctx, host1.ID(), &voucher, rootCid, gsData.AllSelector)
+			} else {
+				sv.ExpectSuccessPush()
+				require.NoError(t, dt2.RegisterVoucherType(&testutil.FakeDTType{}, sv))
+				chid, err = dt1.OpenPushDataChannel(ctx, host2.ID(), &voucher, rootCid, gsData.AllSelector)
+			}
+			require.NoError(t, err)
+
+			initiatorState, err := initiator.WaitForChannel(ctx, chid)
+			require.NoError(t, err)
+			require.Equal(t, datatransfer.Completed, initiatorState.Status())
+			responderState, err := responder.WaitForChannel(ctx, chid)
+			require.NoError(t, err)
+			require.Equal(t, datatransfer.Completed, responderState.Status())
+
+			// the responder can prove the initiator asked for the channel
+			sig := responderState.RequestSignature()
+			require.NotNil(t, sig)
+			require.Equal(t, initiatorState.RequestSignature(), sig)
+			signed := responderState.SignedRequest()
+			require.NotNil(t, signed)
+			require.Equal(t, initiatorState.SignedRequest(), signed)
+			require.Equal(t, chid, signed.ChannelID)
+			require.Equal(t, rootCid, signed.Root)
+			require.Equal(t, isPull, signed.Pull)
+			voucherBytes, err := encoding.Encode(&voucher)
+			require.NoError(t, err)
+			require.Equal(t, voucherBytes, signed.Voucher)
+			require.NoError(t, sig.Verify(*signed))
+			tamperedRequest := *signed
+			tamperedRequest.Root = testutil.GenerateCids(1)[0]
+			require.True(t, xerrors.Is(sig.Verify(tamperedRequest), datatransfer.ErrInvalidSignature))
+
+			// only the receiver signs a receipt, which names what it received,
+			// and it sends the receipt to the sender
+			recipient, sender := responder, initiator
+			if isPull {
+				recipient, sender = initiator, responder
+			}
+			recipientState, err := recipient.ChannelState(ctx, chid)
+			require.NoError(t, err)
+			receipt := recipientState.Receipt()
+			require.NotNil(t, receipt)
+			require.Eventually(t, func() bool {
+				senderState, err := sender.ChannelState(ctx, chid)
+				require.NoError(t, err)
+				return senderState.Receipt() != nil
+			}, 5*time.Second, 10*time.Millisecond)
+			senderState, err := sender.ChannelState(ctx, chid)
+			require.NoError(t, err)
+			require.Equal(t, receipt, senderState.Receipt())
+			selectorHash, err := datatransfer.SelectorHash(gsData.AllSelector)
+			require.NoError(t, err)
+			require.Equal(t, chid, receipt.ChannelID)
+			require.Equal(t, host2.ID(), receipt.Receiver)
+			require.Equal(t, rootCid, receipt.Root)
+			require.Equal(t, selectorHash, receipt.SelectorHash)
+			require.Equal(t, recipientState.Received(), receipt.Bytes)
+			require.Equal(t, uint64(len(recipientState.ReceivedCids())), receipt.Blocks)
+			require.NoError(t, receipt.Verify())
+			tampered := *receipt
+			tampered.Bytes++
+			require.True(t, xerrors.Is(tampered.Verify(), datatransfer.ErrInvalidSignature))
+			tampered = *receipt
+			require.NoError(t, tampered.Sign(key1))
+			require.True(t, xerrors.Is(tampered.Verify(), datatransfer.ErrInvalidSignature))
+		})
+	}
+}
+
+func TestSigningKeyMustMatchPeer(t *testing.T) {
+	ctx := context.Background()
+	gsData := testutil.NewGraphsyncTestingData(ctx, t, nil, nil)
+	tp1 := gsData.SetupGSTransportHost1()
+	otherKey := gsData.Host2.Peerstore().PrivKey(gsData.Host2.ID())
+	_, err := NewDataTransfer(gsData.DtDs1, gsData.TempDir1, gsData.DtNet1, tp1, gsData.StoredCounter1, SignReceipts(otherKey))
+	require.Error(t, err)
+}
+
 func TestDataTransferSubscribing(t *testing.T) {
 	// create network
 	ctx := context.Background()
@@ -0,0 +1,157 @@
+package impl
+
+import (
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/traversal/selector"
+	"golang.org/x/xerrors"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+)
+
+// requestLimits are the limits on the vouchers and selectors of incoming
+// requests. A zero value leaves the limit unenforced
+type requestLimits struct {
+	maxVoucherSize    int
+	maxSelectorDepth  int
+	maxSelectorNodes  int
+	maxRecursionDepth int64
+}
+
+// MaxVoucherSize sets the maximum size in bytes of the encoded voucher in an
+// incoming request. Larger requests are rejected with ErrVoucherTooLarge
+// before they reach a validator or revalidator
+func MaxVoucherSize(size int) DataTransferOption {
+	return func(m *manager) {
+		m.limits.maxVoucherSize = size
+	}
+}
+
+// SelectorLimits sets the maximum nesting depth and number of nodes of the
+// selector in an incoming request. Requests with larger selectors are rejected
+// with ErrSelectorTooDeep or ErrSelectorTooLarge before they reach a validator.
+// A zero value leaves that limit unenforced
+func SelectorLimits(maxDepth int, maxNodes int) DataTransferOption {
+	return func(m *manager) {
+		m.limits.maxSelectorDepth = maxDepth
+		m.limits.maxSelectorNodes = maxNodes
+	}
+}
+
+// MaxRecursionDepth sets the maximum recursion depth of the recursive
+// selectors in an incoming request. Requests that recurse deeper, or without
+// a limit, are rejected with ErrRecursionLimitExceeded before they reach a
+// validator
+func MaxRecursionDepth(depth int64) DataTransferOption {
+	return func(m *manager) {
+		m.limits.maxRecursionDepth = depth
+	}
+}
+
+// isLimitViolation returns true if the request was rejected for exceeding
+// one of the request limits
+func isLimitViolation(err error) bool {
+	return xerrors.Is(err, datatransfer.ErrVoucherTooLarge) ||
+		xerrors.Is(err, datatransfer.ErrSelectorTooDeep) ||
+		xerrors.Is(err, datatransfer.ErrSelectorTooLarge) ||
+		xerrors.Is(err, datatransfer.ErrRecursionLimitExceeded)
+}
+
+// checkRequest returns an error if the voucher or selector in the request
+// exceed the limits
+func (rl requestLimits) checkRequest(incoming datatransfer.Request) error {
+	if err := rl.checkVoucher(incoming); err != nil {
+		return err
+	}
+	if rl.maxSelectorDepth == 0 && rl.maxSelectorNodes == 0 && rl.maxRecursionDepth == 0 {
+		return nil
+	}
+	stor, err := incoming.Selector()
+	if err != nil {
+		return err
+	}
+	nodes := 0
+	return rl.checkSelectorNode(stor, 1, &nodes)
+}
+
+// checkVoucher returns an error if the voucher in the request exceeds the
+// maximum voucher size
+func (rl requestLimits) checkVoucher(incoming datatransfer.Request) error {
+	if rl.maxVoucherSize == 0 {
+		return nil
+	}
+	sizer, ok := incoming.(datatransfer.VoucherSizer)
+	if !ok {
+		return nil
+	}
+	if size := sizer.VoucherSize(); size > rl.maxVoucherSize {
+		return xerrors.Errorf("voucher is %d bytes, maximum is %d: %w", size, rl.maxVoucherSize, datatransfer.ErrVoucherTooLarge)
+	}
+	return nil
+}
+
+func (rl requestLimits) checkSelectorNode(node ipld.Node, depth int, nodes *int) error {
+	*nodes++
+	if rl.maxSelectorNodes != 0 && *nodes > rl.maxSelectorNodes {
+		return xerrors.Errorf("selector has more than %d nodes: %w", rl.maxSelectorNodes, datatransfer.ErrSelectorTooLarge)
+	}
+	if rl.maxSelectorDepth != 0 && depth > rl.maxSelectorDepth {
+		return xerrors.Errorf("selector is nested more than %d deep: %w", rl.maxSelectorDepth, datatransfer.ErrSelectorTooDeep)
+	}
+
+	switch node.ReprKind() {
+	case ipld.ReprKind_Map:
+		it := node.MapIterator()
+		for !it.Done() {
+			key, value, err := it.Next()
+			if err != nil {
+				return err
+			}
+			if rl.maxRecursionDepth != 0 {
+				if k, err := key.AsString(); err == nil && k == selector.SelectorKey_ExploreRecursive {
+					if err := rl.checkRecursionLimit(value); err != nil {
+						return err
+					}
+				}
+			}
+			if err := rl.checkSelectorNode(value, depth+1, nodes); err != nil {
+				return err
+			}
+		}
+	case ipld.ReprKind_List:
+		it := node.ListIterator()
+		for !it.Done() {
+			_, value, err := it.Next()
+			if err != nil {
+				return err
+			}
+			if err := rl.checkSelectorNode(value, depth+1, nodes); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkRecursionLimit checks the limit of an ExploreRecursive selector
+// against the maximum recursion depth
+func (rl requestLimits) checkRecursionLimit(explore ipld.Node) error {
+	limit, err := explore.LookupByString(selector.SelectorKey_Limit)
+	if err != nil {
+		return xerrors.Errorf("recursive selector has no limit: %w", err)
+	}
+	if _, err := limit.LookupByString(selector.SelectorKey_LimitNone); err == nil {
+		return xerrors.Errorf("selector recurses without a limit: %w", datatransfer.ErrRecursionLimitExceeded)
+	}
+	depthNode, err := limit.LookupByString(selector.SelectorKey_LimitDepth)
+	if err != nil {
+		return xerrors.Errorf("recursive selector has an unrecognized limit: %w", err)
+	}
+	depth, err := depthNode.AsInt()
+	if err != nil {
+		return err
+	}
+	if int64(depth) > rl.maxRecursionDepth {
+		return xerrors.Errorf("selector recurses %d deep, maximum is %d: %w", depth, rl.maxRecursionDepth, datatransfer.ErrRecursionLimitExceeded)
+	}
+	return nil
+}
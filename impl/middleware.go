@@ -0,0 +1,85 @@
+package impl
+
+import (
+	"sync"
+
+	"golang.org/x/xerrors"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+)
+
+// validatorMiddlewares holds the middleware chains that get composed around
+// request validators and revalidators
+type validatorMiddlewares struct {
+	lk     sync.RWMutex
+	global []datatransfer.ValidatorMiddleware
+	byType map[datatransfer.TypeIdentifier][]datatransfer.ValidatorMiddleware
+}
+
+func newValidatorMiddlewares() *validatorMiddlewares {
+	return &validatorMiddlewares{
+		byType: make(map[datatransfer.TypeIdentifier][]datatransfer.ValidatorMiddleware),
+	}
+}
+
+func checkMiddleware(middleware datatransfer.ValidatorMiddleware) error {
+	if middleware.WrapValidator == nil && middleware.WrapRevalidator == nil {
+		return xerrors.New("middleware must wrap a validator, a revalidator or both")
+	}
+	return nil
+}
+
+func (vm *validatorMiddlewares) addGlobal(middleware datatransfer.ValidatorMiddleware) error {
+	if err := checkMiddleware(middleware); err != nil {
+		return err
+	}
+	vm.lk.Lock()
+	defer vm.lk.Unlock()
+	vm.global = append(vm.global, middleware)
+	return nil
+}
+
+func (vm *validatorMiddlewares) addForType(voucherType datatransfer.TypeIdentifier, middleware datatransfer.ValidatorMiddleware) error {
+	if err := checkMiddleware(middleware); err != nil {
+		return err
+	}
+	vm.lk.Lock()
+	defer vm.lk.Unlock()
+	vm.byType[voucherType] = append(vm.byType[voucherType], middleware)
+	return nil
+}
+
+// chain returns the middleware for the given voucher type, outermost first
+func (vm *validatorMiddlewares) chain(voucherType datatransfer.TypeIdentifier) []datatransfer.ValidatorMiddleware {
+	vm.lk.RLock()
+	defer vm.lk.RUnlock()
+	typed := vm.byType[voucherType]
+	if len(vm.global) == 0 && len(typed) == 0 {
+		return nil
+	}
+	chain := make([]datatransfer.ValidatorMiddleware, 0, len(vm.global)+len(typed))
+	chain = append(chain, vm.global...)
+	return append(chain, typed...)
+}
+
+// validator composes the middleware for the given voucher type around a validator
+func (vm *validatorMiddlewares) validator(voucherType datatransfer.TypeIdentifier, validator datatransfer.RequestValidator) datatransfer.RequestValidator {
+	chain := vm.chain(voucherType)
+	for i := len(chain) - 1; i >= 0; i-- {
+		if chain[i].WrapValidator != nil {
+			validator = chain[i].WrapValidator(voucherType, validator)
+		}
+	}
+	return validator
+}
+
+// revalidator composes the middleware for the given voucher type around a revalidator
+func (vm *validatorMiddlewares) revalidator(voucherType datatransfer.TypeIdentifier, revalidator datatransfer.Revalidator) datatransfer.Revalidator {
+	chain := vm.chain(voucherType)
+	for i := len(chain) - 1; i >= 0; i-- {
+		if chain[i].WrapRevalidator != nil {
+			revalidator = chain[i].WrapRevalidator(voucherType, revalidator)
+		}
+	}
+	return revalidator
+}
@@ -0,0 +1,60 @@
+package impl
+
+import (
+	"github.com/libp2p/go-libp2p-core/peer"
+	"golang.org/x/xerrors"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+	"github.com/filecoin-project/go-data-transfer/network"
+)
+
+// misbehaviour marks an error as caused by the other peer breaking the
+// protocol, so the peer gets a strike, without changing the error message
+type misbehaviour struct {
+	err error
+}
+
+// peerMisbehaving marks the error as caused by the other peer misbehaving
+func peerMisbehaving(err error) error {
+	return misbehaviour{err}
+}
+
+func (e misbehaviour) Error() string {
+	return e.err.Error()
+}
+
+func (e misbehaviour) Unwrap() error {
+	return e.err
+}
+
+func (e misbehaviour) Is(target error) bool {
+	return target == datatransfer.ErrPeerMisbehaving
+}
+
+// allowRequest returns false if a new or restart request from the peer must
+// be dropped because the network has it over its rate limit or banned
+func (m *manager) allowRequest(p peer.ID) bool {
+	banner, ok := m.dataTransferNetwork.(network.PeerBanner)
+	return !ok || banner.AllowRequest(p)
+}
+
+// recordRejection holds a request the peer sent that was rejected against the
+// peer. Misbehaviour and breaking a request limit are strikes, and other
+// rejections count towards one. Requests that were paused or deferred were
+// not rejected, and requests turned away while draining are not the peer's
+// fault
+func (m *manager) recordRejection(p peer.ID, err error) {
+	if err == nil || err == datatransfer.ErrPause || err == datatransfer.ErrResume || err == datatransfer.ErrDeferred || err == datatransfer.ErrDraining {
+		return
+	}
+	banner, ok := m.dataTransferNetwork.(network.PeerBanner)
+	if !ok {
+		return
+	}
+	if xerrors.Is(err, datatransfer.ErrPeerMisbehaving) || isLimitViolation(err) {
+		log.Infof("strike against peer %s: %s", p, err)
+		banner.AddStrike(p)
+		return
+	}
+	banner.AddRejection(p)
+}
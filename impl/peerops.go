@@ -0,0 +1,93 @@
+package impl
+
+import (
+	"context"
+	"sort"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+	"github.com/filecoin-project/go-data-transfer/channels"
+)
+
+// PausePeer pauses every channel with the given peer that is running on this
+// node's side. Channels that are already paused here, or that are finishing
+// or have ended, are left alone and have no result
+func (m *manager) PausePeer(ctx context.Context, p peer.ID) ([]datatransfer.ChannelResult, error) {
+	log.Infof("pause channels with peer %s", p)
+	return m.applyToPeer(ctx, p, m.canPause, m.PauseDataTransferChannel)
+}
+
+// ResumePeer resumes every channel with the given peer that is paused on this
+// node's side, including channels with a timed pause
+func (m *manager) ResumePeer(ctx context.Context, p peer.ID) ([]datatransfer.ChannelResult, error) {
+	log.Infof("resume channels with peer %s", p)
+	return m.applyToPeer(ctx, p, m.canResume, m.ResumeDataTransferChannel)
+}
+
+// CancelPeer closes every channel with the given peer that is not already
+// cleaning up or ended, including pulls shared with other callers
+func (m *manager) CancelPeer(ctx context.Context, p peer.ID) ([]datatransfer.ChannelResult, error) {
+	log.Infof("cancel channels with peer %s", p)
+	return m.applyToPeer(ctx, p, func(chst datatransfer.ChannelState) bool {
+		return !channels.IsChannelCleaningUp(chst.Status()) && !channels.IsChannelTerminated(chst.Status())
+	}, m.closeChannel)
+}
+
+// applyToPeer applies the operation to each channel with the peer that the
+// filter selects, in channel ID order, and collects the results
+func (m *manager) applyToPeer(ctx context.Context, p peer.ID,
+	filter func(datatransfer.ChannelState) bool,
+	op func(context.Context, datatransfer.ChannelID) error) ([]datatransfer.ChannelResult, error) {
+	chans, err := m.channels.InProgress()
+	if err != nil {
+		return nil, err
+	}
+	var chids []datatransfer.ChannelID
+	for chid, chst := range chans {
+		if chid.OtherParty(m.peerID) == p && filter(chst) {
+			chids = append(chids, chid)
+		}
+	}
+	sort.Slice(chids, func(i, j int) bool {
+		return chids[i].String() < chids[j].String()
+	})
+
+	results := make([]datatransfer.ChannelResult, 0, len(chids))
+	for _, chid := range chids {
+		err := op(ctx, chid)
+		if err != nil {
+			log.Warnf("channel %s: %s", chid, err)
+		}
+		results = append(results, datatransfer.ChannelResult{ChannelID: chid, Err: err})
+	}
+	return results, nil
+}
+
+// canPause returns true if the channel is running on this node's side
+func (m *manager) canPause(chst datatransfer.ChannelState) bool {
+	switch chst.Status() {
+	case datatransfer.Requested, datatransfer.Ongoing:
+		return true
+	case datatransfer.InitiatorPaused:
+		return chst.ChannelID().Initiator != m.peerID
+	case datatransfer.ResponderPaused:
+		return chst.ChannelID().Responder != m.peerID
+	default:
+		return false
+	}
+}
+
+// canResume returns true if the channel is paused on this node's side
+func (m *manager) canResume(chst datatransfer.ChannelState) bool {
+	switch chst.Status() {
+	case datatransfer.BothPaused:
+		return true
+	case datatransfer.InitiatorPaused:
+		return chst.ChannelID().Initiator == m.peerID
+	case datatransfer.ResponderPaused:
+		return chst.ChannelID().Responder == m.peerID
+	default:
+		return false
+	}
+}
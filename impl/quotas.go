@@ -0,0 +1,270 @@
+package impl
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"golang.org/x/xerrors"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+	"github.com/filecoin-project/go-data-transfer/channels"
+)
+
+// QuotaAction is what happens to a channel that receives more data than a
+// quota allows
+type QuotaAction int
+
+const (
+	// QuotaFail fails the channel
+	QuotaFail QuotaAction = iota
+	// QuotaPause pauses the channel. A channel paused for a quota is paused
+	// again by the next block it receives after it is resumed, unless the
+	// quota was raised or, for a peer quota, released
+	QuotaPause
+)
+
+// ReceiveQuotas limits the data this node receives. A zero value leaves that
+// limit unenforced
+type ReceiveQuotas struct {
+	// MaxChannelBytes is the most bytes a single channel can receive
+	MaxChannelBytes uint64
+	// MaxChannelBlocks is the most blocks a single channel can receive
+	MaxChannelBlocks uint64
+	// MaxPeerBytes is the most bytes this node keeps from a single peer,
+	// across all channels with the peer. The bytes each peer has sent are
+	// stored in the datastore, and still count once their channels end,
+	// unless the channel's staged blocks are discarded. Call
+	// Manager.ReleasePeerQuota once data received from a peer is removed
+	MaxPeerBytes uint64
+	// Action is what happens to a channel that exceeds a quota
+	Action QuotaAction
+}
+
+// ReceiveQuota enforces quotas on the data channels receive. Each block is
+// checked before it is recorded, and a channel whose block would exceed a
+// quota is failed or paused with datatransfer.ErrQuotaExceeded
+func ReceiveQuota(quotas ReceiveQuotas) DataTransferOption {
+	return func(m *manager) {
+		m.quotas = &quotaTracker{
+			ReceiveQuotas: quotas,
+			channels:      make(map[datatransfer.ChannelID]*channelUsage),
+			peers:         make(map[peer.ID]*peerUsage),
+		}
+	}
+}
+
+// channelUsage is the data a channel has received
+type channelUsage struct {
+	peer   peer.ID
+	bytes  uint64
+	blocks uint64
+}
+
+// peerUsage is the data received from a peer with channels in progress
+type peerUsage struct {
+	// stored is the bytes kept from the peer's channels that have ended, as
+	// stored in the datastore
+	stored uint64
+	// inFlight is the bytes received by the peer's channels in progress
+	inFlight uint64
+	channels int
+}
+
+// quotaTracker counts the data received by channels in progress and by the
+// peers they are with. Only the totals of peers with channels in progress are
+// held in memory
+type quotaTracker struct {
+	ReceiveQuotas
+	ds datastore.Datastore
+
+	lk       sync.Mutex
+	channels map[datatransfer.ChannelID]*channelUsage
+	peers    map[peer.ID]*peerUsage
+}
+
+func (qt *quotaTracker) loadStored(p peer.ID) (uint64, error) {
+	data, err := qt.ds.Get(datastore.NewKey(p.String()))
+	if err == datastore.ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	stored, n := binary.Uvarint(data)
+	if n <= 0 {
+		return 0, xerrors.Errorf("malformed quota usage for peer %s", p)
+	}
+	return stored, nil
+}
+
+func (qt *quotaTracker) saveStored(p peer.ID, stored uint64) error {
+	key := datastore.NewKey(p.String())
+	if stored == 0 {
+		return qt.ds.Delete(key)
+	}
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, stored)
+	return qt.ds.Put(key, buf[:n])
+}
+
+// track starts counting the data received by a channel, from the amount it
+// had already received. It does nothing if the channel is already counted or
+// has ended. The channel state is read under the lock, so a channel that ends
+// at the same time is either not counted or released once it is
+func (qt *quotaTracker) track(chid datatransfer.ChannelID, channelState func() (datatransfer.ChannelState, error)) error {
+	qt.lk.Lock()
+	defer qt.lk.Unlock()
+	if _, ok := qt.channels[chid]; ok {
+		return nil
+	}
+	chst, err := channelState()
+	if err != nil {
+		return err
+	}
+	if channels.IsChannelTerminated(chst.Status()) {
+		return nil
+	}
+	p := chst.OtherPeer()
+	usage, ok := qt.peers[p]
+	if !ok {
+		stored, err := qt.loadStored(p)
+		if err != nil {
+			return err
+		}
+		usage = &peerUsage{stored: stored}
+		qt.peers[p] = usage
+	}
+	qt.channels[chid] = &channelUsage{peer: p, bytes: chst.Received(), blocks: uint64(len(chst.ReceivedCids()))}
+	usage.inFlight += chst.Received()
+	usage.channels++
+	return nil
+}
+
+// receive counts a block received by a tracked channel, or returns an error
+// without counting it if it would exceed a quota
+func (qt *quotaTracker) receive(chid datatransfer.ChannelID, size uint64) error {
+	qt.lk.Lock()
+	defer qt.lk.Unlock()
+	usage, ok := qt.channels[chid]
+	if !ok {
+		return nil
+	}
+	if qt.MaxChannelBytes != 0 && usage.bytes+size > qt.MaxChannelBytes {
+		return xerrors.Errorf("channel %s would receive more than %d bytes: %w", chid, qt.MaxChannelBytes, datatransfer.ErrQuotaExceeded)
+	}
+	if qt.MaxChannelBlocks != 0 && usage.blocks+1 > qt.MaxChannelBlocks {
+		return xerrors.Errorf("channel %s would receive more than %d blocks: %w", chid, qt.MaxChannelBlocks, datatransfer.ErrQuotaExceeded)
+	}
+	peerUsage := qt.peers[usage.peer]
+	if qt.MaxPeerBytes != 0 && peerUsage.stored+peerUsage.inFlight+size > qt.MaxPeerBytes {
+		return xerrors.Errorf("peer %s would have sent more than %d bytes: %w", usage.peer, qt.MaxPeerBytes, datatransfer.ErrQuotaExceeded)
+	}
+	usage.bytes += size
+	usage.blocks++
+	peerUsage.inFlight += size
+	return nil
+}
+
+// release stops counting a channel that has ended. Unless the data it
+// received was discarded, the data is added to the stored total for the peer
+func (qt *quotaTracker) release(chid datatransfer.ChannelID, discarded bool) error {
+	qt.lk.Lock()
+	defer qt.lk.Unlock()
+	usage, ok := qt.channels[chid]
+	if !ok {
+		return nil
+	}
+	delete(qt.channels, chid)
+	peerUsage := qt.peers[usage.peer]
+	peerUsage.inFlight -= usage.bytes
+	peerUsage.channels--
+	if peerUsage.channels == 0 {
+		delete(qt.peers, usage.peer)
+	}
+	if discarded || usage.bytes == 0 {
+		return nil
+	}
+	peerUsage.stored += usage.bytes
+	return qt.saveStored(usage.peer, peerUsage.stored)
+}
+
+// releasePeer stops counting bytes kept from the peer
+func (qt *quotaTracker) releasePeer(p peer.ID, bytes uint64) error {
+	qt.lk.Lock()
+	defer qt.lk.Unlock()
+	var stored uint64
+	usage, ok := qt.peers[p]
+	if ok {
+		stored = usage.stored
+	} else {
+		var err error
+		stored, err = qt.loadStored(p)
+		if err != nil {
+			return err
+		}
+	}
+	if bytes > stored {
+		bytes = stored
+	}
+	if err := qt.saveStored(p, stored-bytes); err != nil {
+		return err
+	}
+	if ok {
+		usage.stored -= bytes
+	}
+	return nil
+}
+
+// ReleasePeerQuota stops counting bytes received from the peer towards its
+// quota
+func (m *manager) ReleasePeerQuota(ctx context.Context, p peer.ID, bytes uint64) error {
+	if m.quotas == nil {
+		return datatransfer.ErrQuotasDisabled
+	}
+	return m.quotas.releasePeer(p, bytes)
+}
+
+// checkQuota counts a block received on a channel against the quotas. If the
+// block exceeds a quota the channel is failed or paused, and the error for
+// the transport is returned
+func (m *manager) checkQuota(chid datatransfer.ChannelID, size uint64) error {
+	if m.quotas == nil {
+		return nil
+	}
+	err := m.quotas.track(chid, func() (datatransfer.ChannelState, error) {
+		return m.channels.GetByID(context.TODO(), chid)
+	})
+	if err != nil {
+		return err
+	}
+	quotaErr := m.quotas.receive(chid, size)
+	if quotaErr == nil {
+		return nil
+	}
+	log.Warnf("channel %s: %s", chid, quotaErr)
+	if m.quotas.Action != QuotaPause {
+		if err := m.channels.Error(chid, quotaErr); err != nil {
+			return err
+		}
+		return quotaErr
+	}
+
+	chst, err := m.channels.GetByID(context.TODO(), chid)
+	if err != nil {
+		return err
+	}
+	if m.canResume(chst) {
+		// the channel is already paused on our side
+		return datatransfer.ErrPause
+	}
+	if err := m.pause(chid); err != nil {
+		return err
+	}
+	if err := m.dataTransferNetwork.SendMessage(m.channelSpans.context(context.TODO(), chid), chid.OtherParty(m.peerID), m.pauseMessage(chid)); err != nil {
+		log.Warnf("channel %s: unable to send pause message: %s", chid, err)
+	}
+	return datatransfer.ErrPause
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/ipfs/go-cid"
 	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
 	"github.com/libp2p/go-libp2p-core/peer"
+	"golang.org/x/xerrors"
 
 	datatransfer "github.com/filecoin-project/go-data-transfer"
 	"github.com/filecoin-project/go-data-transfer/channels"
@@ -29,7 +30,23 @@ func (r *receiver) ReceiveRequest(
 
 func (r *receiver) receiveRequest(ctx context.Context, initiator peer.ID, incoming datatransfer.Request) error {
 	chid := datatransfer.ChannelID{Initiator: initiator, Responder: r.manager.peerID, ID: incoming.TransferID()}
+	if carrier, ok := incoming.(datatransfer.ReceiptCarrier); ok && carrier.Receipt() != nil {
+		return r.receiveReceipt(initiator, chid, carrier.Receipt())
+	}
 	response, receiveErr := r.manager.OnRequestReceived(chid, incoming)
+	if xerrors.Is(receiveErr, datatransfer.ErrRateLimited) {
+		log.Debugf("dropping request for transfer ID %d from rate limited or banned peer %s", incoming.TransferID(), initiator)
+		return nil
+	}
+	transport, err := r.manager.channelTransport(chid)
+	if err != nil {
+		return err
+	}
+
+	if receiveErr == datatransfer.ErrDeferred {
+		// the response is sent when the request is accepted or rejected
+		return nil
+	}
 
 	if receiveErr == datatransfer.ErrResume {
 		chst, err := r.manager.channels.GetByID(ctx, chid)
@@ -37,7 +54,7 @@ func (r *receiver) receiveRequest(ctx context.Context, initiator peer.ID, incomi
 			return err
 		}
 		if resumeTransportStatesResponder.Contains(chst.Status()) {
-			return r.manager.transport.(datatransfer.PauseableTransport).ResumeChannel(ctx, response, chid)
+			return transport.(datatransfer.PauseableTransport).ResumeChannel(ctx, response, chid)
 		}
 		receiveErr = nil
 	}
@@ -54,22 +71,27 @@ func (r *receiver) receiveRequest(ctx context.Context, initiator peer.ID, incomi
 			}
 
 			stor, _ := incoming.Selector()
-			if err := r.manager.transport.OpenChannel(ctx, initiator, chid, cidlink.Link{Cid: incoming.BaseCid()}, stor, doNotSendCids, response); err != nil {
+			if err := transport.OpenChannel(r.manager.channelSpans.context(ctx, chid), initiator, chid, cidlink.Link{Cid: incoming.BaseCid()}, stor, doNotSendCids, response); err != nil {
 				return err
 			}
 		} else {
-			if err := r.manager.dataTransferNetwork.SendMessage(ctx, initiator, response); err != nil {
+			if err := r.manager.dataTransferNetwork.SendMessage(r.manager.channelSpans.context(ctx, chid), initiator, response); err != nil {
 				return err
 			}
 		}
 	}
 
 	if receiveErr == datatransfer.ErrPause {
-		return r.manager.transport.(datatransfer.PauseableTransport).PauseChannel(ctx, chid)
+		return transport.(datatransfer.PauseableTransport).PauseChannel(ctx, chid)
+	}
+
+	if receiveErr == datatransfer.ErrDraining && !incoming.IsNew() && !incoming.IsRestart() {
+		// a resume rejected while draining leaves the channel paused
+		return receiveErr
 	}
 
 	if receiveErr != nil {
-		_ = r.manager.transport.CloseChannel(ctx, chid)
+		_ = transport.CloseChannel(ctx, chid)
 		return receiveErr
 	}
 
@@ -92,20 +114,40 @@ func (r *receiver) receiveResponse(
 	sender peer.ID,
 	incoming datatransfer.Response) error {
 	chid := datatransfer.ChannelID{Initiator: r.manager.peerID, Responder: sender, ID: incoming.TransferID()}
+	if carrier, ok := incoming.(datatransfer.ReceiptCarrier); ok && carrier.Receipt() != nil {
+		return r.receiveReceipt(sender, chid, carrier.Receipt())
+	}
 	err := r.manager.OnResponseReceived(chid, incoming)
+	transport, transportErr := r.manager.channelTransport(chid)
+	if transportErr != nil {
+		return transportErr
+	}
 	if err == datatransfer.ErrPause {
-		return r.manager.transport.(datatransfer.PauseableTransport).PauseChannel(ctx, chid)
+		return transport.(datatransfer.PauseableTransport).PauseChannel(ctx, chid)
 	}
 	if err != nil {
 		log.Warnf("closing channel %s after getting error processing response from %s: %s",
 			chid, sender, err)
 
-		_ = r.manager.transport.CloseChannel(ctx, chid)
+		_ = transport.CloseChannel(ctx, chid)
 		return err
 	}
 	return nil
 }
 
+// receiveReceipt records the receipt the receiver sent for a channel this
+// node sent data on. Receipts arrive once the channel has completed, so they
+// are not passed to the channel's transport
+func (r *receiver) receiveReceipt(sender peer.ID, chid datatransfer.ChannelID, receipt *datatransfer.Receipt) error {
+	if !r.manager.allowRequest(sender) {
+		log.Debugf("dropping receipt for channel %s from rate limited or banned peer %s", chid, sender)
+		return nil
+	}
+	err := r.manager.receiveReceipt(chid, receipt)
+	r.manager.recordRejection(sender, err)
+	return err
+}
+
 func (r *receiver) ReceiveError(err error) {
 	log.Errorf("received error message on data transfer: %s", err.Error())
 }
@@ -120,30 +162,44 @@ func (r *receiver) ReceiveRestartExistingChannelRequest(ctx context.Context,
 		return
 	}
 
+	if !r.manager.allowRequest(sender) {
+		log.Debugf("dropping restart existing channel request for channel %s from rate limited or banned peer %s", ch, sender)
+		return
+	}
+
+	if r.manager.isDraining() {
+		log.Warnf("cannot restart channel %s for %s: %s", ch, sender, datatransfer.ErrDraining)
+		return
+	}
+
 	log.Infof("channel %s: received restart existing channel request from %s", ch, sender)
 
 	// validate channel exists -> in non-terminal state and that the sender matches
 	channel, err := r.manager.channels.GetByID(ctx, ch)
 	if err != nil || channel == nil {
 		// nothing to do here, we wont handle the request
+		r.manager.recordRejection(sender, datatransfer.ErrRejected)
 		return
 	}
 
 	// initiator should be me
 	if channel.ChannelID().Initiator != r.manager.peerID {
 		log.Error("cannot restart channel %s: channel initiator is not the manager peer", ch)
+		r.manager.recordRejection(sender, datatransfer.ErrRejected)
 		return
 	}
 
 	// other peer should be the counter party on the channel
 	if channel.OtherPeer() != sender {
 		log.Error("cannot restart channel %s: channel counterparty is not the sender peer", ch)
+		r.manager.recordRejection(sender, datatransfer.ErrPeerMisbehaving)
 		return
 	}
 
 	// channel should NOT be terminated
 	if channels.IsChannelTerminated(channel.Status()) {
 		log.Error("cannot restart channel %s: channel already terminated", ch)
+		r.manager.recordRejection(sender, datatransfer.ErrRejected)
 		return
 	}
 
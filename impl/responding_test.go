@@ -2,17 +2,24 @@ package impl_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"math/rand"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-datastore"
 	dss "github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
 	"github.com/ipld/go-ipld-prime"
 	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+	"github.com/ipld/go-ipld-prime/traversal/selector"
+	"github.com/ipld/go-ipld-prime/traversal/selector/builder"
 	"github.com/libp2p/go-libp2p-core/peer"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -23,8 +30,10 @@ import (
 	datatransfer "github.com/filecoin-project/go-data-transfer"
 	"github.com/filecoin-project/go-data-transfer/channels"
 	. "github.com/filecoin-project/go-data-transfer/impl"
+	"github.com/filecoin-project/go-data-transfer/journal"
 	"github.com/filecoin-project/go-data-transfer/message"
 	"github.com/filecoin-project/go-data-transfer/testutil"
+	"github.com/filecoin-project/go-data-transfer/tracing"
 )
 
 func TestDataTransferResponding(t *testing.T) {
@@ -70,6 +79,131 @@ func TestDataTransferResponding(t *testing.T) {
 				require.True(t, response.IsVoucherResult())
 			},
 		},
+		"new push request uses the store selected by the validator": {
+			expectedEvents: []datatransfer.EventCode{datatransfer.Open, datatransfer.Accept},
+			configureValidator: func(sv *testutil.StubbedValidator) {
+				sv.ExpectSuccessPush()
+			},
+			verify: func(t *testing.T, h *receiverHarness) {
+				sv := newStoreSelectingValidator(h.sv)
+				require.NoError(t, h.dt.ReplaceVoucherType(h.voucher, sv))
+				h.network.Delegate.ReceiveRequest(h.ctx, h.peers[1], h.pushRequest)
+				require.Equal(t, []datatransfer.ChannelID{channelID(h.id, h.peers)}, sv.selected)
+				require.Len(t, h.transport.UsedStores, 1)
+				require.Equal(t, channelID(h.id, h.peers), h.transport.UsedStores[0].ChannelID)
+				require.NotNil(t, h.transport.UsedStores[0].Loader)
+				require.NotNil(t, h.transport.UsedStores[0].Storer)
+				require.Len(t, h.transport.OpenedChannels, 1)
+			},
+		},
+		"new push request uses the blockstore selected by the validator": {
+			expectedEvents: []datatransfer.EventCode{datatransfer.Open, datatransfer.Accept},
+			configureValidator: func(sv *testutil.StubbedValidator) {
+				sv.ExpectSuccessPush()
+			},
+			verify: func(t *testing.T, h *receiverHarness) {
+				sv := &blockstoreSelectingValidator{
+					StubbedValidator: h.sv,
+					bs:               blockstore.NewBlockstore(dss.MutexWrap(datastore.NewMapDatastore())),
+				}
+				require.NoError(t, h.dt.ReplaceVoucherType(h.voucher, sv))
+				h.network.Delegate.ReceiveRequest(h.ctx, h.peers[1], h.pushRequest)
+				require.Len(t, h.transport.UsedStores, 1)
+				w, commit, err := h.transport.UsedStores[0].Storer(ipld.LinkContext{})
+				require.NoError(t, err)
+				_, err = w.Write([]byte("block"))
+				require.NoError(t, err)
+				blk := testutil.GenerateCids(1)[0]
+				require.NoError(t, commit(cidlink.Link{Cid: blk}))
+				has, err := sv.bs.Has(blk)
+				require.NoError(t, err)
+				require.True(t, has)
+			},
+		},
+		"new pull request rejected when the validator cannot select a store": {
+			expectedEvents: []datatransfer.EventCode{datatransfer.Open, datatransfer.Error, datatransfer.CleanupComplete},
+			configureValidator: func(sv *testutil.StubbedValidator) {
+				sv.ExpectSuccessPull()
+			},
+			verify: func(t *testing.T, h *receiverHarness) {
+				sv := newStoreSelectingValidator(h.sv)
+				sv.err = errors.New("no store for deal")
+				require.NoError(t, h.dt.ReplaceVoucherType(h.voucher, sv))
+				_, err := h.transport.EventHandler.OnRequestReceived(channelID(h.id, h.peers), h.pullRequest)
+				require.Error(t, err)
+				require.Empty(t, h.transport.UsedStores)
+				require.Equal(t, []datatransfer.ChannelID{channelID(h.id, h.peers)}, h.transport.CleanedUpChannels)
+			},
+		},
+		"duplicate new push request does not select a store again": {
+			expectedEvents: []datatransfer.EventCode{datatransfer.Open, datatransfer.Accept},
+			configureValidator: func(sv *testutil.StubbedValidator) {
+				sv.StubSuccessPush()
+			},
+			verify: func(t *testing.T, h *receiverHarness) {
+				sv := newStoreSelectingValidator(h.sv)
+				require.NoError(t, h.dt.ReplaceVoucherType(h.voucher, sv))
+				h.network.Delegate.ReceiveRequest(h.ctx, h.peers[1], h.pushRequest)
+				h.network.Delegate.ReceiveRequest(h.ctx, h.peers[1], h.pushRequest)
+				require.Len(t, sv.selected, 1)
+				require.Len(t, h.transport.UsedStores, 1)
+			},
+		},
+		"new pull request uses the default store when none is selected": {
+			expectedEvents: []datatransfer.EventCode{datatransfer.Open, datatransfer.Accept},
+			configureValidator: func(sv *testutil.StubbedValidator) {
+				sv.ExpectSuccessPull()
+			},
+			verify: func(t *testing.T, h *receiverHarness) {
+				sv := newStoreSelectingValidator(h.sv)
+				sv.loader, sv.storer = nil, nil
+				require.NoError(t, h.dt.ReplaceVoucherType(h.voucher, sv))
+				_, err := h.transport.EventHandler.OnRequestReceived(channelID(h.id, h.peers), h.pullRequest)
+				require.NoError(t, err)
+				require.Len(t, sv.selected, 1)
+				require.Empty(t, h.transport.UsedStores)
+			},
+		},
+		"new push request while draining": {
+			verify: func(t *testing.T, h *receiverHarness) {
+				require.NoError(t, h.dt.Drain(h.ctx))
+				h.network.Delegate.ReceiveRequest(h.ctx, h.peers[1], h.pushRequest)
+				require.Len(t, h.network.SentMessages, 1)
+				response, ok := h.network.SentMessages[0].Message.(datatransfer.Response)
+				require.True(t, ok)
+				require.False(t, response.Accepted())
+				require.Equal(t, response.TransferID(), h.id)
+				require.True(t, response.IsNew())
+				require.Empty(t, h.transport.OpenedChannels)
+			},
+		},
+		"resume while draining": {
+			expectedEvents: []datatransfer.EventCode{
+				datatransfer.Open,
+				datatransfer.NewVoucherResult,
+				datatransfer.Accept,
+				datatransfer.PauseInitiator,
+				datatransfer.PauseResponder},
+			configureValidator: func(sv *testutil.StubbedValidator) {
+				sv.ExpectSuccessPush()
+				sv.StubResult(testutil.NewFakeDTType())
+			},
+			verify: func(t *testing.T, h *receiverHarness) {
+				h.network.Delegate.ReceiveRequest(h.ctx, h.peers[1], h.pushRequest)
+				_, err := h.transport.EventHandler.OnRequestReceived(channelID(h.id, h.peers), h.pauseUpdate)
+				require.NoError(t, err)
+				ctx, cancel := context.WithCancel(h.ctx)
+				cancel()
+				require.NoError(t, h.dt.Drain(ctx))
+				_, err = h.transport.EventHandler.OnRequestReceived(channelID(h.id, h.peers), h.resumeUpdate)
+				require.EqualError(t, err, datatransfer.ErrDraining.Error())
+				h.network.Delegate.ReceiveRequest(h.ctx, h.peers[1], h.resumeUpdate)
+				require.Empty(t, h.transport.ClosedChannels)
+				chst, err := h.dt.ChannelState(h.ctx, channelID(h.id, h.peers))
+				require.NoError(t, err)
+				require.Equal(t, datatransfer.BothPaused, chst.Status())
+			},
+		},
 		"new push request errors": {
 			configureValidator: func(sv *testutil.StubbedValidator) {
 				sv.ExpectErrorPush()
@@ -177,6 +311,62 @@ func TestDataTransferResponding(t *testing.T) {
 				require.True(t, response.EmptyVoucherResult())
 			},
 		},
+		"validator middleware runs global middleware first, in registration order": {
+			expectedEvents: []datatransfer.EventCode{datatransfer.Open, datatransfer.NewVoucherResult, datatransfer.Accept},
+			configureValidator: func(sv *testutil.StubbedValidator) {
+				sv.ExpectSuccessPush()
+				sv.StubResult(testutil.NewFakeDTType())
+			},
+			verify: func(t *testing.T, h *receiverHarness) {
+				var calls []string
+				require.NoError(t, h.dt.RegisterValidatorMiddleware(recordingMiddleware("global-1", &calls, nil)))
+				require.NoError(t, h.dt.RegisterVoucherTypeMiddleware(h.voucher, recordingMiddleware("typed", &calls, nil)))
+				require.NoError(t, h.dt.RegisterValidatorMiddleware(recordingMiddleware("global-2", &calls, nil)))
+				require.NoError(t, h.dt.RegisterVoucherTypeMiddleware(testutil.NewFakeDTType(), recordingMiddleware("typed-2", &calls, nil)))
+				h.network.Delegate.ReceiveRequest(h.ctx, h.peers[1], h.pushRequest)
+				require.Equal(t, []string{"global-1", "global-2", "typed", "typed-2"}, calls)
+				require.Len(t, h.sv.ValidationsReceived, 1)
+				require.Len(t, h.transport.OpenedChannels, 1)
+			},
+		},
+		"validator middleware short-circuits validation": {
+			verify: func(t *testing.T, h *receiverHarness) {
+				var calls []string
+				err := h.dt.RegisterValidatorMiddleware(recordingMiddleware("quota", &calls, errors.New("quota exceeded")))
+				require.NoError(t, err)
+				h.network.Delegate.ReceiveRequest(h.ctx, h.peers[1], h.pushRequest)
+				require.Equal(t, []string{"quota"}, calls)
+				require.Len(t, h.sv.ValidationsReceived, 0)
+				require.Len(t, h.transport.OpenedChannels, 0)
+				require.Len(t, h.network.SentMessages, 1)
+				response, ok := h.network.SentMessages[0].Message.(datatransfer.Response)
+				require.True(t, ok)
+				require.False(t, response.Accepted())
+			},
+		},
+		"validator middleware must wrap something": {
+			verify: func(t *testing.T, h *receiverHarness) {
+				err := h.dt.RegisterValidatorMiddleware(datatransfer.ValidatorMiddleware{})
+				require.Error(t, err)
+				err = h.dt.RegisterVoucherTypeMiddleware(h.voucher, datatransfer.ValidatorMiddleware{})
+				require.Error(t, err)
+			},
+		},
+		"revalidator middleware wraps revalidation": {
+			expectedEvents: []datatransfer.EventCode{datatransfer.Open, datatransfer.NewVoucherResult, datatransfer.Accept, datatransfer.NewVoucher, datatransfer.ResumeResponder},
+			configureValidator: func(sv *testutil.StubbedValidator) {
+				sv.ExpectSuccessPush()
+				sv.StubResult(testutil.NewFakeDTType())
+			},
+			verify: func(t *testing.T, h *receiverHarness) {
+				var calls []string
+				require.NoError(t, h.dt.RegisterValidatorMiddleware(recordingMiddleware("logging", &calls, nil)))
+				h.network.Delegate.ReceiveRequest(h.ctx, h.peers[1], h.pushRequest)
+				_, err := h.transport.EventHandler.OnRequestReceived(channelID(h.id, h.peers), h.voucherUpdate)
+				require.EqualError(t, err, datatransfer.ErrResume.Error())
+				require.Equal(t, []string{"logging", "logging:revalidate"}, calls)
+			},
+		},
 		"send vouchers from responder fails, push request": {
 			verify: func(t *testing.T, h *receiverHarness) {
 				h.network.Delegate.ReceiveRequest(h.ctx, h.peers[1], h.pushRequest)
@@ -407,104 +597,538 @@ func TestDataTransferResponding(t *testing.T) {
 				datatransfer.ResumeResponder,
 			},
 			configureValidator: func(sv *testutil.StubbedValidator) {
-				sv.ExpectSuccessPull()
-				sv.StubResult(testutil.NewFakeDTType())
+				sv.ExpectSuccessPull()
+				sv.StubResult(testutil.NewFakeDTType())
+			},
+			configureRevalidator: func(srv *testutil.StubbedRevalidator) {
+				srv.ExpectPausePullCheck()
+				srv.StubRevalidationResult(testutil.NewFakeDTType())
+				srv.ExpectSuccessRevalidation()
+				srv.StubCheckResult(testutil.NewFakeDTType())
+			},
+			verify: func(t *testing.T, h *receiverHarness) {
+				_, err := h.transport.EventHandler.OnRequestReceived(channelID(h.id, h.peers), h.pullRequest)
+				require.NoError(t, err)
+				msg, err := h.transport.EventHandler.OnDataQueued(
+					channelID(h.id, h.peers),
+					cidlink.Link{Cid: testutil.GenerateCids(1)[0]},
+					12345)
+				require.EqualError(t, err, datatransfer.ErrPause.Error())
+				response, ok := msg.(datatransfer.Response)
+				require.True(t, ok)
+				require.True(t, response.Accepted())
+				require.Equal(t, response.TransferID(), h.id)
+				require.False(t, response.IsUpdate())
+				require.False(t, response.IsCancel())
+				require.True(t, response.IsPaused())
+				require.True(t, response.IsVoucherResult())
+				require.False(t, response.EmptyVoucherResult())
+				response, err = h.transport.EventHandler.OnRequestReceived(channelID(h.id, h.peers), h.voucherUpdate)
+				require.EqualError(t, err, datatransfer.ErrResume.Error())
+				require.True(t, response.Accepted())
+				require.Equal(t, response.TransferID(), h.id)
+				require.False(t, response.IsUpdate())
+				require.False(t, response.IsCancel())
+				require.False(t, response.IsPaused())
+				require.True(t, response.IsVoucherResult())
+				require.False(t, response.EmptyVoucherResult())
+			},
+		},
+		"validated, finalize, and complete successfully": {
+			expectedEvents: []datatransfer.EventCode{
+				datatransfer.Open,
+				datatransfer.NewVoucherResult,
+				datatransfer.Accept,
+				datatransfer.NewVoucherResult,
+				datatransfer.BeginFinalizing,
+				datatransfer.NewVoucher,
+				datatransfer.ResumeResponder,
+				datatransfer.CleanupComplete,
+			},
+			configureValidator: func(sv *testutil.StubbedValidator) {
+				sv.ExpectSuccessPull()
+				sv.StubResult(testutil.NewFakeDTType())
+			},
+			configureRevalidator: func(srv *testutil.StubbedRevalidator) {
+				srv.ExpectPauseComplete()
+				srv.StubRevalidationResult(testutil.NewFakeDTType())
+				srv.ExpectSuccessRevalidation()
+			},
+			verify: func(t *testing.T, h *receiverHarness) {
+				_, err := h.transport.EventHandler.OnRequestReceived(channelID(h.id, h.peers), h.pullRequest)
+				require.NoError(t, err)
+				err = h.transport.EventHandler.OnChannelCompleted(channelID(h.id, h.peers), true)
+				require.NoError(t, err)
+				require.Len(t, h.network.SentMessages, 1)
+				response, ok := h.network.SentMessages[0].Message.(datatransfer.Response)
+				require.True(t, ok)
+				require.True(t, response.Accepted())
+				require.Equal(t, response.TransferID(), h.id)
+				require.False(t, response.IsUpdate())
+				require.False(t, response.IsCancel())
+				require.True(t, response.IsPaused())
+				require.True(t, response.IsVoucherResult())
+				require.False(t, response.EmptyVoucherResult())
+				response, err = h.transport.EventHandler.OnRequestReceived(channelID(h.id, h.peers), h.voucherUpdate)
+				require.EqualError(t, err, datatransfer.ErrResume.Error())
+				require.Equal(t, response.TransferID(), h.id)
+				require.True(t, response.IsVoucherResult())
+				require.False(t, response.IsPaused())
+			},
+		},
+		"validated, incomplete response": {
+			expectedEvents: []datatransfer.EventCode{
+				datatransfer.Open,
+				datatransfer.NewVoucherResult,
+				datatransfer.Accept,
+				datatransfer.Error,
+				datatransfer.CleanupComplete,
+			},
+			configureValidator: func(sv *testutil.StubbedValidator) {
+				sv.ExpectSuccessPull()
+				sv.StubResult(testutil.NewFakeDTType())
+			},
+			configureRevalidator: func(srv *testutil.StubbedRevalidator) {
+			},
+			verify: func(t *testing.T, h *receiverHarness) {
+				_, err := h.transport.EventHandler.OnRequestReceived(channelID(h.id, h.peers), h.pullRequest)
+				require.NoError(t, err)
+				err = h.transport.EventHandler.OnChannelCompleted(channelID(h.id, h.peers), false)
+				require.NoError(t, err)
+			},
+		},
+		"new push request deferred, then accepted": {
+			expectedEvents: []datatransfer.EventCode{datatransfer.Open, datatransfer.NewVoucherResult, datatransfer.Accept},
+			configureValidator: func(sv *testutil.StubbedValidator) {
+				sv.ExpectDeferPush()
+				sv.StubResult(testutil.NewFakeDTType())
+			},
+			verify: func(t *testing.T, h *receiverHarness) {
+				require.NoError(t, h.dt.RegisterVoucherResultType(testutil.NewFakeDTType()))
+				h.network.Delegate.ReceiveRequest(h.ctx, h.peers[1], h.pushRequest)
+				require.Len(t, h.transport.OpenedChannels, 0)
+				require.Len(t, h.network.SentMessages, 0)
+				chid := channelID(h.id, h.peers)
+				status := h.dt.TransferChannelStatus(h.ctx, chid)
+				require.Equal(t, datatransfer.Requested, status)
+
+				err := h.dt.AcceptChannel(h.ctx, chid)
+				require.NoError(t, err)
+				require.Len(t, h.transport.OpenedChannels, 1)
+				openChannel := h.transport.OpenedChannels[0]
+				require.Equal(t, openChannel.ChannelID, chid)
+				require.Equal(t, openChannel.DataSender, h.peers[1])
+				require.Equal(t, openChannel.Root, cidlink.Link{Cid: h.baseCid})
+				require.Equal(t, openChannel.Selector, h.stor)
+				response, ok := openChannel.Message.(datatransfer.Response)
+				require.True(t, ok)
+				require.True(t, response.Accepted())
+				require.Equal(t, response.TransferID(), h.id)
+				require.False(t, response.IsPaused())
+				require.True(t, response.IsNew())
+				require.True(t, response.IsVoucherResult())
+				require.False(t, response.EmptyVoucherResult())
+
+				err = h.dt.AcceptChannel(h.ctx, chid)
+				require.Error(t, err)
+			},
+		},
+		"new push request deferred, then rejected": {
+			expectedEvents: []datatransfer.EventCode{datatransfer.Open, datatransfer.NewVoucherResult, datatransfer.Error, datatransfer.CleanupComplete},
+			configureValidator: func(sv *testutil.StubbedValidator) {
+				sv.ExpectDeferPush()
+				sv.StubResult(testutil.NewFakeDTType())
+			},
+			verify: func(t *testing.T, h *receiverHarness) {
+				require.NoError(t, h.dt.RegisterVoucherResultType(testutil.NewFakeDTType()))
+				h.network.Delegate.ReceiveRequest(h.ctx, h.peers[1], h.pushRequest)
+				require.Len(t, h.network.SentMessages, 0)
+				chid := channelID(h.id, h.peers)
+
+				err := h.dt.RejectChannel(h.ctx, chid)
+				require.NoError(t, err)
+				require.Len(t, h.transport.OpenedChannels, 0)
+				require.Len(t, h.network.SentMessages, 1)
+				response, ok := h.network.SentMessages[0].Message.(datatransfer.Response)
+				require.True(t, ok)
+				require.False(t, response.Accepted())
+				require.Equal(t, response.TransferID(), h.id)
+				require.True(t, response.IsNew())
+
+				err = h.dt.AcceptChannel(h.ctx, chid)
+				require.Error(t, err)
+			},
+		},
+		"new push request deferred, then accepted by concurrent calls": {
+			expectedEvents: []datatransfer.EventCode{datatransfer.Open, datatransfer.NewVoucherResult, datatransfer.Accept},
+			configureValidator: func(sv *testutil.StubbedValidator) {
+				sv.ExpectDeferPush()
+				sv.StubResult(testutil.NewFakeDTType())
+			},
+			verify: func(t *testing.T, h *receiverHarness) {
+				require.NoError(t, h.dt.RegisterVoucherResultType(testutil.NewFakeDTType()))
+				h.network.Delegate.ReceiveRequest(h.ctx, h.peers[1], h.pushRequest)
+				chid := channelID(h.id, h.peers)
+
+				// only one of the calls may accept the channel, even though the
+				// channel status has not changed yet when the others are made
+				errs := make(chan error, 5)
+				for i := 0; i < cap(errs); i++ {
+					go func() {
+						errs <- h.dt.AcceptChannel(h.ctx, chid)
+					}()
+				}
+				accepted := 0
+				for i := 0; i < cap(errs); i++ {
+					if <-errs == nil {
+						accepted++
+					}
+				}
+				require.Equal(t, 1, accepted)
+				require.Len(t, h.transport.OpenedChannels, 1)
+				require.Error(t, h.dt.RejectChannel(h.ctx, chid))
+				require.Len(t, h.network.SentMessages, 0)
+			},
+		},
+		"new pull request deferred, then accepted": {
+			expectedEvents: []datatransfer.EventCode{datatransfer.Open, datatransfer.Accept},
+			configureValidator: func(sv *testutil.StubbedValidator) {
+				sv.ExpectDeferPull()
+			},
+			verify: func(t *testing.T, h *receiverHarness) {
+				chid := channelID(h.id, h.peers)
+				response, err := h.transport.EventHandler.OnRequestReceived(chid, h.pullRequest)
+				require.EqualError(t, err, datatransfer.ErrPause.Error())
+				require.Nil(t, response)
+				require.Equal(t, datatransfer.Requested, h.dt.TransferChannelStatus(h.ctx, chid))
+
+				err = h.dt.AcceptChannel(h.ctx, chid)
+				require.NoError(t, err)
+				require.Len(t, h.transport.ResumedChannels, 1)
+				resumedChannel := h.transport.ResumedChannels[0]
+				require.Equal(t, chid, resumedChannel.ChannelID)
+				resumeResponse, ok := resumedChannel.Message.(datatransfer.Response)
+				require.True(t, ok)
+				require.True(t, resumeResponse.Accepted())
+				require.True(t, resumeResponse.IsNew())
+				require.False(t, resumeResponse.IsPaused())
+			},
+		},
+		"new pull request deferred, then rejected": {
+			expectedEvents: []datatransfer.EventCode{datatransfer.Open, datatransfer.Error, datatransfer.CleanupComplete},
+			configureValidator: func(sv *testutil.StubbedValidator) {
+				sv.ExpectDeferPull()
+			},
+			verify: func(t *testing.T, h *receiverHarness) {
+				chid := channelID(h.id, h.peers)
+				_, err := h.transport.EventHandler.OnRequestReceived(chid, h.pullRequest)
+				require.EqualError(t, err, datatransfer.ErrPause.Error())
+
+				err = h.dt.RejectChannel(h.ctx, chid)
+				require.NoError(t, err)
+				require.Len(t, h.transport.ClosedChannels, 1)
+				require.Equal(t, chid, h.transport.ClosedChannels[0])
+				require.Len(t, h.network.SentMessages, 1)
+				response, ok := h.network.SentMessages[0].Message.(datatransfer.Response)
+				require.True(t, ok)
+				require.False(t, response.Accepted())
+				require.True(t, response.IsNew())
+			},
+		},
+		"unregistered voucher type rejects new requests, existing channels still decode": {
+			expectedEvents: []datatransfer.EventCode{datatransfer.Open, datatransfer.Accept},
+			configureValidator: func(sv *testutil.StubbedValidator) {
+				sv.ExpectSuccessPush()
+			},
+			verify: func(t *testing.T, h *receiverHarness) {
+				h.network.Delegate.ReceiveRequest(h.ctx, h.peers[1], h.pushRequest)
+				require.Len(t, h.transport.OpenedChannels, 1)
+
+				require.NoError(t, h.dt.UnregisterVoucherType(h.voucher.Type()))
+				require.NoError(t, h.dt.UnregisterRevalidator(h.voucher.Type()))
+				require.Error(t, h.dt.UnregisterVoucherType(h.voucher.Type()))
+
+				chst, err := h.dt.ChannelState(h.ctx, channelID(h.id, h.peers))
+				require.NoError(t, err)
+				require.Equal(t, h.voucher, chst.Voucher())
+
+				request, err := message.NewRequest(h.id+1, false, false, h.voucher.Type(), h.voucher, h.baseCid, h.stor)
+				require.NoError(t, err)
+				h.network.Delegate.ReceiveRequest(h.ctx, h.peers[1], request)
+				require.Len(t, h.sv.ValidationsReceived, 1)
+				require.Len(t, h.transport.OpenedChannels, 1)
+				require.Len(t, h.network.SentMessages, 1)
+				response, ok := h.network.SentMessages[0].Message.(datatransfer.Response)
+				require.True(t, ok)
+				require.False(t, response.Accepted())
+				require.Equal(t, h.id+1, response.TransferID())
+			},
+		},
+		"replaced voucher type validates with the new validator": {
+			expectedEvents: []datatransfer.EventCode{datatransfer.Open, datatransfer.Accept},
+			verify: func(t *testing.T, h *receiverHarness) {
+				sv := testutil.NewStubbedValidator()
+				sv.ExpectSuccessPush()
+				require.NoError(t, h.dt.ReplaceVoucherType(h.voucher, sv))
+				err := h.dt.ReplaceTransportConfigurer(h.voucher, func(datatransfer.ChannelID, datatransfer.Voucher, datatransfer.Transport) {})
+				require.Error(t, err)
+
+				h.network.Delegate.ReceiveRequest(h.ctx, h.peers[1], h.pushRequest)
+				require.Len(t, h.sv.ValidationsReceived, 0)
+				require.Len(t, sv.ValidationsReceived, 1)
+				require.Len(t, h.transport.OpenedChannels, 1)
+				sv.VerifyExpectations(t)
+			},
+		},
+		"revalidation dispatches to the revalidator bound to the channel": {
+			expectedEvents: []datatransfer.EventCode{
+				datatransfer.Open,
+				datatransfer.Accept,
+				datatransfer.DataReceived,
+				datatransfer.DataReceived,
+				datatransfer.DataReceived,
+			},
+			configureValidator: func(sv *testutil.StubbedValidator) {
+				sv.ExpectSuccessPush()
+			},
+			configureRevalidator: func(srv *testutil.StubbedRevalidator) {
+				srv.ExpectSuccessPushCheck()
+			},
+			verify: func(t *testing.T, h *receiverHarness) {
+				claimer := &claimingRevalidator{Revalidator: testutil.NewStubbedRevalidator()}
+				require.NoError(t, h.dt.RegisterRevalidator(&otherVoucher{}, claimer))
+				h.network.Delegate.ReceiveRequest(h.ctx, h.peers[1], h.pushRequest)
+				for _, c := range testutil.GenerateCids(3) {
+					err := h.transport.EventHandler.OnDataReceived(channelID(h.id, h.peers), cidlink.Link{Cid: c}, 12345)
+					require.NoError(t, err)
+				}
+				require.Zero(t, claimer.pushChecks)
+			},
+		},
+		"revalidator that claims an unbound channel is bound to it": {
+			expectedEvents: []datatransfer.EventCode{
+				datatransfer.Open,
+				datatransfer.Accept,
+				datatransfer.DataReceived,
+				datatransfer.DataReceived,
+				datatransfer.Cancel,
+				datatransfer.CleanupComplete,
+			},
+			configureValidator: func(sv *testutil.StubbedValidator) {
+				sv.ExpectSuccessPush()
+			},
+			verify: func(t *testing.T, h *receiverHarness) {
+				require.NoError(t, h.dt.UnregisterRevalidator(h.voucher.Type()))
+				claimer := &claimingRevalidator{Revalidator: testutil.NewStubbedRevalidator()}
+				require.NoError(t, h.dt.RegisterRevalidator(&otherVoucher{}, claimer))
+				h.network.Delegate.ReceiveRequest(h.ctx, h.peers[1], h.pushRequest)
+
+				chid := channelID(h.id, h.peers)
+				bindingKey := datastore.NewKey("/revalidator-bindings/" + chid.String())
+				_, err := h.ds.Get(bindingKey)
+				require.Equal(t, datastore.ErrNotFound, err)
+
+				for _, c := range testutil.GenerateCids(2) {
+					err := h.transport.EventHandler.OnDataReceived(chid, cidlink.Link{Cid: c}, 12345)
+					require.NoError(t, err)
+				}
+				require.Equal(t, 2, claimer.pushChecks)
+				value, err := h.ds.Get(bindingKey)
+				require.NoError(t, err)
+				require.Equal(t, "OtherVoucher", string(value))
+
+				_, err = h.transport.EventHandler.OnRequestReceived(chid, h.cancelUpdate)
+				require.NoError(t, err)
+				require.Eventually(t, func() bool {
+					_, err := h.ds.Get(bindingKey)
+					return err == datastore.ErrNotFound
+				}, time.Second, 10*time.Millisecond)
+			},
+		},
+		"filtered subscriptions receive matching events": {
+			expectedEvents: []datatransfer.EventCode{
+				datatransfer.Open,
+				datatransfer.Accept,
+				datatransfer.DataReceived,
+			},
+			configureValidator: func(sv *testutil.StubbedValidator) {
+				sv.ExpectSuccessPush()
+			},
+			configureRevalidator: func(srv *testutil.StubbedRevalidator) {
+				srv.ExpectSuccessPushCheck()
+			},
+			verify: func(t *testing.T, h *receiverHarness) {
+				chid := channelID(h.id, h.peers)
+				otherChid := datatransfer.ChannelID{ID: h.id + 1, Initiator: h.peers[1], Responder: h.peers[0]}
+				byChannel := &eventCollector{}
+				defer h.dt.SubscribeToChannelEvents(chid, byChannel.subscriber)()
+				byCode := &eventCollector{}
+				defer h.dt.SubscribeToChannelEvents(chid, byCode.subscriber, datatransfer.FilterEventCodes(datatransfer.Accept, datatransfer.DataReceived))()
+				byOtherChannel := &eventCollector{}
+				defer h.dt.SubscribeToChannelEvents(otherChid, byOtherChannel.subscriber)()
+				byPeer := &eventCollector{}
+				defer h.dt.SubscribeToFilteredEvents(byPeer.subscriber, datatransfer.FilterPeer(h.peers[1]))()
+				byOtherPeer := &eventCollector{}
+				defer h.dt.SubscribeToFilteredEvents(byOtherPeer.subscriber, datatransfer.FilterPeer(testutil.GeneratePeers(1)[0]))()
+
+				h.network.Delegate.ReceiveRequest(h.ctx, h.peers[1], h.pushRequest)
+				err := h.transport.EventHandler.OnDataReceived(chid, cidlink.Link{Cid: testutil.GenerateCids(1)[0]}, 12345)
+				require.NoError(t, err)
+
+				all := []datatransfer.EventCode{datatransfer.Open, datatransfer.Accept, datatransfer.DataReceived}
+				require.Eventually(t, func() bool { return len(byChannel.received()) == 3 }, time.Second, 10*time.Millisecond)
+				require.Equal(t, all, byChannel.received())
+				require.Eventually(t, func() bool { return len(byPeer.received()) == 3 }, time.Second, 10*time.Millisecond)
+				require.Equal(t, all, byPeer.received())
+				require.Eventually(t, func() bool { return len(byCode.received()) == 2 }, time.Second, 10*time.Millisecond)
+				require.Equal(t, []datatransfer.EventCode{datatransfer.Accept, datatransfer.DataReceived}, byCode.received())
+				require.Empty(t, byOtherChannel.received())
+				require.Empty(t, byOtherPeer.received())
+			},
+		},
+		"filtered subscription does not block other subscribers by default": {
+			expectedEvents: []datatransfer.EventCode{
+				datatransfer.Open,
+				datatransfer.Accept,
+				datatransfer.DataReceived,
+				datatransfer.DataReceived,
+				datatransfer.DataReceived,
+			},
+			configureValidator: func(sv *testutil.StubbedValidator) {
+				sv.ExpectSuccessPush()
+			},
+			configureRevalidator: func(srv *testutil.StubbedRevalidator) {
+				srv.ExpectSuccessPushCheck()
+			},
+			verify: func(t *testing.T, h *receiverHarness) {
+				chid := channelID(h.id, h.peers)
+				slow := &eventCollector{release: make(chan struct{})}
+				defer close(slow.release)
+				defer h.dt.SubscribeToChannelEvents(chid, slow.subscriber, datatransfer.SubscriberQueueSize(1))()
+				other := &eventCollector{}
+				defer h.dt.SubscribeToChannelEvents(chid, other.subscriber)()
+
+				h.network.Delegate.ReceiveRequest(h.ctx, h.peers[1], h.pushRequest)
+				for _, c := range testutil.GenerateCids(3) {
+					err := h.transport.EventHandler.OnDataReceived(chid, cidlink.Link{Cid: c}, 12345)
+					require.NoError(t, err)
+				}
+				require.Eventually(t, func() bool { return len(other.received()) == 5 }, time.Second, 10*time.Millisecond)
+			},
+		},
+		"filtered subscription drops oldest events on overflow": {
+			expectedEvents: []datatransfer.EventCode{
+				datatransfer.Open,
+				datatransfer.Accept,
+				datatransfer.DataReceived,
+				datatransfer.DataReceived,
+				datatransfer.DataReceived,
+			},
+			configureValidator: func(sv *testutil.StubbedValidator) {
+				sv.ExpectSuccessPush()
 			},
 			configureRevalidator: func(srv *testutil.StubbedRevalidator) {
-				srv.ExpectPausePullCheck()
-				srv.StubRevalidationResult(testutil.NewFakeDTType())
-				srv.ExpectSuccessRevalidation()
-				srv.StubCheckResult(testutil.NewFakeDTType())
+				srv.ExpectSuccessPushCheck()
 			},
 			verify: func(t *testing.T, h *receiverHarness) {
-				_, err := h.transport.EventHandler.OnRequestReceived(channelID(h.id, h.peers), h.pullRequest)
-				require.NoError(t, err)
-				msg, err := h.transport.EventHandler.OnDataQueued(
-					channelID(h.id, h.peers),
-					cidlink.Link{Cid: testutil.GenerateCids(1)[0]},
-					12345)
-				require.EqualError(t, err, datatransfer.ErrPause.Error())
-				response, ok := msg.(datatransfer.Response)
-				require.True(t, ok)
-				require.True(t, response.Accepted())
-				require.Equal(t, response.TransferID(), h.id)
-				require.False(t, response.IsUpdate())
-				require.False(t, response.IsCancel())
-				require.True(t, response.IsPaused())
-				require.True(t, response.IsVoucherResult())
-				require.False(t, response.EmptyVoucherResult())
-				response, err = h.transport.EventHandler.OnRequestReceived(channelID(h.id, h.peers), h.voucherUpdate)
-				require.EqualError(t, err, datatransfer.ErrResume.Error())
-				require.True(t, response.Accepted())
-				require.Equal(t, response.TransferID(), h.id)
-				require.False(t, response.IsUpdate())
-				require.False(t, response.IsCancel())
-				require.False(t, response.IsPaused())
-				require.True(t, response.IsVoucherResult())
-				require.False(t, response.EmptyVoucherResult())
+				chid := channelID(h.id, h.peers)
+				slow := &eventCollector{release: make(chan struct{})}
+				defer h.dt.SubscribeToChannelEvents(chid, slow.subscriber,
+					datatransfer.SubscriberQueueSize(1),
+					datatransfer.SubscriberOverflowPolicy(datatransfer.OverflowDropOldest))()
+				published := &eventCollector{}
+				defer h.dt.SubscribeToEvents(published.subscriber)()
+
+				h.network.Delegate.ReceiveRequest(h.ctx, h.peers[1], h.pushRequest)
+				for _, c := range testutil.GenerateCids(3) {
+					err := h.transport.EventHandler.OnDataReceived(chid, cidlink.Link{Cid: c}, 12345)
+					require.NoError(t, err)
+				}
+				require.Eventually(t, func() bool { return len(published.received()) == 5 }, time.Second, 10*time.Millisecond)
+				close(slow.release)
+				require.Eventually(t, func() bool {
+					received := slow.received()
+					return len(received) > 0 && received[len(received)-1] == datatransfer.DataReceived
+				}, time.Second, 10*time.Millisecond)
+				require.LessOrEqual(t, len(slow.received()), 2)
 			},
 		},
-		"validated, finalize, and complete successfully": {
+		"filtered subscription disconnects on overflow": {
 			expectedEvents: []datatransfer.EventCode{
 				datatransfer.Open,
-				datatransfer.NewVoucherResult,
 				datatransfer.Accept,
-				datatransfer.NewVoucherResult,
-				datatransfer.BeginFinalizing,
-				datatransfer.NewVoucher,
-				datatransfer.ResumeResponder,
-				datatransfer.CleanupComplete,
+				datatransfer.DataReceived,
+				datatransfer.DataReceived,
+				datatransfer.DataReceived,
+				datatransfer.DataReceived,
 			},
 			configureValidator: func(sv *testutil.StubbedValidator) {
-				sv.ExpectSuccessPull()
-				sv.StubResult(testutil.NewFakeDTType())
+				sv.ExpectSuccessPush()
 			},
 			configureRevalidator: func(srv *testutil.StubbedRevalidator) {
-				srv.ExpectPauseComplete()
-				srv.StubRevalidationResult(testutil.NewFakeDTType())
-				srv.ExpectSuccessRevalidation()
+				srv.ExpectSuccessPushCheck()
 			},
 			verify: func(t *testing.T, h *receiverHarness) {
-				_, err := h.transport.EventHandler.OnRequestReceived(channelID(h.id, h.peers), h.pullRequest)
-				require.NoError(t, err)
-				err = h.transport.EventHandler.OnChannelCompleted(channelID(h.id, h.peers), true)
+				chid := channelID(h.id, h.peers)
+				slow := &eventCollector{release: make(chan struct{})}
+				defer h.dt.SubscribeToChannelEvents(chid, slow.subscriber,
+					datatransfer.SubscriberQueueSize(1),
+					datatransfer.SubscriberOverflowPolicy(datatransfer.OverflowDisconnect))()
+				published := &eventCollector{}
+				defer h.dt.SubscribeToEvents(published.subscriber)()
+
+				h.network.Delegate.ReceiveRequest(h.ctx, h.peers[1], h.pushRequest)
+				for _, c := range testutil.GenerateCids(3) {
+					err := h.transport.EventHandler.OnDataReceived(chid, cidlink.Link{Cid: c}, 12345)
+					require.NoError(t, err)
+				}
+				require.Eventually(t, func() bool { return len(published.received()) == 5 }, time.Second, 10*time.Millisecond)
+				close(slow.release)
+				require.Eventually(t, func() bool { return len(slow.received()) > 0 }, time.Second, 10*time.Millisecond)
+				delivered := len(slow.received())
+				require.LessOrEqual(t, delivered, 2)
+
+				err := h.transport.EventHandler.OnDataReceived(chid, cidlink.Link{Cid: testutil.GenerateCids(1)[0]}, 12345)
 				require.NoError(t, err)
-				require.Len(t, h.network.SentMessages, 1)
-				response, ok := h.network.SentMessages[0].Message.(datatransfer.Response)
-				require.True(t, ok)
-				require.True(t, response.Accepted())
-				require.Equal(t, response.TransferID(), h.id)
-				require.False(t, response.IsUpdate())
-				require.False(t, response.IsCancel())
-				require.True(t, response.IsPaused())
-				require.True(t, response.IsVoucherResult())
-				require.False(t, response.EmptyVoucherResult())
-				response, err = h.transport.EventHandler.OnRequestReceived(channelID(h.id, h.peers), h.voucherUpdate)
-				require.EqualError(t, err, datatransfer.ErrResume.Error())
-				require.Equal(t, response.TransferID(), h.id)
-				require.True(t, response.IsVoucherResult())
-				require.False(t, response.IsPaused())
+				require.Eventually(t, func() bool { return len(published.received()) == 6 }, time.Second, 10*time.Millisecond)
+				time.Sleep(20 * time.Millisecond)
+				require.Len(t, slow.received(), delivered)
 			},
 		},
-		"validated, incomplete response": {
+		"filtered subscription blocks on overflow": {
 			expectedEvents: []datatransfer.EventCode{
 				datatransfer.Open,
-				datatransfer.NewVoucherResult,
 				datatransfer.Accept,
-				datatransfer.Error,
-				datatransfer.CleanupComplete,
+				datatransfer.DataReceived,
+				datatransfer.DataReceived,
+				datatransfer.DataReceived,
 			},
 			configureValidator: func(sv *testutil.StubbedValidator) {
-				sv.ExpectSuccessPull()
-				sv.StubResult(testutil.NewFakeDTType())
+				sv.ExpectSuccessPush()
 			},
 			configureRevalidator: func(srv *testutil.StubbedRevalidator) {
+				srv.ExpectSuccessPushCheck()
 			},
 			verify: func(t *testing.T, h *receiverHarness) {
-				_, err := h.transport.EventHandler.OnRequestReceived(channelID(h.id, h.peers), h.pullRequest)
-				require.NoError(t, err)
-				err = h.transport.EventHandler.OnChannelCompleted(channelID(h.id, h.peers), false)
-				require.NoError(t, err)
+				chid := channelID(h.id, h.peers)
+				slow := &eventCollector{delay: 5 * time.Millisecond}
+				defer h.dt.SubscribeToChannelEvents(chid, slow.subscriber,
+					datatransfer.SubscriberQueueSize(1),
+					datatransfer.SubscriberOverflowPolicy(datatransfer.OverflowBlock))()
+
+				h.network.Delegate.ReceiveRequest(h.ctx, h.peers[1], h.pushRequest)
+				for _, c := range testutil.GenerateCids(3) {
+					err := h.transport.EventHandler.OnDataReceived(chid, cidlink.Link{Cid: c}, 12345)
+					require.NoError(t, err)
+				}
+				require.Eventually(t, func() bool { return len(slow.received()) == 5 }, time.Second, 10*time.Millisecond)
+				require.Equal(t, []datatransfer.EventCode{
+					datatransfer.Open,
+					datatransfer.Accept,
+					datatransfer.DataReceived,
+					datatransfer.DataReceived,
+					datatransfer.DataReceived,
+				}, slow.received())
 			},
 		},
 		"new push request, customized transport": {
@@ -607,6 +1231,73 @@ func TestDataTransferResponding(t *testing.T) {
 	}
 }
 
+func TestUnregisterVoucherTypeDuringRequests(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	peers := testutil.GeneratePeers(2)
+	transport := testutil.NewFakeTransport()
+	ds := dss.MutexWrap(datastore.NewMapDatastore())
+	dt, err := NewDataTransfer(ds, os.TempDir(), testutil.NewFakeNetwork(peers[0]), transport, storedcounter.New(ds, datastore.NewKey("counter")))
+	require.NoError(t, err)
+	testutil.StartAndWaitForReady(ctx, t, dt)
+	voucher := testutil.NewFakeDTType()
+	require.NoError(t, dt.RegisterVoucherType(voucher, acceptingValidator{}))
+
+	requestsDone := make(chan struct{})
+	unregisterDone := make(chan struct{})
+	go func() {
+		defer close(unregisterDone)
+		for {
+			select {
+			case <-requestsDone:
+				return
+			default:
+			}
+			if err := dt.UnregisterVoucherType(voucher.Type()); err != nil {
+				t.Error(err)
+				return
+			}
+			if err := dt.RegisterVoucherType(voucher, acceptingValidator{}); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	// requests either validate or are rejected for an unknown voucher type,
+	// but never find a decoder without a validator
+	baseCid := testutil.GenerateCids(1)[0]
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < 250; i++ {
+				id := datatransfer.TransferID(worker*250 + i)
+				request, err := message.NewRequest(id, false, true, voucher.Type(), voucher, baseCid, testutil.AllSelector())
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				_, _ = transport.EventHandler.OnRequestReceived(channelID(id, peers), request)
+			}
+		}(worker)
+	}
+	wg.Wait()
+	close(requestsDone)
+	<-unregisterDone
+}
+
+type acceptingValidator struct{}
+
+func (acceptingValidator) ValidatePush(peer.ID, datatransfer.Voucher, cid.Cid, ipld.Node) (datatransfer.VoucherResult, error) {
+	return nil, nil
+}
+
+func (acceptingValidator) ValidatePull(peer.ID, datatransfer.Voucher, cid.Cid, ipld.Node) (datatransfer.VoucherResult, error) {
+	return nil, nil
+}
+
 func TestDataTransferRestartResponding(t *testing.T) {
 	// create network
 	ctx := context.Background()
@@ -732,6 +1423,44 @@ func TestDataTransferRestartResponding(t *testing.T) {
 				require.Equal(t, h.peers[1], vmsg.Other)
 			},
 		},
+		"restart request fails while draining": {
+			expectedEvents: []datatransfer.EventCode{datatransfer.Open, datatransfer.NewVoucherResult, datatransfer.Accept, datatransfer.PauseResponder},
+			configureValidator: func(sv *testutil.StubbedValidator) {
+				sv.ExpectSuccessPull()
+				sv.StubResult(testutil.NewFakeDTType())
+			},
+			verify: func(t *testing.T, h *receiverHarness) {
+				_, err := h.transport.EventHandler.OnRequestReceived(channelID(h.id, h.peers), h.pullRequest)
+				require.NoError(t, err)
+
+				ctx, cancel := context.WithCancel(h.ctx)
+				cancel()
+				require.NoError(t, h.dt.Drain(ctx))
+				restartReq, err := message.NewRequest(h.id, true, true, h.voucher.Type(), h.voucher, h.baseCid, h.stor)
+				require.NoError(t, err)
+				response, err := h.transport.EventHandler.OnRequestReceived(channelID(h.id, h.peers), restartReq)
+				require.True(t, xerrors.Is(err, datatransfer.ErrDraining))
+				require.True(t, response.IsRestart())
+				require.False(t, response.Accepted())
+				require.Len(t, h.sv.ValidationsReceived, 1)
+			},
+		},
+		"restart existing channel request is dropped while draining": {
+			expectedEvents: []datatransfer.EventCode{datatransfer.Open, datatransfer.PauseInitiator},
+			verify: func(t *testing.T, h *receiverHarness) {
+				channelID, err := h.dt.OpenPushDataChannel(h.ctx, h.peers[1], h.voucher, h.baseCid, h.stor)
+				require.NoError(t, err)
+				ctx, cancel := context.WithCancel(h.ctx)
+				cancel()
+				require.NoError(t, h.dt.Drain(ctx))
+				require.Len(t, h.network.SentMessages, 2)
+
+				restartReq := message.RestartExistingChannelRequest(channelID)
+				h.network.Delegate.ReceiveRestartExistingChannelRequest(h.ctx, h.peers[1], restartReq)
+				require.Len(t, h.network.SentMessages, 2)
+				require.Empty(t, h.transport.OpenedChannels)
+			},
+		},
 		"restart request fails if channel does not exist": {
 			expectedEvents: []datatransfer.EventCode{datatransfer.Open, datatransfer.NewVoucherResult, datatransfer.Accept},
 			configureValidator: func(sv *testutil.StubbedValidator) {
@@ -1016,6 +1745,180 @@ func TestDataTransferRestartResponding(t *testing.T) {
 	}
 }
 
+func TestReadEvents(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	peers := testutil.GeneratePeers(2)
+	ds := dss.MutexWrap(datastore.NewMapDatastore())
+	storedCounter := storedcounter.New(ds, datastore.NewKey("counter"))
+	voucher := testutil.NewFakeDTType()
+	id := datatransfer.TransferID(rand.Int31())
+	chid := channelID(id, peers)
+	pushRequest, err := message.NewRequest(id, false, false, voucher.Type(), voucher, testutil.GenerateCids(1)[0], testutil.AllSelector())
+	require.NoError(t, err)
+
+	t.Run("errors when the journal is disabled", func(t *testing.T) {
+		dt, err := NewDataTransfer(ds, os.TempDir(), testutil.NewFakeNetwork(peers[0]), testutil.NewFakeTransport(), storedCounter)
+		require.NoError(t, err)
+		_, _, err = dt.ReadEvents(ctx, 0, 10)
+		require.EqualError(t, err, datatransfer.ErrJournalDisabled.Error())
+	})
+
+	t.Run("reads journaled events", func(t *testing.T) {
+		network := testutil.NewFakeNetwork(peers[0])
+		dt, err := NewDataTransfer(ds, os.TempDir(), network, testutil.NewFakeTransport(), storedCounter, EventJournal(journal.Retention{}))
+		require.NoError(t, err)
+		testutil.StartAndWaitForReady(ctx, t, dt)
+		sv := testutil.NewStubbedValidator()
+		sv.ExpectSuccessPush()
+		require.NoError(t, dt.RegisterVoucherType(voucher, sv))
+
+		network.Delegate.ReceiveRequest(ctx, peers[1], pushRequest)
+		var entries []datatransfer.JournalEntry
+		require.Eventually(t, func() bool {
+			entries, _, err = dt.ReadEvents(ctx, 0, 10)
+			require.NoError(t, err)
+			return len(entries) == 2
+		}, time.Second, 10*time.Millisecond)
+		require.Equal(t, datatransfer.Open, entries[0].Event.Code)
+		require.Equal(t, datatransfer.Accept, entries[1].Event.Code)
+		require.Equal(t, datatransfer.Ongoing, entries[1].Status)
+		for _, entry := range entries {
+			require.Equal(t, chid, entry.ChannelID)
+		}
+
+		entries, cursor, err := dt.ReadEvents(ctx, 0, 1)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		entries, _, err = dt.ReadEvents(ctx, cursor, 10)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		require.Equal(t, datatransfer.Accept, entries[0].Event.Code)
+	})
+}
+
+func TestTracing(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	peers := testutil.GeneratePeers(2)
+	voucher := testutil.NewFakeDTType()
+	baseCid := testutil.GenerateCids(1)[0]
+	id := datatransfer.TransferID(rand.Int31())
+	chid := channelID(id, peers)
+	pushRequest, err := message.NewRequest(id, false, false, voucher.Type(), voucher, baseCid, testutil.AllSelector())
+	require.NoError(t, err)
+
+	newManager := func(t *testing.T, network *testutil.FakeNetwork, options ...DataTransferOption) datatransfer.Manager {
+		ds := dss.MutexWrap(datastore.NewMapDatastore())
+		storedCounter := storedcounter.New(ds, datastore.NewKey("counter"))
+		dt, err := NewDataTransfer(ds, os.TempDir(), network, testutil.NewFakeTransport(), storedCounter, options...)
+		require.NoError(t, err)
+		testutil.StartAndWaitForReady(ctx, t, dt)
+		return dt
+	}
+
+	t.Run("continues the initiator's trace", func(t *testing.T) {
+		recorder := tracing.NewRecorder()
+		network := testutil.NewFakeNetwork(peers[0])
+		dt := newManager(t, network, Tracer(recorder))
+		sv := testutil.NewStubbedValidator()
+		sv.ExpectSuccessPush()
+		require.NoError(t, dt.RegisterVoucherType(voucher, sv))
+
+		remote := tracing.NewRecorder()
+		_, remoteSpan := remote.Start(ctx, "open")
+		remoteRecord := remote.Named("open")[0]
+		traced := pushRequest.(datatransfer.TraceCarrier).WithTraceContext(remoteSpan.TraceContext())
+		network.Delegate.ReceiveRequest(ctx, peers[1], traced.(datatransfer.Request))
+		sv.VerifyExpectations(t)
+		require.NoError(t, dt.PauseDataTransferChannel(ctx, chid))
+
+		channelSpans := recorder.Named("channel")
+		require.Len(t, channelSpans, 1)
+		channelSpan := channelSpans[0]
+		require.Equal(t, remoteRecord.TraceID, channelSpan.TraceID)
+		require.Equal(t, remoteRecord.SpanID, channelSpan.ParentID)
+		require.Equal(t, chid.String(), channelSpan.Attributes["channel"])
+		require.False(t, channelSpan.Ended)
+		for _, name := range []string{"validate", "pause"} {
+			spans := recorder.Named(name)
+			require.Len(t, spans, 1)
+			require.Equal(t, channelSpan.SpanID, spans[0].ParentID)
+			require.Empty(t, spans[0].Errors)
+			require.True(t, spans[0].Ended)
+		}
+	})
+
+	t.Run("ends the channel span when validation fails", func(t *testing.T) {
+		recorder := tracing.NewRecorder()
+		network := testutil.NewFakeNetwork(peers[0])
+		dt := newManager(t, network, Tracer(recorder))
+		sv := testutil.NewStubbedValidator()
+		sv.ExpectErrorPush()
+		require.NoError(t, dt.RegisterVoucherType(voucher, sv))
+
+		network.Delegate.ReceiveRequest(ctx, peers[1], pushRequest)
+		sv.VerifyExpectations(t)
+
+		channelSpans := recorder.Named("channel")
+		require.Len(t, channelSpans, 1)
+		require.True(t, channelSpans[0].Ended)
+		require.Len(t, channelSpans[0].Errors, 1)
+		validateSpans := recorder.Named("validate")
+		require.Len(t, validateSpans, 1)
+		require.Len(t, validateSpans[0].Errors, 1)
+	})
+
+	t.Run("ends the channel span when a restart request fails validation", func(t *testing.T) {
+		recorder := tracing.NewRecorder()
+		network := testutil.NewFakeNetwork(peers[0])
+		dt := newManager(t, network, Tracer(recorder))
+		require.NoError(t, dt.RegisterVoucherType(voucher, testutil.NewStubbedValidator()))
+
+		restartRequest, err := message.NewRequest(chid.ID, true, false, voucher.Type(), voucher, baseCid, testutil.AllSelector())
+		require.NoError(t, err)
+		network.Delegate.ReceiveRequest(ctx, peers[1], restartRequest)
+
+		channelSpans := recorder.Named("channel")
+		require.Len(t, channelSpans, 1)
+		require.True(t, channelSpans[0].Ended)
+		require.Len(t, channelSpans[0].Errors, 1)
+		restartSpans := recorder.Named("restart")
+		require.Len(t, restartSpans, 1)
+		require.True(t, restartSpans[0].Ended)
+		require.Len(t, restartSpans[0].Errors, 1)
+	})
+
+	t.Run("propagates the trace context when enabled", func(t *testing.T) {
+		recorder := tracing.NewRecorder()
+		network := testutil.NewFakeNetwork(peers[1])
+		dt := newManager(t, network, Tracer(recorder), PropagateTraceContext())
+
+		_, err := dt.OpenPushDataChannel(ctx, peers[0], voucher, baseCid, testutil.AllSelector())
+		require.NoError(t, err)
+		require.Len(t, network.SentMessages, 1)
+		traceContext := network.SentMessages[0].Message.(datatransfer.TraceCarrier).TraceContext()
+		require.NotNil(t, traceContext)
+
+		remote := tracing.NewRecorder()
+		_, continued := remote.Start(remote.Extract(ctx, traceContext), "continued")
+		continued.End()
+		channelSpan := recorder.Named("channel")[0]
+		require.Equal(t, channelSpan.TraceID, remote.Named("continued")[0].TraceID)
+		require.Equal(t, channelSpan.SpanID, remote.Named("continued")[0].ParentID)
+	})
+
+	t.Run("does not propagate the trace context by default", func(t *testing.T) {
+		network := testutil.NewFakeNetwork(peers[1])
+		dt := newManager(t, network, Tracer(tracing.NewRecorder()))
+
+		_, err := dt.OpenPushDataChannel(ctx, peers[0], voucher, baseCid, testutil.AllSelector())
+		require.NoError(t, err)
+		require.Len(t, network.SentMessages, 1)
+		require.Nil(t, network.SentMessages[0].Message.(datatransfer.TraceCarrier).TraceContext())
+	})
+}
+
 type receiverHarness struct {
 	id            datatransfer.TransferID
 	pushRequest   datatransfer.Request
@@ -1041,3 +1944,477 @@ type receiverHarness struct {
 func channelID(id datatransfer.TransferID, peers []peer.ID) datatransfer.ChannelID {
 	return datatransfer.ChannelID{ID: id, Initiator: peers[1], Responder: peers[0]}
 }
+
+// recordingMiddleware returns middleware that records when it runs, and either
+// passes through to the next validator or short-circuits with the given error
+func recordingMiddleware(name string, calls *[]string, err error) datatransfer.ValidatorMiddleware {
+	return datatransfer.ValidatorMiddleware{
+		WrapValidator: func(_ datatransfer.TypeIdentifier, next datatransfer.RequestValidator) datatransfer.RequestValidator {
+			return &recordingValidator{name, calls, err, next}
+		},
+		WrapRevalidator: func(_ datatransfer.TypeIdentifier, next datatransfer.Revalidator) datatransfer.Revalidator {
+			return &recordingRevalidator{next, name, calls}
+		},
+	}
+}
+
+type recordingValidator struct {
+	name  string
+	calls *[]string
+	err   error
+	next  datatransfer.RequestValidator
+}
+
+func (rv *recordingValidator) ValidatePush(sender peer.ID, voucher datatransfer.Voucher, baseCid cid.Cid, selector ipld.Node) (datatransfer.VoucherResult, error) {
+	*rv.calls = append(*rv.calls, rv.name)
+	if rv.err != nil {
+		return nil, rv.err
+	}
+	return rv.next.ValidatePush(sender, voucher, baseCid, selector)
+}
+
+func (rv *recordingValidator) ValidatePull(receiver peer.ID, voucher datatransfer.Voucher, baseCid cid.Cid, selector ipld.Node) (datatransfer.VoucherResult, error) {
+	*rv.calls = append(*rv.calls, rv.name)
+	if rv.err != nil {
+		return nil, rv.err
+	}
+	return rv.next.ValidatePull(receiver, voucher, baseCid, selector)
+}
+
+type recordingRevalidator struct {
+	datatransfer.Revalidator
+	name  string
+	calls *[]string
+}
+
+func (rr *recordingRevalidator) Revalidate(chid datatransfer.ChannelID, voucher datatransfer.Voucher) (datatransfer.VoucherResult, error) {
+	*rr.calls = append(*rr.calls, rr.name+":revalidate")
+	return rr.Revalidator.Revalidate(chid, voucher)
+}
+
+// otherVoucher is a voucher type distinct from testutil.FakeDTType
+type otherVoucher struct {
+	testutil.FakeDTType
+}
+
+func (otherVoucher) Type() datatransfer.TypeIdentifier {
+	return "OtherVoucher"
+}
+
+// claimingRevalidator handles every push data check and counts them
+type claimingRevalidator struct {
+	datatransfer.Revalidator
+	pushChecks int
+}
+
+func (cr *claimingRevalidator) OnPushDataReceived(chid datatransfer.ChannelID, additionalBytesReceived uint64) (bool, datatransfer.VoucherResult, error) {
+	cr.pushChecks++
+	return true, nil, nil
+}
+
+// eventCollector records the codes of the events delivered to its subscriber
+type eventCollector struct {
+	// release, if set, holds up delivery of the first event until it is closed
+	release chan struct{}
+	// delay, if set, is how long each delivery takes
+	delay time.Duration
+
+	lk    sync.Mutex
+	codes []datatransfer.EventCode
+}
+
+func (ec *eventCollector) subscriber(event datatransfer.Event, channelState datatransfer.ChannelState) {
+	if ec.release != nil {
+		<-ec.release
+	}
+	time.Sleep(ec.delay)
+	ec.lk.Lock()
+	defer ec.lk.Unlock()
+	ec.codes = append(ec.codes, event.Code)
+}
+
+func (ec *eventCollector) received() []datatransfer.EventCode {
+	ec.lk.Lock()
+	defer ec.lk.Unlock()
+	return append([]datatransfer.EventCode(nil), ec.codes...)
+}
+
+func TestRequestLimits(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	peers := testutil.GeneratePeers(2)
+	voucher := &testutil.FakeDTType{Data: "voucher"}
+	baseCid := testutil.GenerateCids(1)[0]
+	ssb := builder.NewSelectorSpecBuilder(basicnode.Prototype.Any)
+	shallowRecursion := ssb.ExploreRecursive(selector.RecursionLimitDepth(2), ssb.ExploreAll(ssb.ExploreRecursiveEdge())).Node()
+	deepFields := ssb.ExploreFields(func(efsb builder.ExploreFieldsSpecBuilder) {
+		efsb.Insert("a", ssb.ExploreFields(func(efsb builder.ExploreFieldsSpecBuilder) {
+			efsb.Insert("b", ssb.ExploreFields(func(efsb builder.ExploreFieldsSpecBuilder) {
+				efsb.Insert("c", ssb.Matcher())
+			}))
+		}))
+	}).Node()
+
+	testCases := map[string]struct {
+		options     []DataTransferOption
+		voucher     datatransfer.Voucher
+		selector    ipld.Node
+		expectedErr error
+	}{
+		"accepts requests within the limits": {
+			options:  []DataTransferOption{MaxVoucherSize(64), SelectorLimits(16, 32), MaxRecursionDepth(2)},
+			voucher:  voucher,
+			selector: shallowRecursion,
+		},
+		"rejects a voucher over the maximum size": {
+			options:     []DataTransferOption{MaxVoucherSize(64)},
+			voucher:     &testutil.FakeDTType{Data: string(testutil.RandomBytes(100))},
+			selector:    shallowRecursion,
+			expectedErr: datatransfer.ErrVoucherTooLarge,
+		},
+		"rejects a selector nested too deep": {
+			options:     []DataTransferOption{SelectorLimits(4, 0)},
+			voucher:     voucher,
+			selector:    deepFields,
+			expectedErr: datatransfer.ErrSelectorTooDeep,
+		},
+		"rejects a selector with too many nodes": {
+			options:     []DataTransferOption{SelectorLimits(0, 5)},
+			voucher:     voucher,
+			selector:    deepFields,
+			expectedErr: datatransfer.ErrSelectorTooLarge,
+		},
+		"rejects a selector that recurses too deep": {
+			options:     []DataTransferOption{MaxRecursionDepth(1)},
+			voucher:     voucher,
+			selector:    shallowRecursion,
+			expectedErr: datatransfer.ErrRecursionLimitExceeded,
+		},
+		"rejects a selector that recurses without a limit": {
+			options:     []DataTransferOption{MaxRecursionDepth(100)},
+			voucher:     voucher,
+			selector:    testutil.AllSelector(),
+			expectedErr: datatransfer.ErrRecursionLimitExceeded,
+		},
+	}
+	for testCase, data := range testCases {
+		t.Run(testCase, func(t *testing.T) {
+			id := datatransfer.TransferID(rand.Int31())
+			chid := channelID(id, peers)
+			ds := dss.MutexWrap(datastore.NewMapDatastore())
+			storedCounter := storedcounter.New(ds, datastore.NewKey("counter"))
+			network := testutil.NewFakeNetwork(peers[0])
+			recorder := tracing.NewRecorder()
+			options := append([]DataTransferOption{Tracer(recorder)}, data.options...)
+			dt, err := NewDataTransfer(ds, os.TempDir(), network, testutil.NewFakeTransport(), storedCounter, options...)
+			require.NoError(t, err)
+			testutil.StartAndWaitForReady(ctx, t, dt)
+			sv := testutil.NewStubbedValidator()
+			sv.StubSuccessPush()
+			require.NoError(t, dt.RegisterVoucherType(voucher, sv))
+
+			request, err := message.NewRequest(id, false, false, data.voucher.Type(), data.voucher, baseCid, data.selector)
+			require.NoError(t, err)
+			network.Delegate.ReceiveRequest(ctx, peers[1], request)
+			validateSpans := recorder.Named("validate")
+			require.Len(t, validateSpans, 1)
+			if data.expectedErr == nil {
+				require.Empty(t, validateSpans[0].Errors)
+				require.Len(t, sv.ValidationsReceived, 1)
+				return
+			}
+			require.Len(t, validateSpans[0].Errors, 1)
+			err = validateSpans[0].Errors[0]
+			require.True(t, xerrors.Is(err, data.expectedErr), "expected %s, got %s", data.expectedErr, err)
+			require.Empty(t, sv.ValidationsReceived)
+			require.Len(t, network.SentMessages, 1)
+			response, ok := network.SentMessages[0].Message.(datatransfer.Response)
+			require.True(t, ok)
+			require.False(t, response.Accepted())
+			_, err = dt.ChannelState(ctx, chid)
+			require.Error(t, err)
+		})
+	}
+
+	// a voucher that the registered voucher type cannot decode, so the limits
+	// must reject it before anything tries to
+	undecodable := basicnode.NewString(string(testutil.RandomBytes(100)))
+
+	t.Run("checks the voucher size of a signed request before decoding it", func(t *testing.T) {
+		id := datatransfer.TransferID(rand.Int31())
+		ds := dss.MutexWrap(datastore.NewMapDatastore())
+		storedCounter := storedcounter.New(ds, datastore.NewKey("counter"))
+		network := testutil.NewFakeNetwork(peers[0])
+		recorder := tracing.NewRecorder()
+		dt, err := NewDataTransfer(ds, os.TempDir(), network, testutil.NewFakeTransport(), storedCounter, Tracer(recorder), MaxVoucherSize(64))
+		require.NoError(t, err)
+		testutil.StartAndWaitForReady(ctx, t, dt)
+		sv := testutil.NewStubbedValidator()
+		require.NoError(t, dt.RegisterVoucherType(voucher, sv))
+
+		request, err := message.NewRequest(id, false, false, voucher.Type(), undecodable, baseCid, testutil.AllSelector())
+		require.NoError(t, err)
+		signed := request.(datatransfer.SignatureCarrier).WithRequestSignature(&datatransfer.RequestSignature{
+			PublicKey: testutil.RandomBytes(32),
+			Signature: testutil.RandomBytes(64),
+		})
+		network.Delegate.ReceiveRequest(ctx, peers[1], signed.(datatransfer.Request))
+		channelSpans := recorder.Named("channel")
+		require.Len(t, channelSpans, 1)
+		require.Len(t, channelSpans[0].Errors, 1)
+		require.True(t, xerrors.Is(channelSpans[0].Errors[0], datatransfer.ErrVoucherTooLarge))
+		require.Empty(t, sv.ValidationsReceived)
+	})
+
+	t.Run("checks the voucher size of a restart request before decoding it", func(t *testing.T) {
+		id := datatransfer.TransferID(rand.Int31())
+		ds := dss.MutexWrap(datastore.NewMapDatastore())
+		storedCounter := storedcounter.New(ds, datastore.NewKey("counter"))
+		network := testutil.NewFakeNetwork(peers[0])
+		recorder := tracing.NewRecorder()
+		dt, err := NewDataTransfer(ds, os.TempDir(), network, testutil.NewFakeTransport(), storedCounter, Tracer(recorder), MaxVoucherSize(64))
+		require.NoError(t, err)
+		testutil.StartAndWaitForReady(ctx, t, dt)
+		sv := testutil.NewStubbedValidator()
+		sv.StubSuccessPush()
+		require.NoError(t, dt.RegisterVoucherType(voucher, sv))
+
+		request, err := message.NewRequest(id, false, false, voucher.Type(), voucher, baseCid, testutil.AllSelector())
+		require.NoError(t, err)
+		network.Delegate.ReceiveRequest(ctx, peers[1], request)
+		require.Len(t, sv.ValidationsReceived, 1)
+
+		restart, err := message.NewRequest(id, true, false, voucher.Type(), undecodable, baseCid, testutil.AllSelector())
+		require.NoError(t, err)
+		network.Delegate.ReceiveRequest(ctx, peers[1], restart)
+		restartSpans := recorder.Named("restart")
+		require.Len(t, restartSpans, 1)
+		require.Len(t, restartSpans[0].Errors, 1)
+		require.True(t, xerrors.Is(restartSpans[0].Errors[0], datatransfer.ErrVoucherTooLarge))
+		require.Len(t, sv.ValidationsReceived, 1)
+	})
+
+	t.Run("rejects a voucher update over the maximum size", func(t *testing.T) {
+		id := datatransfer.TransferID(rand.Int31())
+		chid := channelID(id, peers)
+		ds := dss.MutexWrap(datastore.NewMapDatastore())
+		storedCounter := storedcounter.New(ds, datastore.NewKey("counter"))
+		network := testutil.NewFakeNetwork(peers[0])
+		recorder := tracing.NewRecorder()
+		dt, err := NewDataTransfer(ds, os.TempDir(), network, testutil.NewFakeTransport(), storedCounter, Tracer(recorder), MaxVoucherSize(64))
+		require.NoError(t, err)
+		testutil.StartAndWaitForReady(ctx, t, dt)
+		sv := testutil.NewStubbedValidator()
+		sv.StubSuccessPush()
+		require.NoError(t, dt.RegisterVoucherType(voucher, sv))
+		require.NoError(t, dt.RegisterRevalidator(voucher, testutil.NewStubbedRevalidator()))
+
+		request, err := message.NewRequest(id, false, false, voucher.Type(), voucher, baseCid, testutil.AllSelector())
+		require.NoError(t, err)
+		network.Delegate.ReceiveRequest(ctx, peers[1], request)
+		require.Len(t, sv.ValidationsReceived, 1)
+
+		large := &testutil.FakeDTType{Data: string(testutil.RandomBytes(100))}
+		update, err := message.VoucherRequest(id, large.Type(), large)
+		require.NoError(t, err)
+		network.Delegate.ReceiveRequest(ctx, peers[1], update)
+		revalidateSpans := recorder.Named("revalidate")
+		require.Len(t, revalidateSpans, 1)
+		require.Len(t, revalidateSpans[0].Errors, 1)
+		require.True(t, xerrors.Is(revalidateSpans[0].Errors[0], datatransfer.ErrVoucherTooLarge))
+		require.Len(t, network.SentMessages, 1)
+		response, ok := network.SentMessages[0].Message.(datatransfer.Response)
+		require.True(t, ok)
+		require.False(t, response.Accepted())
+		chst, err := dt.ChannelState(ctx, chid)
+		require.NoError(t, err)
+		require.Len(t, chst.Vouchers(), 1)
+	})
+}
+
+func TestPeerBansUnsupported(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	peers := testutil.GeneratePeers(2)
+	ds := dss.MutexWrap(datastore.NewMapDatastore())
+	storedCounter := storedcounter.New(ds, datastore.NewKey("counter"))
+	dt, err := NewDataTransfer(ds, os.TempDir(), testutil.NewFakeNetwork(peers[0]), testutil.NewFakeTransport(), storedCounter)
+	require.NoError(t, err)
+	testutil.StartAndWaitForReady(ctx, t, dt)
+
+	_, err = dt.PeerBans(ctx)
+	require.Equal(t, datatransfer.ErrUnsupported, err)
+	require.Equal(t, datatransfer.ErrUnsupported, dt.ClearPeerBan(ctx, peers[1]))
+}
+
+// bannerNetwork is a fake network that rate limits and strikes peers
+type bannerNetwork struct {
+	*testutil.FakeNetwork
+	allow      bool
+	strikes    []peer.ID
+	rejections []peer.ID
+}
+
+func (bn *bannerNetwork) PeerBans() []datatransfer.PeerBan { return nil }
+func (bn *bannerNetwork) ClearPeerBan(p peer.ID)           {}
+func (bn *bannerNetwork) AllowRequest(p peer.ID) bool      { return bn.allow }
+func (bn *bannerNetwork) AddStrike(p peer.ID)              { bn.strikes = append(bn.strikes, p) }
+func (bn *bannerNetwork) AddRejection(p peer.ID)           { bn.rejections = append(bn.rejections, p) }
+
+func TestPeerStrikes(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	peers := testutil.GeneratePeers(2)
+	voucher := testutil.NewFakeDTType()
+	baseCid := testutil.GenerateCids(1)[0]
+
+	newManager := func(t *testing.T, options ...DataTransferOption) (datatransfer.Manager, *bannerNetwork, *testutil.FakeTransport, *testutil.StubbedValidator) {
+		ds := dss.MutexWrap(datastore.NewMapDatastore())
+		storedCounter := storedcounter.New(ds, datastore.NewKey("counter"))
+		network := &bannerNetwork{FakeNetwork: testutil.NewFakeNetwork(peers[0]), allow: true}
+		transport := testutil.NewFakeTransport()
+		dt, err := NewDataTransfer(ds, os.TempDir(), network, transport, storedCounter, options...)
+		require.NoError(t, err)
+		testutil.StartAndWaitForReady(ctx, t, dt)
+		sv := testutil.NewStubbedValidator()
+		require.NoError(t, dt.RegisterVoucherType(voucher, sv))
+		return dt, network, transport, sv
+	}
+
+	t.Run("rejected requests count towards a strike", func(t *testing.T) {
+		_, network, _, sv := newManager(t)
+		sv.StubErrorPush()
+		request, err := message.NewRequest(datatransfer.TransferID(rand.Int31()), false, false, voucher.Type(), voucher, baseCid, testutil.AllSelector())
+		require.NoError(t, err)
+		network.Delegate.ReceiveRequest(ctx, peers[1], request)
+		require.Len(t, network.SentMessages, 1)
+		require.False(t, network.SentMessages[0].Message.(datatransfer.Response).Accepted())
+		require.Empty(t, network.strikes)
+		require.Equal(t, []peer.ID{peers[1]}, network.rejections)
+	})
+
+	t.Run("requests over the request limits are strikes", func(t *testing.T) {
+		_, network, _, sv := newManager(t, MaxVoucherSize(1), SelectorLimits(1, 0))
+		sv.StubSuccessPush()
+		request, err := message.NewRequest(datatransfer.TransferID(rand.Int31()), false, false, voucher.Type(), voucher, baseCid, testutil.AllSelector())
+		require.NoError(t, err)
+		network.Delegate.ReceiveRequest(ctx, peers[1], request)
+		require.Empty(t, sv.ValidationsReceived)
+		require.Equal(t, []peer.ID{peers[1]}, network.strikes)
+		require.Empty(t, network.rejections)
+	})
+
+	t.Run("accepted and deferred requests are not rejections", func(t *testing.T) {
+		_, network, transport, sv := newManager(t)
+		sv.ExpectSuccessPush()
+		sv.ExpectDeferPull()
+		push, err := message.NewRequest(datatransfer.TransferID(rand.Int31()), false, false, voucher.Type(), voucher, baseCid, testutil.AllSelector())
+		require.NoError(t, err)
+		network.Delegate.ReceiveRequest(ctx, peers[1], push)
+		id := datatransfer.TransferID(rand.Int31())
+		pull, err := message.NewRequest(id, false, true, voucher.Type(), voucher, baseCid, testutil.AllSelector())
+		require.NoError(t, err)
+		_, err = transport.EventHandler.OnRequestReceived(channelID(id, peers), pull)
+		require.Equal(t, datatransfer.ErrPause, err)
+		require.Empty(t, network.strikes)
+		require.Empty(t, network.rejections)
+	})
+
+	t.Run("restarting a channel that does not match is a strike", func(t *testing.T) {
+		_, network, _, sv := newManager(t)
+		sv.StubSuccessPush()
+		id := datatransfer.TransferID(rand.Int31())
+		request, err := message.NewRequest(id, false, false, voucher.Type(), voucher, baseCid, testutil.AllSelector())
+		require.NoError(t, err)
+		network.Delegate.ReceiveRequest(ctx, peers[1], request)
+		require.Empty(t, network.strikes)
+
+		otherCid := testutil.GenerateCids(1)[0]
+		restart, err := message.NewRequest(id, true, false, voucher.Type(), voucher, otherCid, testutil.AllSelector())
+		require.NoError(t, err)
+		network.Delegate.ReceiveRequest(ctx, peers[1], restart)
+		require.Equal(t, []peer.ID{peers[1]}, network.strikes)
+	})
+
+	t.Run("requests from rate limited peers are dropped", func(t *testing.T) {
+		_, network, _, sv := newManager(t)
+		sv.StubSuccessPush()
+		network.allow = false
+		request, err := message.NewRequest(datatransfer.TransferID(rand.Int31()), false, false, voucher.Type(), voucher, baseCid, testutil.AllSelector())
+		require.NoError(t, err)
+		network.Delegate.ReceiveRequest(ctx, peers[1], request)
+		require.Empty(t, sv.ValidationsReceived)
+		require.Empty(t, network.SentMessages)
+	})
+
+	t.Run("requests on other transports are rate limited", func(t *testing.T) {
+		_, network, transport, sv := newManager(t)
+		sv.StubSuccessPull()
+		network.allow = false
+		id := datatransfer.TransferID(rand.Int31())
+		request, err := message.NewRequest(id, false, true, voucher.Type(), voucher, baseCid, testutil.AllSelector())
+		require.NoError(t, err)
+		_, err = transport.EventHandler.OnRequestReceived(channelID(id, peers), request)
+		require.True(t, xerrors.Is(err, datatransfer.ErrRateLimited))
+		require.Empty(t, sv.ValidationsReceived)
+	})
+}
+
+func TestPeerStatsDisabled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	peers := testutil.GeneratePeers(2)
+	ds := dss.MutexWrap(datastore.NewMapDatastore())
+	storedCounter := storedcounter.New(ds, datastore.NewKey("counter"))
+	dt, err := NewDataTransfer(ds, os.TempDir(), testutil.NewFakeNetwork(peers[0]), testutil.NewFakeTransport(), storedCounter)
+	require.NoError(t, err)
+	testutil.StartAndWaitForReady(ctx, t, dt)
+
+	_, err = dt.PeerStats(ctx, peers[1])
+	require.Equal(t, datatransfer.ErrPeerStatsDisabled, err)
+
+	dt, err = NewDataTransfer(ds, os.TempDir(), testutil.NewFakeNetwork(peers[0]), testutil.NewFakeTransport(), storedCounter, TrackPeerStats(0.2))
+	require.NoError(t, err)
+	testutil.StartAndWaitForReady(ctx, t, dt)
+	stats, err := dt.PeerStats(ctx, peers[1])
+	require.NoError(t, err)
+	require.Equal(t, datatransfer.PeerStats{Peer: peers[1]}, stats)
+}
+
+// storeSelectingValidator is a stubbed validator that also selects a store for
+// each channel it validates
+type storeSelectingValidator struct {
+	*testutil.StubbedValidator
+	loader   ipld.Loader
+	storer   ipld.Storer
+	err      error
+	selected []datatransfer.ChannelID
+}
+
+func newStoreSelectingValidator(sv *testutil.StubbedValidator) *storeSelectingValidator {
+	return &storeSelectingValidator{
+		StubbedValidator: sv,
+		loader: func(ipld.Link, ipld.LinkContext) (io.Reader, error) {
+			return nil, errors.New("not found")
+		},
+		storer: func(ipld.LinkContext) (io.Writer, ipld.StoreCommitter, error) {
+			return nil, nil, errors.New("read only")
+		},
+	}
+}
+
+// blockstoreSelectingValidator is a validator that selects a blockstore for
+// each channel it validates
+type blockstoreSelectingValidator struct {
+	*testutil.StubbedValidator
+	bs blockstore.Blockstore
+}
+
+func (bsv *blockstoreSelectingValidator) SelectBlockstore(chid datatransfer.ChannelID, voucher datatransfer.Voucher) (blockstore.Blockstore, error) {
+	return bsv.bs, nil
+}
+
+func (ssv *storeSelectingValidator) SelectStore(chid datatransfer.ChannelID, voucher datatransfer.Voucher) (ipld.Loader, ipld.Storer, error) {
+	ssv.selected = append(ssv.selected, chid)
+	return ssv.loader, ssv.storer, ssv.err
+}
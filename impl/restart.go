@@ -73,7 +73,7 @@ func (m *manager) validateRestartVoucher(channel datatransfer.ChannelState, isPu
 	}
 
 	// revalidate the voucher by reconstructing the request that would have led to the creation of this channel
-	if _, _, err := m.validateVoucher(channel.OtherPeer(), req, isPull, channel.BaseCID(), channel.Selector()); err != nil {
+	if _, _, err := m.validateVoucher(chid, channel.OtherPeer(), req, isPull, channel.BaseCID(), channel.Selector()); err != nil {
 		return err
 	}
 
@@ -91,11 +91,16 @@ func (m *manager) openPushRestartChannel(ctx context.Context, channel datatransf
 	if err != nil {
 		return err
 	}
+	transport, err := m.transports.get(channel.TransportName())
+	if err != nil {
+		return err
+	}
+	req = withTransportName(channel.TransportName(), m.withTraceContext(chid, req))
 
 	processor, has := m.transportConfigurers.Processor(voucher.Type())
 	if has {
 		transportConfigurer := processor.(datatransfer.TransportConfigurer)
-		transportConfigurer(chid, voucher, m.transport)
+		transportConfigurer(chid, voucher, transport)
 	}
 	m.dataTransferNetwork.Protect(requestTo, chid.String())
 
@@ -118,16 +123,19 @@ func (m *manager) openPullRestartChannel(ctx context.Context, channel datatransf
 	if err != nil {
 		return err
 	}
+	transport, err := m.transports.get(channel.TransportName())
+	if err != nil {
+		return err
+	}
+	req = withTransportName(channel.TransportName(), m.withTraceContext(chid, req))
 
-	processor, has := m.transportConfigurers.Processor(voucher.Type())
-	if has {
-		transportConfigurer := processor.(datatransfer.TransportConfigurer)
-		transportConfigurer(chid, voucher, m.transport)
+	if err := m.configureStore(chid, voucher, transport, true, false); err != nil {
+		return err
 	}
 	m.dataTransferNetwork.Protect(requestTo, chid.String())
 
 	log.Infof("sending open channel to %s to restart channel %s", requestTo, chid)
-	if err := m.transport.OpenChannel(ctx, requestTo, chid, cidlink.Link{Cid: baseCid}, selector, channel.ReceivedCids(), req); err != nil {
+	if err := transport.OpenChannel(ctx, requestTo, chid, cidlink.Link{Cid: baseCid}, selector, channel.ReceivedCids(), req); err != nil {
 		return xerrors.Errorf("Unable to send open channel restart request: %w", err)
 	}
 
@@ -148,21 +156,24 @@ func (m *manager) validateRestartRequest(ctx context.Context, otherPeer peer.ID,
 
 	// channel initator should be the sender peer
 	if channel.ChannelID().Initiator != otherPeer {
-		return xerrors.New("other peer is not the initiator of the channel")
+		return peerMisbehaving(xerrors.New("other peer is not the initiator of the channel"))
 	}
 
 	// channel and request baseCid should match
 	if req.BaseCid() != channel.BaseCID() {
-		return xerrors.New("base cid does not match")
+		return peerMisbehaving(xerrors.New("base cid does not match"))
 	}
 
 	// vouchers should match
-	reqVoucher, err := m.decodeVoucher(req, m.validatedTypes)
+	if err := m.limits.checkVoucher(req); err != nil {
+		return err
+	}
+	reqVoucher, _, err := m.decodeVoucher(req, m.validatedTypes)
 	if err != nil {
 		return xerrors.Errorf("failed to decode request voucher: %w", err)
 	}
 	if reqVoucher.Type() != channel.Voucher().Type() {
-		return xerrors.New("channel and request voucher types do not match")
+		return peerMisbehaving(xerrors.New("channel and request voucher types do not match"))
 	}
 
 	reqBz, err := encoding.Encode(reqVoucher)
@@ -175,7 +186,7 @@ func (m *manager) validateRestartRequest(ctx context.Context, otherPeer peer.ID,
 	}
 
 	if !bytes.Equal(reqBz, channelBz) {
-		return xerrors.New("channel and request vouchers do not match")
+		return peerMisbehaving(xerrors.New("channel and request vouchers do not match"))
 	}
 
 	return nil
@@ -0,0 +1,34 @@
+package impl
+
+import (
+	"sync"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+	"github.com/filecoin-project/go-data-transfer/encoding"
+)
+
+// retiredDecoders keeps the decoders for types that have been unregistered, so
+// that channels which already recorded values of those types can still read them
+type retiredDecoders struct {
+	lk       sync.RWMutex
+	decoders map[datatransfer.TypeIdentifier]encoding.Decoder
+}
+
+func newRetiredDecoders() *retiredDecoders {
+	return &retiredDecoders{
+		decoders: make(map[datatransfer.TypeIdentifier]encoding.Decoder),
+	}
+}
+
+func (rd *retiredDecoders) retire(identifier datatransfer.TypeIdentifier, decoder encoding.Decoder) {
+	rd.lk.Lock()
+	defer rd.lk.Unlock()
+	rd.decoders[identifier] = decoder
+}
+
+func (rd *retiredDecoders) Decoder(identifier datatransfer.TypeIdentifier) (encoding.Decoder, bool) {
+	rd.lk.RLock()
+	defer rd.lk.RUnlock()
+	decoder, has := rd.decoders[identifier]
+	return decoder, has
+}
@@ -0,0 +1,141 @@
+package impl
+
+import (
+	"context"
+
+	"github.com/libp2p/go-libp2p-core/crypto"
+	"golang.org/x/xerrors"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+	"github.com/filecoin-project/go-data-transfer/message"
+)
+
+// SignRequests signs the requests this node sends to open channels with the
+// given key, so responders can prove this node asked for the channels. The
+// key must be the private key of this node's peer ID. Only the 1.2 protocol
+// carries the signature, so peers on older protocols receive the requests
+// unsigned
+func SignRequests(key crypto.PrivKey) DataTransferOption {
+	return func(m *manager) {
+		m.requestKey = key
+	}
+}
+
+// SignReceipts signs a receipt with the given key for the data this node
+// receives on each channel that completes, records it on the channel state,
+// and sends it to the sender, which records it on its channel state. Only
+// senders on the 1.2 protocol receive receipts. The key must be the private
+// key of this node's peer ID
+func SignReceipts(key crypto.PrivKey) DataTransferOption {
+	return func(m *manager) {
+		m.receiptKey = key
+	}
+}
+
+// validateSigningKeys checks the signing keys belong to this node
+func (m *manager) validateSigningKeys() error {
+	for _, key := range []crypto.PrivKey{m.requestKey, m.receiptKey} {
+		if key != nil && !m.peerID.MatchesPrivateKey(key) {
+			return xerrors.Errorf("signing key does not belong to %s", m.peerID)
+		}
+	}
+	return nil
+}
+
+// signRequest signs a request this node opens a channel with, records the
+// signature on the channel, and returns the request carrying it
+func (m *manager) signRequest(chid datatransfer.ChannelID, req datatransfer.Request) (datatransfer.Request, error) {
+	if m.requestKey == nil {
+		return req, nil
+	}
+	carrier, ok := req.(datatransfer.SignatureCarrier)
+	if !ok {
+		return req, nil
+	}
+	signed, err := carrier.SignedRequest(chid)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := signed.Sign(m.requestKey)
+	if err != nil {
+		return nil, xerrors.Errorf("signing request: %w", err)
+	}
+	if err := m.channels.RequestSigned(chid, signed, sig); err != nil {
+		return nil, err
+	}
+	return carrier.WithRequestSignature(sig).(datatransfer.Request), nil
+}
+
+// verifyRequestSignature checks the signature on a request to open a channel,
+// if it is signed, and returns it with the part of the request it signs. The
+// signature is checked against the voucher and selector as they are encoded
+// in the request
+func (m *manager) verifyRequestSignature(chid datatransfer.ChannelID, incoming datatransfer.Request) (*datatransfer.SignedRequest, *datatransfer.RequestSignature, error) {
+	carrier, ok := incoming.(datatransfer.SignatureCarrier)
+	if !ok || carrier.RequestSignature() == nil {
+		return nil, nil, nil
+	}
+	// the signature is checked before validation checks the limits, so they
+	// must be checked first
+	if err := m.limits.checkRequest(incoming); err != nil {
+		return nil, nil, err
+	}
+	signed, err := carrier.SignedRequest(chid)
+	if err != nil {
+		return nil, nil, err
+	}
+	sig := carrier.RequestSignature()
+	if err := sig.Verify(signed); err != nil {
+		return nil, nil, peerMisbehaving(xerrors.Errorf("request for channel %s: %w", chid, err))
+	}
+	return &signed, sig, nil
+}
+
+// issueReceipt counts the blocks received on a completed channel and signs a
+// receipt for them, or returns nil if this node does not sign receipts
+func (m *manager) issueReceipt(receipt datatransfer.Receipt) (*datatransfer.Receipt, error) {
+	if m.receiptKey == nil {
+		return nil, nil
+	}
+	received, err := m.cidLists.ReadList(receipt.ChannelID)
+	if err != nil {
+		return nil, err
+	}
+	receipt.Blocks = uint64(len(received))
+	if err := receipt.Sign(m.receiptKey); err != nil {
+		return nil, err
+	}
+	return &receipt, nil
+}
+
+// sendReceipt sends the receipt this node signed for the data it received on
+// a channel to the sender. Only peers on the 1.2 protocol can receive it
+func (m *manager) sendReceipt(chst datatransfer.ChannelState) {
+	receipt := chst.Receipt()
+	chid := chst.ChannelID()
+	var msg datatransfer.Message
+	if chid.Initiator == m.peerID {
+		msg = message.ReceiptRequest(chid.ID, receipt)
+	} else {
+		msg = message.ReceiptResponse(chid.ID, receipt)
+	}
+	if err := m.dataTransferNetwork.SendMessage(m.channelSpans.context(context.Background(), chid), chst.OtherPeer(), msg); err != nil {
+		log.Warnf("unable to send receipt for channel %s to %s: %s", chid, chst.OtherPeer(), err)
+	}
+}
+
+// receiveReceipt checks a receipt the receiver sent for the data this node
+// sent on a channel, and records it on the channel
+func (m *manager) receiveReceipt(chid datatransfer.ChannelID, receipt *datatransfer.Receipt) error {
+	chst, err := m.channels.GetByID(context.TODO(), chid)
+	if err != nil {
+		return err
+	}
+	if chst.Sender() != m.peerID || receipt.Receiver != chst.Recipient() || receipt.ChannelID != chid {
+		return peerMisbehaving(xerrors.Errorf("receipt for channel %s does not match the channel", chid))
+	}
+	if err := receipt.Verify(); err != nil {
+		return peerMisbehaving(xerrors.Errorf("receipt for channel %s: %w", chid, err))
+	}
+	return m.channels.ReceiptReceived(chid, receipt)
+}
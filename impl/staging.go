@@ -0,0 +1,163 @@
+package impl
+
+import (
+	"sync"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-graphsync/storeutil"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+	"github.com/filecoin-project/go-data-transfer/staging"
+)
+
+// StagedStorage stages the blocks this node receives for each channel in the
+// datastore, and only writes them to their destination once the channel
+// completes. The blocks staged for channels that fail or are cancelled are
+// discarded, and restarted channels reuse the blocks they already staged.
+//
+// The destination is the store chosen for the channel by the transport
+// configurer for the voucher type, or else by a
+// datatransfer.BlockstoreSelectingValidator or StoreSelectingValidator, or
+// else the given blockstore. Blocks are committed to a blockstore in one
+// PutMany call, which a blockstore on a batching datastore writes in one
+// batch, so a commit that fails leaves none of them written. Blocks committed
+// to a store chosen as a loader and storer are written one at a time. The
+// destination is only kept in memory, and is chosen again for a channel that
+// completes after this node restarts. Channels that stage blocks must use a
+// datatransfer.StoreConfigurableTransport, and transport configurers must set
+// their store through that interface rather than the concrete transport type
+func StagedStorage(bs blockstore.Blockstore) DataTransferOption {
+	return func(m *manager) {
+		m.staging = &stagedStorage{
+			bs:    bs,
+			dests: make(map[datatransfer.ChannelID]staging.Destination),
+		}
+	}
+}
+
+// channelStore is the store chosen for a channel, either a blockstore or a
+// loader and storer. The zero value is the transport's default store
+type channelStore struct {
+	bs     blockstore.Blockstore
+	loader ipld.Loader
+	storer ipld.Storer
+}
+
+func (cs channelStore) isDefault() bool {
+	return cs.bs == nil && cs.loader == nil && cs.storer == nil
+}
+
+// loaderStorer returns the loader and storer that read from and write to the
+// store
+func (cs channelStore) loaderStorer() (ipld.Loader, ipld.Storer) {
+	if cs.bs != nil {
+		return storeutil.LoaderForBlockstore(cs.bs), storeutil.StorerForBlockstore(cs.bs)
+	}
+	return cs.loader, cs.storer
+}
+
+// storeRecordingTransport is given to the transport configurer for a channel
+// whose blocks are staged, so that a store the configurer sets becomes the
+// destination of the staged blocks instead of replacing the staging store
+type storeRecordingTransport struct {
+	datatransfer.Transport
+	store channelStore
+}
+
+func (t *storeRecordingTransport) UseStore(chid datatransfer.ChannelID, loader ipld.Loader, storer ipld.Storer) error {
+	t.store = channelStore{loader: loader, storer: storer}
+	return nil
+}
+
+// storerDestination commits blocks to a storer one at a time
+type storerDestination ipld.Storer
+
+func (sd storerDestination) PutMany(blks []blocks.Block) error {
+	for _, blk := range blks {
+		w, commit, err := sd(ipld.LinkContext{})
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(blk.RawData()); err != nil {
+			return err
+		}
+		if err := commit(cidlink.Link{Cid: blk.Cid()}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stagedStorage tracks where the blocks staged for each channel are committed
+type stagedStorage struct {
+	store *staging.Store
+	bs    blockstore.Blockstore
+
+	lk    sync.Mutex
+	dests map[datatransfer.ChannelID]staging.Destination
+}
+
+// destination returns where blocks staged into the given store are
+// committed, and the loader for blocks that are not staged
+func (ss *stagedStorage) destination(store channelStore) (staging.Destination, ipld.Loader) {
+	loader, storer := store.loaderStorer()
+	if loader == nil {
+		loader = storeutil.LoaderForBlockstore(ss.bs)
+	}
+	switch {
+	case store.bs != nil:
+		return store.bs, loader
+	case storer != nil:
+		return storerDestination(storer), loader
+	default:
+		return ss.bs, loader
+	}
+}
+
+// stage records the destination for the channel's blocks, and returns the
+// loader and storer that stage them
+func (ss *stagedStorage) stage(chid datatransfer.ChannelID, store channelStore) (ipld.Loader, ipld.Storer) {
+	dest, loader := ss.destination(store)
+	ss.lk.Lock()
+	ss.dests[chid] = dest
+	ss.lk.Unlock()
+	return ss.store.Loader(chid, loader), ss.store.Storer(chid)
+}
+
+// commit writes the blocks staged for the channel to their destination. If
+// the destination is not known because this node restarted since the channel
+// chose it, choose is called to choose it again
+func (ss *stagedStorage) commit(chid datatransfer.ChannelID, choose func() (channelStore, error)) error {
+	ss.lk.Lock()
+	dest, ok := ss.dests[chid]
+	ss.lk.Unlock()
+	if !ok {
+		staged, err := ss.store.HasBlocks(chid)
+		if err != nil || !staged {
+			return err
+		}
+		store, err := choose()
+		if err != nil {
+			return err
+		}
+		dest, _ = ss.destination(store)
+	}
+	if err := ss.store.Commit(chid, dest); err != nil {
+		return err
+	}
+	ss.lk.Lock()
+	delete(ss.dests, chid)
+	ss.lk.Unlock()
+	return nil
+}
+
+// discard removes any blocks still staged for the channel
+func (ss *stagedStorage) discard(chid datatransfer.ChannelID) error {
+	ss.lk.Lock()
+	delete(ss.dests, chid)
+	ss.lk.Unlock()
+	return ss.store.Discard(chid)
+}
@@ -0,0 +1,139 @@
+package impl
+
+import (
+	"sync"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+)
+
+// queuedSubscriber delivers filtered events to a subscriber from its own
+// goroutine, through a bounded queue
+type queuedSubscriber struct {
+	cfg        datatransfer.SubscribeConfig
+	subscriber datatransfer.Subscriber
+	queue      chan internalEvent
+	// disconnected is closed when the queue overflows under the disconnect
+	// policy. Events already queued are still delivered
+	disconnected chan struct{}
+	// done is closed when the subscriber unsubscribes
+	done chan struct{}
+
+	disconnectOnce  sync.Once
+	unsubscribeOnce sync.Once
+	// stopPublishing removes the subscriber from the manager's pubsub. It is
+	// set before subscribed is closed
+	stopPublishing func()
+	subscribed     chan struct{}
+}
+
+func newQueuedSubscriber(subscriber datatransfer.Subscriber, cfg datatransfer.SubscribeConfig) *queuedSubscriber {
+	if cfg.QueueSize < 1 {
+		cfg.QueueSize = 1
+	}
+	return &queuedSubscriber{
+		cfg:          cfg,
+		subscriber:   subscriber,
+		queue:        make(chan internalEvent, cfg.QueueSize),
+		disconnected: make(chan struct{}),
+		done:         make(chan struct{}),
+		subscribed:   make(chan struct{}),
+	}
+}
+
+// publish is called synchronously for every event and queues those that pass
+// the subscriber's filters
+func (qs *queuedSubscriber) publish(event datatransfer.Event, channelState datatransfer.ChannelState) {
+	if !qs.cfg.Matches(event, channelState) {
+		return
+	}
+	select {
+	case <-qs.disconnected:
+		return
+	case <-qs.done:
+		return
+	default:
+	}
+
+	ie := internalEvent{event, channelState}
+	switch qs.cfg.OverflowPolicy {
+	case datatransfer.OverflowBlock:
+		select {
+		case qs.queue <- ie:
+		case <-qs.done:
+		}
+	case datatransfer.OverflowDisconnect:
+		select {
+		case qs.queue <- ie:
+		default:
+			log.Warnf("disconnecting event subscriber after its queue of %d events overflowed", qs.cfg.QueueSize)
+			qs.disconnectOnce.Do(func() {
+				close(qs.disconnected)
+				// removing the subscriber waits for publishing to finish, so it
+				// cannot happen on this goroutine
+				go func() {
+					<-qs.subscribed
+					qs.stopPublishing()
+				}()
+			})
+		}
+	default:
+		for {
+			select {
+			case qs.queue <- ie:
+				return
+			default:
+			}
+			select {
+			case <-qs.queue:
+			default:
+			}
+		}
+	}
+}
+
+// unsubscribe stops delivery of events, discarding any still queued
+func (qs *queuedSubscriber) unsubscribe() {
+	qs.unsubscribeOnce.Do(func() {
+		close(qs.done)
+		qs.stopPublishing()
+	})
+}
+
+// run delivers queued events until the subscriber unsubscribes, or until the
+// queue is empty after a disconnect
+func (qs *queuedSubscriber) run() {
+	for {
+		select {
+		case <-qs.done:
+			return
+		case ie := <-qs.queue:
+			qs.subscriber(ie.evt, ie.state)
+		case <-qs.disconnected:
+			for {
+				select {
+				case <-qs.done:
+					return
+				case ie := <-qs.queue:
+					qs.subscriber(ie.evt, ie.state)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// SubscribeToFilteredEvents subscribes to the events that pass the given
+// filters, delivering them asynchronously through a bounded queue
+func (m *manager) SubscribeToFilteredEvents(subscriber datatransfer.Subscriber, options ...datatransfer.SubscribeOption) datatransfer.Unsubscribe {
+	qs := newQueuedSubscriber(subscriber, datatransfer.NewSubscribeConfig(options...))
+	qs.stopPublishing = m.pubSub.Subscribe(datatransfer.Subscriber(qs.publish))
+	close(qs.subscribed)
+	go qs.run()
+	return qs.unsubscribe
+}
+
+// SubscribeToChannelEvents subscribes to events on a single channel
+func (m *manager) SubscribeToChannelEvents(chid datatransfer.ChannelID, subscriber datatransfer.Subscriber, options ...datatransfer.SubscribeOption) datatransfer.Unsubscribe {
+	return m.SubscribeToFilteredEvents(subscriber, append(options, datatransfer.FilterChannel(chid))...)
+}
@@ -0,0 +1,115 @@
+package impl
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+	"github.com/filecoin-project/go-data-transfer/channels"
+	"github.com/filecoin-project/go-data-transfer/clock"
+)
+
+// PauseDataTransferChannelUntil pauses a channel and resumes it automatically
+// once the given time passes. The deadline is stored with the channel, so the
+// channel is still resumed if the manager restarts in the meantime
+func (m *manager) PauseDataTransferChannelUntil(ctx context.Context, chid datatransfer.ChannelID, until time.Time) error {
+	if err := m.PauseDataTransferChannel(ctx, chid); err != nil {
+		return err
+	}
+	if err := m.channels.TimedPause(chid, until); err != nil {
+		return xerrors.Errorf("unable to record timed pause: %w", err)
+	}
+	m.scheduleResume(chid, until)
+	return nil
+}
+
+// timedPause is the timer that ends a channel's timed pause
+type timedPause struct {
+	timer clock.Timer
+	until time.Time
+}
+
+// scheduleResume sets a timer to resume the channel at the given time,
+// replacing any timer already set for the channel
+func (m *manager) scheduleResume(chid datatransfer.ChannelID, until time.Time) {
+	m.timedPausesLk.Lock()
+	defer m.timedPausesLk.Unlock()
+	if tp, ok := m.timedPauses[chid]; ok {
+		tp.timer.Stop()
+	}
+	tp := &timedPause{until: until}
+	tp.timer = m.clock.AfterFunc(m.clock.Until(until), func() {
+		m.expireTimedPause(chid, tp)
+	})
+	m.timedPauses[chid] = tp
+}
+
+// cancelScheduledResume stops the timer for a channel's timed pause, if any
+func (m *manager) cancelScheduledResume(chid datatransfer.ChannelID) {
+	m.timedPausesLk.Lock()
+	defer m.timedPausesLk.Unlock()
+	if tp, ok := m.timedPauses[chid]; ok {
+		tp.timer.Stop()
+		delete(m.timedPauses, chid)
+	}
+}
+
+// cancelAllScheduledResumes stops the timers for every timed pause
+func (m *manager) cancelAllScheduledResumes() {
+	m.timedPausesLk.Lock()
+	defer m.timedPausesLk.Unlock()
+	for chid, tp := range m.timedPauses {
+		tp.timer.Stop()
+		delete(m.timedPauses, chid)
+	}
+}
+
+// expireTimedPause resumes a channel whose timed pause has ended, unless the
+// channel was resumed or paused with a new deadline in the meantime
+func (m *manager) expireTimedPause(chid datatransfer.ChannelID, tp *timedPause) {
+	m.timedPausesLk.Lock()
+	// the timer may have fired just as it was replaced by a new timed pause,
+	// whose timer must stay scheduled
+	if m.timedPauses[chid] == tp {
+		delete(m.timedPauses, chid)
+	}
+	m.timedPausesLk.Unlock()
+
+	ctx := context.Background()
+	chst, err := m.channels.GetByID(ctx, chid)
+	if err != nil {
+		log.Warnf("unable to get channel %s to end timed pause: %s", chid, err)
+		return
+	}
+	if !chst.PausedUntil().Equal(tp.until) || channels.IsChannelCleaningUp(chst.Status()) || channels.IsChannelTerminated(chst.Status()) {
+		return
+	}
+	log.Infof("timed pause on channel %s has ended, resuming", chid)
+	if err := m.channels.TimedPauseExpired(chid); err != nil {
+		log.Warnf("unable to record end of timed pause on channel %s: %s", chid, err)
+		return
+	}
+	if err := m.ResumeDataTransferChannel(ctx, chid); err != nil {
+		log.Warnf("unable to resume channel %s after timed pause: %s", chid, err)
+	}
+}
+
+// restoreTimedPauses reschedules the timed pauses stored with channels, so
+// they still end after the manager restarts. Pauses whose deadline passed
+// while the manager was stopped end straight away
+func (m *manager) restoreTimedPauses() error {
+	chans, err := m.channels.InProgress()
+	if err != nil {
+		return err
+	}
+	for chid, chst := range chans {
+		until := chst.PausedUntil()
+		if until.IsZero() || channels.IsChannelCleaningUp(chst.Status()) || channels.IsChannelTerminated(chst.Status()) {
+			continue
+		}
+		m.scheduleResume(chid, until)
+	}
+	return nil
+}
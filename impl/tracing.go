@@ -0,0 +1,109 @@
+package impl
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+	"github.com/filecoin-project/go-data-transfer/tracing"
+)
+
+// channelSpans tracks the span covering each channel, from when it is opened
+// or received until it reaches a terminal status
+type channelSpans struct {
+	tracer tracing.Tracer
+	lk     sync.Mutex
+	spans  map[datatransfer.ChannelID]tracing.Span
+}
+
+func newChannelSpans(tracer tracing.Tracer) *channelSpans {
+	return &channelSpans{
+		tracer: tracer,
+		spans:  make(map[datatransfer.ChannelID]tracing.Span),
+	}
+}
+
+// start starts the span for a channel, unless it already has one, and
+// returns the channel span and whether it was started by this call
+func (cs *channelSpans) start(ctx context.Context, chid datatransfer.ChannelID) (tracing.Span, bool) {
+	cs.lk.Lock()
+	defer cs.lk.Unlock()
+	if span, ok := cs.spans[chid]; ok {
+		return span, false
+	}
+	_, span := cs.tracer.Start(ctx, "channel")
+	span.SetAttribute("channel", chid.String())
+	cs.spans[chid] = span
+	return span, true
+}
+
+// context returns a context carrying the span for the channel, if it has one
+func (cs *channelSpans) context(ctx context.Context, chid datatransfer.ChannelID) context.Context {
+	cs.lk.Lock()
+	span, ok := cs.spans[chid]
+	cs.lk.Unlock()
+	if !ok {
+		return ctx
+	}
+	return tracing.ContextWithSpan(ctx, span)
+}
+
+// startChild starts a span for an operation on a channel, as a child of the
+// channel span
+func (cs *channelSpans) startChild(ctx context.Context, chid datatransfer.ChannelID, name string) (context.Context, tracing.Span) {
+	return cs.tracer.Start(cs.context(ctx, chid), name)
+}
+
+// end ends the span for a channel, recording the given error if not nil
+func (cs *channelSpans) end(chid datatransfer.ChannelID, err error) {
+	cs.lk.Lock()
+	span, ok := cs.spans[chid]
+	delete(cs.spans, chid)
+	cs.lk.Unlock()
+	if !ok {
+		return
+	}
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// endChannelSpan ends the span for a channel that reached a terminal status
+func (m *manager) endChannelSpan(chst datatransfer.ChannelState) {
+	var err error
+	if chst.Status() == datatransfer.Failed {
+		err = errors.New(chst.Message())
+	}
+	m.channelSpans.end(chst.ChannelID(), err)
+}
+
+// withTraceContext attaches the trace context of the channel span to a
+// request, if propagation is enabled and the request can carry it
+func (m *manager) withTraceContext(chid datatransfer.ChannelID, req datatransfer.Request) datatransfer.Request {
+	if !m.propagateTraceContext {
+		return req
+	}
+	carrier, ok := req.(datatransfer.TraceCarrier)
+	if !ok {
+		return req
+	}
+	span := tracing.SpanFromContext(m.channelSpans.context(context.Background(), chid))
+	if span == nil {
+		return req
+	}
+	traceContext := span.TraceContext()
+	if traceContext == nil {
+		return req
+	}
+	return carrier.WithTraceContext(traceContext).(datatransfer.Request)
+}
+
+// recordSpanError records an error on a span, ignoring the errors that are
+// used to signal pauses and deferrals
+func recordSpanError(span tracing.Span, err error) {
+	if err != nil && err != datatransfer.ErrPause && err != datatransfer.ErrDeferred {
+		span.RecordError(err)
+	}
+}
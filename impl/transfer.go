@@ -0,0 +1,146 @@
+package impl
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"golang.org/x/xerrors"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+	"github.com/filecoin-project/go-data-transfer/channels"
+)
+
+// transfer tracks a channel opened by this node until it reaches a final status
+type transfer struct {
+	chid  datatransfer.ChannelID
+	done  chan struct{}
+	state datatransfer.ChannelState
+	err   error
+}
+
+func (t *transfer) ChannelID() datatransfer.ChannelID {
+	return t.chid
+}
+
+func (t *transfer) Done() <-chan struct{} {
+	return t.done
+}
+
+func (t *transfer) Err() error {
+	select {
+	case <-t.done:
+		return t.err
+	default:
+		return nil
+	}
+}
+
+func (t *transfer) State() datatransfer.ChannelState {
+	select {
+	case <-t.done:
+		return t.state
+	default:
+		return nil
+	}
+}
+
+func (m *manager) trackTransfer(chid datatransfer.ChannelID) *transfer {
+	t := &transfer{
+		chid: chid,
+		done: make(chan struct{}),
+	}
+	go func() {
+		defer close(t.done)
+		chst, err := m.WaitForChannel(m.stopCtx, chid)
+		if err != nil {
+			if m.stopCtx.Err() != nil {
+				err = datatransfer.ErrManagerStopped
+			}
+			t.err = err
+			return
+		}
+		t.state = chst
+		switch chst.Status() {
+		case datatransfer.Failed:
+			t.err = xerrors.Errorf("%w: %s", datatransfer.ErrChannelFailed, chst.Message())
+		case datatransfer.Cancelled:
+			t.err = xerrors.Errorf("%w: %s", datatransfer.ErrChannelCancelled, chst.Message())
+		}
+	}()
+	return t
+}
+
+// OpenPushTransfer opens a push data transfer and returns a handle that tracks
+// the transfer until it finishes
+func (m *manager) OpenPushTransfer(ctx context.Context, requestTo peer.ID, voucher datatransfer.Voucher, baseCid cid.Cid, selector ipld.Node, options ...datatransfer.OpenOption) (datatransfer.Transfer, error) {
+	chid, err := m.OpenPushDataChannel(ctx, requestTo, voucher, baseCid, selector, options...)
+	if err != nil {
+		return nil, err
+	}
+	return m.trackTransfer(chid), nil
+}
+
+// OpenPullTransfer opens a pull data transfer and returns a handle that tracks
+// the transfer until it finishes
+func (m *manager) OpenPullTransfer(ctx context.Context, requestTo peer.ID, voucher datatransfer.Voucher, baseCid cid.Cid, selector ipld.Node, options ...datatransfer.OpenOption) (datatransfer.Transfer, error) {
+	chid, err := m.OpenPullDataChannel(ctx, requestTo, voucher, baseCid, selector, options...)
+	if err != nil {
+		return nil, err
+	}
+	return m.trackTransfer(chid), nil
+}
+
+// WaitForChannel waits until the channel is in one of the given statuses, or
+// in a final status if none are given, and returns its state
+func (m *manager) WaitForChannel(ctx context.Context, chid datatransfer.ChannelID, statuses ...datatransfer.Status) (datatransfer.ChannelState, error) {
+	isTarget := func(status datatransfer.Status) bool {
+		if len(statuses) == 0 {
+			return channels.IsChannelTerminated(status)
+		}
+		for _, target := range statuses {
+			if status == target {
+				return true
+			}
+		}
+		return false
+	}
+
+	// the first state that is a target, or is final, ends the wait
+	reached := make(chan datatransfer.ChannelState, 1)
+	var once sync.Once
+	check := func(chst datatransfer.ChannelState) {
+		if isTarget(chst.Status()) || channels.IsChannelTerminated(chst.Status()) {
+			once.Do(func() {
+				reached <- chst
+			})
+		}
+	}
+
+	// subscribe before reading the current state, so that a change between
+	// the two is not missed
+	unsub := m.SubscribeToEvents(func(_ datatransfer.Event, chst datatransfer.ChannelState) {
+		if chst.ChannelID() == chid {
+			check(chst)
+		}
+	})
+	defer unsub()
+
+	chst, err := m.channels.GetByID(ctx, chid)
+	if err != nil {
+		return nil, err
+	}
+	check(chst)
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case chst := <-reached:
+		if !isTarget(chst.Status()) {
+			return chst, xerrors.Errorf("channel %s finished with status %s", chid, datatransfer.Statuses[chst.Status()])
+		}
+		return chst, nil
+	}
+}
@@ -0,0 +1,258 @@
+package impl
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ipld/go-ipld-prime"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"golang.org/x/xerrors"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+	"github.com/filecoin-project/go-data-transfer/channels"
+)
+
+// NamedTransport adds a transport that channels can move their data with
+// instead of the default transport given to NewDataTransfer. A channel uses
+// it when it is opened with datatransfer.UseTransport, when its voucher type
+// is assigned to it with VoucherTypeTransport, or when the transport is a
+// datatransfer.PeerCapableTransport that supports the other peer
+func NamedTransport(name string, transport datatransfer.Transport) DataTransferOption {
+	return func(m *manager) {
+		m.transports.add(name, transport)
+	}
+}
+
+// VoucherTypeTransport moves the data for channels opened with the given
+// voucher type with the named transport
+func VoucherTypeTransport(voucherType datatransfer.TypeIdentifier, name string) DataTransferOption {
+	return func(m *manager) {
+		m.transports.voucherTypes[voucherType] = name
+	}
+}
+
+// transports holds the transports the manager moves data with, and
+// remembers which one each channel uses
+type transports struct {
+	byName map[string]datatransfer.Transport
+	// order holds the names of the transports added with NamedTransport, in
+	// the order they were added
+	order        []string
+	voucherTypes map[datatransfer.TypeIdentifier]string
+	err          error
+
+	lk       sync.RWMutex
+	channels map[datatransfer.ChannelID]string
+}
+
+func newTransports(defaultTransport datatransfer.Transport) *transports {
+	return &transports{
+		byName:       map[string]datatransfer.Transport{datatransfer.DefaultTransportName: defaultTransport},
+		voucherTypes: make(map[datatransfer.TypeIdentifier]string),
+		channels:     make(map[datatransfer.ChannelID]string),
+	}
+}
+
+func (ts *transports) add(name string, transport datatransfer.Transport) {
+	if _, ok := ts.byName[name]; ok || name == "" {
+		ts.err = xerrors.Errorf("transport name %q is empty or already used", name)
+		return
+	}
+	ts.byName[name] = transport
+	ts.order = append(ts.order, name)
+}
+
+// validate returns an error if the transports were configured incorrectly
+func (ts *transports) validate() error {
+	if ts.err != nil {
+		return ts.err
+	}
+	for voucherType, name := range ts.voucherTypes {
+		if _, ok := ts.byName[name]; !ok {
+			return xerrors.Errorf("voucher type %s uses transport %q: %w", voucherType, name, datatransfer.ErrTransportNotFound)
+		}
+	}
+	return nil
+}
+
+// get returns the named transport
+func (ts *transports) get(name string) (datatransfer.Transport, error) {
+	if name == "" {
+		name = datatransfer.DefaultTransportName
+	}
+	transport, ok := ts.byName[name]
+	if !ok {
+		return nil, xerrors.Errorf("transport %q: %w", name, datatransfer.ErrTransportNotFound)
+	}
+	return transport, nil
+}
+
+// choose returns the name of the transport for a channel this node opens
+func (ts *transports) choose(ctx context.Context, cfg datatransfer.OpenConfig, voucher datatransfer.Voucher, p peer.ID) (string, error) {
+	if cfg.Transport != "" {
+		if _, err := ts.get(cfg.Transport); err != nil {
+			return "", err
+		}
+		return cfg.Transport, nil
+	}
+	if name, ok := ts.voucherTypes[voucher.Type()]; ok {
+		return name, nil
+	}
+	for _, name := range ts.order {
+		if capable, ok := ts.byName[name].(datatransfer.PeerCapableTransport); ok && capable.SupportsPeer(ctx, p) {
+			return name, nil
+		}
+	}
+	return datatransfer.DefaultTransportName, nil
+}
+
+func (ts *transports) setChannel(chid datatransfer.ChannelID, name string) {
+	ts.lk.Lock()
+	defer ts.lk.Unlock()
+	ts.channels[chid] = name
+}
+
+func (ts *transports) channel(chid datatransfer.ChannelID) (string, bool) {
+	ts.lk.RLock()
+	defer ts.lk.RUnlock()
+	name, ok := ts.channels[chid]
+	return name, ok
+}
+
+func (ts *transports) removeChannel(chid datatransfer.ChannelID) {
+	ts.lk.Lock()
+	defer ts.lk.Unlock()
+	delete(ts.channels, chid)
+}
+
+// channelTransportName returns the name of the transport for the channel, and
+// false if the channel is not known
+func (m *manager) channelTransportName(chid datatransfer.ChannelID) (string, bool) {
+	if name, ok := m.transports.channel(chid); ok {
+		return name, true
+	}
+	chst, err := m.channels.GetByID(context.TODO(), chid)
+	if err != nil {
+		return "", false
+	}
+	name := chst.TransportName()
+	if !channels.IsChannelTerminated(chst.Status()) {
+		m.transports.setChannel(chid, name)
+	}
+	return name, true
+}
+
+// channelTransport returns the transport that moves the data for the channel.
+// Channels that are not known use the default transport
+func (m *manager) channelTransport(chid datatransfer.ChannelID) (datatransfer.Transport, error) {
+	name, _ := m.channelTransportName(chid)
+	return m.transports.get(name)
+}
+
+// requestedTransport returns the name of the transport an incoming request
+// asks for
+func requestedTransport(request datatransfer.Request) string {
+	if carrier, ok := request.(datatransfer.TransportCarrier); ok && carrier.TransportName() != "" {
+		return carrier.TransportName()
+	}
+	return datatransfer.DefaultTransportName
+}
+
+// withTransportName names the transport of the channel in a request, so the
+// other peer uses the same one. Requests on the default transport are left
+// as they are, so peers that do not know about transport names can read them
+func withTransportName(name string, req datatransfer.Request) datatransfer.Request {
+	if name == datatransfer.DefaultTransportName {
+		return req
+	}
+	carrier, ok := req.(datatransfer.TransportCarrier)
+	if !ok {
+		return req
+	}
+	return carrier.WithTransportName(name).(datatransfer.Request)
+}
+
+// transportEvents receives the events from a single transport. It passes them
+// on to the manager, but rejects events for channels that use a different
+// transport, and records the transport that new requests arrive on
+type transportEvents struct {
+	m    *manager
+	name string
+}
+
+var _ datatransfer.EventsHandler = (*transportEvents)(nil)
+
+// checkChannel returns an error if the channel is known and uses a different
+// transport. Events for unknown channels are left for the manager to reject
+func (te *transportEvents) checkChannel(chid datatransfer.ChannelID) error {
+	name, ok := te.m.channelTransportName(chid)
+	if ok && name != te.name {
+		return xerrors.Errorf("channel %s uses transport %q, not %q", chid, name, te.name)
+	}
+	return nil
+}
+
+func (te *transportEvents) OnChannelOpened(chid datatransfer.ChannelID) error {
+	if err := te.checkChannel(chid); err != nil {
+		return err
+	}
+	return te.m.OnChannelOpened(chid)
+}
+
+func (te *transportEvents) OnResponseReceived(chid datatransfer.ChannelID, msg datatransfer.Response) error {
+	if err := te.checkChannel(chid); err != nil {
+		return err
+	}
+	return te.m.OnResponseReceived(chid, msg)
+}
+
+func (te *transportEvents) OnDataReceived(chid datatransfer.ChannelID, link ipld.Link, size uint64) error {
+	if err := te.checkChannel(chid); err != nil {
+		return err
+	}
+	return te.m.OnDataReceived(chid, link, size)
+}
+
+func (te *transportEvents) OnDataQueued(chid datatransfer.ChannelID, link ipld.Link, size uint64) (datatransfer.Message, error) {
+	if err := te.checkChannel(chid); err != nil {
+		return nil, err
+	}
+	return te.m.OnDataQueued(chid, link, size)
+}
+
+func (te *transportEvents) OnDataSent(chid datatransfer.ChannelID, link ipld.Link, size uint64) error {
+	if err := te.checkChannel(chid); err != nil {
+		return err
+	}
+	return te.m.OnDataSent(chid, link, size)
+}
+
+func (te *transportEvents) OnRequestReceived(chid datatransfer.ChannelID, msg datatransfer.Request) (datatransfer.Response, error) {
+	if !msg.IsNew() {
+		if err := te.checkChannel(chid); err != nil {
+			return nil, err
+		}
+	}
+	return te.m.onRequestReceived(te.name, chid, msg)
+}
+
+func (te *transportEvents) OnChannelCompleted(chid datatransfer.ChannelID, success bool) error {
+	if err := te.checkChannel(chid); err != nil {
+		return err
+	}
+	return te.m.OnChannelCompleted(chid, success)
+}
+
+func (te *transportEvents) OnRequestTimedOut(ctx context.Context, chid datatransfer.ChannelID) error {
+	if err := te.checkChannel(chid); err != nil {
+		return err
+	}
+	return te.m.OnRequestTimedOut(ctx, chid)
+}
+
+func (te *transportEvents) OnRequestDisconnected(ctx context.Context, chid datatransfer.ChannelID) error {
+	if err := te.checkChannel(chid); err != nil {
+		return err
+	}
+	return te.m.OnRequestDisconnected(ctx, chid)
+}
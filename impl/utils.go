@@ -32,6 +32,9 @@ var resumeTransportStatesResponder = statusList{
 
 // newRequest encapsulates message creation
 func (m *manager) newRequest(ctx context.Context, selector ipld.Node, isPull bool, voucher datatransfer.Voucher, baseCid cid.Cid, to peer.ID) (datatransfer.Request, error) {
+	if m.isDraining() {
+		return nil, datatransfer.ErrDraining
+	}
 	next, err := m.storedCounter.Next()
 	if err != nil {
 		return nil, err
@@ -57,6 +60,14 @@ func (m *manager) response(isRestart bool, isNew bool, err error, tid datatransf
 	return message.VoucherResultResponse(tid, isAccepted, isPaused, resultType, voucherResult)
 }
 
+func lastVoucherResult(chst datatransfer.ChannelState) datatransfer.VoucherResult {
+	results := chst.VoucherResults()
+	if len(results) == 0 {
+		return nil
+	}
+	return results[len(results)-1]
+}
+
 func (m *manager) completeResponse(err error, tid datatransfer.TransferID, voucherResult datatransfer.VoucherResult) (datatransfer.Response, error) {
 	isAccepted := err == nil || err == datatransfer.ErrPause
 	isPaused := err == datatransfer.ErrPause
@@ -129,15 +140,18 @@ func (m *manager) decodeVoucherResult(response datatransfer.Response) (datatrans
 	return encodable.(datatransfer.Registerable), nil
 }
 
-func (m *manager) decodeVoucher(request datatransfer.Request, registry *registry.Registry) (datatransfer.Voucher, error) {
+// decodeVoucher decodes the voucher in the request and returns it along with
+// the processor registered for its type. Both come from a single registry
+// lookup so a concurrent unregister cannot separate them
+func (m *manager) decodeVoucher(request datatransfer.Request, registry *registry.Registry) (datatransfer.Voucher, registry.Processor, error) {
 	vtypStr := datatransfer.TypeIdentifier(request.VoucherType())
-	decoder, has := registry.Decoder(vtypStr)
+	decoder, processor, has := registry.Entry(vtypStr)
 	if !has {
-		return nil, xerrors.Errorf("unknown voucher type: %s", vtypStr)
+		return nil, nil, xerrors.Errorf("unknown voucher type: %s", vtypStr)
 	}
 	encodable, err := request.Voucher(decoder)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return encodable.(datatransfer.Registerable), nil
+	return encodable.(datatransfer.Registerable), processor, nil
 }
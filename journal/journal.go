@@ -0,0 +1,243 @@
+package journal
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	"golang.org/x/xerrors"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+	"github.com/filecoin-project/go-data-transfer/encoding"
+)
+
+//go:generate cbor-gen-for --map-encoding storedEntry
+
+// storedEntry is how a journal entry is stored on disk
+type storedEntry struct {
+	Sequence  uint64
+	ChannelID datatransfer.ChannelID
+	Code      int64
+	Message   string
+	// Timestamp is the time of the event in nanoseconds since the unix epoch
+	Timestamp int64
+	Status    datatransfer.Status
+}
+
+// Retention limits how many entries the journal keeps. Entries beyond
+// either limit are removed, oldest first, as new entries are appended.
+// A zero value for a limit means that limit does not apply
+type Retention struct {
+	// MaxEntries is the maximum number of entries to keep
+	MaxEntries uint64
+	// MaxAge is the maximum age of an entry
+	MaxAge time.Duration
+}
+
+// perBlockEvents are emitted for every block a channel moves, so recording
+// them would write to the datastore on the transfer's hot path
+var perBlockEvents = map[datatransfer.EventCode]struct{}{
+	datatransfer.DataReceived: {},
+	datatransfer.DataSent:     {},
+	datatransfer.DataQueued:   {},
+}
+
+// DefaultEvents returns the events a journal records unless told otherwise:
+// every event except the ones emitted for each block transferred
+func DefaultEvents() []datatransfer.EventCode {
+	var events []datatransfer.EventCode
+	for code := range datatransfer.Events {
+		if _, ok := perBlockEvents[code]; !ok {
+			events = append(events, code)
+		}
+	}
+	return events
+}
+
+// Journal is an append-only record of data transfer events, stored in a
+// datastore so that consumers can resume reading after a restart
+type Journal struct {
+	ds        datastore.Datastore
+	retention Retention
+	events    map[datatransfer.EventCode]struct{}
+
+	lk sync.Mutex
+	// first is the sequence number of the oldest retained entry
+	first uint64
+	// firstTimestamp is the timestamp of the oldest retained entry
+	firstTimestamp int64
+	// next is the sequence number the next appended entry will have
+	next uint64
+}
+
+// New opens the journal stored in the given datastore. The journal only
+// records the given events, or the DefaultEvents if none are given
+func New(ds datastore.Datastore, retention Retention, events ...datatransfer.EventCode) (*Journal, error) {
+	if len(events) == 0 {
+		events = DefaultEvents()
+	}
+	j := &Journal{
+		ds:        ds,
+		retention: retention,
+		events:    make(map[datatransfer.EventCode]struct{}, len(events)),
+	}
+	for _, code := range events {
+		j.events[code] = struct{}{}
+	}
+	if err := j.loadBounds(); err != nil {
+		return nil, xerrors.Errorf("loading event journal: %w", err)
+	}
+	return j, nil
+}
+
+func (j *Journal) loadBounds() error {
+	results, err := j.ds.Query(query.Query{KeysOnly: true})
+	if err != nil {
+		return err
+	}
+	defer results.Close()
+	empty := true
+	for result := range results.Next() {
+		if result.Error != nil {
+			return result.Error
+		}
+		sequence, err := strconv.ParseUint(datastore.RawKey(result.Key).BaseNamespace(), 10, 64)
+		if err != nil {
+			return xerrors.Errorf("parsing journal key %s: %w", result.Key, err)
+		}
+		if empty || sequence < j.first {
+			j.first = sequence
+		}
+		if empty || sequence >= j.next {
+			j.next = sequence + 1
+		}
+		empty = false
+	}
+	if empty || j.retention.MaxAge == 0 {
+		return nil
+	}
+	return j.loadFirstTimestamp()
+}
+
+// loadFirstTimestamp reads the timestamp of the oldest retained entry, which
+// is kept in memory so appends only read an entry back when one is removed
+func (j *Journal) loadFirstTimestamp() error {
+	oldest, err := j.get(j.first)
+	if err != nil {
+		return err
+	}
+	j.firstTimestamp = oldest.Timestamp
+	return nil
+}
+
+// sequenceKey zero pads sequence numbers so keys sort in sequence order
+func sequenceKey(sequence uint64) datastore.Key {
+	return datastore.NewKey(fmt.Sprintf("%020d", sequence))
+}
+
+// Append records an event on the given channel, if the journal records
+// events of its type
+func (j *Journal) Append(event datatransfer.Event, channelState datatransfer.ChannelState) error {
+	if _, ok := j.events[event.Code]; !ok {
+		return nil
+	}
+
+	j.lk.Lock()
+	defer j.lk.Unlock()
+
+	entry := storedEntry{
+		Sequence:  j.next,
+		ChannelID: channelState.ChannelID(),
+		Code:      int64(event.Code),
+		Message:   event.Message,
+		Timestamp: event.Timestamp.UnixNano(),
+		Status:    channelState.Status(),
+	}
+	data, err := encoding.Encode(&entry)
+	if err != nil {
+		return xerrors.Errorf("encoding journal entry: %w", err)
+	}
+	if err := j.ds.Put(sequenceKey(entry.Sequence), data); err != nil {
+		return xerrors.Errorf("writing journal entry: %w", err)
+	}
+	if j.first == j.next {
+		j.firstTimestamp = entry.Timestamp
+	}
+	j.next++
+	return j.prune(event.Timestamp)
+}
+
+// prune removes the entries that are beyond the retention limits
+func (j *Journal) prune(now time.Time) error {
+	for j.first < j.next {
+		if j.retention.MaxEntries == 0 || j.next-j.first <= j.retention.MaxEntries {
+			if j.retention.MaxAge == 0 || now.Sub(time.Unix(0, j.firstTimestamp)) <= j.retention.MaxAge {
+				return nil
+			}
+		}
+		if err := j.ds.Delete(sequenceKey(j.first)); err != nil {
+			return xerrors.Errorf("removing journal entry: %w", err)
+		}
+		j.first++
+		if j.retention.MaxAge != 0 && j.first < j.next {
+			if err := j.loadFirstTimestamp(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (j *Journal) get(sequence uint64) (*storedEntry, error) {
+	data, err := j.ds.Get(sequenceKey(sequence))
+	if err != nil {
+		return nil, xerrors.Errorf("reading journal entry %d: %w", sequence, err)
+	}
+	var entry storedEntry
+	if err := entry.UnmarshalCBOR(bytes.NewReader(data)); err != nil {
+		return nil, xerrors.Errorf("decoding journal entry %d: %w", sequence, err)
+	}
+	return &entry, nil
+}
+
+// Read returns up to limit entries, starting at the entry with the sequence
+// number given by the cursor, along with the cursor to read the following
+// entries. If the entry at the cursor is no longer retained, reading starts at
+// the oldest retained entry. A limit of zero or less reads all entries
+func (j *Journal) Read(cursor uint64, limit int) ([]datatransfer.JournalEntry, uint64, error) {
+	j.lk.Lock()
+	defer j.lk.Unlock()
+
+	if cursor < j.first {
+		cursor = j.first
+	}
+	end := j.next
+	if limit > 0 && cursor < end && end-cursor > uint64(limit) {
+		end = cursor + uint64(limit)
+	}
+	var entries []datatransfer.JournalEntry
+	for sequence := cursor; sequence < end; sequence++ {
+		stored, err := j.get(sequence)
+		if err != nil {
+			return nil, cursor, err
+		}
+		entries = append(entries, datatransfer.JournalEntry{
+			Sequence:  stored.Sequence,
+			ChannelID: stored.ChannelID,
+			Event: datatransfer.Event{
+				Code:      datatransfer.EventCode(stored.Code),
+				Message:   stored.Message,
+				Timestamp: time.Unix(0, stored.Timestamp),
+			},
+			Status: stored.Status,
+		})
+	}
+	if end > cursor {
+		cursor = end
+	}
+	return entries, cursor, nil
+}
@@ -0,0 +1,176 @@
+package journal_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	dss "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+	"github.com/filecoin-project/go-data-transfer/journal"
+	"github.com/filecoin-project/go-data-transfer/testutil"
+)
+
+func TestJournal(t *testing.T) {
+	peers := testutil.GeneratePeers(2)
+	chid := datatransfer.ChannelID{Initiator: peers[0], Responder: peers[1], ID: 1}
+	start := time.Unix(1000, 0)
+	event := func(code datatransfer.EventCode, offset time.Duration) datatransfer.Event {
+		return datatransfer.Event{Code: code, Message: datatransfer.Events[code], Timestamp: start.Add(offset)}
+	}
+	channelState := &fakeChannelState{chid: chid, status: datatransfer.Ongoing}
+
+	t.Run("appends and reads from a cursor", func(t *testing.T) {
+		j, err := journal.New(dss.MutexWrap(datastore.NewMapDatastore()), journal.Retention{})
+		require.NoError(t, err)
+		codes := []datatransfer.EventCode{datatransfer.Open, datatransfer.Accept, datatransfer.NewVoucher, datatransfer.Complete}
+		for i, code := range codes {
+			require.NoError(t, j.Append(event(code, time.Duration(i)*time.Second), channelState))
+		}
+
+		entries, cursor, err := j.Read(0, 3)
+		require.NoError(t, err)
+		require.Len(t, entries, 3)
+		require.Equal(t, uint64(3), cursor)
+		for i, entry := range entries {
+			require.Equal(t, uint64(i), entry.Sequence)
+			require.Equal(t, chid, entry.ChannelID)
+			require.Equal(t, codes[i], entry.Event.Code)
+			require.Equal(t, datatransfer.Events[codes[i]], entry.Event.Message)
+			require.True(t, start.Add(time.Duration(i)*time.Second).Equal(entry.Event.Timestamp))
+			require.Equal(t, datatransfer.Ongoing, entry.Status)
+		}
+
+		entries, cursor, err = j.Read(cursor, 3)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		require.Equal(t, datatransfer.Complete, entries[0].Event.Code)
+		require.Equal(t, uint64(4), cursor)
+
+		entries, cursor, err = j.Read(cursor, 3)
+		require.NoError(t, err)
+		require.Empty(t, entries)
+		require.Equal(t, uint64(4), cursor)
+	})
+
+	t.Run("resumes after reopening", func(t *testing.T) {
+		ds := dss.MutexWrap(datastore.NewMapDatastore())
+		j, err := journal.New(ds, journal.Retention{})
+		require.NoError(t, err)
+		require.NoError(t, j.Append(event(datatransfer.Open, 0), channelState))
+		require.NoError(t, j.Append(event(datatransfer.Accept, time.Second), channelState))
+		_, cursor, err := j.Read(0, 1)
+		require.NoError(t, err)
+
+		j, err = journal.New(ds, journal.Retention{})
+		require.NoError(t, err)
+		require.NoError(t, j.Append(event(datatransfer.Complete, 2*time.Second), channelState))
+		entries, cursor, err := j.Read(cursor, 0)
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		require.Equal(t, datatransfer.Accept, entries[0].Event.Code)
+		require.Equal(t, datatransfer.Complete, entries[1].Event.Code)
+		require.Equal(t, uint64(2), entries[1].Sequence)
+		require.Equal(t, uint64(3), cursor)
+	})
+
+	t.Run("keeps at most the maximum number of entries", func(t *testing.T) {
+		j, err := journal.New(dss.MutexWrap(datastore.NewMapDatastore()), journal.Retention{MaxEntries: 2})
+		require.NoError(t, err)
+		for i := 0; i < 5; i++ {
+			require.NoError(t, j.Append(event(datatransfer.NewVoucher, time.Duration(i)*time.Second), channelState))
+		}
+		entries, cursor, err := j.Read(0, 0)
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		require.Equal(t, uint64(3), entries[0].Sequence)
+		require.Equal(t, uint64(4), entries[1].Sequence)
+		require.Equal(t, uint64(5), cursor)
+	})
+
+	t.Run("removes entries older than the maximum age", func(t *testing.T) {
+		j, err := journal.New(dss.MutexWrap(datastore.NewMapDatastore()), journal.Retention{MaxAge: time.Minute})
+		require.NoError(t, err)
+		require.NoError(t, j.Append(event(datatransfer.Open, 0), channelState))
+		require.NoError(t, j.Append(event(datatransfer.Accept, 30*time.Second), channelState))
+		require.NoError(t, j.Append(event(datatransfer.Complete, 90*time.Second), channelState))
+		entries, _, err := j.Read(0, 0)
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		require.Equal(t, datatransfer.Accept, entries[0].Event.Code)
+		require.Equal(t, datatransfer.Complete, entries[1].Event.Code)
+	})
+
+	t.Run("only reads entries back to remove them", func(t *testing.T) {
+		ds := &countingDatastore{Datastore: dss.MutexWrap(datastore.NewMapDatastore())}
+		j, err := journal.New(ds, journal.Retention{MaxAge: time.Minute})
+		require.NoError(t, err)
+		require.NoError(t, j.Append(event(datatransfer.Open, 0), channelState))
+		require.NoError(t, j.Append(event(datatransfer.Accept, 30*time.Second), channelState))
+		require.Zero(t, ds.gets)
+
+		// the oldest timestamp is read once when the journal is reopened
+		j, err = journal.New(ds, journal.Retention{MaxAge: time.Minute})
+		require.NoError(t, err)
+		require.Equal(t, 1, ds.gets)
+		require.NoError(t, j.Append(event(datatransfer.NewVoucher, 45*time.Second), channelState))
+		require.Equal(t, 1, ds.gets)
+		require.NoError(t, j.Append(event(datatransfer.Complete, 90*time.Second), channelState))
+		entries, _, err := j.Read(0, 0)
+		require.NoError(t, err)
+		require.Len(t, entries, 3)
+		require.Equal(t, datatransfer.Accept, entries[0].Event.Code)
+	})
+
+	t.Run("skips events emitted for each block by default", func(t *testing.T) {
+		j, err := journal.New(dss.MutexWrap(datastore.NewMapDatastore()), journal.Retention{})
+		require.NoError(t, err)
+		codes := []datatransfer.EventCode{datatransfer.Open, datatransfer.DataQueued, datatransfer.DataSent, datatransfer.DataReceived, datatransfer.Complete}
+		for i, code := range codes {
+			require.NoError(t, j.Append(event(code, time.Duration(i)*time.Second), channelState))
+		}
+		entries, cursor, err := j.Read(0, 0)
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		require.Equal(t, datatransfer.Open, entries[0].Event.Code)
+		require.Equal(t, datatransfer.Complete, entries[1].Event.Code)
+		require.Equal(t, uint64(2), cursor)
+	})
+
+	t.Run("records only the given events", func(t *testing.T) {
+		j, err := journal.New(dss.MutexWrap(datastore.NewMapDatastore()), journal.Retention{}, datatransfer.DataReceived)
+		require.NoError(t, err)
+		require.NoError(t, j.Append(event(datatransfer.Open, 0), channelState))
+		require.NoError(t, j.Append(event(datatransfer.DataReceived, time.Second), channelState))
+		entries, _, err := j.Read(0, 0)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		require.Equal(t, datatransfer.DataReceived, entries[0].Event.Code)
+	})
+}
+
+type countingDatastore struct {
+	datastore.Datastore
+	gets int
+}
+
+func (c *countingDatastore) Get(key datastore.Key) ([]byte, error) {
+	c.gets++
+	return c.Datastore.Get(key)
+}
+
+type fakeChannelState struct {
+	datatransfer.ChannelState
+	chid   datatransfer.ChannelID
+	status datatransfer.Status
+}
+
+func (f *fakeChannelState) ChannelID() datatransfer.ChannelID {
+	return f.chid
+}
+
+func (f *fakeChannelState) Status() datatransfer.Status {
+	return f.status
+}
@@ -2,14 +2,18 @@ package datatransfer
 
 import (
 	"context"
+	"time"
 
 	"github.com/ipfs/go-cid"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
 	"github.com/ipld/go-ipld-prime"
 	"github.com/libp2p/go-libp2p-core/peer"
 )
 
 // RequestValidator is an interface implemented by the client of the
-// data transfer module to validate requests
+// data transfer module to validate requests. Returning ErrDeferred from
+// ValidatePush or ValidatePull leaves a new request in the Requested state
+// until the client calls AcceptChannel or RejectChannel
 type RequestValidator interface {
 	// ValidatePush validates a push request received from the peer that will send data
 	ValidatePush(
@@ -25,8 +29,37 @@ type RequestValidator interface {
 		selector ipld.Node) (VoucherResult, error)
 }
 
+// StoreSelectingValidator is a RequestValidator that also chooses the store
+// the data for each channel it accepts is served from or received into, so
+// the data for different channels can be kept apart. It is only used with a
+// StoreConfigurableTransport
+type StoreSelectingValidator interface {
+	RequestValidator
+	// SelectStore returns the loader and storer for a channel once its
+	// request is validated. Returning a nil loader and storer uses the
+	// transport's default store
+	SelectStore(chid ChannelID, voucher Voucher) (ipld.Loader, ipld.Storer, error)
+}
+
+// BlockstoreSelectingValidator is a RequestValidator that chooses the
+// blockstore the data for each channel it accepts is served from or received
+// into. It takes precedence over SelectStore for validators that implement
+// both. When the manager stages received blocks, they are committed to the
+// selected blockstore in one batch. It is only used with a
+// StoreConfigurableTransport
+type BlockstoreSelectingValidator interface {
+	RequestValidator
+	// SelectBlockstore returns the blockstore for a channel once its request
+	// is validated. Returning nil uses the transport's default store
+	SelectBlockstore(chid ChannelID, voucher Voucher) (blockstore.Blockstore, error)
+}
+
 // Revalidator is a request validator revalidates in progress requests
-// by requesting request additional vouchers, and resuming when it receives them
+// by requesting request additional vouchers, and resuming when it receives them.
+// A channel is owned by the revalidator registered for the voucher type it was
+// validated or last revalidated with, and revalidation calls for the channel
+// go only to that revalidator. Channels with no owner are offered to each
+// revalidator in turn, and the first to handle a call becomes the owner
 type Revalidator interface {
 	// Revalidate revalidates a request with a new voucher
 	Revalidate(channelID ChannelID, voucher Voucher) (VoucherResult, error)
@@ -57,6 +90,20 @@ type Revalidator interface {
 	OnComplete(chid ChannelID) (bool, VoucherResult, error)
 }
 
+// ValidatorMiddleware wraps the validation of requests and revalidation of
+// in progress channels. Either function may be nil, in which case that stage is
+// passed through unwrapped. A wrapper short-circuits the chain by returning
+// without calling the next validator. Revalidator wrappers typically embed next
+// and override only the methods they care about.
+type ValidatorMiddleware struct {
+	// WrapValidator returns a RequestValidator that runs before the next
+	// validator in the chain for the given voucher type
+	WrapValidator func(voucherType TypeIdentifier, next RequestValidator) RequestValidator
+	// WrapRevalidator returns a Revalidator that runs before the next
+	// revalidator in the chain for the given voucher type
+	WrapRevalidator func(voucherType TypeIdentifier, next Revalidator) Revalidator
+}
+
 // TransportConfigurer provides a mechanism to provide transport specific configuration for a given voucher type
 type TransportConfigurer func(chid ChannelID, voucher Voucher, transport Transport)
 
@@ -76,6 +123,13 @@ type Manager interface {
 	// Stop terminates all data transfers and ends processing
 	Stop(ctx context.Context) error
 
+	// Drain stops accepting new channels, and restarts or resumes from peers,
+	// and waits for the channels in progress to finish until the context is
+	// done. Channels still running
+	// then are paused, and their peers sent pause messages, so they can be
+	// resumed after a restart. Call Stop once Drain returns
+	Drain(ctx context.Context) error
+
 	// RegisterVoucherType registers a validator for the given voucher type
 	// will error if voucher type does not implement voucher
 	// or if there is a voucher type registered with an identical identifier
@@ -88,6 +142,16 @@ type Manager interface {
 	// or a different validator that satisfies the revalidator interface.
 	RegisterRevalidator(voucherType Voucher, revalidator Revalidator) error
 
+	// RegisterValidatorMiddleware registers middleware that wraps the validators
+	// and revalidators for every voucher type. Middleware registered earlier runs
+	// first, and all global middleware runs before voucher type middleware
+	RegisterValidatorMiddleware(middleware ValidatorMiddleware) error
+
+	// RegisterVoucherTypeMiddleware registers middleware that wraps the validator
+	// and revalidator for the given voucher type only. Middleware registered
+	// earlier runs first
+	RegisterVoucherTypeMiddleware(voucherType Voucher, middleware ValidatorMiddleware) error
+
 	// RegisterVoucherResultType allows deserialization of a voucher result,
 	// so that a listener can read the metadata
 	RegisterVoucherResultType(resultType VoucherResult) error
@@ -96,13 +160,65 @@ type Manager interface {
 	// type
 	RegisterTransportConfigurer(voucherType Voucher, configurer TransportConfigurer) error
 
+	// UnregisterVoucherType removes the validator for the given voucher type.
+	// New requests and restarts using the type are rejected from then on, while
+	// channels that already recorded vouchers of the type can still read them
+	UnregisterVoucherType(voucherType TypeIdentifier) error
+
+	// ReplaceVoucherType replaces the validator for a registered voucher type.
+	// The new validator is used for all later validations, including restarts
+	// of channels that were validated by the old one
+	ReplaceVoucherType(voucherType Voucher, validator RequestValidator) error
+
+	// UnregisterRevalidator removes the revalidator for the given voucher type.
+	// In progress channels it was revalidating continue without revalidation,
+	// and voucher updates of the type are rejected
+	UnregisterRevalidator(voucherType TypeIdentifier) error
+
+	// ReplaceRevalidator replaces the revalidator for a registered voucher
+	// type. The new revalidator receives all later revalidation calls,
+	// including those for in progress channels
+	ReplaceRevalidator(voucherType Voucher, revalidator Revalidator) error
+
+	// UnregisterVoucherResultType removes the given voucher result type.
+	// Incoming voucher results of the type are rejected from then on, while
+	// channels that already recorded results of the type can still read them
+	UnregisterVoucherResultType(resultType TypeIdentifier) error
+
+	// ReplaceVoucherResultType replaces the decoding of a registered voucher
+	// result type
+	ReplaceVoucherResultType(resultType VoucherResult) error
+
+	// UnregisterTransportConfigurer removes the transport configurer for the
+	// given voucher type. Channels that were already configured are unaffected
+	UnregisterTransportConfigurer(voucherType TypeIdentifier) error
+
+	// ReplaceTransportConfigurer replaces the transport configurer for a
+	// registered voucher type. It only applies to channels opened, accepted or
+	// restarted afterwards
+	ReplaceTransportConfigurer(voucherType Voucher, configurer TransportConfigurer) error
+
 	// open a data transfer that will send data to the recipient peer and
 	// transfer parts of the piece that match the selector
-	OpenPushDataChannel(ctx context.Context, to peer.ID, voucher Voucher, baseCid cid.Cid, selector ipld.Node) (ChannelID, error)
+	OpenPushDataChannel(ctx context.Context, to peer.ID, voucher Voucher, baseCid cid.Cid, selector ipld.Node, options ...OpenOption) (ChannelID, error)
 
 	// open a data transfer that will request data from the sending peer and
 	// transfer parts of the piece that match the selector
-	OpenPullDataChannel(ctx context.Context, to peer.ID, voucher Voucher, baseCid cid.Cid, selector ipld.Node) (ChannelID, error)
+	OpenPullDataChannel(ctx context.Context, to peer.ID, voucher Voucher, baseCid cid.Cid, selector ipld.Node, options ...OpenOption) (ChannelID, error)
+
+	// OpenPushTransfer opens a push data transfer like OpenPushDataChannel,
+	// and returns a handle that tracks the transfer until it finishes
+	OpenPushTransfer(ctx context.Context, to peer.ID, voucher Voucher, baseCid cid.Cid, selector ipld.Node, options ...OpenOption) (Transfer, error)
+
+	// OpenPullTransfer opens a pull data transfer like OpenPullDataChannel,
+	// and returns a handle that tracks the transfer until it finishes
+	OpenPullTransfer(ctx context.Context, to peer.ID, voucher Voucher, baseCid cid.Cid, selector ipld.Node, options ...OpenOption) (Transfer, error)
+
+	// WaitForChannel waits until the channel is in one of the given statuses,
+	// or in a final status (Completed, Failed or Cancelled) if none are given,
+	// and returns its state. It returns an error if the channel reaches a
+	// final status other than those given, or if the context is cancelled
+	WaitForChannel(ctx context.Context, chid ChannelID, statuses ...Status) (ChannelState, error)
 
 	// send an intermediate voucher as needed when the receiver sends a request for revalidation
 	SendVoucher(ctx context.Context, chid ChannelID, voucher Voucher) error
@@ -116,6 +232,23 @@ type Manager interface {
 	// resume a data transfer channel (only allowed if transport supports it)
 	ResumeDataTransferChannel(ctx context.Context, chid ChannelID) error
 
+	// pause a data transfer channel until the given time, then resume it
+	// automatically (only allowed if transport supports it)
+	PauseDataTransferChannelUntil(ctx context.Context, chid ChannelID, until time.Time) error
+
+	// PausePeer pauses every channel with the given peer that is running on
+	// this node's side, and returns the result for each channel it paused
+	PausePeer(ctx context.Context, p peer.ID) ([]ChannelResult, error)
+
+	// ResumePeer resumes every channel with the given peer that is paused on
+	// this node's side, and returns the result for each channel it resumed
+	ResumePeer(ctx context.Context, p peer.ID) ([]ChannelResult, error)
+
+	// CancelPeer closes every channel with the given peer that has not yet
+	// ended, including pulls shared by several callers, and returns the
+	// result for each channel it closed
+	CancelPeer(ctx context.Context, p peer.ID) ([]ChannelResult, error)
+
 	// get status of a transfer
 	TransferChannelStatus(ctx context.Context, x ChannelID) Status
 
@@ -125,9 +258,48 @@ type Manager interface {
 	// get notified when certain types of events happen
 	SubscribeToEvents(subscriber Subscriber) Unsubscribe
 
+	// SubscribeToFilteredEvents subscribes to the events that pass the given
+	// filters. Events are delivered asynchronously through a bounded queue
+	SubscribeToFilteredEvents(subscriber Subscriber, options ...SubscribeOption) Unsubscribe
+
+	// SubscribeToChannelEvents subscribes to events on a single channel.
+	// Events are delivered asynchronously through a bounded queue
+	SubscribeToChannelEvents(chid ChannelID, subscriber Subscriber, options ...SubscribeOption) Unsubscribe
+
+	// ReadEvents reads up to limit entries from the event journal, starting
+	// at the given cursor, and returns the cursor to continue reading from.
+	// Returns ErrJournalDisabled unless the journal was enabled
+	ReadEvents(ctx context.Context, cursor uint64, limit int) ([]JournalEntry, uint64, error)
+
+	// PeerStats returns the scorecard of channel outcomes for the given peer.
+	// Returns ErrPeerStatsDisabled unless peer stats tracking was enabled
+	PeerStats(ctx context.Context, p peer.ID) (PeerStats, error)
+
+	// ReleasePeerQuota stops counting the given number of bytes received from
+	// the peer towards its receive quota, once that data has been removed.
+	// Returns ErrQuotasDisabled unless receive quotas were enabled
+	ReleasePeerQuota(ctx context.Context, p peer.ID, bytes uint64) error
+
 	// get all in progress transfers
 	InProgressChannels(ctx context.Context) (map[ChannelID]ChannelState, error)
 
 	// RestartDataTransferChannel restarts an existing data transfer channel
 	RestartDataTransferChannel(ctx context.Context, chid ChannelID) error
+
+	// AcceptChannel accepts a request whose validation was deferred, sending
+	// the response to the initiator and starting the transfer. Only the first
+	// call to AcceptChannel or RejectChannel for a channel succeeds
+	AcceptChannel(ctx context.Context, chid ChannelID) error
+
+	// RejectChannel rejects a request whose validation was deferred and
+	// notifies the initiator
+	RejectChannel(ctx context.Context, chid ChannelID) error
+
+	// PeerBans returns the peers currently banned from opening or restarting
+	// channels. Returns ErrUnsupported if the network does not ban peers
+	PeerBans(ctx context.Context) ([]PeerBan, error)
+
+	// ClearPeerBan lifts the ban on the given peer and clears its strikes.
+	// Returns ErrUnsupported if the network does not ban peers
+	ClearPeerBan(ctx context.Context, p peer.ID) error
 }
@@ -12,7 +12,14 @@ import (
 )
 
 var (
+	// ProtocolDataTransfer1_2 is the protocol identifier for graphsync messages
+	// that may carry a trace context, transport name, request signature and
+	// receipt
+	ProtocolDataTransfer1_2 protocol.ID = "/fil/datatransfer/1.2.0"
+
 	// ProtocolDataTransfer1_1 is the protocol identifier for graphsync messages
+	// This protocol does NOT carry trace contexts, transport names or request
+	// signatures, which are dropped from messages sent with it, or receipts.
 	ProtocolDataTransfer1_1 protocol.ID = "/fil/datatransfer/1.1.0"
 
 	// ProtocolDataTransfer1_0 is the protocol identifier for legacy graphsync messages
@@ -59,3 +66,56 @@ type Response interface {
 	VoucherResult(decoder encoding.Decoder) (encoding.Encodable, error)
 	EmptyVoucherResult() bool
 }
+
+// TraceCarrier is implemented by messages that can carry a trace context to
+// the other peer, so that its spans for a channel continue the sender's trace
+type TraceCarrier interface {
+	// TraceContext returns the trace context carried by the message, or nil
+	TraceContext() []byte
+	// WithTraceContext returns a copy of the message carrying the given
+	// trace context
+	WithTraceContext(traceContext []byte) Message
+}
+
+// TransportCarrier is implemented by requests that can name the transport
+// the initiator chose for a channel, so the responder moves the channel's data
+// with the same transport
+type TransportCarrier interface {
+	// TransportName returns the name of the transport the request asks for,
+	// or the empty string for the default transport
+	TransportName() string
+	// WithTransportName returns a copy of the message asking for the named
+	// transport
+	WithTransportName(name string) Message
+}
+
+// SignatureCarrier is implemented by requests that can carry the initiator's
+// signature over the request, so the responder can prove the initiator asked
+// for the channel
+type SignatureCarrier interface {
+	// RequestSignature returns the initiator's signature over the request, or
+	// nil if it is not signed
+	RequestSignature() *RequestSignature
+	// WithRequestSignature returns a copy of the message carrying the given
+	// signature
+	WithRequestSignature(sig *RequestSignature) Message
+	// SignedRequest returns the part of the request for the given channel
+	// that the initiator signs, from the voucher and selector as they are
+	// encoded in the request
+	SignedRequest(chid ChannelID) (SignedRequest, error)
+}
+
+// ReceiptCarrier is implemented by messages that can carry the receipt a
+// receiver signed for the data it received on a channel, which the receiver
+// sends to the sender once the channel completes
+type ReceiptCarrier interface {
+	// Receipt returns the receipt carried by the message, or nil
+	Receipt() *Receipt
+}
+
+// VoucherSizer is implemented by requests that can report the size of their
+// encoded voucher without decoding it
+type VoucherSizer interface {
+	// VoucherSize returns the size in bytes of the encoded voucher
+	VoucherSize() int
+}
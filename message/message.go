@@ -1,6 +1,13 @@
 package message
 
 import (
+	"encoding/json"
+
+	"github.com/libp2p/go-libp2p-core/protocol"
+	"golang.org/x/xerrors"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+	"github.com/filecoin-project/go-data-transfer/message/message1_0"
 	"github.com/filecoin-project/go-data-transfer/message/message1_1"
 )
 
@@ -16,3 +23,24 @@ var UpdateResponse = message1_1.UpdateResponse
 var FromNet = message1_1.FromNet
 var CompleteResponse = message1_1.CompleteResponse
 var CancelRequest = message1_1.CancelRequest
+var ReceiptRequest = message1_1.ReceiptRequest
+var ReceiptResponse = message1_1.ReceiptResponse
+
+// FromJSON decodes a message of any protocol version from the JSON written by
+// its MarshalJSON method
+func FromJSON(data []byte) (datatransfer.Message, error) {
+	var msg struct {
+		Protocol protocol.ID
+	}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, xerrors.Errorf("decoding message: %w", err)
+	}
+	switch msg.Protocol {
+	case datatransfer.ProtocolDataTransfer1_1:
+		return message1_1.FromJSON(data)
+	case datatransfer.ProtocolDataTransfer1_0:
+		return message1_0.FromJSON(data)
+	default:
+		return nil, xerrors.Errorf("unsupported message protocol %q", msg.Protocol)
+	}
+}
@@ -0,0 +1,99 @@
+package message1_0
+
+import (
+	"encoding/json"
+
+	xerrors "golang.org/x/xerrors"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+	"github.com/filecoin-project/go-data-transfer/message/types"
+)
+
+// MarshalJSON encodes the request as a types.MessageJSON
+func (trq *transferRequest) MarshalJSON() ([]byte, error) {
+	selector, err := types.SelectorToJSON(trq.Stor)
+	if err != nil {
+		return nil, err
+	}
+	request := &types.RequestJSON{
+		Type:        types.MessageTypeName(trq.Type),
+		TransferID:  trq.XferID,
+		BaseCid:     trq.BCid,
+		Selector:    selector,
+		Pull:        trq.Pull,
+		Paused:      trq.Paus,
+		Partial:     trq.Part,
+		VoucherType: trq.VTyp,
+		Voucher:     types.DeferredBytes(trq.Vouch),
+	}
+	return json.Marshal(types.MessageJSON{
+		Protocol:  datatransfer.ProtocolDataTransfer1_0,
+		IsRequest: true,
+		Request:   request,
+	})
+}
+
+// MarshalJSON encodes the response as a types.MessageJSON
+func (trsp *transferResponse) MarshalJSON() ([]byte, error) {
+	return json.Marshal(types.MessageJSON{
+		Protocol: datatransfer.ProtocolDataTransfer1_0,
+		Response: &types.ResponseJSON{
+			Type:              types.MessageTypeName(trsp.Type),
+			TransferID:        trsp.XferID,
+			Accepted:          trsp.Acpt,
+			Paused:            trsp.Paus,
+			VoucherResultType: trsp.VTyp,
+			VoucherResult:     types.DeferredBytes(trsp.VRes),
+		},
+	})
+}
+
+// FromJSON decodes a message encoded with MarshalJSON
+func FromJSON(data []byte) (datatransfer.Message, error) {
+	var msg types.MessageJSON
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, err
+	}
+	if msg.Protocol != datatransfer.ProtocolDataTransfer1_0 {
+		return nil, xerrors.Errorf("message is for protocol %s", msg.Protocol)
+	}
+	if (msg.IsRequest && msg.Request == nil) || (!msg.IsRequest && msg.Response == nil) {
+		return nil, xerrors.Errorf("invalid/malformed message")
+	}
+
+	if msg.IsRequest {
+		typ, err := types.ParseMessageTypeName(msg.Request.Type)
+		if err != nil {
+			return nil, err
+		}
+		selector, err := types.SelectorFromJSON(msg.Request.Selector)
+		if err != nil {
+			return nil, err
+		}
+		request := &transferRequest{
+			BCid:   msg.Request.BaseCid,
+			Type:   typ,
+			Paus:   msg.Request.Paused,
+			Part:   msg.Request.Partial,
+			Pull:   msg.Request.Pull,
+			Stor:   selector,
+			Vouch:  types.BytesDeferred(msg.Request.Voucher),
+			VTyp:   msg.Request.VoucherType,
+			XferID: msg.Request.TransferID,
+		}
+		return request, nil
+	}
+
+	typ, err := types.ParseMessageTypeName(msg.Response.Type)
+	if err != nil {
+		return nil, err
+	}
+	return &transferResponse{
+		Type:   typ,
+		Acpt:   msg.Response.Accepted,
+		Paus:   msg.Response.Paused,
+		XferID: msg.Response.TransferID,
+		VRes:   types.BytesDeferred(msg.Response.VoucherResult),
+		VTyp:   msg.Response.VoucherResultType,
+	}, nil
+}
@@ -86,6 +86,14 @@ func (trq *transferRequest) Voucher(decoder encoding.Decoder) (encoding.Encodabl
 	return decoder.DecodeFromCbor(trq.Vouch.Raw)
 }
 
+// VoucherSize returns the size of the encoded voucher
+func (trq *transferRequest) VoucherSize() int {
+	if trq.Vouch == nil {
+		return 0
+	}
+	return len(trq.Vouch.Raw)
+}
+
 func (trq *transferRequest) EmptyVoucher() bool {
 	return trq.VTyp == datatransfer.EmptyTypeIdentifier
 }
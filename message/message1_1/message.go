@@ -164,20 +164,45 @@ func CompleteResponse(id datatransfer.TransferID, isAccepted bool, isPaused bool
 	}, nil
 }
 
+// ReceiptRequest returns a request carrying the receipt the initiator signed
+// for the data it received on a channel
+func ReceiptRequest(id datatransfer.TransferID, receipt *datatransfer.Receipt) datatransfer.Request {
+	return &transferRequest1_1{
+		Type:    uint64(types.ReceiptMessage),
+		XferID:  uint64(id),
+		receipt: receipt,
+	}
+}
+
+// ReceiptResponse returns a response carrying the receipt the responder
+// signed for the data it received on a channel
+func ReceiptResponse(id datatransfer.TransferID, receipt *datatransfer.Receipt) datatransfer.Response {
+	return &transferResponse1_1{
+		Type:    uint64(types.ReceiptMessage),
+		XferID:  uint64(id),
+		receipt: receipt,
+	}
+}
+
 // FromNet can read a network stream to deserialize a GraphSyncMessage
 func FromNet(r io.Reader) (datatransfer.Message, error) {
-	tresp := transferMessage1_1{}
+	tresp := transferMessage1_1Ext{}
 	err := tresp.UnmarshalCBOR(r)
 	if err != nil {
 		return nil, err
 	}
 
-	if (tresp.IsRequest() && tresp.Request == nil) || (!tresp.IsRequest() && tresp.Response == nil) {
+	if (tresp.IsRq && tresp.Request == nil) || (!tresp.IsRq && tresp.Response == nil) {
 		return nil, xerrors.Errorf("invalid/malformed message")
 	}
 
-	if tresp.IsRequest() {
+	if tresp.IsRq {
+		tresp.Request.traceContext = tresp.TrCtx
+		tresp.Request.transport = tresp.Trans
+		tresp.Request.signature = tresp.Sig
+		tresp.Request.receipt = tresp.Rcpt
 		return tresp.Request, nil
 	}
+	tresp.Response.receipt = tresp.Rcpt
 	return tresp.Response, nil
 }
@@ -2,15 +2,20 @@ package message1_1_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"math/rand"
 	"testing"
 
 	basicnode "github.com/ipld/go-ipld-prime/node/basic"
 	"github.com/ipld/go-ipld-prime/traversal/selector/builder"
+	"github.com/libp2p/go-libp2p-core/peer"
+	libp2ptest "github.com/libp2p/go-libp2p-core/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	datatransfer "github.com/filecoin-project/go-data-transfer"
+	"github.com/filecoin-project/go-data-transfer/encoding"
+	"github.com/filecoin-project/go-data-transfer/message"
 	"github.com/filecoin-project/go-data-transfer/message/message1_1"
 	"github.com/filecoin-project/go-data-transfer/testutil"
 )
@@ -349,6 +354,186 @@ func TestToNetFromNetEquivalency(t *testing.T) {
 	require.Equal(t, deserializedRequest.IsRequest(), request.IsRequest())
 }
 
+func TestTraceContextToNetFromNet(t *testing.T) {
+	baseCid := testutil.GenerateCids(1)[0]
+	selector := builder.NewSelectorSpecBuilder(basicnode.Prototype.Any).Matcher().Node()
+	id := datatransfer.TransferID(rand.Int31())
+	voucher := testutil.NewFakeDTType()
+	request, err := message1_1.NewRequest(id, false, false, voucher.Type(), voucher, baseCid, selector)
+	require.NoError(t, err)
+
+	// messages without a trace context are encoded without the field
+	plain := new(bytes.Buffer)
+	require.NoError(t, request.ToNet(plain))
+	require.False(t, bytes.Contains(plain.Bytes(), []byte("TrCtx")))
+	deserialized, err := message1_1.FromNet(plain)
+	require.NoError(t, err)
+	require.Nil(t, deserialized.(datatransfer.TraceCarrier).TraceContext())
+
+	traceContext := []byte("trace context")
+	traced := request.(datatransfer.TraceCarrier).WithTraceContext(traceContext)
+	require.Nil(t, request.(datatransfer.TraceCarrier).TraceContext())
+	buf := new(bytes.Buffer)
+	require.NoError(t, traced.ToNet(buf))
+	deserialized, err = message1_1.FromNet(buf)
+	require.NoError(t, err)
+	deserializedRequest, ok := deserialized.(datatransfer.Request)
+	require.True(t, ok)
+	require.Equal(t, traceContext, deserializedRequest.(datatransfer.TraceCarrier).TraceContext())
+	require.Equal(t, request.TransferID(), deserializedRequest.TransferID())
+	testutil.AssertEqualFakeDTVoucher(t, request, deserializedRequest)
+	testutil.AssertEqualSelector(t, request, deserializedRequest)
+}
+
+func TestTransportNameToNetFromNet(t *testing.T) {
+	baseCid := testutil.GenerateCids(1)[0]
+	selector := builder.NewSelectorSpecBuilder(basicnode.Prototype.Any).Matcher().Node()
+	id := datatransfer.TransferID(rand.Int31())
+	voucher := testutil.NewFakeDTType()
+	request, err := message1_1.NewRequest(id, false, false, voucher.Type(), voucher, baseCid, selector)
+	require.NoError(t, err)
+
+	// messages sent on the 1.1 protocol are encoded without the extended fields
+	traced := request.(datatransfer.TraceCarrier).WithTraceContext([]byte("trace context"))
+	forOldPeer, err := traced.MessageForProtocol(datatransfer.ProtocolDataTransfer1_1)
+	require.NoError(t, err)
+	buf := new(bytes.Buffer)
+	require.NoError(t, forOldPeer.ToNet(buf))
+	require.False(t, bytes.Contains(buf.Bytes(), []byte("TrCtx")))
+	deserialized, err := message1_1.FromNet(buf)
+	require.NoError(t, err)
+	require.Equal(t, "", deserialized.(datatransfer.TransportCarrier).TransportName())
+	require.Nil(t, deserialized.(datatransfer.TraceCarrier).TraceContext())
+
+	named := traced.(datatransfer.TransportCarrier).WithTransportName("other")
+	require.Equal(t, "", traced.(datatransfer.TransportCarrier).TransportName())
+	buf = new(bytes.Buffer)
+	require.NoError(t, named.ToNet(buf))
+	deserialized, err = message1_1.FromNet(buf)
+	require.NoError(t, err)
+	deserializedRequest, ok := deserialized.(datatransfer.Request)
+	require.True(t, ok)
+	require.Equal(t, "other", deserializedRequest.(datatransfer.TransportCarrier).TransportName())
+	require.Equal(t, []byte("trace context"), deserializedRequest.(datatransfer.TraceCarrier).TraceContext())
+	require.Equal(t, request.TransferID(), deserializedRequest.TransferID())
+	testutil.AssertEqualFakeDTVoucher(t, request, deserializedRequest)
+
+	encoded, err := json.Marshal(named)
+	require.NoError(t, err)
+	decoded, err := message1_1.FromJSON(encoded)
+	require.NoError(t, err)
+	require.Equal(t, "other", decoded.(datatransfer.TransportCarrier).TransportName())
+}
+
+func TestRequestSignatureToNetFromNet(t *testing.T) {
+	baseCid := testutil.GenerateCids(1)[0]
+	selector := builder.NewSelectorSpecBuilder(basicnode.Prototype.Any).Matcher().Node()
+	id := datatransfer.TransferID(rand.Int31())
+	voucher := testutil.NewFakeDTType()
+	request, err := message1_1.NewRequest(id, false, false, voucher.Type(), voucher, baseCid, selector)
+	require.NoError(t, err)
+
+	// unsigned requests are encoded without the field
+	buf := new(bytes.Buffer)
+	require.NoError(t, request.ToNet(buf))
+	require.False(t, bytes.Contains(buf.Bytes(), []byte("Sig")))
+	deserialized, err := message1_1.FromNet(buf)
+	require.NoError(t, err)
+	require.Nil(t, deserialized.(datatransfer.SignatureCarrier).RequestSignature())
+
+	sig := &datatransfer.RequestSignature{PublicKey: []byte("public key"), Signature: []byte("signature")}
+	signed := request.(datatransfer.SignatureCarrier).WithRequestSignature(sig)
+	require.Nil(t, request.(datatransfer.SignatureCarrier).RequestSignature())
+	buf = new(bytes.Buffer)
+	require.NoError(t, signed.ToNet(buf))
+	deserialized, err = message1_1.FromNet(buf)
+	require.NoError(t, err)
+	deserializedRequest, ok := deserialized.(datatransfer.Request)
+	require.True(t, ok)
+	require.Equal(t, sig, deserializedRequest.(datatransfer.SignatureCarrier).RequestSignature())
+	require.Equal(t, "", deserializedRequest.(datatransfer.TransportCarrier).TransportName())
+	require.Equal(t, request.TransferID(), deserializedRequest.TransferID())
+	testutil.AssertEqualFakeDTVoucher(t, request, deserializedRequest)
+
+	encoded, err := json.Marshal(signed)
+	require.NoError(t, err)
+	decoded, err := message1_1.FromJSON(encoded)
+	require.NoError(t, err)
+	require.Equal(t, sig, decoded.(datatransfer.SignatureCarrier).RequestSignature())
+}
+
+func TestSignedRequestUsesEncodedFields(t *testing.T) {
+	baseCid := testutil.GenerateCids(1)[0]
+	selector := builder.NewSelectorSpecBuilder(basicnode.Prototype.Any).Matcher().Node()
+	id := datatransfer.TransferID(rand.Int31())
+	voucher := testutil.NewFakeDTType()
+	request, err := message1_1.NewRequest(id, false, true, voucher.Type(), voucher, baseCid, selector)
+	require.NoError(t, err)
+	chid := datatransfer.ChannelID{Initiator: peer.ID("initiator"), Responder: peer.ID("responder"), ID: id}
+
+	signed, err := request.(datatransfer.SignatureCarrier).SignedRequest(chid)
+	require.NoError(t, err)
+	voucherBytes, err := encoding.Encode(voucher)
+	require.NoError(t, err)
+	selectorHash, err := datatransfer.SelectorHash(selector)
+	require.NoError(t, err)
+	require.Equal(t, datatransfer.SignedRequest{
+		ChannelID:    chid,
+		Root:         baseCid,
+		SelectorHash: selectorHash,
+		Pull:         true,
+		VoucherType:  voucher.Type(),
+		Voucher:      voucherBytes,
+	}, signed)
+
+	cancel := message1_1.CancelRequest(id)
+	_, err = cancel.(datatransfer.SignatureCarrier).SignedRequest(chid)
+	require.Error(t, err)
+}
+
+func TestReceiptToNetFromNet(t *testing.T) {
+	id := datatransfer.TransferID(rand.Int31())
+	peers := make([]peer.ID, 2)
+	for i := range peers {
+		var err error
+		peers[i], err = libp2ptest.RandPeerID()
+		require.NoError(t, err)
+	}
+	receipt := &datatransfer.Receipt{
+		ChannelID:    datatransfer.ChannelID{Initiator: peers[0], Responder: peers[1], ID: id},
+		Receiver:     peers[1],
+		Root:         testutil.GenerateCids(1)[0],
+		SelectorHash: []byte("selector hash"),
+		Bytes:        100,
+		Blocks:       2,
+		PublicKey:    []byte("public key"),
+		Signature:    []byte("signature"),
+	}
+	for _, msg := range []datatransfer.Message{message1_1.ReceiptRequest(id, receipt), message1_1.ReceiptResponse(id, receipt)} {
+		buf := new(bytes.Buffer)
+		require.NoError(t, msg.ToNet(buf))
+		deserialized, err := message1_1.FromNet(buf)
+		require.NoError(t, err)
+		require.Equal(t, msg.IsRequest(), deserialized.IsRequest())
+		require.Equal(t, id, deserialized.TransferID())
+		require.Equal(t, receipt, deserialized.(datatransfer.ReceiptCarrier).Receipt())
+
+		encoded, err := json.Marshal(msg)
+		require.NoError(t, err)
+		decoded, err := message1_1.FromJSON(encoded)
+		require.NoError(t, err)
+		require.Equal(t, receipt, decoded.(datatransfer.ReceiptCarrier).Receipt())
+
+		// older protocols cannot carry receipts
+		_, err = msg.MessageForProtocol(datatransfer.ProtocolDataTransfer1_2)
+		require.NoError(t, err)
+		_, err = msg.MessageForProtocol(datatransfer.ProtocolDataTransfer1_1)
+		require.Error(t, err)
+		_, err = msg.MessageForProtocol(datatransfer.ProtocolDataTransfer1_0)
+		require.Error(t, err)
+	}
+}
+
 func TestFromNetMessageValidation(t *testing.T) {
 	// craft request message with nil request struct
 	buf := []byte{0x83, 0xf5, 0xf6, 0xf6}
@@ -371,3 +556,71 @@ func NewTestTransferRequest() (datatransfer.Request, error) {
 	voucher := testutil.NewFakeDTType()
 	return message1_1.NewRequest(id, false, isPull, voucher.Type(), voucher, bcid, selector)
 }
+
+func TestJSON(t *testing.T) {
+	baseCid := testutil.GenerateCids(1)[0]
+	selector := builder.NewSelectorSpecBuilder(basicnode.Prototype.Any).Matcher().Node()
+	id := datatransfer.TransferID(rand.Int31())
+	voucher := &testutil.FakeDTType{Data: "json voucher"}
+	request, err := message1_1.NewRequest(id, false, true, voucher.Type(), voucher, baseCid, selector)
+	require.NoError(t, err)
+	traceContext := []byte("trace context")
+	traced := request.(datatransfer.TraceCarrier).WithTraceContext(traceContext)
+
+	encoded, err := json.Marshal(traced)
+	require.NoError(t, err)
+	require.Contains(t, string(encoded), `"Type":"New"`)
+	decoded, err := message1_1.FromJSON(encoded)
+	require.NoError(t, err)
+	decodedRequest, ok := decoded.(datatransfer.Request)
+	require.True(t, ok)
+	require.Equal(t, request.TransferID(), decodedRequest.TransferID())
+	require.Equal(t, request.IsPull(), decodedRequest.IsPull())
+	require.Equal(t, request.BaseCid(), decodedRequest.BaseCid())
+	require.Equal(t, traceContext, decodedRequest.(datatransfer.TraceCarrier).TraceContext())
+	testutil.AssertEqualFakeDTVoucher(t, request, decodedRequest)
+	testutil.AssertEqualSelector(t, request, decodedRequest)
+
+	peers := make([]peer.ID, 2)
+	for i := range peers {
+		peers[i], err = libp2ptest.RandPeerID()
+		require.NoError(t, err)
+	}
+	chid := datatransfer.ChannelID{Initiator: peers[0], Responder: peers[1], ID: id}
+	restart := message1_1.RestartExistingChannelRequest(chid)
+	encoded, err = json.Marshal(restart)
+	require.NoError(t, err)
+	decoded, err = message.FromJSON(encoded)
+	require.NoError(t, err)
+	restartChannel, err := decoded.(datatransfer.Request).RestartChannelId()
+	require.NoError(t, err)
+	require.Equal(t, chid, restartChannel)
+
+	response, err := message1_1.NewResponse(id, true, false, voucher.Type(), voucher)
+	require.NoError(t, err)
+	encoded, err = json.Marshal(response)
+	require.NoError(t, err)
+	decoded, err = message.FromJSON(encoded)
+	require.NoError(t, err)
+	decodedResponse, ok := decoded.(datatransfer.Response)
+	require.True(t, ok)
+	require.Equal(t, response.TransferID(), decodedResponse.TransferID())
+	require.Equal(t, response.Accepted(), decodedResponse.Accepted())
+	require.True(t, decodedResponse.IsNew())
+	testutil.AssertFakeDTVoucherResult(t, decodedResponse, voucher)
+
+	// messages downgraded to the 1.0 protocol are encoded as 1.0 messages
+	downgraded, err := request.MessageForProtocol(datatransfer.ProtocolDataTransfer1_0)
+	require.NoError(t, err)
+	encoded, err = json.Marshal(downgraded)
+	require.NoError(t, err)
+	require.Contains(t, string(encoded), string(datatransfer.ProtocolDataTransfer1_0))
+	decoded, err = message.FromJSON(encoded)
+	require.NoError(t, err)
+	require.Equal(t, request.TransferID(), decoded.TransferID())
+	_, err = message1_1.FromJSON(encoded)
+	require.Error(t, err)
+
+	_, err = message.FromJSON([]byte(`{"Protocol":"/fil/datatransfer/0.1.0"}`))
+	require.Error(t, err)
+}
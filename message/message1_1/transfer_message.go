@@ -6,7 +6,7 @@ import (
 	datatransfer "github.com/filecoin-project/go-data-transfer"
 )
 
-//go:generate cbor-gen-for --map-encoding transferMessage1_1
+//go:generate cbor-gen-for --map-encoding transferMessage1_1 transferMessage1_1Ext
 
 // transferMessage1_1 is the transfer message for the 1.1 Data Transfer Protocol.
 type transferMessage1_1 struct {
@@ -16,6 +16,23 @@ type transferMessage1_1 struct {
 	Response *transferResponse1_1
 }
 
+// transferMessage1_1Ext is the transfer message for the 1.2 Data Transfer
+// Protocol, which adds an optional trace context, transport name, request
+// signature and receipt to the 1.1 message. 1.1 decoders reject its extra
+// fields, so it is only sent on the 1.2 protocol, and only for messages that
+// carry one of them. It is used to receive messages on both protocols.
+type transferMessage1_1Ext struct {
+	IsRq bool
+
+	Request  *transferRequest1_1
+	Response *transferResponse1_1
+
+	TrCtx []byte
+	Trans string
+	Sig   *datatransfer.RequestSignature
+	Rcpt  *datatransfer.Receipt
+}
+
 // ========= datatransfer.Message interface
 
 // IsRequest returns true if this message is a data request
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 
+	datatransfer "github.com/filecoin-project/go-data-transfer"
 	cbg "github.com/whyrusleeping/cbor-gen"
 	xerrors "golang.org/x/xerrors"
 )
@@ -172,3 +173,315 @@ func (t *transferMessage1_1) UnmarshalCBOR(r io.Reader) error {
 
 	return nil
 }
+func (t *transferMessage1_1Ext) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+	if _, err := w.Write([]byte{167}); err != nil {
+		return err
+	}
+
+	scratch := make([]byte, 9)
+
+	// t.IsRq (bool) (bool)
+	if len("IsRq") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"IsRq\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("IsRq"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("IsRq")); err != nil {
+		return err
+	}
+
+	if err := cbg.WriteBool(w, t.IsRq); err != nil {
+		return err
+	}
+
+	// t.Request (message1_1.transferRequest1_1) (struct)
+	if len("Request") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Request\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("Request"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Request")); err != nil {
+		return err
+	}
+
+	if err := t.Request.MarshalCBOR(w); err != nil {
+		return err
+	}
+
+	// t.Response (message1_1.transferResponse1_1) (struct)
+	if len("Response") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Response\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("Response"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Response")); err != nil {
+		return err
+	}
+
+	if err := t.Response.MarshalCBOR(w); err != nil {
+		return err
+	}
+
+	// t.TrCtx ([]uint8) (slice)
+	if len("TrCtx") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"TrCtx\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("TrCtx"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("TrCtx")); err != nil {
+		return err
+	}
+
+	if len(t.TrCtx) > cbg.ByteArrayMaxLen {
+		return xerrors.Errorf("Byte array in field t.TrCtx was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajByteString, uint64(len(t.TrCtx))); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(t.TrCtx[:]); err != nil {
+		return err
+	}
+
+	// t.Trans (string) (string)
+	if len("Trans") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Trans\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("Trans"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Trans")); err != nil {
+		return err
+	}
+
+	if len(t.Trans) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.Trans was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len(t.Trans))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string(t.Trans)); err != nil {
+		return err
+	}
+
+	// t.Sig (datatransfer.RequestSignature) (struct)
+	if len("Sig") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Sig\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("Sig"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Sig")); err != nil {
+		return err
+	}
+
+	if err := t.Sig.MarshalCBOR(w); err != nil {
+		return err
+	}
+
+	// t.Rcpt (datatransfer.Receipt) (struct)
+	if len("Rcpt") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Rcpt\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("Rcpt"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Rcpt")); err != nil {
+		return err
+	}
+
+	if err := t.Rcpt.MarshalCBOR(w); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (t *transferMessage1_1Ext) UnmarshalCBOR(r io.Reader) error {
+	*t = transferMessage1_1Ext{}
+
+	br := cbg.GetPeeker(r)
+	scratch := make([]byte, 8)
+
+	maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+	if maj != cbg.MajMap {
+		return fmt.Errorf("cbor input should be of type map")
+	}
+
+	if extra > cbg.MaxLength {
+		return fmt.Errorf("transferMessage1_1Ext: map struct too large (%d)", extra)
+	}
+
+	var name string
+	n := extra
+
+	for i := uint64(0); i < n; i++ {
+
+		{
+			sval, err := cbg.ReadStringBuf(br, scratch)
+			if err != nil {
+				return err
+			}
+
+			name = string(sval)
+		}
+
+		switch name {
+		// t.IsRq (bool) (bool)
+		case "IsRq":
+
+			maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+			if err != nil {
+				return err
+			}
+			if maj != cbg.MajOther {
+				return fmt.Errorf("booleans must be major type 7")
+			}
+			switch extra {
+			case 20:
+				t.IsRq = false
+			case 21:
+				t.IsRq = true
+			default:
+				return fmt.Errorf("booleans are either major type 7, value 20 or 21 (got %d)", extra)
+			}
+			// t.Request (message1_1.transferRequest1_1) (struct)
+		case "Request":
+
+			{
+
+				b, err := br.ReadByte()
+				if err != nil {
+					return err
+				}
+				if b != cbg.CborNull[0] {
+					if err := br.UnreadByte(); err != nil {
+						return err
+					}
+					t.Request = new(transferRequest1_1)
+					if err := t.Request.UnmarshalCBOR(br); err != nil {
+						return xerrors.Errorf("unmarshaling t.Request pointer: %w", err)
+					}
+				}
+
+			}
+			// t.Response (message1_1.transferResponse1_1) (struct)
+		case "Response":
+
+			{
+
+				b, err := br.ReadByte()
+				if err != nil {
+					return err
+				}
+				if b != cbg.CborNull[0] {
+					if err := br.UnreadByte(); err != nil {
+						return err
+					}
+					t.Response = new(transferResponse1_1)
+					if err := t.Response.UnmarshalCBOR(br); err != nil {
+						return xerrors.Errorf("unmarshaling t.Response pointer: %w", err)
+					}
+				}
+
+			}
+			// t.TrCtx ([]uint8) (slice)
+		case "TrCtx":
+
+			maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+			if err != nil {
+				return err
+			}
+
+			if extra > cbg.ByteArrayMaxLen {
+				return fmt.Errorf("t.TrCtx: byte array too large (%d)", extra)
+			}
+			if maj != cbg.MajByteString {
+				return fmt.Errorf("expected byte array")
+			}
+
+			if extra > 0 {
+				t.TrCtx = make([]uint8, extra)
+			}
+
+			if _, err := io.ReadFull(br, t.TrCtx[:]); err != nil {
+				return err
+			}
+			// t.Trans (string) (string)
+		case "Trans":
+
+			{
+				sval, err := cbg.ReadStringBuf(br, scratch)
+				if err != nil {
+					return err
+				}
+
+				t.Trans = string(sval)
+			}
+			// t.Sig (datatransfer.RequestSignature) (struct)
+		case "Sig":
+
+			{
+
+				b, err := br.ReadByte()
+				if err != nil {
+					return err
+				}
+				if b != cbg.CborNull[0] {
+					if err := br.UnreadByte(); err != nil {
+						return err
+					}
+					t.Sig = new(datatransfer.RequestSignature)
+					if err := t.Sig.UnmarshalCBOR(br); err != nil {
+						return xerrors.Errorf("unmarshaling t.Sig pointer: %w", err)
+					}
+				}
+
+			}
+			// t.Rcpt (datatransfer.Receipt) (struct)
+		case "Rcpt":
+
+			{
+
+				b, err := br.ReadByte()
+				if err != nil {
+					return err
+				}
+				if b != cbg.CborNull[0] {
+					if err := br.UnreadByte(); err != nil {
+						return err
+					}
+					t.Rcpt = new(datatransfer.Receipt)
+					if err := t.Rcpt.UnmarshalCBOR(br); err != nil {
+						return xerrors.Errorf("unmarshaling t.Rcpt pointer: %w", err)
+					}
+				}
+
+			}
+
+		default:
+			return fmt.Errorf("unknown struct field %d: '%s'", i, name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package message1_1
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+	"github.com/ipld/go-ipld-prime/traversal/selector/builder"
+	"github.com/stretchr/testify/require"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+)
+
+// TestBaselineDecoderReadsProtocol1_1 checks that requests carrying a trace
+// context, transport name and signature can still be read by peers that only
+// know the 1.1 message envelope, as long as they are sent on the 1.1 protocol
+func TestBaselineDecoderReadsProtocol1_1(t *testing.T) {
+	baseCid, err := cid.Parse("bafy2bzacea3wsdh6y3a36tb3skempjoxqpuyompjbmfeyf34fi3uy6uue42v4")
+	require.NoError(t, err)
+	selector := builder.NewSelectorSpecBuilder(basicnode.Prototype.Any).Matcher().Node()
+	id := datatransfer.TransferID(rand.Int31())
+	voucher := basicnode.NewString("voucher")
+	request, err := NewRequest(id, false, true, "StringVoucher", voucher, baseCid, selector)
+	require.NoError(t, err)
+	request = request.(datatransfer.TraceCarrier).WithTraceContext([]byte("trace context")).(datatransfer.Request)
+	request = request.(datatransfer.TransportCarrier).WithTransportName("other").(datatransfer.Request)
+	request = request.(datatransfer.SignatureCarrier).WithRequestSignature(&datatransfer.RequestSignature{
+		PublicKey: []byte("public key"),
+		Signature: []byte("signature"),
+	}).(datatransfer.Request)
+
+	forOldPeer, err := request.MessageForProtocol(datatransfer.ProtocolDataTransfer1_1)
+	require.NoError(t, err)
+	buf := new(bytes.Buffer)
+	require.NoError(t, forOldPeer.ToNet(buf))
+	var baseline transferMessage1_1
+	require.NoError(t, baseline.UnmarshalCBOR(buf))
+	require.True(t, baseline.IsRq)
+	require.Equal(t, uint64(id), baseline.Request.XferID)
+
+	// the original request is unchanged
+	require.Equal(t, "other", request.(datatransfer.TransportCarrier).TransportName())
+
+	forNewPeer, err := request.MessageForProtocol(datatransfer.ProtocolDataTransfer1_2)
+	require.NoError(t, err)
+	buf = new(bytes.Buffer)
+	require.NoError(t, forNewPeer.ToNet(buf))
+	require.Error(t, baseline.UnmarshalCBOR(bytes.NewReader(buf.Bytes())))
+	deserialized, err := FromNet(buf)
+	require.NoError(t, err)
+	require.Equal(t, "other", deserialized.(datatransfer.TransportCarrier).TransportName())
+	require.Equal(t, []byte("trace context"), deserialized.(datatransfer.TraceCarrier).TraceContext())
+}
@@ -34,16 +34,51 @@ type transferRequest1_1 struct {
 	XferID uint64
 
 	RestartChannel datatransfer.ChannelID
+
+	// traceContext is only sent when set, in the extended message envelope
+	// of the 1.2 protocol
+	traceContext []byte
+	// transport is only sent when set, in the extended message envelope of
+	// the 1.2 protocol
+	transport string
+	// signature is only sent when set, in the extended message envelope of
+	// the 1.2 protocol
+	signature *datatransfer.RequestSignature
+	// receipt is only sent in the extended message envelope of the 1.2
+	// protocol
+	receipt *datatransfer.Receipt
+}
+
+// hasExtensions returns true if the request carries any field that is only
+// sent in the extended message envelope
+func (trq *transferRequest1_1) hasExtensions() bool {
+	return trq.traceContext != nil || trq.transport != "" || trq.signature != nil || trq.receipt != nil
 }
 
 func (trq *transferRequest1_1) MessageForProtocol(targetProtocol protocol.ID) (datatransfer.Message, error) {
 	switch targetProtocol {
-	case datatransfer.ProtocolDataTransfer1_1:
+	case datatransfer.ProtocolDataTransfer1_2:
 		return trq, nil
+	case datatransfer.ProtocolDataTransfer1_1:
+		if trq.IsReceipt() {
+			return nil, xerrors.New("receipts not supported on 1.1")
+		}
+		if !trq.hasExtensions() {
+			return trq, nil
+		}
+		// 1.1 peers cannot decode the extended message envelope
+		withoutExtensions := *trq
+		withoutExtensions.traceContext = nil
+		withoutExtensions.transport = ""
+		withoutExtensions.signature = nil
+		return &withoutExtensions, nil
 	case datatransfer.ProtocolDataTransfer1_0:
 		if trq.IsRestart() || trq.IsRestartExistingChannelRequest() {
 			return nil, xerrors.New("restart not supported on 1.0")
 		}
+		if trq.IsReceipt() {
+			return nil, xerrors.New("receipts not supported on 1.0")
+		}
 
 		lreq := message1_0.NewTransferRequest(
 			trq.BCid,
@@ -83,6 +118,11 @@ func (trq *transferRequest1_1) RestartChannelId() (datatransfer.ChannelID, error
 	return trq.RestartChannel, nil
 }
 
+// IsReceipt returns true if the request carries a receipt for a channel
+func (trq *transferRequest1_1) IsReceipt() bool {
+	return trq.Type == uint64(types.ReceiptMessage)
+}
+
 func (trq *transferRequest1_1) IsNew() bool {
 	return trq.Type == uint64(types.NewMessage)
 }
@@ -122,6 +162,14 @@ func (trq *transferRequest1_1) Voucher(decoder encoding.Decoder) (encoding.Encod
 	return decoder.DecodeFromCbor(trq.Vouch.Raw)
 }
 
+// VoucherSize returns the size of the encoded voucher
+func (trq *transferRequest1_1) VoucherSize() int {
+	if trq.Vouch == nil {
+		return 0
+	}
+	return len(trq.Vouch.Raw)
+}
+
 func (trq *transferRequest1_1) EmptyVoucher() bool {
 	return trq.VTyp == datatransfer.EmptyTypeIdentifier
 }
@@ -161,6 +209,17 @@ func (trq *transferRequest1_1) IsPartial() bool {
 // ToNet serializes a transfer request. It's a wrapper for MarshalCBOR to provide
 // symmetry with FromNet
 func (trq *transferRequest1_1) ToNet(w io.Writer) error {
+	if trq.hasExtensions() {
+		msg := transferMessage1_1Ext{
+			IsRq:    true,
+			Request: trq,
+			TrCtx:   trq.traceContext,
+			Trans:   trq.transport,
+			Sig:     trq.signature,
+			Rcpt:    trq.receipt,
+		}
+		return msg.MarshalCBOR(w)
+	}
 	msg := transferMessage1_1{
 		IsRq:     true,
 		Request:  trq,
@@ -168,3 +227,63 @@ func (trq *transferRequest1_1) ToNet(w io.Writer) error {
 	}
 	return msg.MarshalCBOR(w)
 }
+
+// TraceContext returns the trace context carried by the request, or nil
+func (trq *transferRequest1_1) TraceContext() []byte {
+	return trq.traceContext
+}
+
+// WithTraceContext returns a copy of the request carrying the given trace context
+func (trq *transferRequest1_1) WithTraceContext(traceContext []byte) datatransfer.Message {
+	withTrace := *trq
+	withTrace.traceContext = traceContext
+	return &withTrace
+}
+
+// TransportName returns the name of the transport the request asks for, or
+// the empty string for the default transport
+func (trq *transferRequest1_1) TransportName() string {
+	return trq.transport
+}
+
+// WithTransportName returns a copy of the request asking for the named transport
+func (trq *transferRequest1_1) WithTransportName(name string) datatransfer.Message {
+	withTransport := *trq
+	withTransport.transport = name
+	return &withTransport
+}
+
+// RequestSignature returns the initiator's signature over the request, or nil
+func (trq *transferRequest1_1) RequestSignature() *datatransfer.RequestSignature {
+	return trq.signature
+}
+
+// WithRequestSignature returns a copy of the request carrying the given
+// signature
+func (trq *transferRequest1_1) WithRequestSignature(sig *datatransfer.RequestSignature) datatransfer.Message {
+	withSignature := *trq
+	withSignature.signature = sig
+	return &withSignature
+}
+
+// SignedRequest returns the part of the request for the given channel that the
+// initiator signs, from the voucher and selector as they are encoded in the
+// request
+func (trq *transferRequest1_1) SignedRequest(chid datatransfer.ChannelID) (datatransfer.SignedRequest, error) {
+	if trq.Stor == nil || trq.Vouch == nil {
+		return datatransfer.SignedRequest{}, xerrors.New("request has no selector or voucher to sign")
+	}
+	return datatransfer.SignedRequest{
+		ChannelID:    chid,
+		Root:         trq.BaseCid(),
+		SelectorHash: datatransfer.EncodedSelectorHash(trq.Stor.Raw),
+		Pull:         trq.Pull,
+		VoucherType:  trq.VTyp,
+		Voucher:      trq.Vouch.Raw,
+	}, nil
+}
+
+// Receipt returns the receipt carried by the request, or nil
+func (trq *transferRequest1_1) Receipt() *datatransfer.Receipt {
+	return trq.receipt
+}
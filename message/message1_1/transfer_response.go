@@ -24,6 +24,10 @@ type transferResponse1_1 struct {
 	XferID uint64
 	VRes   *cbg.Deferred
 	VTyp   datatransfer.TypeIdentifier
+
+	// receipt is only sent in the extended message envelope of the 1.2
+	// protocol
+	receipt *datatransfer.Receipt
 }
 
 func (trsp *transferResponse1_1) TransferID() datatransfer.TransferID {
@@ -85,15 +89,33 @@ func (trq *transferResponse1_1) IsRestart() bool {
 	return trq.Type == uint64(types.RestartMessage)
 }
 
+// IsReceipt returns true if the response carries a receipt for a channel
+func (trsp *transferResponse1_1) IsReceipt() bool {
+	return trsp.Type == uint64(types.ReceiptMessage)
+}
+
+// Receipt returns the receipt carried by the response, or nil
+func (trsp *transferResponse1_1) Receipt() *datatransfer.Receipt {
+	return trsp.receipt
+}
+
 func (trsp *transferResponse1_1) EmptyVoucherResult() bool {
 	return trsp.VTyp == datatransfer.EmptyTypeIdentifier
 }
 
 func (trsp *transferResponse1_1) MessageForProtocol(targetProtocol protocol.ID) (datatransfer.Message, error) {
 	switch targetProtocol {
+	case datatransfer.ProtocolDataTransfer1_2:
+		return trsp, nil
 	case datatransfer.ProtocolDataTransfer1_1:
+		if trsp.IsReceipt() {
+			return nil, xerrors.New("receipts not supported for 1.1 protocol")
+		}
 		return trsp, nil
 	case datatransfer.ProtocolDataTransfer1_0:
+		if trsp.IsReceipt() {
+			return nil, xerrors.New("receipts not supported for 1.0 protocol")
+		}
 		// this should never happen but dosen't hurt to have this here for sanity
 		if trsp.IsRestart() {
 			return nil, xerrors.New("restart not supported for 1.0 protocol")
@@ -117,6 +139,14 @@ func (trsp *transferResponse1_1) MessageForProtocol(targetProtocol protocol.ID)
 // ToNet serializes a transfer response. It's a wrapper for MarshalCBOR to provide
 // symmetry with FromNet
 func (trsp *transferResponse1_1) ToNet(w io.Writer) error {
+	if trsp.receipt != nil {
+		msg := transferMessage1_1Ext{
+			IsRq:     false,
+			Response: trsp,
+			Rcpt:     trsp.receipt,
+		}
+		return msg.MarshalCBOR(w)
+	}
 	msg := transferMessage1_1{
 		IsRq:     false,
 		Request:  nil,
@@ -0,0 +1,124 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime/codec/dagcbor"
+	"github.com/ipld/go-ipld-prime/codec/dagjson"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+	"github.com/libp2p/go-libp2p-core/protocol"
+	cbg "github.com/whyrusleeping/cbor-gen"
+	"golang.org/x/xerrors"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+)
+
+// MessageJSON is the JSON representation of a message of any protocol version
+type MessageJSON struct {
+	Protocol  protocol.ID
+	IsRequest bool
+	Request   *RequestJSON  `json:",omitempty"`
+	Response  *ResponseJSON `json:",omitempty"`
+}
+
+// RequestJSON is the JSON representation of a request. The selector is
+// encoded as DAG-JSON, and the voucher is kept in its CBOR encoded form
+type RequestJSON struct {
+	Type           string
+	TransferID     uint64
+	BaseCid        *cid.Cid        `json:",omitempty"`
+	Selector       json.RawMessage `json:",omitempty"`
+	Pull           bool
+	Paused         bool
+	Partial        bool
+	VoucherType    datatransfer.TypeIdentifier    `json:",omitempty"`
+	Voucher        []byte                         `json:",omitempty"`
+	RestartChannel *datatransfer.ChannelID        `json:",omitempty"`
+	TraceContext   []byte                         `json:",omitempty"`
+	Transport      string                         `json:",omitempty"`
+	Signature      *datatransfer.RequestSignature `json:",omitempty"`
+	Receipt        *datatransfer.Receipt          `json:",omitempty"`
+}
+
+// ResponseJSON is the JSON representation of a response. The voucher result
+// is kept in its CBOR encoded form
+type ResponseJSON struct {
+	Type              string
+	TransferID        uint64
+	Accepted          bool
+	Paused            bool
+	VoucherResultType datatransfer.TypeIdentifier `json:",omitempty"`
+	VoucherResult     []byte                      `json:",omitempty"`
+	Receipt           *datatransfer.Receipt       `json:",omitempty"`
+}
+
+// MessageTypeName returns the human readable name of a message type
+func MessageTypeName(messageType uint64) string {
+	name, ok := MessageTypes[MessageType(messageType)]
+	if !ok {
+		return fmt.Sprintf("%d", messageType)
+	}
+	return name
+}
+
+// ParseMessageTypeName returns the message type with the given human readable
+// name, as written by MessageTypeName
+func ParseMessageTypeName(name string) (uint64, error) {
+	for messageType, messageTypeName := range MessageTypes {
+		if messageTypeName == name {
+			return uint64(messageType), nil
+		}
+	}
+	return 0, xerrors.Errorf("unknown message type %q", name)
+}
+
+// DeferredBytes returns the raw bytes of a deferred CBOR value, or nil
+func DeferredBytes(deferred *cbg.Deferred) []byte {
+	if deferred == nil {
+		return nil
+	}
+	return deferred.Raw
+}
+
+// BytesDeferred returns a deferred CBOR value for the given raw bytes, or nil
+func BytesDeferred(raw []byte) *cbg.Deferred {
+	if raw == nil {
+		return nil
+	}
+	return &cbg.Deferred{Raw: raw}
+}
+
+// SelectorToJSON converts a CBOR encoded selector to DAG-JSON
+func SelectorToJSON(selector *cbg.Deferred) (json.RawMessage, error) {
+	if selector == nil {
+		return nil, nil
+	}
+	nb := basicnode.Prototype.Any.NewBuilder()
+	if err := dagcbor.Decoder(nb, bytes.NewReader(selector.Raw)); err != nil {
+		return nil, xerrors.Errorf("decoding selector: %w", err)
+	}
+	buf := new(bytes.Buffer)
+	if err := dagjson.Encoder(nb.Build(), buf); err != nil {
+		return nil, xerrors.Errorf("encoding selector: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// SelectorFromJSON converts a DAG-JSON encoded selector to CBOR
+func SelectorFromJSON(selector json.RawMessage) (*cbg.Deferred, error) {
+	if selector == nil {
+		return nil, nil
+	}
+	nb := basicnode.Prototype.Any.NewBuilder()
+	if err := dagjson.Decoder(nb, bytes.NewReader(selector)); err != nil {
+		return nil, xerrors.Errorf("decoding selector: %w", err)
+	}
+	buf := new(bytes.Buffer)
+	if err := dagcbor.Encoder(nb.Build(), buf); err != nil {
+		return nil, xerrors.Errorf("encoding selector: %w", err)
+	}
+	return &cbg.Deferred{Raw: buf.Bytes()}, nil
+}
@@ -13,4 +13,18 @@ const (
 
 	RestartMessage
 	RestartExistingChannelRequestMessage
+	ReceiptMessage
 )
+
+// MessageTypes are human readable names for message types
+var MessageTypes = map[MessageType]string{
+	NewMessage:                           "New",
+	UpdateMessage:                        "Update",
+	CancelMessage:                        "Cancel",
+	CompleteMessage:                      "Complete",
+	VoucherMessage:                       "Voucher",
+	VoucherResultMessage:                 "VoucherResult",
+	RestartMessage:                       "Restart",
+	RestartExistingChannelRequestMessage: "RestartExistingChannelRequest",
+	ReceiptMessage:                       "Receipt",
+}
@@ -46,3 +46,26 @@ type Receiver interface {
 
 	ReceiveError(error)
 }
+
+// PeerBanner is implemented by networks that rate limit requests from peers
+// and temporarily ban peers that misbehave
+type PeerBanner interface {
+	// PeerBans returns the peers that are currently banned
+	PeerBans() []datatransfer.PeerBan
+
+	// ClearPeerBan lifts the ban on the given peer and clears its strikes
+	ClearPeerBan(p peer.ID)
+
+	// AllowRequest returns false if a new or restart request from the peer
+	// should be dropped, because the peer is banned or over its rate limit.
+	// A request over the rate limit counts as a strike against the peer
+	AllowRequest(p peer.ID) bool
+
+	// AddStrike records misbehaviour by the peer, banning it once it collects
+	// the maximum strikes
+	AddStrike(p peer.ID)
+
+	// AddRejection records a request from the peer that was rejected, which
+	// counts towards a strike against the peer
+	AddRejection(p peer.ID)
+}
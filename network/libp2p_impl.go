@@ -15,8 +15,10 @@ import (
 	"golang.org/x/xerrors"
 
 	datatransfer "github.com/filecoin-project/go-data-transfer"
+	"github.com/filecoin-project/go-data-transfer/clock"
 	"github.com/filecoin-project/go-data-transfer/message"
 	"github.com/filecoin-project/go-data-transfer/message/message1_0"
+	"github.com/filecoin-project/go-data-transfer/tracing"
 )
 
 var log = logging.Logger("data_transfer_network")
@@ -39,7 +41,7 @@ const defaultMaxAttemptDuration = 5 * time.Minute
 // The multiplier in the backoff time for each retry
 const defaultBackoffFactor = 5
 
-var defaultDataTransferProtocols = []protocol.ID{datatransfer.ProtocolDataTransfer1_1, datatransfer.ProtocolDataTransfer1_0}
+var defaultDataTransferProtocols = []protocol.ID{datatransfer.ProtocolDataTransfer1_2, datatransfer.ProtocolDataTransfer1_1, datatransfer.ProtocolDataTransfer1_0}
 
 // Option is an option for configuring the libp2p storage market network
 type Option func(*libp2pDataTransferNetwork)
@@ -70,6 +72,66 @@ func RetryParameters(minDuration time.Duration, maxDuration time.Duration, attem
 	}
 }
 
+// Tracer sets the tracer used to record a span for each message sent
+func Tracer(tracer tracing.Tracer) Option {
+	return func(impl *libp2pDataTransferNetwork) {
+		impl.tracer = tracer
+	}
+}
+
+// MaxMessageSize sets the maximum size in bytes of a message received from a
+// peer. The stream a larger message arrives on is reset, and the receiver is
+// sent ErrMessageTooLarge. By default the size is not limited
+func MaxMessageSize(size int64) Option {
+	return func(impl *libp2pDataTransferNetwork) {
+		impl.maxMessageSize = size
+	}
+}
+
+// RequestRateLimit limits the new and restart requests accepted from each peer
+// to perSecond on average, with bursts of up to burst requests. The data
+// transfer manager checks the limit for requests on every transport, and
+// drops requests over it, which count as a strike against the peer
+func RequestRateLimit(perSecond float64, burst int) Option {
+	return func(impl *libp2pDataTransferNetwork) {
+		impl.limiter.rate = perSecond
+		impl.limiter.burst = burst
+	}
+}
+
+// PeerBanPolicy bans a peer from opening or restarting channels for the given
+// duration once it collects maxStrikes strikes. Peers get a strike for each
+// request over the rate limit, each message that cannot be decoded and each
+// request the data transfer manager rejects because the peer misbehaved or
+// broke a request limit. Requests rejected for other reasons, such as by a
+// validator or a quota, count towards a strike as set by RejectionsPerStrike.
+// Strikes expire once a peer goes as long as the ban duration without one
+func PeerBanPolicy(maxStrikes int, banDuration time.Duration) Option {
+	return func(impl *libp2pDataTransferNetwork) {
+		impl.limiter.maxStrikes = maxStrikes
+		impl.limiter.banDuration = banDuration
+	}
+}
+
+// RejectionsPerStrike sets how many requests from a peer the data transfer
+// manager must reject, for reasons other than misbehaviour, before they count
+// as a strike against the peer. Rejections expire like strikes do. Zero stops
+// rejections counting as strikes. The default is one strike per rejection
+func RejectionsPerStrike(rejections int) Option {
+	return func(impl *libp2pDataTransferNetwork) {
+		impl.limiter.rejectionsPerStrike = rejections
+	}
+}
+
+// Clock sets the clock used for stream open backoff and for the request
+// rate limits and peer bans
+func Clock(clk clock.Clock) Option {
+	return func(impl *libp2pDataTransferNetwork) {
+		impl.clock = clk
+		impl.limiter.now = clk.Now
+	}
+}
+
 // NewFromLibp2pHost returns a GraphSyncNetwork supported by underlying Libp2p host.
 func NewFromLibp2pHost(host host.Host, options ...Option) DataTransferNetwork {
 	dataTransferNetwork := libp2pDataTransferNetwork{
@@ -82,6 +144,9 @@ func NewFromLibp2pHost(host host.Host, options ...Option) DataTransferNetwork {
 		maxAttemptDuration:    defaultMaxAttemptDuration,
 		backoffFactor:         defaultBackoffFactor,
 		dtProtocols:           defaultDataTransferProtocols,
+		tracer:                tracing.NoopTracer,
+		limiter:               newPeerLimiter(),
+		clock:                 clock.New(),
 	}
 
 	for _, option := range options {
@@ -105,6 +170,10 @@ type libp2pDataTransferNetwork struct {
 	maxAttemptDuration    time.Duration
 	dtProtocols           []protocol.ID
 	backoffFactor         float64
+	tracer                tracing.Tracer
+	maxMessageSize        int64
+	limiter               *peerLimiter
+	clock                 clock.Clock
 }
 
 func (impl *libp2pDataTransferNetwork) openStream(ctx context.Context, id peer.ID, protocols ...protocol.ID) (network.Stream, error) {
@@ -120,7 +189,7 @@ func (impl *libp2pDataTransferNetwork) openStream(ctx context.Context, id peer.I
 		defer cancel()
 
 		// will use the first among the given protocols that the remote peer supports
-		at := time.Now()
+		at := impl.clock.Now()
 		s, err := impl.host.NewStream(tctx, id, protocols...)
 		if err == nil {
 			return s, err
@@ -134,12 +203,12 @@ func (impl *libp2pDataTransferNetwork) openStream(ctx context.Context, id peer.I
 
 		d := b.Duration()
 		log.Warnf("failed to open stream to %s on attempt %g of %g after %s, waiting %s to try again, err: %s",
-			id, nAttempts, impl.maxStreamOpenAttempts, time.Since(at), d, err)
+			id, nAttempts, impl.maxStreamOpenAttempts, impl.clock.Since(at), d, err)
 
 		select {
 		case <-ctx.Done():
 			return nil, ctx.Err()
-		case <-time.After(d):
+		case <-impl.clock.After(d):
 		}
 	}
 }
@@ -149,10 +218,28 @@ func (dtnet *libp2pDataTransferNetwork) SendMessage(
 	p peer.ID,
 	outgoing datatransfer.Message) error {
 
+	ctx, span := dtnet.tracer.Start(ctx, "send-message")
+	defer span.End()
+	span.SetAttribute("peer", p.String())
+
+	err := dtnet.sendMessage(ctx, span, p, outgoing)
+	if err != nil {
+		span.RecordError(err)
+	}
+	return err
+}
+
+func (dtnet *libp2pDataTransferNetwork) sendMessage(
+	ctx context.Context,
+	span tracing.Span,
+	p peer.ID,
+	outgoing datatransfer.Message) error {
+
 	s, err := dtnet.openStream(ctx, p, dtnet.dtProtocols...)
 	if err != nil {
 		return err
 	}
+	span.SetAttribute("protocol", string(s.Protocol()))
 
 	outgoing, err = outgoing.MessageForProtocol(s.Protocol())
 	if err != nil {
@@ -190,18 +277,32 @@ func (dtnet *libp2pDataTransferNetwork) handleNewStream(s network.Stream) {
 		return
 	}
 
+	var r io.Reader = s
+	var limited *messageLimitReader
+	if dtnet.maxMessageSize > 0 {
+		limited = &messageLimitReader{r: s, limit: dtnet.maxMessageSize}
+		r = limited
+	}
+
 	for {
+		if limited != nil {
+			limited.reset()
+		}
 		var received datatransfer.Message
 		var err error
-		if s.Protocol() == datatransfer.ProtocolDataTransfer1_1 {
-			received, err = message.FromNet(s)
+		if s.Protocol() == datatransfer.ProtocolDataTransfer1_0 {
+			received, err = message1_0.FromNet(r)
 		} else {
-			received, err = message1_0.FromNet(s)
+			received, err = message.FromNet(r)
 		}
 
+		if limited != nil && limited.exceeded {
+			err = xerrors.Errorf("message from %s exceeds %d bytes: %w", s.Conn().RemotePeer(), dtnet.maxMessageSize, datatransfer.ErrMessageTooLarge)
+		}
 		if err != nil {
 			if err != io.EOF {
 				s.Reset() // nolint: errcheck,gosec
+				dtnet.limiter.addStrike(s.Conn().RemotePeer())
 				go dtnet.receiver.ReceiveError(err)
 				log.Debugf("net handleNewStream from %s error: %s", s.Conn().RemotePeer(), err)
 			}
@@ -230,6 +331,26 @@ func (dtnet *libp2pDataTransferNetwork) handleNewStream(s network.Stream) {
 	}
 }
 
+func (dtnet *libp2pDataTransferNetwork) PeerBans() []datatransfer.PeerBan {
+	return dtnet.limiter.PeerBans()
+}
+
+func (dtnet *libp2pDataTransferNetwork) ClearPeerBan(p peer.ID) {
+	dtnet.limiter.ClearPeerBan(p)
+}
+
+func (dtnet *libp2pDataTransferNetwork) AllowRequest(p peer.ID) bool {
+	return dtnet.limiter.allowRequest(p)
+}
+
+func (dtnet *libp2pDataTransferNetwork) AddStrike(p peer.ID) {
+	dtnet.limiter.addStrike(p)
+}
+
+func (dtnet *libp2pDataTransferNetwork) AddRejection(p peer.ID) {
+	dtnet.limiter.addRejection(p)
+}
+
 func (dtnet *libp2pDataTransferNetwork) ID() peer.ID {
 	return dtnet.host.ID()
 }
@@ -256,6 +377,7 @@ func (dtnet *libp2pDataTransferNetwork) msgToStream(ctx context.Context, s netwo
 	}
 
 	switch s.Protocol() {
+	case datatransfer.ProtocolDataTransfer1_2:
 	case datatransfer.ProtocolDataTransfer1_1:
 	case datatransfer.ProtocolDataTransfer1_0:
 	default:
@@ -272,3 +394,33 @@ func (dtnet *libp2pDataTransferNetwork) msgToStream(ctx context.Context, s netwo
 	}
 	return nil
 }
+
+// messageLimitReader limits the number of bytes read for a single message
+type messageLimitReader struct {
+	r         io.Reader
+	limit     int64
+	remaining int64
+	exceeded  bool
+}
+
+// reset starts counting the bytes for a new message
+func (mlr *messageLimitReader) reset() {
+	mlr.remaining = mlr.limit
+	mlr.exceeded = false
+}
+
+func (mlr *messageLimitReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return mlr.r.Read(p)
+	}
+	if mlr.remaining <= 0 {
+		mlr.exceeded = true
+		return 0, datatransfer.ErrMessageTooLarge
+	}
+	if int64(len(p)) > mlr.remaining {
+		p = p[:mlr.remaining]
+	}
+	n, err := mlr.r.Read(p)
+	mlr.remaining -= int64(n)
+	return n, err
+}
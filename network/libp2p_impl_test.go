@@ -19,9 +19,11 @@ import (
 	"golang.org/x/xerrors"
 
 	datatransfer "github.com/filecoin-project/go-data-transfer"
+	"github.com/filecoin-project/go-data-transfer/clock"
 	"github.com/filecoin-project/go-data-transfer/message"
 	"github.com/filecoin-project/go-data-transfer/network"
 	"github.com/filecoin-project/go-data-transfer/testutil"
+	"github.com/filecoin-project/go-data-transfer/tracing"
 )
 
 // Receiver is an interface for receiving messages from the DataTransferNetwork.
@@ -32,6 +34,7 @@ type receiver struct {
 	lastResponse       datatransfer.Response
 	lastSender         peer.ID
 	connectedPeers     chan peer.ID
+	errorReceived      chan error
 }
 
 func (r *receiver) ReceiveRequest(
@@ -59,6 +62,9 @@ func (r *receiver) ReceiveResponse(
 }
 
 func (r *receiver) ReceiveError(err error) {
+	if r.errorReceived != nil {
+		r.errorReceived <- err
+	}
 }
 
 func (r *receiver) ReceiveRestartExistingChannelRequest(ctx context.Context, sender peer.ID, incoming datatransfer.Request) {
@@ -201,6 +207,307 @@ func (w wrappedHost) NewStream(ctx context.Context, p peer.ID, pids ...protocol.
 
 // TestSendMessageRetry verifies that if the number of retry attempts
 // is greater than the number of errors, SendMessage will succeed.
+func TestSendMessageTracing(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	mn := mocknet.New(ctx)
+
+	host1, err := mn.GenPeer()
+	require.NoError(t, err)
+	host2, err := mn.GenPeer()
+	require.NoError(t, err)
+	err = mn.LinkAll()
+	require.NoError(t, err)
+
+	recorder := tracing.NewRecorder()
+	dtnet1 := network.NewFromLibp2pHost(host1, network.Tracer(recorder))
+	dtnet2 := network.NewFromLibp2pHost(host2)
+	r := &receiver{
+		messageReceived: make(chan struct{}),
+		connectedPeers:  make(chan peer.ID, 2),
+	}
+	dtnet1.SetDelegate(r)
+	dtnet2.SetDelegate(r)
+
+	baseCid := testutil.GenerateCids(1)[0]
+	selector := builder.NewSelectorSpecBuilder(basicnode.Prototype.Any).Matcher().Node()
+	id := datatransfer.TransferID(rand.Int31())
+	voucher := testutil.NewFakeDTType()
+	request, err := message.NewRequest(id, false, false, voucher.Type(), voucher, baseCid, selector)
+	require.NoError(t, err)
+
+	parentCtx, parent := recorder.Start(ctx, "parent")
+	traced := request.(datatransfer.TraceCarrier).WithTraceContext(parent.TraceContext())
+	require.NoError(t, dtnet1.SendMessage(parentCtx, host2.ID(), traced))
+
+	select {
+	case <-ctx.Done():
+		t.Fatal("did not receive message sent")
+	case <-r.messageReceived:
+	}
+	require.Equal(t, parent.TraceContext(), r.lastRequest.(datatransfer.TraceCarrier).TraceContext())
+
+	spans := recorder.Named("send-message")
+	require.Len(t, spans, 1)
+	require.Equal(t, recorder.Named("parent")[0].SpanID, spans[0].ParentID)
+	require.Equal(t, host2.ID().String(), spans[0].Attributes["peer"])
+	require.Equal(t, string(datatransfer.ProtocolDataTransfer1_2), spans[0].Attributes["protocol"])
+	require.Empty(t, spans[0].Errors)
+	require.True(t, spans[0].Ended)
+}
+
+func TestSendMessageToProtocol1_1Peer(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	mn := mocknet.New(ctx)
+
+	host1, err := mn.GenPeer()
+	require.NoError(t, err)
+	host2, err := mn.GenPeer()
+	require.NoError(t, err)
+	err = mn.LinkAll()
+	require.NoError(t, err)
+
+	dtnet1 := network.NewFromLibp2pHost(host1)
+	dtnet2 := network.NewFromLibp2pHost(host2, network.DataTransferProtocols([]protocol.ID{
+		datatransfer.ProtocolDataTransfer1_1,
+		datatransfer.ProtocolDataTransfer1_0,
+	}))
+	r := &receiver{
+		messageReceived: make(chan struct{}),
+		connectedPeers:  make(chan peer.ID, 2),
+	}
+	dtnet1.SetDelegate(r)
+	dtnet2.SetDelegate(r)
+
+	baseCid := testutil.GenerateCids(1)[0]
+	selector := builder.NewSelectorSpecBuilder(basicnode.Prototype.Any).Matcher().Node()
+	id := datatransfer.TransferID(rand.Int31())
+	voucher := testutil.NewFakeDTType()
+	request, err := message.NewRequest(id, false, false, voucher.Type(), voucher, baseCid, selector)
+	require.NoError(t, err)
+	traced := request.(datatransfer.TraceCarrier).WithTraceContext([]byte("trace context"))
+	require.NoError(t, dtnet1.SendMessage(ctx, host2.ID(), traced))
+
+	select {
+	case <-ctx.Done():
+		t.Fatal("did not receive message sent")
+	case <-r.messageReceived:
+	}
+	require.Equal(t, id, r.lastRequest.TransferID())
+	require.Nil(t, r.lastRequest.(datatransfer.TraceCarrier).TraceContext())
+}
+
+func TestMaxMessageSize(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	mn := mocknet.New(ctx)
+
+	host1, err := mn.GenPeer()
+	require.NoError(t, err)
+	host2, err := mn.GenPeer()
+	require.NoError(t, err)
+	err = mn.LinkAll()
+	require.NoError(t, err)
+
+	dtnet1 := network.NewFromLibp2pHost(host1)
+	dtnet2 := network.NewFromLibp2pHost(host2, network.MaxMessageSize(512))
+	r := &receiver{
+		messageReceived: make(chan struct{}),
+		connectedPeers:  make(chan peer.ID, 2),
+		errorReceived:   make(chan error, 1),
+	}
+	dtnet1.SetDelegate(r)
+	dtnet2.SetDelegate(r)
+
+	baseCid := testutil.GenerateCids(1)[0]
+	selector := builder.NewSelectorSpecBuilder(basicnode.Prototype.Any).Matcher().Node()
+	id := datatransfer.TransferID(rand.Int31())
+
+	small := &testutil.FakeDTType{Data: "small voucher"}
+	request, err := message.NewRequest(id, false, false, small.Type(), small, baseCid, selector)
+	require.NoError(t, err)
+	require.NoError(t, dtnet1.SendMessage(ctx, host2.ID(), request))
+	select {
+	case <-ctx.Done():
+		t.Fatal("did not receive message sent")
+	case <-r.messageReceived:
+	case err := <-r.errorReceived:
+		t.Fatalf("received error: %s", err)
+	}
+	require.Equal(t, id, r.lastRequest.TransferID())
+
+	large := &testutil.FakeDTType{Data: string(testutil.RandomBytes(1024))}
+	request, err = message.NewRequest(id+1, false, false, large.Type(), large, baseCid, selector)
+	require.NoError(t, err)
+	_ = dtnet1.SendMessage(ctx, host2.ID(), request)
+	select {
+	case <-ctx.Done():
+		t.Fatal("did not receive error")
+	case <-r.messageReceived:
+		t.Fatal("received message larger than the maximum size")
+	case err := <-r.errorReceived:
+		require.True(t, xerrors.Is(err, datatransfer.ErrMessageTooLarge))
+	}
+
+	// later messages are still received
+	request, err = message.NewRequest(id+2, false, false, small.Type(), small, baseCid, selector)
+	require.NoError(t, err)
+	require.NoError(t, dtnet1.SendMessage(ctx, host2.ID(), request))
+	select {
+	case <-ctx.Done():
+		t.Fatal("did not receive message sent")
+	case <-r.messageReceived:
+	case err := <-r.errorReceived:
+		t.Fatalf("received error: %s", err)
+	}
+	require.Equal(t, id+2, r.lastRequest.TransferID())
+}
+
+func TestRequestRateLimit(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	mn := mocknet.New(ctx)
+
+	host1, err := mn.GenPeer()
+	require.NoError(t, err)
+
+	clk := clock.NewMock()
+	dtnet := network.NewFromLibp2pHost(host1, network.RequestRateLimit(0.001, 2), network.Clock(clk))
+	banner := dtnet.(network.PeerBanner)
+	p := testutil.GeneratePeers(1)[0]
+
+	require.True(t, banner.AllowRequest(p))
+	require.True(t, banner.AllowRequest(p))
+
+	// the bucket is empty, so further requests are dropped
+	require.False(t, banner.AllowRequest(p))
+
+	// rate limiting alone never bans peers
+	require.Empty(t, banner.PeerBans())
+
+	// the bucket refills over time
+	clk.Add(1000 * time.Second)
+	require.True(t, banner.AllowRequest(p))
+}
+
+func TestPeerBans(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	mn := mocknet.New(ctx)
+
+	host1, err := mn.GenPeer()
+	require.NoError(t, err)
+	host2, err := mn.GenPeer()
+	require.NoError(t, err)
+	err = mn.LinkAll()
+	require.NoError(t, err)
+
+	dtnet1 := network.NewFromLibp2pHost(host1)
+	dtnet2 := network.NewFromLibp2pHost(host2, network.PeerBanPolicy(3, time.Hour))
+	r := &receiver{
+		messageReceived: make(chan struct{}),
+		connectedPeers:  make(chan peer.ID, 2),
+		errorReceived:   make(chan error, 1),
+	}
+	dtnet1.SetDelegate(r)
+	dtnet2.SetDelegate(r)
+	banner := dtnet2.(network.PeerBanner)
+
+	baseCid := testutil.GenerateCids(1)[0]
+	selector := builder.NewSelectorSpecBuilder(basicnode.Prototype.Any).Matcher().Node()
+	voucher := testutil.NewFakeDTType()
+	id := datatransfer.TransferID(rand.Int31())
+
+	// the network does not strike peers for the rejections it sends, since
+	// the data transfer manager reports the requests it rejects
+	request, err := message.NewRequest(id, false, false, voucher.Type(), voucher, baseCid, selector)
+	require.NoError(t, err)
+	require.NoError(t, dtnet1.SendMessage(ctx, host2.ID(), request))
+	select {
+	case <-ctx.Done():
+		t.Fatal("did not receive message sent")
+	case <-r.messageReceived:
+	}
+	for i := 0; i < 3; i++ {
+		rejection, err := message.NewResponse(id, false, false, datatransfer.EmptyTypeIdentifier, nil)
+		require.NoError(t, err)
+		require.NoError(t, dtnet2.SendMessage(ctx, host1.ID(), rejection))
+		select {
+		case <-ctx.Done():
+			t.Fatal("did not receive message sent")
+		case <-r.messageReceived:
+		}
+	}
+	require.Empty(t, banner.PeerBans())
+
+	// two reported misbehaviours and a message that cannot be decoded are
+	// strikes
+	banner.AddStrike(host1.ID())
+	banner.AddStrike(host1.ID())
+	require.Empty(t, banner.PeerBans())
+	s, err := host1.NewStream(ctx, host2.ID(), datatransfer.ProtocolDataTransfer1_1)
+	require.NoError(t, err)
+	_, err = s.Write([]byte{0xff, 0xff, 0xff})
+	require.NoError(t, err)
+	_ = s.Close()
+	select {
+	case <-ctx.Done():
+		t.Fatal("did not receive error")
+	case <-r.errorReceived:
+	}
+
+	bans := banner.PeerBans()
+	require.Len(t, bans, 1)
+	require.Equal(t, host1.ID(), bans[0].Peer)
+	require.Equal(t, 3, bans[0].Strikes)
+	require.True(t, bans[0].Until.After(time.Now().Add(59*time.Minute)))
+
+	// banned peers cannot open channels
+	require.False(t, banner.AllowRequest(host1.ID()))
+
+	banner.ClearPeerBan(host1.ID())
+	require.Empty(t, banner.PeerBans())
+	require.True(t, banner.AllowRequest(host1.ID()))
+}
+
+func TestRejectionsPerStrike(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	mn := mocknet.New(ctx)
+	host, err := mn.GenPeer()
+	require.NoError(t, err)
+	p := testutil.GeneratePeers(1)[0]
+
+	// by default each rejection is a strike
+	banner := network.NewFromLibp2pHost(host, network.PeerBanPolicy(2, time.Hour)).(network.PeerBanner)
+	banner.AddRejection(p)
+	require.Empty(t, banner.PeerBans())
+	banner.AddRejection(p)
+	require.Len(t, banner.PeerBans(), 1)
+
+	banner = network.NewFromLibp2pHost(host, network.PeerBanPolicy(2, time.Hour), network.RejectionsPerStrike(2)).(network.PeerBanner)
+	for i := 0; i < 3; i++ {
+		banner.AddRejection(p)
+	}
+	require.Empty(t, banner.PeerBans())
+	banner.AddRejection(p)
+	bans := banner.PeerBans()
+	require.Len(t, bans, 1)
+	require.Equal(t, 2, bans[0].Strikes)
+
+	banner = network.NewFromLibp2pHost(host, network.PeerBanPolicy(1, time.Hour), network.RejectionsPerStrike(0)).(network.PeerBanner)
+	banner.AddRejection(p)
+	require.Empty(t, banner.PeerBans())
+}
+
 func TestSendMessageRetry(t *testing.T) {
 	tcases := []struct {
 		attempts   int
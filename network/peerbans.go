@@ -0,0 +1,216 @@
+package network
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+)
+
+// peerState is the rate limiting and ban state for a single peer
+type peerState struct {
+	tokens        float64
+	lastRefill    time.Time
+	rejections    int
+	lastRejection time.Time
+	strikes       int
+	lastStrike    time.Time
+	bannedAt      int
+	bannedTill    time.Time
+}
+
+// peerLimiter rate limits the new and restart requests received from each
+// peer with a token bucket, and bans peers that collect too many strikes.
+// Strikes are given for requests over the rate limit, messages that cannot
+// be decoded, and misbehaviour the data transfer manager reports. Requests
+// the manager rejects count towards a strike
+type peerLimiter struct {
+	lk    sync.Mutex
+	now   func() time.Time
+	peers map[peer.ID]*peerState
+
+	// requests per second and burst size, rate limiting is off if zero
+	rate  float64
+	burst int
+
+	// strikes before a ban and ban length, banning is off if zero
+	maxStrikes  int
+	banDuration time.Duration
+
+	// rejected requests per strike, rejections are not strikes if zero
+	rejectionsPerStrike int
+}
+
+func newPeerLimiter() *peerLimiter {
+	return &peerLimiter{
+		now:                 time.Now,
+		peers:               make(map[peer.ID]*peerState),
+		rejectionsPerStrike: 1,
+	}
+}
+
+func (pl *peerLimiter) enabled() bool {
+	return pl.rate > 0 || pl.maxStrikes > 0
+}
+
+// allowRequest returns false if a new or restart request from the peer should
+// be dropped, because the peer is banned or over its rate limit
+func (pl *peerLimiter) allowRequest(p peer.ID) bool {
+	if !pl.enabled() {
+		return true
+	}
+	pl.lk.Lock()
+	defer pl.lk.Unlock()
+
+	now := pl.now()
+	ps := pl.peerState(p, now)
+	if pl.banned(ps, now) {
+		return false
+	}
+	if pl.rate <= 0 {
+		return true
+	}
+	ps.tokens += now.Sub(ps.lastRefill).Seconds() * pl.rate
+	if ps.tokens > float64(pl.burst) {
+		ps.tokens = float64(pl.burst)
+	}
+	ps.lastRefill = now
+	if ps.tokens < 1 {
+		pl.strike(p, ps, now)
+		return false
+	}
+	ps.tokens--
+	return true
+}
+
+// addStrike records misbehaviour by the peer, banning it once it reaches
+// the maximum strikes
+func (pl *peerLimiter) addStrike(p peer.ID) {
+	if pl.maxStrikes <= 0 {
+		return
+	}
+	pl.lk.Lock()
+	defer pl.lk.Unlock()
+	now := pl.now()
+	ps := pl.peerState(p, now)
+	if pl.banned(ps, now) {
+		return
+	}
+	pl.strike(p, ps, now)
+}
+
+// addRejection records a rejected request from the peer, giving it a strike
+// once it collects rejectionsPerStrike rejections
+func (pl *peerLimiter) addRejection(p peer.ID) {
+	if pl.maxStrikes <= 0 || pl.rejectionsPerStrike <= 0 {
+		return
+	}
+	pl.lk.Lock()
+	defer pl.lk.Unlock()
+	now := pl.now()
+	ps := pl.peerState(p, now)
+	if pl.banned(ps, now) {
+		return
+	}
+	// rejections expire like strikes do
+	if now.Sub(ps.lastRejection) > pl.banDuration {
+		ps.rejections = 0
+	}
+	ps.rejections++
+	ps.lastRejection = now
+	if ps.rejections >= pl.rejectionsPerStrike {
+		ps.rejections = 0
+		pl.strike(p, ps, now)
+	}
+}
+
+func (pl *peerLimiter) strike(p peer.ID, ps *peerState, now time.Time) {
+	if pl.maxStrikes <= 0 {
+		return
+	}
+	// strikes expire once the peer behaves for as long as a ban lasts
+	if now.Sub(ps.lastStrike) > pl.banDuration {
+		ps.strikes = 0
+	}
+	ps.strikes++
+	ps.lastStrike = now
+	if ps.strikes >= pl.maxStrikes {
+		log.Warnf("banning peer %s for %s after %d strikes", p, pl.banDuration, ps.strikes)
+		ps.bannedAt = ps.strikes
+		ps.bannedTill = now.Add(pl.banDuration)
+		ps.strikes = 0
+	}
+}
+
+func (pl *peerLimiter) banned(ps *peerState, now time.Time) bool {
+	return now.Before(ps.bannedTill)
+}
+
+func (pl *peerLimiter) peerState(p peer.ID, now time.Time) *peerState {
+	ps, ok := pl.peers[p]
+	if !ok {
+		if len(pl.peers) >= pruneThreshold {
+			pl.prune(now)
+		}
+		ps = &peerState{tokens: float64(pl.burst), lastRefill: now}
+		pl.peers[p] = ps
+	}
+	return ps
+}
+
+// the number of peers tracked before idle peers are pruned
+const pruneThreshold = 1024
+
+// prune forgets peers that are not banned, have no live strikes or
+// rejections and have a full token bucket, since their state is the same as a new peer's
+func (pl *peerLimiter) prune(now time.Time) {
+	for p, ps := range pl.peers {
+		if pl.banned(ps, now) {
+			continue
+		}
+		if ps.strikes > 0 && now.Sub(ps.lastStrike) <= pl.banDuration {
+			continue
+		}
+		if ps.rejections > 0 && now.Sub(ps.lastRejection) <= pl.banDuration {
+			continue
+		}
+		if pl.rate > 0 && ps.tokens+now.Sub(ps.lastRefill).Seconds()*pl.rate < float64(pl.burst) {
+			continue
+		}
+		delete(pl.peers, p)
+	}
+}
+
+// PeerBans returns the peers that are currently banned
+func (pl *peerLimiter) PeerBans() []datatransfer.PeerBan {
+	pl.lk.Lock()
+	defer pl.lk.Unlock()
+	now := pl.now()
+	var bans []datatransfer.PeerBan
+	for p, ps := range pl.peers {
+		if pl.banned(ps, now) {
+			bans = append(bans, datatransfer.PeerBan{Peer: p, Until: ps.bannedTill, Strikes: ps.bannedAt})
+		}
+	}
+	sort.Slice(bans, func(i, j int) bool {
+		return bans[i].Peer < bans[j].Peer
+	})
+	return bans
+}
+
+// ClearPeerBan lifts the ban on the given peer and clears its strikes
+func (pl *peerLimiter) ClearPeerBan(p peer.ID) {
+	pl.lk.Lock()
+	defer pl.lk.Unlock()
+	ps, ok := pl.peers[p]
+	if !ok {
+		return
+	}
+	ps.rejections = 0
+	ps.strikes = 0
+	ps.bannedAt = 0
+	ps.bannedTill = time.Time{}
+}
@@ -0,0 +1,274 @@
+package peerstats
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"golang.org/x/xerrors"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+	"github.com/filecoin-project/go-data-transfer/encoding"
+)
+
+//go:generate cbor-gen-for --map-encoding storedStats
+
+// rateScale is the fixed point scale the success rate is stored with
+const rateScale = 1000000
+
+// DefaultWeight is how much each new channel counts towards the rolling
+// averages when no weight is given
+const DefaultWeight = 0.1
+
+// storedStats is how the stats for a peer are stored on disk
+type storedStats struct {
+	Completed   uint64
+	Failed      uint64
+	Cancelled   uint64
+	Disconnects uint64
+	Restarts    uint64
+	Stalls      uint64
+	// SuccessRate is the rolling success rate in millionths
+	SuccessRate uint64
+	// Throughput is the rolling throughput in bytes per second
+	Throughput uint64
+	// ThroughputSamples counts the completed channels whose throughput was
+	// measured
+	ThroughputSamples uint64
+	// LastSeen is the time of the last activity in nanoseconds since the
+	// unix epoch
+	LastSeen int64
+}
+
+// channelProgress tracks a channel that is in progress, to measure its
+// throughput and detect stalls
+type channelProgress struct {
+	start      time.Time
+	startBytes uint64
+	// checkpointBytes is the bytes moved when the channel was opened or
+	// last restarted
+	checkpointBytes uint64
+}
+
+// Tracker keeps a scorecard for each peer from the events on channels with
+// the peer, stored in a datastore so it survives restarts. Only the stats of
+// peers with channels in progress, or with changes not yet written, are
+// cached; others are read from the datastore when needed
+type Tracker struct {
+	ds     datastore.Datastore
+	weight float64
+
+	lk    sync.Mutex
+	peers map[peer.ID]*storedStats
+	dirty map[peer.ID]struct{}
+	// active counts the channels in progress with each peer
+	active   map[peer.ID]int
+	channels map[datatransfer.ChannelID]*channelProgress
+}
+
+// New opens the peer stats stored in the given datastore. Weight is how much
+// each new channel counts towards the rolling averages, from 0 to 1
+func New(ds datastore.Datastore, weight float64) *Tracker {
+	if weight <= 0 || weight > 1 {
+		weight = DefaultWeight
+	}
+	return &Tracker{
+		ds:       ds,
+		weight:   weight,
+		peers:    make(map[peer.ID]*storedStats),
+		dirty:    make(map[peer.ID]struct{}),
+		active:   make(map[peer.ID]int),
+		channels: make(map[datatransfer.ChannelID]*channelProgress),
+	}
+}
+
+func peerKey(p peer.ID) datastore.Key {
+	return datastore.NewKey(peer.Encode(p))
+}
+
+// load returns the stats for the peer, reading them from the datastore the
+// first time they are needed
+func (t *Tracker) load(p peer.ID) (*storedStats, error) {
+	stats, ok := t.peers[p]
+	if ok {
+		return stats, nil
+	}
+	stats = &storedStats{}
+	data, err := t.ds.Get(peerKey(p))
+	switch err {
+	case nil:
+		if err := stats.UnmarshalCBOR(bytes.NewReader(data)); err != nil {
+			return nil, xerrors.Errorf("decoding stats for peer %s: %w", p, err)
+		}
+	case datastore.ErrNotFound:
+	default:
+		return nil, xerrors.Errorf("reading stats for peer %s: %w", p, err)
+	}
+	t.peers[p] = stats
+	return stats, nil
+}
+
+func (t *Tracker) save(p peer.ID, stats *storedStats) error {
+	data, err := encoding.Encode(stats)
+	if err != nil {
+		return xerrors.Errorf("encoding stats for peer %s: %w", p, err)
+	}
+	if err := t.ds.Put(peerKey(p), data); err != nil {
+		return xerrors.Errorf("writing stats for peer %s: %w", p, err)
+	}
+	delete(t.dirty, p)
+	t.evict(p)
+	return nil
+}
+
+// evict drops the cached stats for the peer if it has no channels in
+// progress and no changes that are not yet written
+func (t *Tracker) evict(p peer.ID) {
+	if t.active[p] > 0 {
+		return
+	}
+	if _, dirty := t.dirty[p]; dirty {
+		return
+	}
+	delete(t.peers, p)
+}
+
+// Record updates the stats for the peer on the other side of the channel.
+// Stats are written to the datastore when a channel ends, is restarted or is
+// disconnected. Other activity is only written by Flush
+func (t *Tracker) Record(event datatransfer.Event, channelState datatransfer.ChannelState) error {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+
+	chid := channelState.ChannelID()
+	p := channelState.OtherPeer()
+	stats, err := t.load(p)
+	if err != nil {
+		return err
+	}
+	moved := channelState.Sent() + channelState.Received()
+	progress, ok := t.channels[chid]
+	if !ok {
+		progress = &channelProgress{start: event.Timestamp, startBytes: moved, checkpointBytes: moved}
+		// events on channels that already ended do not start tracking them
+		// again
+		if !isFinal(channelState.Status()) {
+			t.channels[chid] = progress
+			t.active[p]++
+		}
+	}
+
+	switch event.Code {
+	case datatransfer.Disconnected, datatransfer.Error:
+	default:
+		if event.Timestamp.UnixNano() > stats.LastSeen {
+			stats.LastSeen = event.Timestamp.UnixNano()
+			t.dirty[p] = struct{}{}
+		}
+	}
+
+	switch event.Code {
+	case datatransfer.Restart, datatransfer.Disconnected:
+		if event.Code == datatransfer.Restart {
+			stats.Restarts++
+		} else {
+			stats.Disconnects++
+		}
+		if moved == progress.checkpointBytes {
+			stats.Stalls++
+		}
+		progress.checkpointBytes = moved
+		return t.save(p, stats)
+	case datatransfer.CleanupComplete:
+		if !isFinal(channelState.Status()) {
+			return nil
+		}
+		if _, ok := t.channels[chid]; ok {
+			delete(t.channels, chid)
+			t.active[p]--
+			if t.active[p] == 0 {
+				delete(t.active, p)
+			}
+		}
+		t.recordOutcome(stats, channelState.Status(), moved-progress.startBytes, event.Timestamp.Sub(progress.start))
+		return t.save(p, stats)
+	}
+	return nil
+}
+
+func isFinal(status datatransfer.Status) bool {
+	return status == datatransfer.Completed || status == datatransfer.Failed || status == datatransfer.Cancelled
+}
+
+func (t *Tracker) recordOutcome(stats *storedStats, status datatransfer.Status, moved uint64, elapsed time.Duration) {
+	first := stats.Completed+stats.Failed+stats.Cancelled == 0
+	var success float64
+	switch status {
+	case datatransfer.Completed:
+		stats.Completed++
+		success = rateScale
+	case datatransfer.Failed:
+		stats.Failed++
+	case datatransfer.Cancelled:
+		stats.Cancelled++
+	}
+	if first {
+		stats.SuccessRate = uint64(success)
+	} else {
+		stats.SuccessRate = uint64(t.weight*success + (1-t.weight)*float64(stats.SuccessRate))
+	}
+
+	if status != datatransfer.Completed || moved == 0 || elapsed <= 0 {
+		return
+	}
+	throughput := float64(moved) / elapsed.Seconds()
+	if stats.ThroughputSamples == 0 {
+		stats.Throughput = uint64(throughput)
+	} else {
+		stats.Throughput = uint64(t.weight*throughput + (1-t.weight)*float64(stats.Throughput))
+	}
+	stats.ThroughputSamples++
+}
+
+// Stats returns the stats for the given peer. A peer with no recorded
+// channels has empty stats
+func (t *Tracker) Stats(p peer.ID) (datatransfer.PeerStats, error) {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+
+	stats, err := t.load(p)
+	if err != nil {
+		return datatransfer.PeerStats{}, err
+	}
+	peerStats := datatransfer.PeerStats{
+		Peer:        p,
+		Completed:   stats.Completed,
+		Failed:      stats.Failed,
+		Cancelled:   stats.Cancelled,
+		SuccessRate: float64(stats.SuccessRate) / rateScale,
+		Throughput:  stats.Throughput,
+		Disconnects: stats.Disconnects,
+		Restarts:    stats.Restarts,
+		Stalls:      stats.Stalls,
+	}
+	if stats.LastSeen != 0 {
+		peerStats.LastSeen = time.Unix(0, stats.LastSeen)
+	}
+	t.evict(p)
+	return peerStats, nil
+}
+
+// Flush writes the stats that have changed since they were last written
+func (t *Tracker) Flush() error {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+
+	for p := range t.dirty {
+		if err := t.save(p, t.peers[p]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
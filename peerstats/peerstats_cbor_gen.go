@@ -0,0 +1,395 @@
+// Code generated by github.com/whyrusleeping/cbor-gen. DO NOT EDIT.
+
+package peerstats
+
+import (
+	"fmt"
+	"io"
+
+	cbg "github.com/whyrusleeping/cbor-gen"
+	xerrors "golang.org/x/xerrors"
+)
+
+var _ = xerrors.Errorf
+
+func (t *storedStats) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+	if _, err := w.Write([]byte{170}); err != nil {
+		return err
+	}
+
+	scratch := make([]byte, 9)
+
+	// t.Completed (uint64) (uint64)
+	if len("Completed") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Completed\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("Completed"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Completed")); err != nil {
+		return err
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.Completed)); err != nil {
+		return err
+	}
+
+	// t.Failed (uint64) (uint64)
+	if len("Failed") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Failed\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("Failed"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Failed")); err != nil {
+		return err
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.Failed)); err != nil {
+		return err
+	}
+
+	// t.Cancelled (uint64) (uint64)
+	if len("Cancelled") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Cancelled\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("Cancelled"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Cancelled")); err != nil {
+		return err
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.Cancelled)); err != nil {
+		return err
+	}
+
+	// t.Disconnects (uint64) (uint64)
+	if len("Disconnects") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Disconnects\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("Disconnects"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Disconnects")); err != nil {
+		return err
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.Disconnects)); err != nil {
+		return err
+	}
+
+	// t.Restarts (uint64) (uint64)
+	if len("Restarts") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Restarts\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("Restarts"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Restarts")); err != nil {
+		return err
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.Restarts)); err != nil {
+		return err
+	}
+
+	// t.Stalls (uint64) (uint64)
+	if len("Stalls") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Stalls\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("Stalls"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Stalls")); err != nil {
+		return err
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.Stalls)); err != nil {
+		return err
+	}
+
+	// t.SuccessRate (uint64) (uint64)
+	if len("SuccessRate") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"SuccessRate\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("SuccessRate"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("SuccessRate")); err != nil {
+		return err
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.SuccessRate)); err != nil {
+		return err
+	}
+
+	// t.Throughput (uint64) (uint64)
+	if len("Throughput") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Throughput\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("Throughput"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Throughput")); err != nil {
+		return err
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.Throughput)); err != nil {
+		return err
+	}
+
+	// t.ThroughputSamples (uint64) (uint64)
+	if len("ThroughputSamples") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"ThroughputSamples\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("ThroughputSamples"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("ThroughputSamples")); err != nil {
+		return err
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.ThroughputSamples)); err != nil {
+		return err
+	}
+
+	// t.LastSeen (int64) (int64)
+	if len("LastSeen") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"LastSeen\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("LastSeen"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("LastSeen")); err != nil {
+		return err
+	}
+
+	if t.LastSeen >= 0 {
+		if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.LastSeen)); err != nil {
+			return err
+		}
+	} else {
+		if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajNegativeInt, uint64(-t.LastSeen-1)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *storedStats) UnmarshalCBOR(r io.Reader) error {
+	*t = storedStats{}
+
+	br := cbg.GetPeeker(r)
+	scratch := make([]byte, 8)
+
+	maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+	if maj != cbg.MajMap {
+		return fmt.Errorf("cbor input should be of type map")
+	}
+
+	if extra > cbg.MaxLength {
+		return fmt.Errorf("storedStats: map struct too large (%d)", extra)
+	}
+
+	var name string
+	n := extra
+
+	for i := uint64(0); i < n; i++ {
+
+		{
+			sval, err := cbg.ReadStringBuf(br, scratch)
+			if err != nil {
+				return err
+			}
+
+			name = string(sval)
+		}
+
+		switch name {
+		// t.Completed (uint64) (uint64)
+		case "Completed":
+
+			{
+
+				maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+				if err != nil {
+					return err
+				}
+				if maj != cbg.MajUnsignedInt {
+					return fmt.Errorf("wrong type for uint64 field")
+				}
+				t.Completed = uint64(extra)
+
+			}
+			// t.Failed (uint64) (uint64)
+		case "Failed":
+
+			{
+
+				maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+				if err != nil {
+					return err
+				}
+				if maj != cbg.MajUnsignedInt {
+					return fmt.Errorf("wrong type for uint64 field")
+				}
+				t.Failed = uint64(extra)
+
+			}
+			// t.Cancelled (uint64) (uint64)
+		case "Cancelled":
+
+			{
+
+				maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+				if err != nil {
+					return err
+				}
+				if maj != cbg.MajUnsignedInt {
+					return fmt.Errorf("wrong type for uint64 field")
+				}
+				t.Cancelled = uint64(extra)
+
+			}
+			// t.Disconnects (uint64) (uint64)
+		case "Disconnects":
+
+			{
+
+				maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+				if err != nil {
+					return err
+				}
+				if maj != cbg.MajUnsignedInt {
+					return fmt.Errorf("wrong type for uint64 field")
+				}
+				t.Disconnects = uint64(extra)
+
+			}
+			// t.Restarts (uint64) (uint64)
+		case "Restarts":
+
+			{
+
+				maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+				if err != nil {
+					return err
+				}
+				if maj != cbg.MajUnsignedInt {
+					return fmt.Errorf("wrong type for uint64 field")
+				}
+				t.Restarts = uint64(extra)
+
+			}
+			// t.Stalls (uint64) (uint64)
+		case "Stalls":
+
+			{
+
+				maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+				if err != nil {
+					return err
+				}
+				if maj != cbg.MajUnsignedInt {
+					return fmt.Errorf("wrong type for uint64 field")
+				}
+				t.Stalls = uint64(extra)
+
+			}
+			// t.SuccessRate (uint64) (uint64)
+		case "SuccessRate":
+
+			{
+
+				maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+				if err != nil {
+					return err
+				}
+				if maj != cbg.MajUnsignedInt {
+					return fmt.Errorf("wrong type for uint64 field")
+				}
+				t.SuccessRate = uint64(extra)
+
+			}
+			// t.Throughput (uint64) (uint64)
+		case "Throughput":
+
+			{
+
+				maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+				if err != nil {
+					return err
+				}
+				if maj != cbg.MajUnsignedInt {
+					return fmt.Errorf("wrong type for uint64 field")
+				}
+				t.Throughput = uint64(extra)
+
+			}
+			// t.ThroughputSamples (uint64) (uint64)
+		case "ThroughputSamples":
+
+			{
+
+				maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+				if err != nil {
+					return err
+				}
+				if maj != cbg.MajUnsignedInt {
+					return fmt.Errorf("wrong type for uint64 field")
+				}
+				t.ThroughputSamples = uint64(extra)
+
+			}
+			// t.LastSeen (int64) (int64)
+		case "LastSeen":
+			{
+				maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+				var extraI int64
+				if err != nil {
+					return err
+				}
+				switch maj {
+				case cbg.MajUnsignedInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 positive overflow")
+					}
+				case cbg.MajNegativeInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 negative oveflow")
+					}
+					extraI = -1 - extraI
+				default:
+					return fmt.Errorf("wrong type for int64 field: %d", maj)
+				}
+
+				t.LastSeen = int64(extraI)
+			}
+
+		default:
+			return fmt.Errorf("unknown struct field %d: '%s'", i, name)
+		}
+	}
+
+	return nil
+}
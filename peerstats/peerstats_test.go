@@ -0,0 +1,172 @@
+package peerstats_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	dss "github.com/ipfs/go-datastore/sync"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/require"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+	"github.com/filecoin-project/go-data-transfer/peerstats"
+	"github.com/filecoin-project/go-data-transfer/testutil"
+)
+
+func TestTracker(t *testing.T) {
+	peers := testutil.GeneratePeers(3)
+	start := time.Unix(1000, 0)
+	event := func(code datatransfer.EventCode, offset time.Duration) datatransfer.Event {
+		return datatransfer.Event{Code: code, Message: datatransfer.Events[code], Timestamp: start.Add(offset)}
+	}
+	channel := func(id datatransfer.TransferID) *fakeChannelState {
+		return &fakeChannelState{
+			chid:  datatransfer.ChannelID{Initiator: peers[0], Responder: peers[1], ID: id},
+			other: peers[1],
+		}
+	}
+
+	t.Run("records channel outcomes", func(t *testing.T) {
+		tracker := peerstats.New(dss.MutexWrap(datastore.NewMapDatastore()), 0.5)
+
+		completed := channel(1)
+		require.NoError(t, tracker.Record(event(datatransfer.Open, 0), completed))
+		completed.received = 1000
+		require.NoError(t, tracker.Record(event(datatransfer.DataReceived, time.Second), completed))
+		completed.status = datatransfer.Completed
+		require.NoError(t, tracker.Record(event(datatransfer.CleanupComplete, 2*time.Second), completed))
+
+		stats, err := tracker.Stats(peers[1])
+		require.NoError(t, err)
+		require.Equal(t, peers[1], stats.Peer)
+		require.Equal(t, uint64(1), stats.Completed)
+		require.Equal(t, 1.0, stats.SuccessRate)
+		require.Equal(t, uint64(500), stats.Throughput)
+		require.True(t, start.Add(2*time.Second).Equal(stats.LastSeen))
+
+		failed := channel(2)
+		require.NoError(t, tracker.Record(event(datatransfer.Open, 3*time.Second), failed))
+		failed.status = datatransfer.Failed
+		require.NoError(t, tracker.Record(event(datatransfer.Error, 4*time.Second), failed))
+		require.NoError(t, tracker.Record(event(datatransfer.CleanupComplete, 5*time.Second), failed))
+
+		stats, err = tracker.Stats(peers[1])
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), stats.Completed)
+		require.Equal(t, uint64(1), stats.Failed)
+		require.Equal(t, 0.5, stats.SuccessRate)
+		require.Equal(t, uint64(500), stats.Throughput)
+		require.True(t, start.Add(5*time.Second).Equal(stats.LastSeen))
+
+		// terminal statuses only count once the channel is cleaned up
+		require.NoError(t, tracker.Record(event(datatransfer.NewVoucher, 6*time.Second), failed))
+		stats, err = tracker.Stats(peers[1])
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), stats.Failed)
+	})
+
+	t.Run("counts stalls", func(t *testing.T) {
+		tracker := peerstats.New(dss.MutexWrap(datastore.NewMapDatastore()), 0)
+		chst := channel(1)
+		require.NoError(t, tracker.Record(event(datatransfer.Open, 0), chst))
+		require.NoError(t, tracker.Record(event(datatransfer.Disconnected, time.Second), chst))
+		require.NoError(t, tracker.Record(event(datatransfer.Restart, 2*time.Second), chst))
+		chst.received = 100
+		require.NoError(t, tracker.Record(event(datatransfer.DataReceived, 3*time.Second), chst))
+		require.NoError(t, tracker.Record(event(datatransfer.Restart, 4*time.Second), chst))
+
+		stats, err := tracker.Stats(peers[1])
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), stats.Disconnects)
+		require.Equal(t, uint64(2), stats.Restarts)
+		require.Equal(t, uint64(2), stats.Stalls)
+	})
+
+	t.Run("persists stats", func(t *testing.T) {
+		ds := dss.MutexWrap(datastore.NewMapDatastore())
+		tracker := peerstats.New(ds, 0)
+		chst := channel(1)
+		require.NoError(t, tracker.Record(event(datatransfer.Open, 0), chst))
+		chst.status = datatransfer.Cancelled
+		require.NoError(t, tracker.Record(event(datatransfer.CleanupComplete, time.Second), chst))
+		require.NoError(t, tracker.Record(event(datatransfer.Accept, 2*time.Second), channel(2)))
+
+		stats, err := peerstats.New(ds, 0).Stats(peers[1])
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), stats.Cancelled)
+		require.Equal(t, 0.0, stats.SuccessRate)
+		require.True(t, start.Add(time.Second).Equal(stats.LastSeen))
+
+		// other activity is written when flushed
+		require.NoError(t, tracker.Flush())
+		stats, err = peerstats.New(ds, 0).Stats(peers[1])
+		require.NoError(t, err)
+		require.True(t, start.Add(2*time.Second).Equal(stats.LastSeen))
+	})
+
+	t.Run("reloads peers without channels in progress", func(t *testing.T) {
+		ds := &countingDatastore{Datastore: dss.MutexWrap(datastore.NewMapDatastore())}
+		tracker := peerstats.New(ds, 0.5)
+
+		ongoing := channel(1)
+		require.NoError(t, tracker.Record(event(datatransfer.Open, 0), ongoing))
+		gets := ds.gets
+		_, err := tracker.Stats(peers[1])
+		require.NoError(t, err)
+		require.Equal(t, gets, ds.gets)
+
+		ongoing.status = datatransfer.Completed
+		require.NoError(t, tracker.Record(event(datatransfer.CleanupComplete, time.Second), ongoing))
+		gets = ds.gets
+		stats, err := tracker.Stats(peers[1])
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), stats.Completed)
+		require.Equal(t, gets+1, ds.gets)
+	})
+
+	t.Run("unknown peers have empty stats", func(t *testing.T) {
+		tracker := peerstats.New(dss.MutexWrap(datastore.NewMapDatastore()), 0)
+		stats, err := tracker.Stats(peers[2])
+		require.NoError(t, err)
+		require.Equal(t, datatransfer.PeerStats{Peer: peers[2]}, stats)
+	})
+}
+
+type countingDatastore struct {
+	datastore.Datastore
+	gets int
+}
+
+func (c *countingDatastore) Get(key datastore.Key) ([]byte, error) {
+	c.gets++
+	return c.Datastore.Get(key)
+}
+
+type fakeChannelState struct {
+	datatransfer.ChannelState
+	chid     datatransfer.ChannelID
+	other    peer.ID
+	status   datatransfer.Status
+	received uint64
+}
+
+func (f *fakeChannelState) ChannelID() datatransfer.ChannelID {
+	return f.chid
+}
+
+func (f *fakeChannelState) OtherPeer() peer.ID {
+	return f.other
+}
+
+func (f *fakeChannelState) Status() datatransfer.Status {
+	return f.status
+}
+
+func (f *fakeChannelState) Sent() uint64 {
+	return 0
+}
+
+func (f *fakeChannelState) Received() uint64 {
+	return f.received
+}
@@ -10,6 +10,7 @@ import (
 
 	datatransfer "github.com/filecoin-project/go-data-transfer"
 	"github.com/filecoin-project/go-data-transfer/channels"
+	"github.com/filecoin-project/go-data-transfer/clock"
 )
 
 var log = logging.Logger("dt-pushchanmon")
@@ -38,10 +39,18 @@ type Config struct {
 	ChecksPerInterval      uint32
 	RestartBackoff         time.Duration
 	MaxConsecutiveRestarts uint32
+	// Clock is used for the monitor's timers. The system clock is used if it
+	// is nil
+	Clock clock.Clock
 }
 
 func NewMonitor(mgr monitorAPI, cfg *Config) *Monitor {
 	checkConfig(cfg)
+	if cfg != nil && cfg.Clock == nil {
+		withClock := *cfg
+		withClock.Clock = clock.New()
+		cfg = &withClock
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Monitor{
 		ctx:      ctx,
@@ -128,7 +137,7 @@ func (m *Monitor) run() {
 
 	// Check data-rate ChecksPerInterval times per interval
 	tickInterval := m.cfg.Interval / time.Duration(m.cfg.ChecksPerInterval)
-	ticker := time.NewTicker(tickInterval)
+	ticker := m.cfg.Clock.NewTicker(tickInterval)
 	defer ticker.Stop()
 
 	log.Infof("Starting push channel monitor with "+
@@ -139,7 +148,7 @@ func (m *Monitor) run() {
 		select {
 		case <-m.ctx.Done():
 			return
-		case <-ticker.C:
+		case <-ticker.Chan():
 			m.checkDataRate()
 		}
 	}
@@ -288,13 +297,13 @@ func (mc *monitoredChannel) restartChannel() {
 	mc.restartLk.Lock()
 	restartedAt := mc.restartedAt
 	if restartedAt.IsZero() {
-		mc.restartedAt = time.Now()
+		mc.restartedAt = mc.cfg.Clock.Now()
 	}
 	mc.restartLk.Unlock()
 
 	if !restartedAt.IsZero() {
 		log.Debugf("%s: restart called but already restarting channel (for %s so far; restart backoff is %s)",
-			mc.chid, time.Since(mc.restartedAt), mc.cfg.RestartBackoff)
+			mc.chid, mc.cfg.Clock.Since(mc.restartedAt), mc.cfg.RestartBackoff)
 		return
 	}
 
@@ -327,7 +336,7 @@ func (mc *monitoredChannel) restartChannel() {
 			mc.chid, mc.cfg.RestartBackoff)
 		// Backoff a little time after a restart before attempting another
 		select {
-		case <-time.After(mc.cfg.RestartBackoff):
+		case <-mc.cfg.Clock.After(mc.cfg.RestartBackoff):
 		case <-mc.ctx.Done():
 		}
 
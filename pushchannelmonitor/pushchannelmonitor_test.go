@@ -13,6 +13,7 @@ import (
 	"golang.org/x/xerrors"
 
 	datatransfer "github.com/filecoin-project/go-data-transfer"
+	"github.com/filecoin-project/go-data-transfer/clock"
 )
 
 func TestPushChannelMonitorAutoRestart(t *testing.T) {
@@ -312,6 +313,47 @@ func TestPushChannelMonitorMaxConsecutiveRestarts(t *testing.T) {
 	verifyChannelShutdown(t, mch)
 }
 
+func TestPushChannelMonitorRestartBackoff(t *testing.T) {
+	ch1 := datatransfer.ChannelID{
+		Initiator: "initiator",
+		Responder: "responder",
+		ID:        1,
+	}
+	ch := &mockChannelState{chid: ch1}
+	mockAPI := newMockMonitorAPI(ch, false)
+
+	clk := clock.NewMock()
+	m := NewMonitor(mockAPI, &Config{
+		Interval:               time.Hour,
+		ChecksPerInterval:      1,
+		MinBytesSent:           1,
+		RestartBackoff:         time.Minute,
+		MaxConsecutiveRestarts: 3,
+		Clock:                  clk,
+	})
+
+	// Note: Don't start monitor, we'll call checkDataRate() manually
+
+	m.AddChannel(ch1)
+	mockAPI.dataQueued(10)
+	mockAPI.dataSent(5)
+	m.checkDataRate()
+	m.checkDataRate()
+	require.NoError(t, mockAPI.awaitRestart())
+
+	// No other restart is attempted until the backoff has passed
+	clk.WaitForTimers(1)
+	clk.Add(time.Minute - time.Second)
+	m.checkDataRate()
+	require.Error(t, mockAPI.awaitRestart())
+
+	clk.Add(time.Second)
+	require.Eventually(t, func() bool {
+		m.checkDataRate()
+		return mockAPI.awaitRestart() == nil
+	}, time.Second, time.Millisecond)
+}
+
 func getFirstMonitoredChannel(m *Monitor) *monitoredChannel {
 	var mch *monitoredChannel
 	for mch = range m.channels {
@@ -510,3 +552,23 @@ func (m *mockChannelState) LastVoucherResult() datatransfer.VoucherResult {
 func (m *mockChannelState) ReceivedCids() []cid.Cid {
 	panic("implement me")
 }
+
+func (m *mockChannelState) PausedUntil() time.Time {
+	panic("implement me")
+}
+
+func (m *mockChannelState) TransportName() string {
+	panic("implement me")
+}
+
+func (m *mockChannelState) RequestSignature() *datatransfer.RequestSignature {
+	panic("implement me")
+}
+
+func (m *mockChannelState) SignedRequest() *datatransfer.SignedRequest {
+	panic("implement me")
+}
+
+func (m *mockChannelState) Receipt() *datatransfer.Receipt {
+	panic("implement me")
+}
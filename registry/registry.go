@@ -52,6 +52,36 @@ func (r *Registry) Register(entry datatransfer.Registerable, processor Processor
 	return nil
 }
 
+// Replace replaces the decoder and processor for an entry type that is already
+// registered
+func (r *Registry) Replace(entry datatransfer.Registerable, processor Processor) error {
+	identifier := entry.Type()
+	decoder, err := encoding.NewDecoder(entry)
+	if err != nil {
+		return xerrors.Errorf("replacing entry type %s: %w", identifier, err)
+	}
+	r.registryLk.Lock()
+	defer r.registryLk.Unlock()
+	if _, ok := r.entries[identifier]; !ok {
+		return xerrors.Errorf("identifier not registered: %s", identifier)
+	}
+	r.entries[identifier] = registryEntry{decoder, processor}
+	return nil
+}
+
+// Unregister removes the entry for the given identifier, returning the decoder
+// that was registered for it
+func (r *Registry) Unregister(identifier datatransfer.TypeIdentifier) (encoding.Decoder, error) {
+	r.registryLk.Lock()
+	defer r.registryLk.Unlock()
+	entry, ok := r.entries[identifier]
+	if !ok {
+		return nil, xerrors.Errorf("identifier not registered: %s", identifier)
+	}
+	delete(r.entries, identifier)
+	return entry.decoder, nil
+}
+
 // Decoder gets a decoder for the given identifier
 func (r *Registry) Decoder(identifier datatransfer.TypeIdentifier) (encoding.Decoder, bool) {
 	r.registryLk.RLock()
@@ -68,6 +98,15 @@ func (r *Registry) Processor(identifier datatransfer.TypeIdentifier) (Processor,
 	return entry.processor, has
 }
 
+// Entry gets both the decoder and the processing interface for the given
+// identifier, from the same registration
+func (r *Registry) Entry(identifier datatransfer.TypeIdentifier) (encoding.Decoder, Processor, bool) {
+	r.registryLk.RLock()
+	entry, has := r.entries[identifier]
+	r.registryLk.RUnlock()
+	return entry.decoder, entry.processor, has
+}
+
 // Each iterates through all of the entries in this registry
 func (r *Registry) Each(process func(datatransfer.TypeIdentifier, encoding.Decoder, Processor) error) error {
 	r.registryLk.RLock()
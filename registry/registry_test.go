@@ -39,5 +39,42 @@ func TestRegistry(t *testing.T) {
 		require.False(t, has)
 		require.Nil(t, processor)
 	})
-
+	t.Run("it reads entries", func(t *testing.T) {
+		decoder, processor, has := r.Entry("FakeDTType")
+		require.True(t, has)
+		require.NotNil(t, decoder)
+		require.NotNil(t, processor)
+		decoder, processor, has = r.Entry("OtherType")
+		require.False(t, has)
+		require.Nil(t, decoder)
+		require.Nil(t, processor)
+	})
+	t.Run("it replaces", func(t *testing.T) {
+		err := r.Replace(&testutil.FakeDTType{}, func() {})
+		require.NoError(t, err)
+		processor, has := r.Processor("FakeDTType")
+		require.True(t, has)
+		require.NotNil(t, processor)
+	})
+	t.Run("it errors when replacing an unregistered type", func(t *testing.T) {
+		err := registry.NewRegistry().Replace(&testutil.FakeDTType{}, func() {})
+		require.EqualError(t, err, "identifier not registered: FakeDTType")
+	})
+	t.Run("it unregisters", func(t *testing.T) {
+		decoder, err := r.Unregister("FakeDTType")
+		require.NoError(t, err)
+		require.NotNil(t, decoder)
+		_, has := r.Decoder("FakeDTType")
+		require.False(t, has)
+		_, has = r.Processor("FakeDTType")
+		require.False(t, has)
+	})
+	t.Run("it errors when unregistering an unregistered type", func(t *testing.T) {
+		_, err := r.Unregister("FakeDTType")
+		require.EqualError(t, err, "identifier not registered: FakeDTType")
+	})
+	t.Run("it registers again after unregistering", func(t *testing.T) {
+		err := r.Register(&testutil.FakeDTType{}, func() {})
+		require.NoError(t, err)
+	})
 }
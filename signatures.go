@@ -0,0 +1,154 @@
+package datatransfer
+
+import (
+	"bytes"
+	"crypto/sha256"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-data-transfer/encoding"
+)
+
+//go:generate cbor-gen-for --map-encoding SignedRequest RequestSignature Receipt
+
+// SelectorHash returns the hash that signed requests and receipts use to
+// identify a selector, which is the sha256 digest of its dag-cbor encoding
+func SelectorHash(selector ipld.Node) ([]byte, error) {
+	selBytes, err := encoding.Encode(selector)
+	if err != nil {
+		return nil, err
+	}
+	return EncodedSelectorHash(selBytes), nil
+}
+
+// EncodedSelectorHash returns the hash of a selector from its dag-cbor
+// encoding, as carried in a request
+func EncodedSelectorHash(selBytes []byte) []byte {
+	digest := sha256.Sum256(selBytes)
+	return digest[:]
+}
+
+// SignedRequest is the part of a request that an initiator signs. The voucher
+// and selector hash are taken from the encoded voucher and selector carried in
+// the request, so the signature does not depend on how they are encoded again
+type SignedRequest struct {
+	ChannelID    ChannelID
+	Root         cid.Cid
+	SelectorHash []byte
+	Pull         bool
+	VoucherType  TypeIdentifier
+	Voucher      []byte
+}
+
+// Sign signs the request with the initiator's private key
+func (sr SignedRequest) Sign(key crypto.PrivKey) (*RequestSignature, error) {
+	var buf bytes.Buffer
+	if err := sr.MarshalCBOR(&buf); err != nil {
+		return nil, err
+	}
+	sig, err := key.Sign(buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	pubKey, err := crypto.MarshalPublicKey(key.GetPublic())
+	if err != nil {
+		return nil, err
+	}
+	return &RequestSignature{PublicKey: pubKey, Signature: sig}, nil
+}
+
+// RequestSignature is an initiator's signature over a request. It carries
+// the initiator's public key, so the request can be verified without
+// looking the key up
+type RequestSignature struct {
+	PublicKey []byte
+	Signature []byte
+}
+
+// Verify checks that the signature was made over the request by the
+// initiator of its channel
+func (rs *RequestSignature) Verify(sr SignedRequest) error {
+	pubKey, err := crypto.UnmarshalPublicKey(rs.PublicKey)
+	if err != nil {
+		return xerrors.Errorf("decoding public key: %s: %w", err, ErrInvalidSignature)
+	}
+	var buf bytes.Buffer
+	if err := sr.MarshalCBOR(&buf); err != nil {
+		return err
+	}
+	return verifySignature(pubKey, sr.ChannelID.Initiator, buf.Bytes(), rs.Signature)
+}
+
+// Receipt is a receiver's signed statement that it received the given data
+// on a channel that completed. It carries the receiver's public key, so it can
+// be verified without looking the key up
+type Receipt struct {
+	ChannelID    ChannelID
+	Receiver     peer.ID
+	Root         cid.Cid
+	SelectorHash []byte
+	Bytes        uint64
+	Blocks       uint64
+	PublicKey    []byte
+	Signature    []byte
+}
+
+// signingBytes returns the encoding of the receipt without its signature
+func (r Receipt) signingBytes() ([]byte, error) {
+	r.Signature = nil
+	var buf bytes.Buffer
+	if err := r.MarshalCBOR(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Sign signs the receipt with the receiver's private key
+func (r *Receipt) Sign(key crypto.PrivKey) error {
+	pubKey, err := crypto.MarshalPublicKey(key.GetPublic())
+	if err != nil {
+		return err
+	}
+	r.PublicKey = pubKey
+	data, err := r.signingBytes()
+	if err != nil {
+		return err
+	}
+	sig, err := key.Sign(data)
+	if err != nil {
+		return err
+	}
+	r.Signature = sig
+	return nil
+}
+
+// Verify checks that the receipt was signed by its receiver
+func (r *Receipt) Verify() error {
+	pubKey, err := crypto.UnmarshalPublicKey(r.PublicKey)
+	if err != nil {
+		return xerrors.Errorf("decoding public key: %s: %w", err, ErrInvalidSignature)
+	}
+	data, err := r.signingBytes()
+	if err != nil {
+		return err
+	}
+	return verifySignature(pubKey, r.Receiver, data, r.Signature)
+}
+
+func verifySignature(pubKey crypto.PubKey, signer peer.ID, data []byte, sig []byte) error {
+	if !signer.MatchesPublicKey(pubKey) {
+		return xerrors.Errorf("public key does not belong to %s: %w", signer, ErrInvalidSignature)
+	}
+	ok, err := pubKey.Verify(data, sig)
+	if err != nil {
+		return xerrors.Errorf("verifying signature: %s: %w", err, ErrInvalidSignature)
+	}
+	if !ok {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,773 @@
+// Code generated by github.com/whyrusleeping/cbor-gen. DO NOT EDIT.
+
+package datatransfer
+
+import (
+	"fmt"
+	"io"
+
+	peer "github.com/libp2p/go-libp2p-core/peer"
+	cbg "github.com/whyrusleeping/cbor-gen"
+	xerrors "golang.org/x/xerrors"
+)
+
+var _ = xerrors.Errorf
+
+func (t *SignedRequest) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+	if _, err := w.Write([]byte{166}); err != nil {
+		return err
+	}
+
+	scratch := make([]byte, 9)
+
+	// t.ChannelID (datatransfer.ChannelID) (struct)
+	if len("ChannelID") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"ChannelID\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("ChannelID"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("ChannelID")); err != nil {
+		return err
+	}
+
+	if err := t.ChannelID.MarshalCBOR(w); err != nil {
+		return err
+	}
+
+	// t.Root (cid.Cid) (struct)
+	if len("Root") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Root\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("Root"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Root")); err != nil {
+		return err
+	}
+
+	if err := cbg.WriteCidBuf(scratch, w, t.Root); err != nil {
+		return xerrors.Errorf("failed to write cid field t.Root: %w", err)
+	}
+
+	// t.SelectorHash ([]uint8) (slice)
+	if len("SelectorHash") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"SelectorHash\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("SelectorHash"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("SelectorHash")); err != nil {
+		return err
+	}
+
+	if len(t.SelectorHash) > cbg.ByteArrayMaxLen {
+		return xerrors.Errorf("Byte array in field t.SelectorHash was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajByteString, uint64(len(t.SelectorHash))); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(t.SelectorHash[:]); err != nil {
+		return err
+	}
+
+	// t.Pull (bool) (bool)
+	if len("Pull") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Pull\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("Pull"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Pull")); err != nil {
+		return err
+	}
+
+	if err := cbg.WriteBool(w, t.Pull); err != nil {
+		return err
+	}
+
+	// t.VoucherType (datatransfer.TypeIdentifier) (string)
+	if len("VoucherType") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"VoucherType\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("VoucherType"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("VoucherType")); err != nil {
+		return err
+	}
+
+	if len(t.VoucherType) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.VoucherType was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len(t.VoucherType))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string(t.VoucherType)); err != nil {
+		return err
+	}
+
+	// t.Voucher ([]uint8) (slice)
+	if len("Voucher") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Voucher\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("Voucher"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Voucher")); err != nil {
+		return err
+	}
+
+	if len(t.Voucher) > cbg.ByteArrayMaxLen {
+		return xerrors.Errorf("Byte array in field t.Voucher was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajByteString, uint64(len(t.Voucher))); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(t.Voucher[:]); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (t *SignedRequest) UnmarshalCBOR(r io.Reader) error {
+	*t = SignedRequest{}
+
+	br := cbg.GetPeeker(r)
+	scratch := make([]byte, 8)
+
+	maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+	if maj != cbg.MajMap {
+		return fmt.Errorf("cbor input should be of type map")
+	}
+
+	if extra > cbg.MaxLength {
+		return fmt.Errorf("SignedRequest: map struct too large (%d)", extra)
+	}
+
+	var name string
+	n := extra
+
+	for i := uint64(0); i < n; i++ {
+
+		{
+			sval, err := cbg.ReadStringBuf(br, scratch)
+			if err != nil {
+				return err
+			}
+
+			name = string(sval)
+		}
+
+		switch name {
+		// t.ChannelID (datatransfer.ChannelID) (struct)
+		case "ChannelID":
+
+			{
+
+				if err := t.ChannelID.UnmarshalCBOR(br); err != nil {
+					return xerrors.Errorf("unmarshaling t.ChannelID: %w", err)
+				}
+
+			}
+			// t.Root (cid.Cid) (struct)
+		case "Root":
+
+			{
+
+				c, err := cbg.ReadCid(br)
+				if err != nil {
+					return xerrors.Errorf("failed to read cid field t.Root: %w", err)
+				}
+
+				t.Root = c
+
+			}
+			// t.SelectorHash ([]uint8) (slice)
+		case "SelectorHash":
+
+			maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+			if err != nil {
+				return err
+			}
+
+			if extra > cbg.ByteArrayMaxLen {
+				return fmt.Errorf("t.SelectorHash: byte array too large (%d)", extra)
+			}
+			if maj != cbg.MajByteString {
+				return fmt.Errorf("expected byte array")
+			}
+
+			if extra > 0 {
+				t.SelectorHash = make([]uint8, extra)
+			}
+
+			if _, err := io.ReadFull(br, t.SelectorHash[:]); err != nil {
+				return err
+			}
+			// t.Pull (bool) (bool)
+		case "Pull":
+
+			maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+			if err != nil {
+				return err
+			}
+			if maj != cbg.MajOther {
+				return fmt.Errorf("booleans must be major type 7")
+			}
+			switch extra {
+			case 20:
+				t.Pull = false
+			case 21:
+				t.Pull = true
+			default:
+				return fmt.Errorf("booleans are either major type 7, value 20 or 21 (got %d)", extra)
+			}
+			// t.VoucherType (datatransfer.TypeIdentifier) (string)
+		case "VoucherType":
+
+			{
+				sval, err := cbg.ReadStringBuf(br, scratch)
+				if err != nil {
+					return err
+				}
+
+				t.VoucherType = TypeIdentifier(sval)
+			}
+			// t.Voucher ([]uint8) (slice)
+		case "Voucher":
+
+			maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+			if err != nil {
+				return err
+			}
+
+			if extra > cbg.ByteArrayMaxLen {
+				return fmt.Errorf("t.Voucher: byte array too large (%d)", extra)
+			}
+			if maj != cbg.MajByteString {
+				return fmt.Errorf("expected byte array")
+			}
+
+			if extra > 0 {
+				t.Voucher = make([]uint8, extra)
+			}
+
+			if _, err := io.ReadFull(br, t.Voucher[:]); err != nil {
+				return err
+			}
+
+		default:
+			return fmt.Errorf("unknown struct field %d: '%s'", i, name)
+		}
+	}
+
+	return nil
+}
+func (t *RequestSignature) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+	if _, err := w.Write([]byte{162}); err != nil {
+		return err
+	}
+
+	scratch := make([]byte, 9)
+
+	// t.PublicKey ([]uint8) (slice)
+	if len("PublicKey") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"PublicKey\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("PublicKey"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("PublicKey")); err != nil {
+		return err
+	}
+
+	if len(t.PublicKey) > cbg.ByteArrayMaxLen {
+		return xerrors.Errorf("Byte array in field t.PublicKey was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajByteString, uint64(len(t.PublicKey))); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(t.PublicKey[:]); err != nil {
+		return err
+	}
+
+	// t.Signature ([]uint8) (slice)
+	if len("Signature") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Signature\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("Signature"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Signature")); err != nil {
+		return err
+	}
+
+	if len(t.Signature) > cbg.ByteArrayMaxLen {
+		return xerrors.Errorf("Byte array in field t.Signature was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajByteString, uint64(len(t.Signature))); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(t.Signature[:]); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (t *RequestSignature) UnmarshalCBOR(r io.Reader) error {
+	*t = RequestSignature{}
+
+	br := cbg.GetPeeker(r)
+	scratch := make([]byte, 8)
+
+	maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+	if maj != cbg.MajMap {
+		return fmt.Errorf("cbor input should be of type map")
+	}
+
+	if extra > cbg.MaxLength {
+		return fmt.Errorf("RequestSignature: map struct too large (%d)", extra)
+	}
+
+	var name string
+	n := extra
+
+	for i := uint64(0); i < n; i++ {
+
+		{
+			sval, err := cbg.ReadStringBuf(br, scratch)
+			if err != nil {
+				return err
+			}
+
+			name = string(sval)
+		}
+
+		switch name {
+		// t.PublicKey ([]uint8) (slice)
+		case "PublicKey":
+
+			maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+			if err != nil {
+				return err
+			}
+
+			if extra > cbg.ByteArrayMaxLen {
+				return fmt.Errorf("t.PublicKey: byte array too large (%d)", extra)
+			}
+			if maj != cbg.MajByteString {
+				return fmt.Errorf("expected byte array")
+			}
+
+			if extra > 0 {
+				t.PublicKey = make([]uint8, extra)
+			}
+
+			if _, err := io.ReadFull(br, t.PublicKey[:]); err != nil {
+				return err
+			}
+			// t.Signature ([]uint8) (slice)
+		case "Signature":
+
+			maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+			if err != nil {
+				return err
+			}
+
+			if extra > cbg.ByteArrayMaxLen {
+				return fmt.Errorf("t.Signature: byte array too large (%d)", extra)
+			}
+			if maj != cbg.MajByteString {
+				return fmt.Errorf("expected byte array")
+			}
+
+			if extra > 0 {
+				t.Signature = make([]uint8, extra)
+			}
+
+			if _, err := io.ReadFull(br, t.Signature[:]); err != nil {
+				return err
+			}
+
+		default:
+			return fmt.Errorf("unknown struct field %d: '%s'", i, name)
+		}
+	}
+
+	return nil
+}
+func (t *Receipt) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+	if _, err := w.Write([]byte{168}); err != nil {
+		return err
+	}
+
+	scratch := make([]byte, 9)
+
+	// t.ChannelID (datatransfer.ChannelID) (struct)
+	if len("ChannelID") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"ChannelID\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("ChannelID"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("ChannelID")); err != nil {
+		return err
+	}
+
+	if err := t.ChannelID.MarshalCBOR(w); err != nil {
+		return err
+	}
+
+	// t.Receiver (peer.ID) (string)
+	if len("Receiver") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Receiver\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("Receiver"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Receiver")); err != nil {
+		return err
+	}
+
+	if len(t.Receiver) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.Receiver was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len(t.Receiver))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string(t.Receiver)); err != nil {
+		return err
+	}
+
+	// t.Root (cid.Cid) (struct)
+	if len("Root") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Root\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("Root"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Root")); err != nil {
+		return err
+	}
+
+	if err := cbg.WriteCidBuf(scratch, w, t.Root); err != nil {
+		return xerrors.Errorf("failed to write cid field t.Root: %w", err)
+	}
+
+	// t.SelectorHash ([]uint8) (slice)
+	if len("SelectorHash") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"SelectorHash\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("SelectorHash"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("SelectorHash")); err != nil {
+		return err
+	}
+
+	if len(t.SelectorHash) > cbg.ByteArrayMaxLen {
+		return xerrors.Errorf("Byte array in field t.SelectorHash was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajByteString, uint64(len(t.SelectorHash))); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(t.SelectorHash[:]); err != nil {
+		return err
+	}
+
+	// t.Bytes (uint64) (uint64)
+	if len("Bytes") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Bytes\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("Bytes"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Bytes")); err != nil {
+		return err
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.Bytes)); err != nil {
+		return err
+	}
+
+	// t.Blocks (uint64) (uint64)
+	if len("Blocks") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Blocks\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("Blocks"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Blocks")); err != nil {
+		return err
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.Blocks)); err != nil {
+		return err
+	}
+
+	// t.PublicKey ([]uint8) (slice)
+	if len("PublicKey") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"PublicKey\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("PublicKey"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("PublicKey")); err != nil {
+		return err
+	}
+
+	if len(t.PublicKey) > cbg.ByteArrayMaxLen {
+		return xerrors.Errorf("Byte array in field t.PublicKey was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajByteString, uint64(len(t.PublicKey))); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(t.PublicKey[:]); err != nil {
+		return err
+	}
+
+	// t.Signature ([]uint8) (slice)
+	if len("Signature") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Signature\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("Signature"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Signature")); err != nil {
+		return err
+	}
+
+	if len(t.Signature) > cbg.ByteArrayMaxLen {
+		return xerrors.Errorf("Byte array in field t.Signature was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajByteString, uint64(len(t.Signature))); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(t.Signature[:]); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (t *Receipt) UnmarshalCBOR(r io.Reader) error {
+	*t = Receipt{}
+
+	br := cbg.GetPeeker(r)
+	scratch := make([]byte, 8)
+
+	maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+	if maj != cbg.MajMap {
+		return fmt.Errorf("cbor input should be of type map")
+	}
+
+	if extra > cbg.MaxLength {
+		return fmt.Errorf("Receipt: map struct too large (%d)", extra)
+	}
+
+	var name string
+	n := extra
+
+	for i := uint64(0); i < n; i++ {
+
+		{
+			sval, err := cbg.ReadStringBuf(br, scratch)
+			if err != nil {
+				return err
+			}
+
+			name = string(sval)
+		}
+
+		switch name {
+		// t.ChannelID (datatransfer.ChannelID) (struct)
+		case "ChannelID":
+
+			{
+
+				if err := t.ChannelID.UnmarshalCBOR(br); err != nil {
+					return xerrors.Errorf("unmarshaling t.ChannelID: %w", err)
+				}
+
+			}
+			// t.Receiver (peer.ID) (string)
+		case "Receiver":
+
+			{
+				sval, err := cbg.ReadStringBuf(br, scratch)
+				if err != nil {
+					return err
+				}
+
+				t.Receiver = peer.ID(sval)
+			}
+			// t.Root (cid.Cid) (struct)
+		case "Root":
+
+			{
+
+				c, err := cbg.ReadCid(br)
+				if err != nil {
+					return xerrors.Errorf("failed to read cid field t.Root: %w", err)
+				}
+
+				t.Root = c
+
+			}
+			// t.SelectorHash ([]uint8) (slice)
+		case "SelectorHash":
+
+			maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+			if err != nil {
+				return err
+			}
+
+			if extra > cbg.ByteArrayMaxLen {
+				return fmt.Errorf("t.SelectorHash: byte array too large (%d)", extra)
+			}
+			if maj != cbg.MajByteString {
+				return fmt.Errorf("expected byte array")
+			}
+
+			if extra > 0 {
+				t.SelectorHash = make([]uint8, extra)
+			}
+
+			if _, err := io.ReadFull(br, t.SelectorHash[:]); err != nil {
+				return err
+			}
+			// t.Bytes (uint64) (uint64)
+		case "Bytes":
+
+			{
+
+				maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+				if err != nil {
+					return err
+				}
+				if maj != cbg.MajUnsignedInt {
+					return fmt.Errorf("wrong type for uint64 field")
+				}
+				t.Bytes = uint64(extra)
+
+			}
+			// t.Blocks (uint64) (uint64)
+		case "Blocks":
+
+			{
+
+				maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+				if err != nil {
+					return err
+				}
+				if maj != cbg.MajUnsignedInt {
+					return fmt.Errorf("wrong type for uint64 field")
+				}
+				t.Blocks = uint64(extra)
+
+			}
+			// t.PublicKey ([]uint8) (slice)
+		case "PublicKey":
+
+			maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+			if err != nil {
+				return err
+			}
+
+			if extra > cbg.ByteArrayMaxLen {
+				return fmt.Errorf("t.PublicKey: byte array too large (%d)", extra)
+			}
+			if maj != cbg.MajByteString {
+				return fmt.Errorf("expected byte array")
+			}
+
+			if extra > 0 {
+				t.PublicKey = make([]uint8, extra)
+			}
+
+			if _, err := io.ReadFull(br, t.PublicKey[:]); err != nil {
+				return err
+			}
+			// t.Signature ([]uint8) (slice)
+		case "Signature":
+
+			maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+			if err != nil {
+				return err
+			}
+
+			if extra > cbg.ByteArrayMaxLen {
+				return fmt.Errorf("t.Signature: byte array too large (%d)", extra)
+			}
+			if maj != cbg.MajByteString {
+				return fmt.Errorf("expected byte array")
+			}
+
+			if extra > 0 {
+				t.Signature = make([]uint8, extra)
+			}
+
+			if _, err := io.ReadFull(br, t.Signature[:]); err != nil {
+				return err
+			}
+
+		default:
+			return fmt.Errorf("unknown struct field %d: '%s'", i, name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,176 @@
+package staging
+
+import (
+	"bytes"
+	"io"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	"github.com/ipfs/go-datastore/query"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"golang.org/x/xerrors"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+)
+
+// Store keeps the blocks received for each channel in a scratch area of the
+// datastore, until they are committed to their destination or discarded.
+// Staged blocks are persisted, so a restarted channel can reuse them
+type Store struct {
+	ds datastore.Batching
+}
+
+// New returns a staging store that keeps blocks in the given datastore
+func New(ds datastore.Batching) *Store {
+	return &Store{ds: ds}
+}
+
+func (s *Store) channelDS(chid datatransfer.ChannelID) datastore.Batching {
+	return namespace.Wrap(s.ds, datastore.NewKey(chid.String()))
+}
+
+// Loader returns a loader that reads the blocks staged for the channel, and
+// falls back to the given loader for blocks that are not staged. The fallback
+// may be nil
+func (s *Store) Loader(chid datatransfer.ChannelID, fallback ipld.Loader) ipld.Loader {
+	ds := s.channelDS(chid)
+	return func(lnk ipld.Link, lnkCtx ipld.LinkContext) (io.Reader, error) {
+		c, ok := lnk.(cidlink.Link)
+		if !ok {
+			return nil, xerrors.Errorf("unsupported link type %T", lnk)
+		}
+		data, err := ds.Get(datastore.NewKey(c.Cid.String()))
+		if err == nil {
+			return bytes.NewReader(data), nil
+		}
+		if err != datastore.ErrNotFound || fallback == nil {
+			return nil, err
+		}
+		return fallback(lnk, lnkCtx)
+	}
+}
+
+// Storer returns a storer that stages blocks for the channel
+func (s *Store) Storer(chid datatransfer.ChannelID) ipld.Storer {
+	ds := s.channelDS(chid)
+	return func(lnkCtx ipld.LinkContext) (io.Writer, ipld.StoreCommitter, error) {
+		var buf bytes.Buffer
+		committer := func(lnk ipld.Link) error {
+			c, ok := lnk.(cidlink.Link)
+			if !ok {
+				return xerrors.Errorf("unsupported link type %T", lnk)
+			}
+			return ds.Put(datastore.NewKey(c.Cid.String()), buf.Bytes())
+		}
+		return &buf, committer, nil
+	}
+}
+
+// Destination is where staged blocks are committed. A blockstore is a
+// Destination
+type Destination interface {
+	PutMany([]blocks.Block) error
+}
+
+// Commit writes the blocks staged for the channel to the destination in a
+// single PutMany call, then removes them from the staging area. A blockstore
+// on a batching datastore writes them in one batch, so a commit that fails
+// leaves none of them in the blockstore. Blocks are only removed from the
+// staging area once they have all been written, so the commit can be tried
+// again
+func (s *Store) Commit(chid datatransfer.ChannelID, dest Destination) error {
+	ds := s.channelDS(chid)
+	var staged []blocks.Block
+	keys, err := s.forEach(ds, func(c cid.Cid, data []byte) error {
+		blk, err := blocks.NewBlockWithCid(data, c)
+		if err != nil {
+			return err
+		}
+		staged = append(staged, blk)
+		return nil
+	})
+	if err != nil {
+		return xerrors.Errorf("failed to read staged blocks for channel %s: %w", chid, err)
+	}
+	if len(staged) == 0 {
+		return nil
+	}
+	if err := dest.PutMany(staged); err != nil {
+		return xerrors.Errorf("failed to commit staged blocks for channel %s: %w", chid, err)
+	}
+	return deleteKeys(ds, keys)
+}
+
+// HasBlocks returns true if any blocks are staged for the channel
+func (s *Store) HasBlocks(chid datatransfer.ChannelID) (bool, error) {
+	results, err := s.channelDS(chid).Query(query.Query{KeysOnly: true, Limit: 1})
+	if err != nil {
+		return false, err
+	}
+	defer results.Close()
+	for r := range results.Next() {
+		if r.Error != nil {
+			return false, r.Error
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// Discard removes the blocks staged for the channel
+func (s *Store) Discard(chid datatransfer.ChannelID) error {
+	ds := s.channelDS(chid)
+	keys, err := s.forEach(ds, nil)
+	if err != nil {
+		return err
+	}
+	return deleteKeys(ds, keys)
+}
+
+// forEach calls fn for each staged block in ds, and returns the keys of the
+// blocks
+func (s *Store) forEach(ds datastore.Batching, fn func(cid.Cid, []byte) error) ([]datastore.Key, error) {
+	results, err := ds.Query(query.Query{KeysOnly: fn == nil})
+	if err != nil {
+		return nil, err
+	}
+	defer results.Close()
+
+	var keys []datastore.Key
+	for r := range results.Next() {
+		if r.Error != nil {
+			return nil, r.Error
+		}
+		key := datastore.RawKey(r.Key)
+		if fn != nil {
+			c, err := cid.Decode(key.BaseNamespace())
+			if err != nil {
+				return nil, err
+			}
+			if err := fn(c, r.Value); err != nil {
+				return nil, err
+			}
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func deleteKeys(ds datastore.Batching, keys []datastore.Key) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	batch, err := ds.Batch()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := batch.Delete(key); err != nil {
+			return err
+		}
+	}
+	return batch.Commit()
+}
@@ -0,0 +1,147 @@
+package staging_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+	dss "github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/stretchr/testify/require"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+	"github.com/filecoin-project/go-data-transfer/staging"
+	"github.com/filecoin-project/go-data-transfer/testutil"
+)
+
+func TestStore(t *testing.T) {
+	peers := testutil.GeneratePeers(2)
+	chid := datatransfer.ChannelID{Initiator: peers[0], Responder: peers[1], ID: 1}
+	other := datatransfer.ChannelID{Initiator: peers[0], Responder: peers[1], ID: 2}
+	links := []ipld.Link{cidlink.Link{Cid: testutil.GenerateCids(1)[0]}, cidlink.Link{Cid: testutil.GenerateCids(1)[0]}}
+
+	stage := func(t *testing.T, s *staging.Store, chid datatransfer.ChannelID, lnk ipld.Link, data string) {
+		w, commit, err := s.Storer(chid)(ipld.LinkContext{})
+		require.NoError(t, err)
+		_, err = w.Write([]byte(data))
+		require.NoError(t, err)
+		require.NoError(t, commit(lnk))
+	}
+	load := func(loader ipld.Loader, lnk ipld.Link) (string, error) {
+		r, err := loader(lnk, ipld.LinkContext{})
+		if err != nil {
+			return "", err
+		}
+		data, err := ioutil.ReadAll(r)
+		return string(data), err
+	}
+	newDest := func() blockstore.Blockstore {
+		return blockstore.NewBlockstore(dss.MutexWrap(datastore.NewMapDatastore()))
+	}
+	committed := func(t *testing.T, dest blockstore.Blockstore, lnk ipld.Link) string {
+		blk, err := dest.Get(lnk.(cidlink.Link).Cid)
+		require.NoError(t, err)
+		return string(blk.RawData())
+	}
+
+	t.Run("commits staged blocks", func(t *testing.T) {
+		ds := dss.MutexWrap(datastore.NewMapDatastore())
+		s := staging.New(ds)
+		stage(t, s, chid, links[0], "block 0")
+		stage(t, s, other, links[1], "block 1")
+
+		fallback := func(lnk ipld.Link, _ ipld.LinkContext) (io.Reader, error) {
+			return bytes.NewReader([]byte("fallback")), nil
+		}
+		data, err := load(s.Loader(chid, fallback), links[0])
+		require.NoError(t, err)
+		require.Equal(t, "block 0", data)
+		data, err = load(s.Loader(chid, fallback), links[1])
+		require.NoError(t, err)
+		require.Equal(t, "fallback", data)
+		_, err = load(s.Loader(chid, nil), links[1])
+		require.Equal(t, datastore.ErrNotFound, err)
+
+		// staged blocks survive a restart
+		dest := newDest()
+		require.NoError(t, staging.New(ds).Commit(chid, dest))
+		require.Equal(t, "block 0", committed(t, dest, links[0]))
+		has, err := dest.Has(links[1].(cidlink.Link).Cid)
+		require.NoError(t, err)
+		require.False(t, has)
+		_, err = load(s.Loader(chid, nil), links[0])
+		require.Equal(t, datastore.ErrNotFound, err)
+
+		// other channels are left alone
+		data, err = load(s.Loader(other, nil), links[1])
+		require.NoError(t, err)
+		require.Equal(t, "block 1", data)
+	})
+
+	t.Run("failed commit writes nothing and keeps staged blocks", func(t *testing.T) {
+		s := staging.New(dss.MutexWrap(datastore.NewMapDatastore()))
+		stage(t, s, chid, links[0], "block 0")
+		stage(t, s, chid, links[1], "block 1")
+		failingDS := &failingBatchDatastore{Batching: dss.MutexWrap(datastore.NewMapDatastore())}
+		failing := blockstore.NewBlockstore(failingDS)
+		require.Error(t, s.Commit(chid, failing))
+		for _, lnk := range links {
+			has, err := failing.Has(lnk.(cidlink.Link).Cid)
+			require.NoError(t, err)
+			require.False(t, has)
+		}
+
+		dest := newDest()
+		require.NoError(t, s.Commit(chid, dest))
+		require.Equal(t, "block 0", committed(t, dest, links[0]))
+		require.Equal(t, "block 1", committed(t, dest, links[1]))
+	})
+
+	t.Run("discards staged blocks", func(t *testing.T) {
+		s := staging.New(dss.MutexWrap(datastore.NewMapDatastore()))
+		stage(t, s, chid, links[0], "block 0")
+		stage(t, s, other, links[1], "block 1")
+		staged, err := s.HasBlocks(chid)
+		require.NoError(t, err)
+		require.True(t, staged)
+		require.NoError(t, s.Discard(chid))
+		staged, err = s.HasBlocks(chid)
+		require.NoError(t, err)
+		require.False(t, staged)
+		_, err = load(s.Loader(chid, nil), links[0])
+		require.Equal(t, datastore.ErrNotFound, err)
+		dest := newDest()
+		require.NoError(t, s.Commit(chid, dest))
+		has, err := dest.Has(links[0].(cidlink.Link).Cid)
+		require.NoError(t, err)
+		require.False(t, has)
+		_, err = load(s.Loader(other, nil), links[1])
+		require.NoError(t, err)
+	})
+}
+
+// failingBatchDatastore is a datastore whose batches fail to commit
+type failingBatchDatastore struct {
+	datastore.Batching
+}
+
+func (ds *failingBatchDatastore) Batch() (datastore.Batch, error) {
+	return &failingBatch{}, nil
+}
+
+type failingBatch struct {
+	datastore.Batch
+}
+
+func (b *failingBatch) Put(datastore.Key, []byte) error {
+	return nil
+}
+
+func (b *failingBatch) Commit() error {
+	return errors.New("disk full")
+}
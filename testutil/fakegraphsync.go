@@ -24,7 +24,7 @@ import (
 func matchDtMessage(t *testing.T, extensions []graphsync.ExtensionData) datatransfer.Message {
 	var matchedExtension *graphsync.ExtensionData
 	for _, ext := range extensions {
-		if ext.Name == extension.ExtensionDataTransfer1_1 {
+		if ext.Name == extension.ExtensionDataTransfer1_2 {
 			matchedExtension = &ext
 			break
 		}
@@ -390,8 +390,9 @@ func (fgs *FakeGraphSync) RegisterNetworkErrorListener(listener graphsync.OnNetw
 var _ graphsync.GraphExchange = &FakeGraphSync{}
 
 type fakeBlkData struct {
-	link ipld.Link
-	size uint64
+	link       ipld.Link
+	size       uint64
+	sizeOnWire uint64
 }
 
 func (fbd fakeBlkData) Link() ipld.Link {
@@ -403,14 +404,25 @@ func (fbd fakeBlkData) BlockSize() uint64 {
 }
 
 func (fbd fakeBlkData) BlockSizeOnWire() uint64 {
-	return fbd.size
+	return fbd.sizeOnWire
 }
 
 // NewFakeBlockData returns a fake block that matches the block data interface
 func NewFakeBlockData() graphsync.BlockData {
+	size := rand.Uint64()
+	return &fakeBlkData{
+		link:       cidlink.Link{Cid: GenerateCids(1)[0]},
+		size:       size,
+		sizeOnWire: size,
+	}
+}
+
+// NewFakeUnsentBlockData returns a fake block that was traversed but not sent
+// on the wire
+func NewFakeUnsentBlockData() graphsync.BlockData {
 	return &fakeBlkData{
 		link: cidlink.Link{Cid: GenerateCids(1)[0]},
-		size: rand.Uint64(),
+		size: uint64(rand.Uint32()),
 	}
 }
 
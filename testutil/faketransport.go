@@ -32,6 +32,13 @@ type CustomizedTransfer struct {
 	Voucher   datatransfer.Voucher
 }
 
+// UsedStore records a call to use a store for a channel
+type UsedStore struct {
+	ChannelID datatransfer.ChannelID
+	Loader    ipld.Loader
+	Storer    ipld.Storer
+}
+
 // FakeTransport is a fake transport with mocked results
 type FakeTransport struct {
 	OpenedChannels      []OpenedChannel
@@ -44,6 +51,8 @@ type FakeTransport struct {
 	ResumeChannelErr    error
 	CleanedUpChannels   []datatransfer.ChannelID
 	CustomizedTransfers []CustomizedTransfer
+	UsedStores          []UsedStore
+	UseStoreErr         error
 	EventHandler        datatransfer.EventsHandler
 	SetEventHandlerErr  error
 }
@@ -100,3 +109,9 @@ func (ft *FakeTransport) CleanupChannel(chid datatransfer.ChannelID) {
 func (ft *FakeTransport) RecordCustomizedTransfer(chid datatransfer.ChannelID, voucher datatransfer.Voucher) {
 	ft.CustomizedTransfers = append(ft.CustomizedTransfers, CustomizedTransfer{chid, voucher})
 }
+
+// UseStore records a call to use a loader and storer for a channel
+func (ft *FakeTransport) UseStore(chid datatransfer.ChannelID, loader ipld.Loader, storer ipld.Storer) error {
+	ft.UsedStores = append(ft.UsedStores, UsedStore{chid, loader, storer})
+	return ft.UseStoreErr
+}
@@ -3,6 +3,8 @@ package testutil
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
@@ -10,6 +12,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/ipfs/go-blockservice"
 	"github.com/ipfs/go-datastore"
@@ -33,9 +36,11 @@ import (
 	basicnode "github.com/ipld/go-ipld-prime/node/basic"
 	"github.com/ipld/go-ipld-prime/traversal/selector"
 	"github.com/ipld/go-ipld-prime/traversal/selector/builder"
+	"github.com/libp2p/go-libp2p-core/crypto"
 	"github.com/libp2p/go-libp2p-core/host"
 	"github.com/libp2p/go-libp2p-core/protocol"
 	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	ma "github.com/multiformats/go-multiaddr"
 	"github.com/stretchr/testify/require"
 
 	"github.com/filecoin-project/go-storedcounter"
@@ -60,6 +65,7 @@ const unixfsChunkSize uint64 = 1 << 10
 const unixfsLinksPerLevel = 1024
 
 var extsForProtocol = map[protocol.ID]graphsync.ExtensionName{
+	datatransfer.ProtocolDataTransfer1_2: extension.ExtensionDataTransfer1_2,
 	datatransfer.ProtocolDataTransfer1_1: extension.ExtensionDataTransfer1_1,
 	datatransfer.ProtocolDataTransfer1_0: extension.ExtensionDataTransfer1_0,
 }
@@ -131,11 +137,8 @@ func NewGraphsyncTestingData(ctx context.Context, t *testing.T, host1Protocols [
 
 	// setup network
 	var err error
-	gsData.Host1, err = gsData.Mn.GenPeer()
-	require.NoError(t, err)
-
-	gsData.Host2, err = gsData.Mn.GenPeer()
-	require.NoError(t, err)
+	gsData.Host1 = genPeer(t, gsData.Mn, 1)
+	gsData.Host2 = genPeer(t, gsData.Mn, 2)
 
 	err = gsData.Mn.LinkAll()
 	require.NoError(t, err)
@@ -170,6 +173,18 @@ func NewGraphsyncTestingData(ctx context.Context, t *testing.T, host1Protocols [
 	return gsData
 }
 
+// genPeer adds a host with a real private key to the mock network, so it can
+// sign messages its peers verify
+func genPeer(t *testing.T, mn mocknet.Mocknet, n int) host.Host {
+	sk, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	require.NoError(t, err)
+	a, err := ma.NewMultiaddr(fmt.Sprintf("/ip4/127.0.0.1/tcp/%d", 4000+n))
+	require.NoError(t, err)
+	h, err := mn.AddPeer(sk, a)
+	require.NoError(t, err)
+	return h
+}
+
 // SetupGraphsyncHost1 sets up a new, real graphsync instance on top of the first host
 func (gsData *GraphsyncTestingData) SetupGraphsyncHost1() graphsync.GraphExchange {
 	// setup graphsync
@@ -311,3 +326,24 @@ func VerifyHasFile(ctx context.Context, t *testing.T, dagService ipldformat.DAGS
 	// verify original bytes match final bytes!
 	require.EqualValues(t, fileBytes, finalBytes)
 }
+
+// AssertNoTransportLeaks asserts that the given graphsync transport holds no
+// state for any channel once its channels have terminated, allowing a short
+// time for cleanup to finish
+func AssertNoTransportLeaks(ctx context.Context, t *testing.T, transport datatransfer.Transport) {
+	gsTransport, ok := transport.(*gstransport.Transport)
+	require.True(t, ok, "transport is not a graphsync transport")
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	for {
+		stats := gsTransport.Stats()
+		if stats.Empty() {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			require.Failf(t, "graphsync transport leaked channel state", "%+v", stats)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
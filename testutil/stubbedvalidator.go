@@ -59,6 +59,11 @@ func (sv *StubbedValidator) StubPausePush() {
 	sv.pushError = datatransfer.ErrPause
 }
 
+// StubDeferPush sets ValidatePush to defer acceptance
+func (sv *StubbedValidator) StubDeferPush() {
+	sv.pushError = datatransfer.ErrDeferred
+}
+
 // ExpectErrorPush expects ValidatePush to error
 func (sv *StubbedValidator) ExpectErrorPush() {
 	sv.expectPush = true
@@ -77,6 +82,12 @@ func (sv *StubbedValidator) ExpectPausePush() {
 	sv.StubPausePush()
 }
 
+// ExpectDeferPush expects ValidatePush to defer acceptance
+func (sv *StubbedValidator) ExpectDeferPush() {
+	sv.expectPush = true
+	sv.StubDeferPush()
+}
+
 // StubErrorPull sets ValidatePull to error
 func (sv *StubbedValidator) StubErrorPull() {
 	sv.pullError = errors.New("something went wrong")
@@ -110,6 +121,17 @@ func (sv *StubbedValidator) ExpectPausePull() {
 	sv.StubPausePull()
 }
 
+// StubDeferPull sets ValidatePull to defer acceptance
+func (sv *StubbedValidator) StubDeferPull() {
+	sv.pullError = datatransfer.ErrDeferred
+}
+
+// ExpectDeferPull expects ValidatePull to defer acceptance
+func (sv *StubbedValidator) ExpectDeferPull() {
+	sv.expectPull = true
+	sv.StubDeferPull()
+}
+
 // VerifyExpectations verifies the specified calls were made
 func (sv *StubbedValidator) VerifyExpectations(t *testing.T) {
 	if sv.expectPush {
@@ -0,0 +1,628 @@
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+	"github.com/filecoin-project/go-data-transfer/message"
+)
+
+// TransportPair is a pair of transports on connected peers, for the transport
+// conformance suite. Neither transport has an event handler set yet
+type TransportPair struct {
+	Sender       datatransfer.Transport
+	SenderPeer   peer.ID
+	Receiver     datatransfer.Transport
+	ReceiverPeer peer.ID
+	// Root and Selector select a DAG of several blocks that the sender holds
+	// and the receiver does not
+	Root     ipld.Link
+	Selector ipld.Node
+}
+
+// TransportFactory makes a new pair of transports for each conformance test
+type TransportFactory func(ctx context.Context, t *testing.T) TransportPair
+
+// RunTransportConformance checks that the transports made by the factory
+// meet the contract the data transfer manager relies on:
+// - channels opened for a push or a pull move all the data and complete on
+// both sides, with the EventsHandler callbacks in order
+// - closed channels do not complete, and cleaned up channels are forgotten
+// - blocks in doNotSendCids are not queued for sending
+// - the pause and resume contract of PauseableTransport, including ErrPause
+// and ErrResume returned by the callbacks, if both transports implement it
+//
+// Callbacks that must not happen, such as data moving on a paused channel or
+// after a channel completes, are caught as the transports make them, and
+// reported once both transports have shut down
+func RunTransportConformance(t *testing.T, newTransports TransportFactory) {
+	testCases := map[string]struct {
+		pauseable bool
+		run       func(t *testing.T, c *conformanceTest)
+	}{
+		"event handler must be set once": {
+			run: func(t *testing.T, c *conformanceTest) {
+				chid := c.channelID(true)
+				msg := c.pullRequest(t, chid)
+				err := c.pair.Receiver.OpenChannel(c.ctx, c.pair.SenderPeer, chid, c.pair.Root, c.pair.Selector, nil, msg)
+				require.True(t, xerrors.Is(err, datatransfer.ErrHandlerNotSet))
+				err = c.pair.Receiver.CloseChannel(c.ctx, chid)
+				require.True(t, xerrors.Is(err, datatransfer.ErrHandlerNotSet))
+
+				c.setEventHandlers(t)
+				err = c.pair.Receiver.SetEventHandler(c.receiver)
+				require.True(t, xerrors.Is(err, datatransfer.ErrHandlerAlreadySet))
+			},
+		},
+		"pull moves all the data": {
+			run: func(t *testing.T, c *conformanceTest) {
+				chid := c.pull(t, nil)
+				c.awaitCompletion(t, chid)
+				c.requireCompletedInOrder(t, chid)
+
+				receiverEvents := c.receiver.forChannel(chid)
+				require.Equal(t, onChannelOpened, receiverEvents[0].name)
+				responseIndex := indexOf(receiverEvents, onResponseReceived)
+				require.NotEqual(t, -1, responseIndex, "receiver did not get the response")
+				require.Less(t, responseIndex, indexOf(receiverEvents, onDataReceived), "receiver got data before the response")
+
+				senderEvents := c.sender.forChannel(chid)
+				require.Equal(t, onRequestReceived, senderEvents[0].name)
+				require.Equal(t, -1, indexOf(senderEvents, onChannelOpened), "sender opened the channel")
+			},
+		},
+		"push moves all the data": {
+			run: func(t *testing.T, c *conformanceTest) {
+				chid := c.push(t, nil)
+				c.awaitCompletion(t, chid)
+				c.requireCompletedInOrder(t, chid)
+
+				receiverEvents := c.receiver.forChannel(chid)
+				require.Equal(t, onChannelOpened, receiverEvents[0].name)
+				require.Equal(t, -1, indexOf(receiverEvents, onRequestReceived), "receiver got a request")
+
+				senderEvents := c.sender.forChannel(chid)
+				require.Equal(t, onResponseReceived, senderEvents[0].name)
+				require.Equal(t, -1, indexOf(senderEvents, onChannelOpened), "sender opened the channel")
+			},
+		},
+		"closed channels do not complete": {
+			run: func(t *testing.T, c *conformanceTest) {
+				// hold the first block until the receiver closes the channel
+				release := make(chan struct{})
+				c.sender.dataQueued = func(chid datatransfer.ChannelID, link ipld.Link, size uint64) (datatransfer.Message, error) {
+					select {
+					case <-c.ctx.Done():
+					case <-release:
+					}
+					return nil, nil
+				}
+				chid := c.pull(t, nil)
+				c.await(t, c.sender, chid, "sender to queue data", hasEvent(onDataQueued))
+				c.receiver.close(chid)
+				require.NoError(t, c.pair.Receiver.CloseChannel(c.ctx, chid))
+				close(release)
+			},
+		},
+		"unknown channels cannot be closed": {
+			run: func(t *testing.T, c *conformanceTest) {
+				c.setEventHandlers(t)
+				require.Error(t, c.pair.Receiver.CloseChannel(c.ctx, c.channelID(true)))
+				require.Error(t, c.pair.Sender.CloseChannel(c.ctx, c.channelID(true)))
+			},
+		},
+		"cleaned up channels are forgotten": {
+			run: func(t *testing.T, c *conformanceTest) {
+				chid := c.pull(t, nil)
+				c.awaitCompletion(t, chid)
+				c.pair.Receiver.CleanupChannel(chid)
+				c.pair.Sender.CleanupChannel(chid)
+				require.Error(t, c.pair.Receiver.CloseChannel(c.ctx, chid))
+				require.Error(t, c.pair.Sender.CloseChannel(c.ctx, chid))
+			},
+		},
+		"blocks in doNotSendCids are not queued": {
+			run: func(t *testing.T, c *conformanceTest) {
+				first := c.pull(t, nil)
+				c.awaitCompletion(t, first)
+				var doNotSendCids []cid.Cid
+				for _, link := range dataLinks(c.receiver.forChannel(first), onDataReceived) {
+					doNotSendCids = append(doNotSendCids, link.(cidlink.Link).Cid)
+				}
+
+				// the receiver holds every block now, so the sender queues none
+				// of them
+				second := c.pull(t, doNotSendCids)
+				c.awaitCompletion(t, second)
+				c.requireCompletedInOrder(t, second)
+				require.Empty(t, dataLinks(c.sender.forChannel(second), onDataQueued))
+			},
+		},
+		"ErrPause for a new request holds the data until the sender resumes": {
+			pauseable: true,
+			run: func(t *testing.T, c *conformanceTest) {
+				c.sender.requestReceived = func(chid datatransfer.ChannelID, request datatransfer.Request) (datatransfer.Response, error) {
+					response, err := acceptRequest(chid, request)
+					if err != nil {
+						return nil, err
+					}
+					c.sender.pause(chid)
+					return response, datatransfer.ErrPause
+				}
+				chid := c.pull(t, nil)
+				c.await(t, c.sender, chid, "sender to get the request", hasEvent(onRequestReceived))
+				c.resume(t, c.sender, nil, chid)
+				c.awaitCompletion(t, chid)
+				c.requireCompletedInOrder(t, chid)
+			},
+		},
+		"ErrPause from OnDataQueued pauses the sender until it resumes": {
+			pauseable: true,
+			run: func(t *testing.T, c *conformanceTest) {
+				var once sync.Once
+				c.sender.dataQueued = func(chid datatransfer.ChannelID, link ipld.Link, size uint64) (datatransfer.Message, error) {
+					err := error(nil)
+					once.Do(func() {
+						c.sender.pause(chid)
+						err = datatransfer.ErrPause
+					})
+					return nil, err
+				}
+				chid := c.pull(t, nil)
+				c.await(t, c.sender, chid, "sender to queue data", hasEvent(onDataQueued))
+				c.resume(t, c.sender, nil, chid)
+				c.awaitCompletion(t, chid)
+				c.requireCompletedInOrder(t, chid)
+			},
+		},
+		"ErrPause from OnDataReceived pauses the receiver until it resumes": {
+			pauseable: true,
+			run: func(t *testing.T, c *conformanceTest) {
+				var once sync.Once
+				c.receiver.dataReceived = func(chid datatransfer.ChannelID, link ipld.Link, size uint64) error {
+					err := error(nil)
+					once.Do(func() {
+						c.receiver.pause(chid)
+						err = datatransfer.ErrPause
+					})
+					return err
+				}
+				chid := c.pull(t, nil)
+				c.await(t, c.receiver, chid, "receiver to get data", hasEvent(onDataReceived))
+				c.resume(t, c.receiver, message.UpdateRequest(chid.ID, false), chid)
+				c.awaitCompletion(t, chid)
+				c.requireCompletedInOrder(t, chid)
+			},
+		},
+		"ErrResume for an update request does not fail the channel": {
+			pauseable: true,
+			run: func(t *testing.T, c *conformanceTest) {
+				// the sender pauses on the first block and sends the receiver
+				// an update request, which the receiver answers with ErrResume
+				var once sync.Once
+				c.sender.dataQueued = func(chid datatransfer.ChannelID, link ipld.Link, size uint64) (datatransfer.Message, error) {
+					var msg datatransfer.Message
+					err := error(nil)
+					once.Do(func() {
+						msg = message.UpdateRequest(chid.ID, true)
+						err = datatransfer.ErrPause
+					})
+					return msg, err
+				}
+				c.receiver.requestReceived = func(chid datatransfer.ChannelID, request datatransfer.Request) (datatransfer.Response, error) {
+					return message.UpdateResponse(chid.ID, false), datatransfer.ErrResume
+				}
+				chid := c.push(t, nil)
+				c.await(t, c.receiver, chid, "receiver to get the update", hasEvent(onRequestReceived))
+				c.await(t, c.sender, chid, "sender to get the update response", func(events []transportEvent) bool {
+					return count(events, onResponseReceived) == 2
+				})
+
+				require.NoError(t, c.sender.pauseable(t).ResumeChannel(c.ctx, nil, chid))
+				c.awaitCompletion(t, chid)
+				c.requireCompletedInOrder(t, chid)
+			},
+		},
+		"unknown channels cannot be paused": {
+			pauseable: true,
+			run: func(t *testing.T, c *conformanceTest) {
+				c.setEventHandlers(t)
+				require.Error(t, c.receiver.pauseable(t).PauseChannel(c.ctx, c.channelID(true)))
+				require.Error(t, c.sender.pauseable(t).PauseChannel(c.ctx, c.channelID(true)))
+			},
+		},
+	}
+	for testCase, data := range testCases {
+		t.Run(testCase, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			pair := newTransports(ctx, t)
+			if data.pauseable {
+				_, senderPauses := pair.Sender.(datatransfer.PauseableTransport)
+				_, receiverPauses := pair.Receiver.(datatransfer.PauseableTransport)
+				if !senderPauses || !receiverPauses {
+					t.Skip("transport cannot pause channels")
+				}
+			}
+			c := &conformanceTest{
+				ctx:      ctx,
+				pair:     pair,
+				sender:   newRecordedEvents(pair.Sender, onDataQueued),
+				receiver: newRecordedEvents(pair.Receiver, onDataReceived),
+				nextID:   datatransfer.TransferID(rand.Int31()),
+			}
+			data.run(t, c)
+			require.NoError(t, pair.Receiver.Shutdown(ctx))
+			require.NoError(t, pair.Sender.Shutdown(ctx))
+			require.Empty(t, c.sender.violations(), "sender made callbacks it must not make")
+			require.Empty(t, c.receiver.violations(), "receiver made callbacks it must not make")
+		})
+	}
+}
+
+type conformanceTest struct {
+	ctx         context.Context
+	pair        TransportPair
+	sender      *recordedEvents
+	receiver    *recordedEvents
+	handlersSet bool
+	nextID      datatransfer.TransferID
+}
+
+// channelID returns the ID for a new channel
+func (c *conformanceTest) channelID(isPull bool) datatransfer.ChannelID {
+	id := c.nextID
+	c.nextID++
+	if isPull {
+		return datatransfer.ChannelID{Initiator: c.pair.ReceiverPeer, Responder: c.pair.SenderPeer, ID: id}
+	}
+	return datatransfer.ChannelID{Initiator: c.pair.SenderPeer, Responder: c.pair.ReceiverPeer, ID: id}
+}
+
+func (c *conformanceTest) setEventHandlers(t *testing.T) {
+	if c.handlersSet {
+		return
+	}
+	require.NoError(t, c.pair.Sender.SetEventHandler(c.sender))
+	require.NoError(t, c.pair.Receiver.SetEventHandler(c.receiver))
+	c.handlersSet = true
+}
+
+func (c *conformanceTest) pullRequest(t *testing.T, chid datatransfer.ChannelID) datatransfer.Request {
+	voucher := NewFakeDTType()
+	request, err := message.NewRequest(chid.ID, false, true, voucher.Type(), voucher, c.pair.Root.(cidlink.Link).Cid, c.pair.Selector)
+	require.NoError(t, err)
+	return request
+}
+
+// pull opens a channel for the receiver to pull the DAG from the sender
+func (c *conformanceTest) pull(t *testing.T, doNotSendCids []cid.Cid) datatransfer.ChannelID {
+	c.setEventHandlers(t)
+	chid := c.channelID(true)
+	msg := c.pullRequest(t, chid)
+	require.NoError(t, c.pair.Receiver.OpenChannel(c.ctx, c.pair.SenderPeer, chid, c.pair.Root, c.pair.Selector, doNotSendCids, msg))
+	return chid
+}
+
+// push opens a channel for the sender to push the DAG to the receiver, as
+// the receiver does once it accepts a push request
+func (c *conformanceTest) push(t *testing.T, doNotSendCids []cid.Cid) datatransfer.ChannelID {
+	c.setEventHandlers(t)
+	chid := c.channelID(false)
+	msg, err := message.NewResponse(chid.ID, true, false, datatransfer.EmptyTypeIdentifier, nil)
+	require.NoError(t, err)
+	require.NoError(t, c.pair.Receiver.OpenChannel(c.ctx, c.pair.SenderPeer, chid, c.pair.Root, c.pair.Selector, doNotSendCids, msg))
+	return chid
+}
+
+// await waits until the events recorded for the channel meet the condition
+func (c *conformanceTest) await(t *testing.T, events *recordedEvents, chid datatransfer.ChannelID, desc string, cond func([]transportEvent) bool) {
+	for !cond(events.forChannel(chid)) {
+		select {
+		case <-c.ctx.Done():
+			require.FailNowf(t, "timed out", "waiting for %s on channel %s", desc, chid)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// resume resumes a channel that the given side paused. The transport may only
+// accept the resume once the pause takes effect, so it is retried until then
+func (c *conformanceTest) resume(t *testing.T, events *recordedEvents, msg datatransfer.Message, chid datatransfer.ChannelID) {
+	transport := events.pauseable(t)
+	for {
+		// the channel may move data as soon as the resume takes effect,
+		// which can be before ResumeChannel returns
+		events.unpause(chid)
+		err := transport.ResumeChannel(c.ctx, msg, chid)
+		if err == nil {
+			return
+		}
+		events.pause(chid)
+		select {
+		case <-c.ctx.Done():
+			require.FailNowf(t, "timed out", "resuming channel %s: %s", chid, err)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// awaitCompletion waits until both sides report the channel completed
+func (c *conformanceTest) awaitCompletion(t *testing.T, chid datatransfer.ChannelID) {
+	c.await(t, c.receiver, chid, "receiver to complete", hasEvent(onChannelCompleted))
+	c.await(t, c.sender, chid, "sender to complete", hasEvent(onChannelCompleted))
+}
+
+// requireCompletedInOrder checks that both sides completed the channel
+// successfully, and that no block was sent before it was queued. Completing
+// again or moving data after completing is caught as it happens
+func (c *conformanceTest) requireCompletedInOrder(t *testing.T, chid datatransfer.ChannelID) {
+	for side, events := range map[string][]transportEvent{
+		"sender":   c.sender.forChannel(chid),
+		"receiver": c.receiver.forChannel(chid),
+	} {
+		completed := indexOf(events, onChannelCompleted)
+		require.NotEqual(t, -1, completed, "%s did not complete", side)
+		require.True(t, events[completed].success, "%s did not complete successfully", side)
+	}
+
+	sent := make(map[string]struct{})
+	for _, event := range c.sender.forChannel(chid) {
+		if event.size == 0 {
+			continue
+		}
+		switch event.name {
+		case onDataQueued:
+			_, ok := sent[event.link.String()]
+			require.False(t, ok, "block %s sent before it was queued", event.link)
+		case onDataSent:
+			sent[event.link.String()] = struct{}{}
+		}
+	}
+}
+
+const (
+	onChannelOpened    = "OnChannelOpened"
+	onResponseReceived = "OnResponseReceived"
+	onDataReceived     = "OnDataReceived"
+	onDataQueued       = "OnDataQueued"
+	onDataSent         = "OnDataSent"
+	onRequestReceived  = "OnRequestReceived"
+	onChannelCompleted = "OnChannelCompleted"
+	onRequestTimedOut  = "OnRequestTimedOut"
+	onDisconnected     = "OnRequestDisconnected"
+)
+
+// transportEvent is a call a transport made to its event handler
+type transportEvent struct {
+	name    string
+	chid    datatransfer.ChannelID
+	link    ipld.Link
+	size    uint64
+	success bool
+}
+
+// recordedEvents is an event handler that records each call, and answers
+// with the functions a test sets, or accepts everything if they are not set.
+// It also records a violation for each call the transport must not make:
+// moving data on a channel this side paused, completing a channel this side
+// paused or closed, or moving data on or completing a channel again after it
+// completed
+type recordedEvents struct {
+	transport datatransfer.Transport
+	// dataEvent is the callback that moves data on this side, which must
+	// not happen while this side has the channel paused
+	dataEvent string
+
+	lk        sync.Mutex
+	events    []transportEvent
+	paused    map[datatransfer.ChannelID]struct{}
+	closed    map[datatransfer.ChannelID]struct{}
+	completed map[datatransfer.ChannelID]struct{}
+	violated  []string
+
+	requestReceived  func(chid datatransfer.ChannelID, request datatransfer.Request) (datatransfer.Response, error)
+	responseReceived func(chid datatransfer.ChannelID, response datatransfer.Response) error
+	dataReceived     func(chid datatransfer.ChannelID, link ipld.Link, size uint64) error
+	dataQueued       func(chid datatransfer.ChannelID, link ipld.Link, size uint64) (datatransfer.Message, error)
+}
+
+var _ datatransfer.EventsHandler = (*recordedEvents)(nil)
+
+func newRecordedEvents(transport datatransfer.Transport, dataEvent string) *recordedEvents {
+	return &recordedEvents{
+		transport: transport,
+		dataEvent: dataEvent,
+		paused:    make(map[datatransfer.ChannelID]struct{}),
+		closed:    make(map[datatransfer.ChannelID]struct{}),
+		completed: make(map[datatransfer.ChannelID]struct{}),
+	}
+}
+
+func (re *recordedEvents) record(event transportEvent) {
+	re.lk.Lock()
+	defer re.lk.Unlock()
+	re.events = append(re.events, event)
+
+	_, paused := re.paused[event.chid]
+	_, closed := re.closed[event.chid]
+	_, completed := re.completed[event.chid]
+	movesData := event.name == onDataReceived || event.name == onDataQueued || event.name == onDataSent
+	switch {
+	case paused && event.name == re.dataEvent:
+		re.violation("%s on paused channel %s", event.name, event.chid)
+	case (paused || closed) && event.name == onChannelCompleted:
+		re.violation("%s on paused or closed channel %s", event.name, event.chid)
+	case completed && (movesData || event.name == onChannelCompleted):
+		re.violation("%s on channel %s after it completed", event.name, event.chid)
+	}
+	if event.name == onChannelCompleted {
+		re.completed[event.chid] = struct{}{}
+	}
+}
+
+func (re *recordedEvents) violation(format string, args ...interface{}) {
+	re.violated = append(re.violated, fmt.Sprintf(format, args...))
+}
+
+// violations returns the calls the transport made that it must not make
+func (re *recordedEvents) violations() []string {
+	re.lk.Lock()
+	defer re.lk.Unlock()
+	return append([]string(nil), re.violated...)
+}
+
+// pause records that this side paused the channel
+func (re *recordedEvents) pause(chid datatransfer.ChannelID) {
+	re.lk.Lock()
+	defer re.lk.Unlock()
+	re.paused[chid] = struct{}{}
+}
+
+// unpause records that this side is resuming the channel
+func (re *recordedEvents) unpause(chid datatransfer.ChannelID) {
+	re.lk.Lock()
+	defer re.lk.Unlock()
+	delete(re.paused, chid)
+}
+
+// close records that this side is closing the channel
+func (re *recordedEvents) close(chid datatransfer.ChannelID) {
+	re.lk.Lock()
+	defer re.lk.Unlock()
+	re.closed[chid] = struct{}{}
+}
+
+// forChannel returns the events recorded for the channel, in order
+func (re *recordedEvents) forChannel(chid datatransfer.ChannelID) []transportEvent {
+	re.lk.Lock()
+	defer re.lk.Unlock()
+	var events []transportEvent
+	for _, event := range re.events {
+		if event.chid == chid {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func (re *recordedEvents) pauseable(t *testing.T) datatransfer.PauseableTransport {
+	transport, ok := re.transport.(datatransfer.PauseableTransport)
+	require.True(t, ok, "transport cannot pause channels")
+	return transport
+}
+
+func (re *recordedEvents) OnChannelOpened(chid datatransfer.ChannelID) error {
+	re.record(transportEvent{name: onChannelOpened, chid: chid})
+	return nil
+}
+
+func (re *recordedEvents) OnResponseReceived(chid datatransfer.ChannelID, response datatransfer.Response) error {
+	re.record(transportEvent{name: onResponseReceived, chid: chid})
+	if re.responseReceived != nil {
+		return re.responseReceived(chid, response)
+	}
+	return nil
+}
+
+func (re *recordedEvents) OnDataReceived(chid datatransfer.ChannelID, link ipld.Link, size uint64) error {
+	re.record(transportEvent{name: onDataReceived, chid: chid, link: link, size: size})
+	if re.dataReceived != nil {
+		return re.dataReceived(chid, link, size)
+	}
+	return nil
+}
+
+func (re *recordedEvents) OnDataQueued(chid datatransfer.ChannelID, link ipld.Link, size uint64) (datatransfer.Message, error) {
+	re.record(transportEvent{name: onDataQueued, chid: chid, link: link, size: size})
+	if re.dataQueued != nil {
+		return re.dataQueued(chid, link, size)
+	}
+	return nil, nil
+}
+
+func (re *recordedEvents) OnDataSent(chid datatransfer.ChannelID, link ipld.Link, size uint64) error {
+	re.record(transportEvent{name: onDataSent, chid: chid, link: link, size: size})
+	return nil
+}
+
+func (re *recordedEvents) OnRequestReceived(chid datatransfer.ChannelID, request datatransfer.Request) (datatransfer.Response, error) {
+	re.record(transportEvent{name: onRequestReceived, chid: chid})
+	if re.requestReceived != nil {
+		return re.requestReceived(chid, request)
+	}
+	return acceptRequest(chid, request)
+}
+
+func (re *recordedEvents) OnChannelCompleted(chid datatransfer.ChannelID, success bool) error {
+	re.record(transportEvent{name: onChannelCompleted, chid: chid, success: success})
+	return nil
+}
+
+func (re *recordedEvents) OnRequestTimedOut(ctx context.Context, chid datatransfer.ChannelID) error {
+	re.record(transportEvent{name: onRequestTimedOut, chid: chid})
+	return nil
+}
+
+func (re *recordedEvents) OnRequestDisconnected(ctx context.Context, chid datatransfer.ChannelID) error {
+	re.record(transportEvent{name: onDisconnected, chid: chid})
+	return nil
+}
+
+// acceptRequest accepts new requests and carries on with any other request
+func acceptRequest(chid datatransfer.ChannelID, request datatransfer.Request) (datatransfer.Response, error) {
+	if !request.IsNew() {
+		return nil, nil
+	}
+	return message.NewResponse(chid.ID, true, false, datatransfer.EmptyTypeIdentifier, nil)
+}
+
+func hasEvent(name string) func([]transportEvent) bool {
+	return func(events []transportEvent) bool {
+		return indexOf(events, name) != -1
+	}
+}
+
+// indexOf returns the index of the first event with the given name, or -1
+func indexOf(events []transportEvent, name string) int {
+	for i, event := range events {
+		if event.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func count(events []transportEvent, name string) int {
+	n := 0
+	for _, event := range events {
+		if event.name == name {
+			n++
+		}
+	}
+	return n
+}
+
+// dataLinks returns the links of the blocks with data in the events with the
+// given name
+func dataLinks(events []transportEvent, name string) []ipld.Link {
+	var links []ipld.Link
+	for _, event := range events {
+		if event.name == name && event.size > 0 {
+			links = append(links, event.link)
+		}
+	}
+	return links
+}
@@ -0,0 +1,142 @@
+package tracing
+
+import (
+	"context"
+	"encoding/binary"
+	"math/rand"
+	"sync"
+)
+
+// RecordedSpan is the record of a span started by a Recorder
+type RecordedSpan struct {
+	Name string
+	// TraceID identifies the trace the span belongs to
+	TraceID uint64
+	// SpanID identifies the span
+	SpanID uint64
+	// ParentID is the SpanID of the span's parent, or zero for a root span
+	ParentID   uint64
+	Attributes map[string]string
+	Errors     []error
+	Ended      bool
+}
+
+// Recorder is a Tracer that records spans in memory, for use in tests
+type Recorder struct {
+	lk    sync.Mutex
+	spans []*recorderSpan
+}
+
+// NewRecorder returns a new, empty recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+type remoteParentKey struct{}
+
+type spanContext struct {
+	traceID uint64
+	spanID  uint64
+}
+
+// Start starts a span as a child of the span carried by the context
+func (r *Recorder) Start(ctx context.Context, name string) (context.Context, Span) {
+	var parent spanContext
+	if recorded, ok := SpanFromContext(ctx).(*recorderSpan); ok {
+		parent = spanContext{recorded.record.TraceID, recorded.record.SpanID}
+	} else if remote, ok := ctx.Value(remoteParentKey{}).(spanContext); ok {
+		parent = remote
+	}
+	traceID := parent.traceID
+	if traceID == 0 {
+		traceID = rand.Uint64()
+	}
+	span := &recorderSpan{record: RecordedSpan{
+		Name:       name,
+		TraceID:    traceID,
+		SpanID:     rand.Uint64(),
+		ParentID:   parent.spanID,
+		Attributes: make(map[string]string),
+	}}
+	r.lk.Lock()
+	r.spans = append(r.spans, span)
+	r.lk.Unlock()
+	return ContextWithSpan(ctx, span), span
+}
+
+// Extract returns a context carrying the remote span described by the given
+// trace context
+func (r *Recorder) Extract(ctx context.Context, traceContext []byte) context.Context {
+	if len(traceContext) != 16 {
+		return ctx
+	}
+	return context.WithValue(ctx, remoteParentKey{}, spanContext{
+		traceID: binary.BigEndian.Uint64(traceContext[:8]),
+		spanID:  binary.BigEndian.Uint64(traceContext[8:]),
+	})
+}
+
+// Spans returns the records of all spans started so far, in the order they
+// were started
+func (r *Recorder) Spans() []RecordedSpan {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	records := make([]RecordedSpan, 0, len(r.spans))
+	for _, span := range r.spans {
+		records = append(records, span.snapshot())
+	}
+	return records
+}
+
+// Named returns the records of the spans with the given name
+func (r *Recorder) Named(name string) []RecordedSpan {
+	var named []RecordedSpan
+	for _, record := range r.Spans() {
+		if record.Name == name {
+			named = append(named, record)
+		}
+	}
+	return named
+}
+
+type recorderSpan struct {
+	lk     sync.Mutex
+	record RecordedSpan
+}
+
+func (s *recorderSpan) SetAttribute(key string, value string) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+	s.record.Attributes[key] = value
+}
+
+func (s *recorderSpan) RecordError(err error) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+	s.record.Errors = append(s.record.Errors, err)
+}
+
+func (s *recorderSpan) TraceContext() []byte {
+	traceContext := make([]byte, 16)
+	binary.BigEndian.PutUint64(traceContext[:8], s.record.TraceID)
+	binary.BigEndian.PutUint64(traceContext[8:], s.record.SpanID)
+	return traceContext
+}
+
+func (s *recorderSpan) End() {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+	s.record.Ended = true
+}
+
+func (s *recorderSpan) snapshot() RecordedSpan {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+	record := s.record
+	record.Attributes = make(map[string]string, len(s.record.Attributes))
+	for key, value := range s.record.Attributes {
+		record.Attributes[key] = value
+	}
+	record.Errors = append([]error(nil), s.record.Errors...)
+	return record
+}
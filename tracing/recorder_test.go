@@ -0,0 +1,55 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-data-transfer/tracing"
+)
+
+func TestRecorder(t *testing.T) {
+	ctx := context.Background()
+	recorder := tracing.NewRecorder()
+
+	rootCtx, root := recorder.Start(ctx, "root")
+	root.SetAttribute("key", "value")
+	_, child := recorder.Start(rootCtx, "child")
+	child.RecordError(errors.New("something went wrong"))
+	child.End()
+
+	spans := recorder.Spans()
+	require.Len(t, spans, 2)
+	require.Equal(t, "root", spans[0].Name)
+	require.Zero(t, spans[0].ParentID)
+	require.Equal(t, map[string]string{"key": "value"}, spans[0].Attributes)
+	require.False(t, spans[0].Ended)
+	require.Equal(t, "child", spans[1].Name)
+	require.Equal(t, spans[0].TraceID, spans[1].TraceID)
+	require.Equal(t, spans[0].SpanID, spans[1].ParentID)
+	require.EqualError(t, spans[1].Errors[0], "something went wrong")
+	require.True(t, spans[1].Ended)
+
+	// spans continue a trace extracted from another peer
+	remote := tracing.NewRecorder()
+	remoteCtx := remote.Extract(ctx, root.TraceContext())
+	_, continued := remote.Start(remoteCtx, "continued")
+	continued.End()
+	root.End()
+	recorded := remote.Named("continued")
+	require.Len(t, recorded, 1)
+	require.Equal(t, spans[0].TraceID, recorded[0].TraceID)
+	require.Equal(t, spans[0].SpanID, recorded[0].ParentID)
+	require.True(t, recorder.Named("root")[0].Ended)
+}
+
+func TestNoopTracer(t *testing.T) {
+	ctx := context.Background()
+	spanCtx, span := tracing.NoopTracer.Start(ctx, "span")
+	require.Equal(t, ctx, spanCtx)
+	require.Nil(t, span.TraceContext())
+	require.Equal(t, ctx, tracing.NoopTracer.Extract(ctx, []byte("trace context")))
+	require.Nil(t, tracing.SpanFromContext(ctx))
+}
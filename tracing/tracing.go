@@ -0,0 +1,64 @@
+// Package tracing defines the tracing abstraction used by the data transfer
+// manager, network and transports, along with a no-op tracer that is used by
+// default and an in-memory recorder for tests
+package tracing
+
+import "context"
+
+// Span is a traced unit of work
+type Span interface {
+	// SetAttribute records a key / value pair on the span
+	SetAttribute(key string, value string)
+	// RecordError records an error on the span
+	RecordError(err error)
+	// TraceContext returns the serialized context of the span, which can be
+	// sent to another peer so that its spans continue the same trace. It
+	// returns nil if the span cannot be propagated
+	TraceContext() []byte
+	// End finishes the span
+	End()
+}
+
+// Tracer starts spans
+type Tracer interface {
+	// Start starts a span as a child of the span carried by the context, if
+	// there is one, and returns a context carrying the new span
+	Start(ctx context.Context, name string) (context.Context, Span)
+	// Extract returns a context carrying the remote span described by a trace
+	// context received from another peer, so spans started from it continue
+	// the remote trace
+	Extract(ctx context.Context, traceContext []byte) context.Context
+}
+
+type spanKey struct{}
+
+// ContextWithSpan returns a context carrying the given span
+func ContextWithSpan(ctx context.Context, span Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the span carried by the context, or nil
+func SpanFromContext(ctx context.Context) Span {
+	span, _ := ctx.Value(spanKey{}).(Span)
+	return span
+}
+
+// NoopTracer is a tracer that records nothing
+var NoopTracer Tracer = noopTracer{}
+
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+func (noopTracer) Extract(ctx context.Context, traceContext []byte) context.Context {
+	return ctx
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key string, value string) {}
+func (noopSpan) RecordError(err error)                 {}
+func (noopSpan) TraceContext() []byte                  { return nil }
+func (noopSpan) End()                                  {}
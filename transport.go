@@ -82,6 +82,8 @@ type Transport interface {
 	// Note: from a data transfer symantic standpoint, it doesn't matter if the
 	// request is push or pull -- OpenChannel is called by the party that is
 	// intending to receive data
+	// The sender does not send or queue the blocks in doNotSendCids, which the
+	// receiver already has
 	OpenChannel(ctx context.Context,
 		dataSender peer.ID,
 		channelID ChannelID,
@@ -113,3 +115,54 @@ type PauseableTransport interface {
 		chid ChannelID,
 	) error
 }
+
+// StoreConfigurableTransport is a transport that can read and write the data
+// for each channel with its own loader and storer, instead of its default
+// store
+type StoreConfigurableTransport interface {
+	Transport
+	// UseStore sets the loader and storer used for the given channel
+	UseStore(chid ChannelID, loader ipld.Loader, storer ipld.Storer) error
+}
+
+// DefaultTransportName is the name of the transport given to the manager
+// when it is created, which moves the data for channels that do not choose
+// another transport
+const DefaultTransportName = "default"
+
+// PeerCapableTransport is a transport that can tell whether a peer supports
+// it. When the manager has several transports, it moves a channel's data with
+// the first one that supports the peer
+type PeerCapableTransport interface {
+	Transport
+	// SupportsPeer returns true if the given peer can move data with this
+	// transport
+	SupportsPeer(ctx context.Context, p peer.ID) bool
+}
+
+// OpenConfig is the configuration for opening a channel
+type OpenConfig struct {
+	// Transport, if set, is the name of the transport that moves the
+	// channel's data
+	Transport string
+}
+
+// OpenOption configures a channel when it is opened
+type OpenOption func(*OpenConfig)
+
+// NewOpenConfig returns the configuration for the given open options
+func NewOpenConfig(options ...OpenOption) OpenConfig {
+	var cfg OpenConfig
+	for _, option := range options {
+		option(&cfg)
+	}
+	return cfg
+}
+
+// UseTransport moves the channel's data with the named transport, instead of
+// the one the manager would choose
+func UseTransport(name string) OpenOption {
+	return func(cfg *OpenConfig) {
+		cfg.Transport = name
+	}
+}
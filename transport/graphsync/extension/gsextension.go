@@ -14,7 +14,9 @@ import (
 )
 
 const (
-	// ExtensionDataTransfer1_1 is the identifier for the current data transfer extension to graphsync
+	// ExtensionDataTransfer1_2 is the identifier for the current data transfer extension to graphsync
+	ExtensionDataTransfer1_2 = graphsync.ExtensionName("fil/data-transfer/1.2")
+	// ExtensionDataTransfer1_1 is the identifier for the 1.1 data transfer extension to graphsync
 	ExtensionDataTransfer1_1 = graphsync.ExtensionName("fil/data-transfer/1.1")
 	// ExtensionDataTransfer1_0 is the identifier for the legacy data transfer extension to graphsync
 	ExtensionDataTransfer1_0 = graphsync.ExtensionName("fil/data-transfer")
@@ -22,6 +24,7 @@ const (
 
 // ProtocolMap maps graphsync extensions to their libp2p protocols
 var ProtocolMap = map[graphsync.ExtensionName]protocol.ID{
+	ExtensionDataTransfer1_2: datatransfer.ProtocolDataTransfer1_2,
 	ExtensionDataTransfer1_1: datatransfer.ProtocolDataTransfer1_1,
 	ExtensionDataTransfer1_0: datatransfer.ProtocolDataTransfer1_0,
 }
@@ -65,22 +68,19 @@ type GsExtended interface {
 //    * nil + error if the extendedData fails to unmarshal
 //    * unmarshaled ExtensionDataTransferData + nil if all goes well
 func GetTransferData(extendedData GsExtended) (datatransfer.Message, error) {
-	extName := ExtensionDataTransfer1_1
-	data, ok := extendedData.Extension(extName)
-	if !ok {
-		extName = ExtensionDataTransfer1_0
-		data, ok = extendedData.Extension(extName)
-		if !ok {
-			return nil, nil
+	for _, extName := range []graphsync.ExtensionName{ExtensionDataTransfer1_2, ExtensionDataTransfer1_1, ExtensionDataTransfer1_0} {
+		data, ok := extendedData.Extension(extName)
+		if ok {
+			return decoders[extName](bytes.NewReader(data))
 		}
 	}
-	reader := bytes.NewReader(data)
-	return decoders[extName](reader)
+	return nil, nil
 }
 
 type decoder func(io.Reader) (datatransfer.Message, error)
 
 var decoders = map[graphsync.ExtensionName]decoder{
+	ExtensionDataTransfer1_2: message.FromNet,
 	ExtensionDataTransfer1_1: message.FromNet,
 	ExtensionDataTransfer1_0: message1_0.FromNet,
 }
@@ -10,6 +10,7 @@ import (
 	"github.com/ipfs/go-graphsync/cidset"
 	logging "github.com/ipfs/go-log/v2"
 	ipld "github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
 	peer "github.com/libp2p/go-libp2p-core/peer"
 	"golang.org/x/xerrors"
 
@@ -31,7 +32,7 @@ type responseProgress struct {
 	maximumSent uint64
 }
 
-var defaultSupportedExtensions = []graphsync.ExtensionName{extension.ExtensionDataTransfer1_1, extension.ExtensionDataTransfer1_0}
+var defaultSupportedExtensions = []graphsync.ExtensionName{extension.ExtensionDataTransfer1_2, extension.ExtensionDataTransfer1_1, extension.ExtensionDataTransfer1_0}
 
 // Option is an option for setting up the graphsync transport
 type Option func(*Transport)
@@ -57,9 +58,13 @@ type Transport struct {
 	requestorCancelledMap map[datatransfer.ChannelID]struct{}
 	pendingExtensions     map[datatransfer.ChannelID][]graphsync.ExtensionData
 	responseProgressMap   map[datatransfer.ChannelID]*responseProgress
+	doNotSendCids         map[datatransfer.ChannelID]*cid.Set
 	stores                map[datatransfer.ChannelID]struct{}
 	supportedExtensions   []graphsync.ExtensionName
 	unregisterFuncs       []graphsync.UnregisterHookFunc
+	// requests tracks the requests this node opened that have not yet
+	// reported how they ended
+	requests sync.WaitGroup
 }
 
 // NewTransport makes a new hooks manager with the given hook events interface
@@ -73,6 +78,7 @@ func NewTransport(peerID peer.ID, gs graphsync.GraphExchange, options ...Option)
 		pendingExtensions:     make(map[datatransfer.ChannelID][]graphsync.ExtensionData),
 		channelIDMap:          make(map[datatransfer.ChannelID]graphsyncKey),
 		responseProgressMap:   make(map[datatransfer.ChannelID]*responseProgress),
+		doNotSendCids:         make(map[datatransfer.ChannelID]*cid.Set),
 		pending:               make(map[datatransfer.ChannelID]chan struct{}),
 		stores:                make(map[datatransfer.ChannelID]struct{}),
 		supportedExtensions:   defaultSupportedExtensions,
@@ -112,6 +118,7 @@ func (t *Transport) OpenChannel(ctx context.Context,
 	}
 	t.pending[channelID] = make(chan struct{})
 	t.contextCancelMap[channelID] = internalCancel
+	t.requests.Add(1)
 	t.dataLock.Unlock()
 
 	if len(doNotSendCids) != 0 {
@@ -129,7 +136,10 @@ func (t *Transport) OpenChannel(ctx context.Context,
 	}
 	responseChan, errChan := t.gs.Request(internalCtx, dataSender, root, stor, exts...)
 
-	go t.executeGsRequest(ctx, internalCtx, channelID, responseChan, errChan)
+	go func() {
+		defer t.requests.Done()
+		t.executeGsRequest(ctx, internalCtx, channelID, responseChan, errChan)
+	}()
 	return nil
 }
 
@@ -274,11 +284,12 @@ func (t *Transport) CloseChannel(ctx context.Context, chid datatransfer.ChannelI
 		cancelFn()
 		return nil
 	}
-	t.dataLock.Lock()
-	if _, ok := t.requestorCancelledMap[chid]; ok {
+	t.dataLock.RLock()
+	_, requestorCancelled := t.requestorCancelledMap[chid]
+	t.dataLock.RUnlock()
+	if requestorCancelled {
 		return nil
 	}
-	t.dataLock.Unlock()
 	return t.gs.CancelResponse(gsKey.p, gsKey.requestID)
 }
 
@@ -286,10 +297,7 @@ func (t *Transport) CloseChannel(ctx context.Context, chid datatransfer.ChannelI
 // data for the channel
 func (t *Transport) CleanupChannel(chid datatransfer.ChannelID) {
 	t.dataLock.Lock()
-	gsKey, ok := t.channelIDMap[chid]
-	if ok {
-		t.cleanupChannel(chid, gsKey)
-	}
+	t.cleanupChannel(chid)
 	t.dataLock.Unlock()
 }
 
@@ -313,7 +321,8 @@ func (t *Transport) SetEventHandler(events datatransfer.EventsHandler) error {
 	return nil
 }
 
-// Shutdown disconnects a transport interface from graphsync
+// Shutdown disconnects a transport interface from graphsync, and waits for
+// the requests it opened to report how they ended
 func (t *Transport) Shutdown(ctx context.Context) error {
 	for _, unregisterFunc := range t.unregisterFuncs {
 		unregisterFunc()
@@ -323,7 +332,18 @@ func (t *Transport) Shutdown(ctx context.Context) error {
 		cancel()
 	}
 	t.dataLock.RUnlock()
-	return nil
+
+	done := make(chan struct{})
+	go func() {
+		t.requests.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 // UseStore tells the graphsync transport to use the given loader and storer for this channelID
@@ -413,19 +433,30 @@ func (t *Transport) gsBlockSentHook(p peer.ID, request graphsync.RequestData, bl
 }
 
 func (t *Transport) gsOutgoingBlockHook(p peer.ID, request graphsync.RequestData, block graphsync.BlockData, hookActions graphsync.OutgoingBlockHookActions) {
-	t.dataLock.RLock()
+	t.dataLock.Lock()
 	chid, ok := t.graphsyncRequestMap[graphsyncKey{request.ID(), p}]
 	if !ok {
-		t.dataLock.RUnlock()
+		t.dataLock.Unlock()
 		return
 	}
 	rp := t.responseProgressMap[chid]
-	t.dataLock.RUnlock()
 	rp.currentSent += block.BlockSize()
 	if rp.currentSent <= rp.maximumSent {
+		t.dataLock.Unlock()
 		return
 	}
 	rp.maximumSent = rp.currentSent
+	doNotSend := t.doNotSendCids[chid]
+	t.dataLock.Unlock()
+
+	// blocks the requestor asked us not to send are traversed but not sent.
+	// Blocks graphsync dedups within the request are also not sent on the
+	// wire, but they are still queued as before
+	if block.BlockSizeOnWire() == 0 && doNotSend != nil {
+		if link, ok := block.Link().(cidlink.Link); ok && doNotSend.Has(link.Cid) {
+			return
+		}
+	}
 
 	msg, err := t.events.OnDataQueued(chid, block.Link(), block.BlockSize())
 	if err != nil && err != datatransfer.ErrPause {
@@ -489,7 +520,7 @@ func (t *Transport) gsReqRecdHook(p peer.ID, request graphsync.RequestData, hook
 		}
 	}
 
-	if err != nil && err != datatransfer.ErrPause {
+	if err != nil && err != datatransfer.ErrPause && err != datatransfer.ErrResume {
 		hookActions.TerminateWithError(err)
 		return
 	}
@@ -498,6 +529,12 @@ func (t *Transport) gsReqRecdHook(p peer.ID, request graphsync.RequestData, hook
 		hookActions.PauseResponse()
 	}
 
+	doNotSend, err := doNotSendCidSet(request)
+	if err != nil {
+		hookActions.TerminateWithError(err)
+		return
+	}
+
 	t.dataLock.Lock()
 	gsKey := graphsyncKey{request.ID(), p}
 	if _, ok := t.requestorCancelledMap[chid]; ok {
@@ -516,6 +553,11 @@ func (t *Transport) gsReqRecdHook(p peer.ID, request graphsync.RequestData, hook
 	} else {
 		t.responseProgressMap[chid] = &responseProgress{}
 	}
+	if doNotSend != nil {
+		t.doNotSendCids[chid] = doNotSend
+	} else {
+		delete(t.doNotSendCids, chid)
+	}
 	_, ok := t.stores[chid]
 	if ok {
 		hookActions.UsePersistenceOption("data-transfer-" + chid.String())
@@ -544,15 +586,24 @@ func (t *Transport) gsCompletedResponseListener(p peer.ID, request graphsync.Req
 	}
 }
 
-func (t *Transport) cleanupChannel(chid datatransfer.ChannelID, gsKey graphsyncKey) {
+// cleanupChannel removes all data for the channel, including data recorded
+// before the channel was mapped to a graphsync request
+func (t *Transport) cleanupChannel(chid datatransfer.ChannelID) {
+	gsKey, ok := t.channelIDMap[chid]
+	if ok {
+		delete(t.graphsyncRequestMap, gsKey)
+	}
 	delete(t.channelIDMap, chid)
 	delete(t.contextCancelMap, chid)
-	delete(t.pending, chid)
-	delete(t.graphsyncRequestMap, gsKey)
+	if pending, ok := t.pending[chid]; ok {
+		close(pending)
+		delete(t.pending, chid)
+	}
 	delete(t.responseProgressMap, chid)
+	delete(t.doNotSendCids, chid)
 	delete(t.pendingExtensions, chid)
 	delete(t.requestorCancelledMap, chid)
-	_, ok := t.stores[chid]
+	_, ok = t.stores[chid]
 	if ok {
 		err := t.gs.UnregisterPersistenceOption("data-transfer-" + chid.String())
 		if err != nil {
@@ -584,10 +635,15 @@ func (t *Transport) gsRequestUpdatedHook(p peer.ID, request graphsync.RequestDat
 		}
 	}
 
-	if err != nil && err != datatransfer.ErrPause {
+	// a resume rejected while draining leaves the response paused
+	if err != nil && err != datatransfer.ErrPause && err != datatransfer.ErrResume && err != datatransfer.ErrDraining {
 		hookActions.TerminateWithError(err)
+		return
 	}
 
+	if err == datatransfer.ErrResume {
+		hookActions.UnpauseResponse()
+	}
 }
 
 // gsIncomingResponseHook is a graphsync.OnIncomingResponseHook. We use it to pass on responses
@@ -614,11 +670,25 @@ func (t *Transport) gsIncomingResponseHook(p peer.ID, response graphsync.Respons
 		}
 	}
 
-	if err != nil {
+	if err != nil && err != datatransfer.ErrResume {
 		hookActions.TerminateWithError(err)
 	}
 }
 
+// doNotSendCidSet decodes the set of cids the requestor asked not to be sent,
+// if the request has one
+func doNotSendCidSet(request graphsync.RequestData) (*cid.Set, error) {
+	data, ok := request.Extension(graphsync.ExtensionDoNotSendCIDs)
+	if !ok {
+		return nil, nil
+	}
+	set, err := cidset.DecodeCidSet(data)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to decode do not send cids: %w", err)
+	}
+	return set, nil
+}
+
 func (t *Transport) processExtension(chid datatransfer.ChannelID, gsMsg extension.GsExtended, p peer.ID) (datatransfer.Message, error) {
 
 	// if this is a push request the sender is us.
@@ -9,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-graphsync"
 	"github.com/ipfs/go-graphsync/cidset"
 	"github.com/ipld/go-ipld-prime"
@@ -114,6 +115,22 @@ func TestManager(t *testing.T) {
 				require.NoError(t, gsData.incomingResponseHookActions.TerminationError)
 			},
 		},
+		"outgoing gs request with recognized dt request continues when gs response returns resume": {
+			responseConfig: gsResponseConfig{
+				dtIsResponse: true,
+			},
+			events: fakeEvents{
+				OnResponseReceivedErrors: []error{datatransfer.ErrResume},
+			},
+			action: func(gsData *harness) {
+				gsData.outgoingRequestHook()
+				gsData.incomingResponseHOok()
+			},
+			check: func(t *testing.T, events *fakeEvents, gsData *harness) {
+				require.Equal(t, 1, events.OnResponseReceivedCallCount)
+				require.NoError(t, gsData.incomingResponseHookActions.TerminationError)
+			},
+		},
 		"outgoing gs request with recognized dt request cannot receive gs response with dt request": {
 			action: func(gsData *harness) {
 				gsData.outgoingRequestHook()
@@ -456,6 +473,48 @@ func TestManager(t *testing.T) {
 				require.NoError(t, gsData.requestUpdatedHookActions.TerminationError)
 			},
 		},
+		"incoming gs request with recognized dt request resumes response on update with resume": {
+			events: fakeEvents{
+				OnRequestReceivedErrors: []error{nil, datatransfer.ErrResume},
+			},
+			action: func(gsData *harness) {
+				gsData.incomingRequestHook()
+				gsData.requestUpdatedHook()
+			},
+			check: func(t *testing.T, events *fakeEvents, gsData *harness) {
+				require.Equal(t, 2, events.OnRequestReceivedCallCount)
+				require.True(t, gsData.requestUpdatedHookActions.Unpaused)
+				require.NoError(t, gsData.requestUpdatedHookActions.TerminationError)
+			},
+		},
+		"incoming gs request with recognized dt request does not resume response on update with error": {
+			events: fakeEvents{
+				OnRequestReceivedErrors: []error{nil, errors.New("something went wrong")},
+			},
+			action: func(gsData *harness) {
+				gsData.incomingRequestHook()
+				gsData.requestUpdatedHook()
+			},
+			check: func(t *testing.T, events *fakeEvents, gsData *harness) {
+				require.Equal(t, 2, events.OnRequestReceivedCallCount)
+				require.False(t, gsData.requestUpdatedHookActions.Unpaused)
+				require.Error(t, gsData.requestUpdatedHookActions.TerminationError)
+			},
+		},
+		"incoming gs request with recognized dt request is validated when request received returns resume": {
+			events: fakeEvents{
+				OnRequestReceivedErrors: []error{datatransfer.ErrResume},
+			},
+			action: func(gsData *harness) {
+				gsData.incomingRequestHook()
+			},
+			check: func(t *testing.T, events *fakeEvents, gsData *harness) {
+				require.Equal(t, 1, events.OnRequestReceivedCallCount)
+				require.True(t, gsData.incomingRequestHookActions.Validated)
+				require.False(t, gsData.incomingRequestHookActions.Paused)
+				require.NoError(t, gsData.incomingRequestHookActions.TerminationError)
+			},
+		},
 		"incoming gs request with recognized dt request can send message on update": {
 			events: fakeEvents{
 				RequestReceivedResponse: testutil.NewDTResponse(t, datatransfer.TransferID(rand.Uint64())),
@@ -653,8 +712,8 @@ func TestManager(t *testing.T) {
 				requestReceived := gsData.fgs.AssertRequestReceived(gsData.ctx, t)
 
 				ext := requestReceived.Extensions
-				require.Len(t, ext, 3)
-				doNotSend := ext[2]
+				require.Len(t, ext, 4)
+				doNotSend := ext[3]
 
 				name := doNotSend.Name
 				require.Equal(t, graphsync.ExtensionDoNotSendCIDs, name)
@@ -664,6 +723,78 @@ func TestManager(t *testing.T) {
 				require.Equal(t, cs.Len(), 2)
 			},
 		},
+		"stats report the state held for a channel until it is cleaned up": {
+			action: func(gsData *harness) {
+				gsData.incomingRequestHook()
+				gsData.outgoingBlockHook()
+				gsData.requestorCancelledListener()
+				loader := func(ipld.Link, ipld.LinkContext) (io.Reader, error) {
+					return nil, nil
+				}
+				storer := func(ipld.LinkContext) (io.Writer, ipld.StoreCommitter, error) {
+					return nil, nil, nil
+				}
+				_ = gsData.transport.UseStore(datatransfer.ChannelID{ID: gsData.transferID, Responder: gsData.self, Initiator: gsData.other}, loader, storer)
+				_ = gsData.transport.ResumeChannel(gsData.ctx, gsData.incoming, datatransfer.ChannelID{ID: gsData.transferID, Responder: gsData.self, Initiator: gsData.other})
+			},
+			check: func(t *testing.T, events *fakeEvents, gsData *harness) {
+				chid := datatransfer.ChannelID{ID: gsData.transferID, Responder: gsData.self, Initiator: gsData.other}
+				stats := gsData.transport.Stats()
+				require.False(t, stats.Empty())
+				require.Equal(t, 1, stats.GraphsyncRequests)
+				require.Equal(t, 1, stats.ChannelIDs)
+				require.Equal(t, 1, stats.RequestorCancelled)
+				require.Equal(t, 1, stats.PendingExtensions)
+				require.Equal(t, 1, stats.ResponseProgress)
+				require.Equal(t, 1, stats.Stores)
+				require.Equal(t, 0, stats.ContextCancels)
+				require.Equal(t, 0, stats.Pending)
+				require.Equal(t, []ChannelStats{{
+					ChannelID:           chid,
+					HasRequest:          true,
+					RequestID:           gsData.request.ID(),
+					RequestPeer:         gsData.other,
+					RequestorCancelled:  true,
+					PendingExtensions:   3,
+					HasStore:            true,
+					HasResponseProgress: true,
+					CurrentSent:         gsData.block.BlockSize(),
+					MaximumSent:         gsData.block.BlockSize(),
+				}}, stats.Channels)
+
+				gsData.transport.CleanupChannel(chid)
+				stats = gsData.transport.Stats()
+				require.True(t, stats.Empty())
+				require.Empty(t, stats.Channels)
+			},
+		},
+		"cleanup removes an outgoing request that was never mapped": {
+			action: func(gsData *harness) {
+				gsData.fgs.LeaveRequestsOpen()
+				stor, _ := gsData.outgoing.Selector()
+				_ = gsData.transport.OpenChannel(
+					gsData.ctx,
+					gsData.other,
+					datatransfer.ChannelID{ID: gsData.transferID, Responder: gsData.other, Initiator: gsData.self},
+					cidlink.Link{Cid: gsData.outgoing.BaseCid()},
+					stor,
+					nil,
+					gsData.outgoing)
+			},
+			check: func(t *testing.T, events *fakeEvents, gsData *harness) {
+				chid := datatransfer.ChannelID{ID: gsData.transferID, Responder: gsData.other, Initiator: gsData.self}
+				gsData.fgs.AssertRequestReceived(gsData.ctx, t)
+				stats := gsData.transport.Stats()
+				require.Equal(t, 1, stats.Pending)
+				require.Equal(t, 1, stats.ContextCancels)
+				require.Equal(t, []ChannelStats{{ChannelID: chid, Pending: true, Cancellable: true}}, stats.Channels)
+
+				gsData.transport.CleanupChannel(chid)
+				require.True(t, gsData.transport.Stats().Empty())
+				// callers waiting for the request to be mapped are released
+				require.Error(t, gsData.transport.PauseChannel(gsData.ctx, chid))
+			},
+		},
 		"open channel cancels an existing request with the same channel ID": {
 			action: func(gsData *harness) {
 				cids := testutil.GenerateCids(2)
@@ -956,6 +1087,73 @@ func TestManager(t *testing.T) {
 	}
 }
 
+func TestTransportConformance(t *testing.T) {
+	testutil.RunTransportConformance(t, func(ctx context.Context, t *testing.T) testutil.TransportPair {
+		gsData := testutil.NewGraphsyncTestingData(ctx, t, nil, nil)
+		root := gsData.LoadUnixFSFile(t, false)
+		return testutil.TransportPair{
+			Sender:       gsData.SetupGSTransportHost1(),
+			SenderPeer:   gsData.Host1.ID(),
+			Receiver:     gsData.SetupGSTransportHost2(),
+			ReceiverPeer: gsData.Host2.ID(),
+			Root:         root,
+			Selector:     gsData.AllSelector,
+		}
+	})
+}
+
+func TestOutgoingBlocksNotSent(t *testing.T) {
+	peers := testutil.GeneratePeers(2)
+	transferID := datatransfer.TransferID(rand.Uint64())
+	requestID := graphsync.RequestID(rand.Int31())
+	chid := datatransfer.ChannelID{ID: transferID, Initiator: peers[1], Responder: peers[0]}
+	notSent := testutil.NewFakeUnsentBlockData()
+	deduped := testutil.NewFakeUnsentBlockData()
+	sent := testutil.NewFakeBlockData()
+
+	set := cid.NewSet()
+	set.Add(notSent.Link().(cidlink.Link).Cid)
+	doNotSendData, err := cidset.EncodeCidSet(set)
+	require.NoError(t, err)
+	dtConfig := dtConfig{}
+	extensions := dtConfig.extensions(t, transferID)
+	extensions[graphsync.ExtensionDoNotSendCIDs] = doNotSendData
+	request := testutil.NewFakeRequest(requestID, extensions)
+
+	fgs := testutil.NewFakeGraphSync()
+	transport := NewTransport(peers[0], fgs)
+	events := &fakeQueuedEvents{}
+	require.NoError(t, transport.SetEventHandler(events))
+	fgs.IncomingRequestHook(peers[1], request, &testutil.FakeIncomingRequestHookActions{})
+	stats := transport.Stats()
+	require.Equal(t, 1, stats.DoNotSend)
+	require.Len(t, stats.Channels, 1)
+	require.Equal(t, 1, stats.Channels[0].DoNotSendCids)
+
+	for _, block := range []graphsync.BlockData{sent, notSent, deduped} {
+		fgs.OutgoingBlockHook(peers[1], request, block, &testutil.FakeOutgoingBlockHookActions{})
+	}
+	// a block in the do not send set is not queued, but a block graphsync
+	// did not send because it dedups it within the request is still queued
+	require.Equal(t, []ipld.Link{sent.Link(), deduped.Link()}, events.queued)
+	require.Equal(t, sent.BlockSize()+deduped.BlockSize(), events.queuedBytes)
+
+	transport.CleanupChannel(chid)
+	require.True(t, transport.Stats().Empty())
+}
+
+type fakeQueuedEvents struct {
+	fakeEvents
+	queued      []ipld.Link
+	queuedBytes uint64
+}
+
+func (fe *fakeQueuedEvents) OnDataQueued(chid datatransfer.ChannelID, link ipld.Link, size uint64) (datatransfer.Message, error) {
+	fe.queued = append(fe.queued, link)
+	fe.queuedBytes += size
+	return fe.fakeEvents.OnDataQueued(chid, link, size)
+}
+
 type fakeEvents struct {
 	ChannelOpenedChannelID      datatransfer.ChannelID
 	RequestReceivedChannelID    datatransfer.ChannelID
@@ -0,0 +1,152 @@
+package graphsync
+
+import (
+	"sort"
+
+	"github.com/ipfs/go-graphsync"
+	peer "github.com/libp2p/go-libp2p-core/peer"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+)
+
+// ChannelStats is a snapshot of the state the transport holds for a single
+// channel
+type ChannelStats struct {
+	ChannelID datatransfer.ChannelID
+	// HasRequest is true once the channel is mapped to a graphsync request,
+	// in which case RequestID and RequestPeer identify the request
+	HasRequest  bool
+	RequestID   graphsync.RequestID
+	RequestPeer peer.ID
+	// Pending is true while an outgoing request is waiting to be mapped
+	Pending bool
+	// Cancellable is true if the channel has an outgoing request context
+	Cancellable bool
+	// RequestorCancelled is true if the requestor cancelled the graphsync
+	// request for this channel
+	RequestorCancelled bool
+	// PendingExtensions is the number of extensions waiting to be sent when
+	// the requestor sends a new request
+	PendingExtensions int
+	// HasStore is true if a custom loader and storer is registered
+	HasStore bool
+	// DoNotSendCids is the number of blocks the requestor asked not to be
+	// sent on this channel
+	DoNotSendCids int
+	// HasResponseProgress is true if the channel is tracking data sent in
+	// response to a graphsync request, in which case CurrentSent and
+	// MaximumSent are the bytes sent in the current and largest response
+	HasResponseProgress bool
+	CurrentSent         uint64
+	MaximumSent         uint64
+}
+
+// Stats is a snapshot of the internal state of the transport
+type Stats struct {
+	// GraphsyncRequests is the number of graphsync requests mapped to channels
+	GraphsyncRequests int
+	// ChannelIDs is the number of channels mapped to graphsync requests
+	ChannelIDs int
+	// ContextCancels is the number of outgoing request contexts
+	ContextCancels int
+	// Pending is the number of outgoing requests waiting to be mapped
+	Pending int
+	// RequestorCancelled is the number of channels whose requestor cancelled
+	RequestorCancelled int
+	// PendingExtensions is the number of channels with extensions waiting
+	// to be sent
+	PendingExtensions int
+	// ResponseProgress is the number of channels tracking response progress
+	ResponseProgress int
+	// Stores is the number of channels with a custom loader and storer
+	Stores int
+	// DoNotSend is the number of channels with blocks the requestor asked
+	// not to be sent
+	DoNotSend int
+	// Channels holds the state for each channel known to the transport,
+	// ordered by channel ID
+	Channels []ChannelStats
+}
+
+// Empty returns true if the transport holds no state for any channel
+func (s Stats) Empty() bool {
+	return s.GraphsyncRequests == 0 &&
+		s.ChannelIDs == 0 &&
+		s.ContextCancels == 0 &&
+		s.Pending == 0 &&
+		s.RequestorCancelled == 0 &&
+		s.PendingExtensions == 0 &&
+		s.ResponseProgress == 0 &&
+		s.Stores == 0 &&
+		s.DoNotSend == 0
+}
+
+// Stats returns a snapshot of the internal state of the transport
+func (t *Transport) Stats() Stats {
+	t.dataLock.RLock()
+	defer t.dataLock.RUnlock()
+
+	stats := Stats{
+		GraphsyncRequests:  len(t.graphsyncRequestMap),
+		ChannelIDs:         len(t.channelIDMap),
+		ContextCancels:     len(t.contextCancelMap),
+		Pending:            len(t.pending),
+		RequestorCancelled: len(t.requestorCancelledMap),
+		PendingExtensions:  len(t.pendingExtensions),
+		ResponseProgress:   len(t.responseProgressMap),
+		Stores:             len(t.stores),
+		DoNotSend:          len(t.doNotSendCids),
+	}
+
+	channels := make(map[datatransfer.ChannelID]*ChannelStats)
+	channelStats := func(chid datatransfer.ChannelID) *ChannelStats {
+		cs, ok := channels[chid]
+		if !ok {
+			cs = &ChannelStats{ChannelID: chid}
+			channels[chid] = cs
+		}
+		return cs
+	}
+	for _, chid := range t.graphsyncRequestMap {
+		channelStats(chid)
+	}
+	for chid, gsKey := range t.channelIDMap {
+		cs := channelStats(chid)
+		cs.HasRequest = true
+		cs.RequestID = gsKey.requestID
+		cs.RequestPeer = gsKey.p
+	}
+	for chid := range t.contextCancelMap {
+		channelStats(chid).Cancellable = true
+	}
+	for chid := range t.pending {
+		channelStats(chid).Pending = true
+	}
+	for chid := range t.requestorCancelledMap {
+		channelStats(chid).RequestorCancelled = true
+	}
+	for chid, extensions := range t.pendingExtensions {
+		channelStats(chid).PendingExtensions = len(extensions)
+	}
+	for chid, rp := range t.responseProgressMap {
+		cs := channelStats(chid)
+		cs.HasResponseProgress = true
+		cs.CurrentSent = rp.currentSent
+		cs.MaximumSent = rp.maximumSent
+	}
+	for chid := range t.stores {
+		channelStats(chid).HasStore = true
+	}
+	for chid, set := range t.doNotSendCids {
+		channelStats(chid).DoNotSendCids = set.Len()
+	}
+
+	stats.Channels = make([]ChannelStats, 0, len(channels))
+	for _, cs := range channels {
+		stats.Channels = append(stats.Channels, *cs)
+	}
+	sort.Slice(stats.Channels, func(i, j int) bool {
+		return stats.Channels[i].ChannelID.String() < stats.Channels[j].ChannelID.String()
+	})
+	return stats
+}
@@ -2,6 +2,7 @@ package datatransfer
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/ipfs/go-cid"
 	"github.com/ipld/go-ipld-prime"
@@ -132,4 +133,90 @@ type ChannelState interface {
 
 	// Queued returns the number of bytes read from the node and queued for sending
 	Queued() uint64
+
+	// PausedUntil returns when this node will resume a timed pause, or the zero
+	// time if the channel has no timed pause
+	PausedUntil() time.Time
+	// TransportName returns the name of the transport that moves the data for
+	// this channel
+	TransportName() string
+	// RequestSignature returns the initiator's signature over the request
+	// that opened this channel, or nil if the request was not signed
+	RequestSignature() *RequestSignature
+	// SignedRequest returns the part of the request that opened this channel
+	// that the initiator signed, as it was encoded in the request, or nil if
+	// the request was not signed
+	SignedRequest() *SignedRequest
+	// Receipt returns the receipt the receiver signed for the data it
+	// received on this channel once it completed, or nil if there is none.
+	// The sender has the receipt once the receiver sends it
+	Receipt() *Receipt
+}
+
+// Transfer is a handle on a data transfer opened by this node, which tracks
+// the transfer until its channel reaches a final status
+type Transfer interface {
+	// ChannelID returns the ID of the transfer's channel
+	ChannelID() ChannelID
+
+	// Done returns a channel that is closed when the transfer finishes
+	Done() <-chan struct{}
+
+	// Err returns nil if the transfer completed successfully, or an error
+	// wrapping ErrChannelFailed or ErrChannelCancelled if it did not. It
+	// returns nil until the transfer finishes, and ErrManagerStopped if the
+	// manager stops first
+	Err() error
+
+	// State returns the channel state as of when the transfer finished, or
+	// nil until the transfer finishes
+	State() ChannelState
+}
+
+// ChannelResult is the outcome of an operation applied to one of several
+// channels at once
+type ChannelResult struct {
+	// ChannelID is the channel the operation was applied to
+	ChannelID ChannelID
+	// Err is the error the operation returned for the channel, or nil if it
+	// succeeded
+	Err error
+}
+
+// PeerBan describes a peer that is temporarily banned from opening or
+// restarting channels after misbehaving
+type PeerBan struct {
+	// Peer is the banned peer
+	Peer peer.ID
+	// Until is when the ban expires
+	Until time.Time
+	// Strikes is the number of strikes that led to the ban
+	Strikes int
+}
+
+// PeerStats is a scorecard of the outcomes of channels with a peer. Rolling
+// values weight recent channels more heavily than older ones
+type PeerStats struct {
+	// Peer is the peer the stats are for
+	Peer peer.ID
+	// Completed, Failed and Cancelled count the channels with the peer that
+	// ended in each final status
+	Completed uint64
+	Failed    uint64
+	Cancelled uint64
+	// SuccessRate is the rolling fraction of channels that completed, from 0
+	// to 1
+	SuccessRate float64
+	// Throughput is the rolling average rate of completed channels, in bytes
+	// per second
+	Throughput uint64
+	// Disconnects counts the times a channel with the peer was disconnected
+	Disconnects uint64
+	// Restarts counts the times a channel with the peer was restarted
+	Restarts uint64
+	// Stalls counts the disconnects and restarts that happened when no data
+	// had moved since the channel was opened or last restarted
+	Stalls uint64
+	// LastSeen is the time of the last activity on a channel with the peer
+	LastSeen time.Time
 }